@@ -19,10 +19,11 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
+	"github.com/automationbroker/apb/pkg/runner"
 	"github.com/automationbroker/apb/pkg/util"
 	"github.com/automationbroker/bundle-lib/bundle"
-	"github.com/automationbroker/bundle-lib/clients"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	apiv1 "k8s.io/api/core/v1"
@@ -30,6 +31,8 @@ import (
 )
 
 var bindingNamespace string
+var bindingTimeout time.Duration
+var bindingPollInterval time.Duration
 
 var bindingCmd = &cobra.Command{
 	Use:   "binding",
@@ -51,6 +54,8 @@ func init() {
 	rootCmd.AddCommand(bindingCmd)
 	// Binding Add Flags
 	bindingAddCmd.Flags().StringVarP(&bindingNamespace, "namespace", "n", "", "Namespace of binding")
+	bindingAddCmd.Flags().DurationVar(&bindingTimeout, "timeout", time.Minute, "How long to wait for the APB to write the credentials to the secret. Zero reads it once")
+	bindingAddCmd.Flags().DurationVar(&bindingPollInterval, "poll-interval", 2*time.Second, "How often to check the secret for credentials")
 
 	bindingCmd.AddCommand(bindingAddCmd)
 }
@@ -90,7 +95,7 @@ func addBinding(args []string) {
 		Data: data,
 	}
 
-	k8scli, err := clients.Kubernetes()
+	k8scli, err := util.KubernetesClient()
 	if err != nil {
 		panic(err.Error())
 	}
@@ -109,17 +114,12 @@ func addBinding(args []string) {
 
 // ExtractCredentialsAsSecret - Extract credentials from APB as secret in namespace.
 func extractCredentialsAsSecret(podname string, namespace string) ([]byte, error) {
-	k8s, err := clients.Kubernetes()
+	k8s, err := util.KubernetesClient()
 	if err != nil {
 		return nil, fmt.Errorf("Unable to retrieve kubernetes client - [%v]", err)
 	}
 
-	secret, err := k8s.GetSecretData(podname, namespace)
-	if err != nil {
-		return nil, fmt.Errorf("Unable to retrieve secret [%v] - [%v]", podname, err)
-	}
-
-	return secret["fields"], nil
+	return runner.WaitForCredentials(k8s.Client.CoreV1().Secrets(namespace), podname, bindingPollInterval, bindingTimeout)
 }
 
 func buildExtractedCredentials(output []byte) (*bundle.ExtractedCredentials, error) {
@@ -28,8 +28,8 @@ import (
 	"strings"
 
 	"github.com/automationbroker/apb/pkg/config"
-
 	"github.com/automationbroker/apb/pkg/util"
+
 	"github.com/automationbroker/bundle-lib/clients"
 	osb "github.com/pmorie/go-open-service-broker-client/v2"
 	log "github.com/sirupsen/logrus"
@@ -93,7 +93,7 @@ func listBrokerCatalog(brokerRouteName string, brokerNamespace string) {
 	if brokerNamespaceFlag != "" {
 		brokerNamespace = brokerNamespaceFlag
 	}
-	kube, err := clients.Kubernetes()
+	kube, err := util.KubernetesClient()
 	if err != nil {
 		log.Errorf("Failed to connect to cluster: %v", err)
 		return
@@ -148,7 +148,7 @@ func bootstrapBroker(brokerRouteName string, brokerNamespace string) {
 	if brokerNamespaceFlag != "" {
 		brokerNamespace = brokerNamespaceFlag
 	}
-	kube, err := clients.Kubernetes()
+	kube, err := util.KubernetesClient()
 	if err != nil {
 		log.Errorf("Failed to connect to cluster: %v", err)
 		return
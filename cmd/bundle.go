@@ -19,19 +19,24 @@ package cmd
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/automationbroker/apb/pkg/config"
 	"github.com/automationbroker/apb/pkg/runner"
+	"github.com/automationbroker/apb/pkg/tracing"
 	"github.com/automationbroker/apb/pkg/util"
 	"github.com/automationbroker/bundle-lib/bundle"
 	"github.com/automationbroker/bundle-lib/registries"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -68,6 +73,7 @@ var bundleListCmd = &cobra.Command{
 }
 
 var bundleRegistry string
+var listSupports string
 
 var bundleInfoCmd = &cobra.Command{
 	Use:   "info <apb-name>",
@@ -79,41 +85,234 @@ var bundleInfoCmd = &cobra.Command{
 	},
 }
 
+var bundleActionsCmd = &cobra.Command{
+	Use:   "actions <apb-name>",
+	Short: "List the actions supported by an APB",
+	Long:  `Print the actions an APB supports and the parameters which apply to each`,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		showBundleActions(args[0], bundleRegistry)
+	},
+}
+
+var constraintsPlan string
+
+var bundleConstraintsCmd = &cobra.Command{
+	Use:   "constraints <apb-name>",
+	Short: "List the constraints on an APB's parameters",
+	Long:  `Print the type, default and constraints of each parameter of the APB's plans, as they are validated when it is run`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		showPlanConstraints(args[0], bundleRegistry, constraintsPlan)
+	},
+}
+
+var schemaPlan string
+var schemaAction string
+var schemaOutputDir string
+
+var bundleSchemaCmd = &cobra.Command{
+	Use:   "schema <apb-name>",
+	Short: "Print the JSON schema of a plan's parameters",
+	Long:  `Print the JSON schema the parameters of an APB's plan are validated against for provision or bind, or write one file per plan into a directory, for rendering forms which validate as the APB is run`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !writeBundleSchema(args[0]) {
+			os.Exit(1)
+		}
+	},
+}
+
+var bundleCatalogCmd = &cobra.Command{
+	Use:   "catalog",
+	Short: "Print the catalog of APBs as JSON",
+	Long:  `Print the APBs cached from the configured registries, with their plans and each parameter's default, constraints and JSON schema, as a single JSON document for rendering forms`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		printCatalog()
+	},
+}
+
+var specValidateLocal bool
+
+var bundleSpecValidateCmd = &cobra.Command{
+	Use:   "spec-validate <apb-name>",
+	Short: "Check an APB's spec for authoring mistakes",
+	Long:  `Check the plans and parameters of an APB's spec for mistakes which would otherwise surface when it is run, such as enum entries which don't match their parameter's type or parameter names which aren't valid Ansible variable names`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !validateBundleSpec(args[0], bundleRegistry, specValidateLocal) {
+			os.Exit(1)
+		}
+	},
+}
+
+var bundleDescribeInstanceCmd = &cobra.Command{
+	Use:   "describe-instance <instance-id>",
+	Short: "Print what is recorded about a provisioned APB",
+	Long:  `Print the APB, plan, parameters and last action recorded for an instance. The ID is printed when the instance is provisioned, and a unique prefix of it is enough`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		showInstance(args[0])
+	},
+}
+
+var bundleResumeCmd = &cobra.Command{
+	Use:   "resume [pod-name]",
+	Short: "Resume waiting for the APB pod of an earlier run",
+	Long:  `Follow an APB pod created by an earlier run, e.g. one whose apb process died while waiting, as that run would have: waiting for it, printing its result and recording the outcome of its instance. Give the pod's name, or --instance to resume the pod of the instance's last action`,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		resumeBundle(args)
+	},
+}
+
 var bundleNamespace string
 var sandboxRole string
 var kubeConfig string
 var printLogs bool
 var skipParams bool
+var dnsPolicy string
+var imagePullPolicy string
+var dnsNameservers []string
+var dnsSearches []string
+var dnsOptions []string
+var cleanup bool
+var forceCleanup bool
+var localBundle bool
+var dryRun bool
+var validateOnly bool
+var previewParams bool
+var showSecrets bool
+var outputFormat string
+var diffPrevious bool
+var outputDir string
+var serviceClassID string
+var noTUI bool
+var generateNamespace bool
+var createProject bool
+var namespaceLabels []string
+var namespaceAnnotations []string
+var fsGroup int64
+var schedulerName string
+var runID string
+var batchManifest string
+var batchConcurrency int
+var namespaceConcurrency int
+var wait bool
+var waitTimeout time.Duration
+var unschedulableTimeout time.Duration
+var reconnectTimeout time.Duration
+var operationTimeout time.Duration
+var podDeadline time.Duration
+var eventsOut string
+var resultsOut string
+var terminationMessagePath string
+var terminationMessagePolicy string
+var actionTimeouts []string
+var retries int
+var transforms []string
+var logSince time.Duration
+var logTail int64
+var retryExitCodes []int
+var retryBackoff time.Duration
+var parameterValues []string
+var jsonParameterValues []string
+var nonInteractive bool
+var jsonErrors bool
+var planName string
+var defaultPlan string
+var resumeInstanceID string
+var scriptPath string
+var paramsStdin bool
+var paramsFile string
+var account string
+var serviceAccount string
+var clusterName string
+var checkArch bool
+var checkLabels bool
+var cleanupOnInterrupt bool
+var specsConfigMap string
+var specsFile string
+var extraVarsSecret string
+var fromCR string
+var annotateParams []string
+var annotateAllParams bool
+var storeParams bool
+var checkQuota bool
+var podNameTemplate string
+var skipValidation bool
+var remoteSchemaRefs bool
+var remoteSchemaTimeout time.Duration
+var traceRuns bool
+var minimalExtraVars bool
+var extraVarsIndent int
+var planMessage string
+var printCommand bool
+var rememberParams bool
+var resourceRequests []string
+var resourceLimits []string
+var ephemeralStorageRequest string
+var ephemeralStorageLimit string
 
 var bundleProvisionCmd = &cobra.Command{
-	Use:   "provision <apb-name>",
+	Use:   "provision [apb-name]",
 	Short: "Provision APB images",
 	Long:  `Provision an APB from a registry adapter`,
-	Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		executeBundle("provision", args)
+		executeBundle(cmd, "provision", args)
 	},
 }
 
 var bundleDeprovisionCmd = &cobra.Command{
-	Use:   "deprovision <bundle-name>",
+	Use:   "deprovision [bundle-name]",
 	Short: "Deprovision APB images",
 	Long:  `Deprovision an APB from a registry adapter`,
-	Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		executeBundle("deprovision", args)
+		executeBundle(cmd, "deprovision", args)
+	},
+}
+
+var bundleUpdateCmd = &cobra.Command{
+	Use:   "update [apb-name]",
+	Short: "Update APB images",
+	Long:  `Update a provisioned APB, prompting with the parameters it was last run with`,
+	Run: func(cmd *cobra.Command, args []string) {
+		executeBundle(cmd, "update", args)
 	},
 }
 
 var bundleTestCmd = &cobra.Command{
-	Use:   "test <apb-name>",
+	Use:   "test [apb-name]",
 	Short: "test APB images",
 	Long:  `Test an APB from a registry adapter`,
-	Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		pn := executeBundle("test", args)
+		args = selectBundleArgs(args)
+		if len(args) == 0 {
+			os.Exit(1)
+		}
+		pn := executeBundle(cmd, "test", args)
+		if validateOnly || previewParams {
+			// only the parameters were checked or previewed, so there is
+			// no test pod
+			return
+		}
+		if batchManifest != "" {
+			// the batch reported each namespace's pod and exits non-zero
+			// when any failed
+			return
+		}
 		if pn == "" {
 			log.Errorf("Failed to execute bundle")
+			os.Exit(1)
+		}
+		if dryRun {
+			return
+		}
+		// the runner waits for the test pod on --wait and cleanup, and only
+		// succeeds if the test passed
+		if wait || waitTimeout > 0 || len(actionTimeouts) > 0 || cleanup || forceCleanup {
+			fmt.Printf("Test succeeded for bundle [%v]\n", args[0])
 			return
 		}
 		//using bundleNamespace here is safe because executeBundle ensures it's not empty
@@ -123,7 +322,7 @@ var bundleTestCmd = &cobra.Command{
 			return
 		}
 		log.Errorf("Test failed for bundle [%v]. Check the logs for pod [%v] to see what went wrong.", args[0], pn)
-		return
+		os.Exit(1)
 	},
 }
 
@@ -163,6 +362,7 @@ func init() {
 	bundleCmd.AddCommand(bundlePrepareCmd)
 
 	bundleListCmd.Flags().BoolVar(&Refresh, "refresh", false, "refresh list of specs")
+	bundleListCmd.Flags().StringVar(&listSupports, "supports", "", "Only list the APBs which support this action, e.g. bind")
 	rootCmd.AddCommand(createHiddenCmd(bundleListCmd, "running 'apb bundle list'. To list APBs known to a broker, run 'apb broker catalog'"))
 	bundleCmd.AddCommand(bundleListCmd)
 
@@ -170,10 +370,35 @@ func init() {
 	rootCmd.AddCommand(createHiddenCmd(bundleInfoCmd, "running 'apb bundle info'"))
 	bundleCmd.AddCommand(bundleInfoCmd)
 
+	bundleActionsCmd.Flags().StringVarP(&bundleRegistry, "registry", "r", "", "Registry to retrieve APB actions from")
+	rootCmd.AddCommand(createHiddenCmd(bundleActionsCmd, ""))
+	bundleCmd.AddCommand(bundleActionsCmd)
+
+	bundleCmd.AddCommand(bundleDescribeInstanceCmd)
+
+	bundleConstraintsCmd.Flags().StringVarP(&bundleRegistry, "registry", "r", "", "Registry to retrieve the APB from")
+	bundleConstraintsCmd.Flags().StringVar(&constraintsPlan, "plan", "", "Only list the constraints of this plan")
+	bundleCmd.AddCommand(bundleConstraintsCmd)
+
+	bundleCmd.AddCommand(bundleCatalogCmd)
+
+	bundleSchemaCmd.Flags().StringVar(&schemaPlan, "plan", "", "Plan to print the schema of, required for APBs with several plans")
+	bundleSchemaCmd.Flags().StringVar(&schemaAction, "action", "provision", "Action whose parameters the schema is for (provision or bind)")
+	bundleSchemaCmd.Flags().StringVar(&schemaOutputDir, "output-dir", "", "Write the schema of every plan to <plan>.<action>.json in this directory instead")
+	bundleCmd.AddCommand(bundleSchemaCmd)
+
+	bundleSpecValidateCmd.Flags().StringVarP(&bundleRegistry, "registry", "r", "", "Registry to load the APB spec from")
+	bundleSpecValidateCmd.Flags().BoolVar(&specValidateLocal, "local", false, "Validate the apb.yml in the directory given in place of the APB name")
+	bundleSpecValidateCmd.Flags().BoolVar(&jsonErrors, "json-errors", false, "Print the problems found to stderr as JSON")
+	bundleCmd.AddCommand(bundleSpecValidateCmd)
+
 	bundleProvisionCmd.Flags().StringVarP(&bundleNamespace, "namespace", "n", "", "Namespace to provision APB to")
 	bundleProvisionCmd.Flags().StringVarP(&sandboxRole, "sandbox-role", "s", "edit", "ClusterRole to be applied to APB sandbox")
 	bundleProvisionCmd.Flags().StringVarP(&bundleRegistry, "registry", "r", "", "Registry to load APB from")
 	bundleProvisionCmd.Flags().BoolVarP(&printLogs, "follow", "f", false, "Print logs from provision pod")
+	bundleProvisionCmd.Flags().StringVar(&batchManifest, "batch", "", "Manifest of the namespaces to run the APB in, with their parameter overrides")
+	addBatchFlags(bundleProvisionCmd)
+	addRunFlags(bundleProvisionCmd)
 	rootCmd.AddCommand(createHiddenCmd(bundleProvisionCmd, ""))
 	bundleCmd.AddCommand(bundleProvisionCmd)
 
@@ -181,6 +406,7 @@ func init() {
 	bundleTestCmd.Flags().StringVarP(&sandboxRole, "sandbox-role", "s", "edit", "ClusterRole to be applied to APB sandbox")
 	bundleTestCmd.Flags().StringVarP(&bundleRegistry, "registry", "r", "", "Registry to load APB from")
 	bundleTestCmd.Flags().BoolVarP(&printLogs, "follow", "f", false, "Print logs from provision pod")
+	addRunFlags(bundleTestCmd)
 	rootCmd.AddCommand(createHiddenCmd(bundleTestCmd, "running `apb bundle test` instead."))
 	bundleCmd.AddCommand(bundleTestCmd)
 
@@ -189,9 +415,27 @@ func init() {
 	bundleDeprovisionCmd.Flags().StringVarP(&bundleRegistry, "registry", "r", "", "Registry to load APB from")
 	bundleDeprovisionCmd.Flags().BoolVarP(&printLogs, "follow", "f", false, "Print logs from deprovision pod")
 	bundleDeprovisionCmd.Flags().BoolVar(&skipParams, "skip-params", false, "Don't prompt for parameters")
+	bundleDeprovisionCmd.Flags().StringVar(&batchManifest, "batch", "", "Manifest of the namespaces to run the APB in, with their parameter overrides")
+	addBatchFlags(bundleDeprovisionCmd)
+	addRunFlags(bundleDeprovisionCmd)
 	rootCmd.AddCommand(createHiddenCmd(bundleDeprovisionCmd, ""))
 	bundleCmd.AddCommand(bundleDeprovisionCmd)
 
+	bundleUpdateCmd.Flags().StringVarP(&bundleNamespace, "namespace", "n", "", "Namespace of APB to update")
+	bundleUpdateCmd.Flags().StringVarP(&sandboxRole, "sandbox-role", "s", "edit", "ClusterRole to be applied to APB sandbox")
+	bundleUpdateCmd.Flags().StringVarP(&bundleRegistry, "registry", "r", "", "Registry to load APB from")
+	bundleUpdateCmd.Flags().BoolVarP(&printLogs, "follow", "f", false, "Print logs from update pod")
+	bundleUpdateCmd.Flags().StringVar(&batchManifest, "batch", "", "Manifest of the namespaces to run the APB in, with their parameter overrides")
+	addBatchFlags(bundleUpdateCmd)
+	addRunFlags(bundleUpdateCmd)
+	bundleCmd.AddCommand(bundleUpdateCmd)
+
+	bundleResumeCmd.Flags().StringVarP(&bundleNamespace, "namespace", "n", "", "Namespace of the APB pod")
+	bundleResumeCmd.Flags().StringVar(&resumeInstanceID, "instance", "", "Resume the pod of the last action run on this instance instead of a named pod")
+	bundleResumeCmd.Flags().BoolVarP(&printLogs, "follow", "f", false, "Print logs from the APB pod")
+	addWaitFlags(bundleResumeCmd)
+	bundleCmd.AddCommand(bundleResumeCmd)
+
 	rootCmd.AddCommand(bundleInitStub)
 	bundleCmd.AddCommand(bundleInitStub)
 
@@ -205,7 +449,6 @@ func init() {
 // ListImages finds and prints inforomation on bundle images from all the registries
 func ListImages() {
 	var regConfigs []config.Registry
-	var newRegConfigs []config.Registry
 
 	err := config.Registries.UnmarshalKey("Registries", &regConfigs)
 	if err != nil {
@@ -213,9 +456,37 @@ func ListImages() {
 		return
 	}
 
+	newRegConfigs := refreshRegistries(regConfigs, Refresh, false)
+	if listSupports != "" {
+		listed := runner.ListBundles(newRegConfigs, runner.SupportingAction(listSupports))
+		if countSpecs(listed) == 0 {
+			fmt.Printf("No APBs support action [%v]\n", listSupports)
+		} else {
+			printRegConfigSpecs(listed)
+		}
+	} else {
+		printRegConfigSpecs(newRegConfigs)
+	}
+
+	err = config.UpdateCachedRegistries(config.Registries, newRegConfigs)
+	if err != nil {
+		log.Errorf("Error updating cache - %v", err)
+		return
+	}
+}
+
+// refreshRegistries fetches the specs of registries with none cached, with
+// expired or unusable cached specs, or of every registry when force is set
+func refreshRegistries(regConfigs []config.Registry, force bool, quiet bool) []config.Registry {
+	ttl := specCacheTTL()
+	now := time.Now()
+	var newRegConfigs []config.Registry
 	for _, regConfig := range regConfigs {
-		if len(regConfig.Specs) > 0 && Refresh == false {
-			fmt.Printf("Found specs already in registry: [%s]\n", regConfig.Config.Name)
+		cached := len(regConfig.Specs) > 0 && regConfig.SpecsValid()
+		if cached && !force && !regConfig.SpecsExpired(ttl, now) {
+			if !quiet {
+				fmt.Printf("Found specs already in registry: [%s]\n", regConfig.Config.Name)
+			}
 			newRegConfigs = append(newRegConfigs, regConfig)
 			continue
 		}
@@ -223,38 +494,669 @@ func ListImages() {
 		specs, err := getImages(regConfig)
 		if err != nil {
 			log.Errorf("Error getting images - %v", err)
+			if cached {
+				log.Warningf("Using cached specs for registry [%s]", regConfig.Config.Name)
+			}
+			newRegConfigs = append(newRegConfigs, regConfig)
 			continue
 		}
 
 		regConfig.Specs = specs
+		regConfig.SpecsFetched = now.Unix()
 		newRegConfigs = append(newRegConfigs, regConfig)
 	}
-	printRegConfigSpecs(newRegConfigs)
+	return newRegConfigs
+}
 
-	err = config.UpdateCachedRegistries(config.Registries, newRegConfigs)
-	if err != nil {
-		log.Errorf("Error updating cache - %v", err)
+// refreshStaleRegistries refreshes the cached specs which have expired
+// before an APB is run from them
+func refreshStaleRegistries() {
+	var regConfigs []config.Registry
+	if err := config.Registries.UnmarshalKey("Registries", &regConfigs); err != nil {
+		log.Warningf("Failed to read cached specs: %v", err)
 		return
 	}
+	ttl := specCacheTTL()
+	now := time.Now()
+	stale := Refresh
+	for _, regConfig := range regConfigs {
+		if len(regConfig.Specs) == 0 || !regConfig.SpecsValid() || regConfig.SpecsExpired(ttl, now) {
+			stale = true
+		}
+	}
+	if !stale {
+		return
+	}
+	newRegConfigs := refreshRegistries(regConfigs, Refresh, true)
+	if err := config.UpdateCachedRegistries(config.Registries, newRegConfigs); err != nil {
+		log.Warningf("Failed to update cached specs: %v", err)
+	}
 }
 
-func executeBundle(action string, args []string) (podName string) {
+func specCacheTTL() time.Duration {
+	if config.LoadedDefaults.SpecCacheTTL == "" {
+		return 0
+	}
+	ttl, err := time.ParseDuration(config.LoadedDefaults.SpecCacheTTL)
+	if err != nil {
+		log.Warningf("Ignoring invalid SpecCacheTTL [%v]: %v", config.LoadedDefaults.SpecCacheTTL, err)
+		return 0
+	}
+	return ttl
+}
+
+func executeBundle(cmd *cobra.Command, action string, args []string) (podName string) {
+	if validateOnly {
+		validateBundleParameters(action, args)
+		return ""
+	}
+	if previewParams {
+		previewBundleParameters(action, args)
+		return ""
+	}
+	if !connectCluster() {
+		os.Exit(1)
+	}
+	if batchManifest != "" {
+		executeBatch(action, args)
+		return ""
+	}
 	if bundleNamespace == "" {
 		bundleNamespace = util.GetCurrentNamespace(kubeConfig)
 		if bundleNamespace == "" {
 			log.Errorf("Failed to get current namespace. Try supplying it with --namespace.")
-			return ""
+			os.Exit(1)
 		}
 	}
+	args = selectBundleArgs(args)
+	if len(args) == 0 {
+		os.Exit(1)
+	}
 	log.Debugf("Running bundle [%v] with action [%v] in namespace [%v].", args[0], action, bundleNamespace)
-	pn, err := runner.RunBundle(action, bundleNamespace, args[0], sandboxRole, bundleRegistry, printLogs, skipParams, args[1:])
+	opts := runOptions()
+	if scriptPath != "" || printCommand {
+		opts = append(opts, runner.WithRunFlags(reproducedFlags(cmd.Flags())))
+	}
+	if localBundle {
+		opts = append(opts, runner.WithLocalBundle(args[0]))
+	} else if specsConfigMap == "" && specsFile == "" {
+		refreshStaleRegistries()
+	}
+	pn, err := runner.RunBundle(action, bundleNamespace, args[0], sandboxRole, bundleRegistry, printLogs, skipParams, args[1:], opts...)
 	if err != nil {
+		if verrs, ok := err.(runner.ValidationErrors); ok && jsonErrors {
+			printValidationErrors(verrs)
+			os.Exit(1)
+		}
 		log.Errorf("Failed to execute bundle [%v]: %v", args[0], err)
-		return ""
+		os.Exit(1)
 	}
 	return pn
 }
 
+// validateBundleParameters collects and validates the parameters of the
+// action without contacting the cluster or creating the APB pod. It exits
+// zero when they are valid, and non-zero with every problem found when not.
+func validateBundleParameters(action string, args []string) {
+	if batchManifest != "" {
+		log.Errorf("--validate-only can't be used with --batch")
+		os.Exit(1)
+	}
+	if specsConfigMap != "" {
+		log.Errorf("--validate-only can't read specs from a config map on the cluster. Use --specs-file instead.")
+		os.Exit(1)
+	}
+	if len(args) == 0 {
+		log.Errorf("An APB name is required with --validate-only")
+		os.Exit(1)
+	}
+	opts := append(runOptions(), runner.WithValidateOnly())
+	if localBundle {
+		opts = append(opts, runner.WithLocalBundle(args[0]))
+	} else if specsFile == "" {
+		refreshStaleRegistries()
+	}
+	_, err := runner.RunBundle(action, bundleNamespace, args[0], sandboxRole, bundleRegistry, false, skipParams, args[1:], opts...)
+	if err != nil {
+		if verrs, ok := err.(runner.ValidationErrors); ok {
+			if jsonErrors {
+				printValidationErrors(verrs)
+			} else {
+				for _, verr := range verrs {
+					log.Error(verr.Message)
+				}
+			}
+			os.Exit(1)
+		}
+		log.Errorf("Failed to validate the parameters of APB [%v]: %v", args[0], err)
+		os.Exit(1)
+	}
+	fmt.Printf("Parameters of APB [%v] are valid for %v\n", args[0], action)
+}
+
+// previewBundleParameters collects the parameters of the action, prompting
+// for them unless told not to, and prints them and the extra vars the APB
+// would get without contacting the cluster or creating the APB pod
+func previewBundleParameters(action string, args []string) {
+	if batchManifest != "" {
+		log.Errorf("--preview-params can't be used with --batch")
+		os.Exit(1)
+	}
+	if specsConfigMap != "" {
+		log.Errorf("--preview-params can't read specs from a config map on the cluster. Use --specs-file instead.")
+		os.Exit(1)
+	}
+	if bundleNamespace == "" {
+		// the current context is read from the kubeconfig, not the cluster
+		bundleNamespace = util.GetCurrentNamespace(kubeConfig)
+	}
+	args = selectBundleArgs(args)
+	if len(args) == 0 {
+		os.Exit(1)
+	}
+	opts := append(runOptions(), runner.WithParameterPreview(os.Stdout, showSecrets))
+	if localBundle {
+		opts = append(opts, runner.WithLocalBundle(args[0]))
+	} else if specsFile == "" {
+		refreshStaleRegistries()
+	}
+	_, err := runner.RunBundle(action, bundleNamespace, args[0], sandboxRole, bundleRegistry, false, skipParams, args[1:], opts...)
+	if err != nil {
+		if verrs, ok := err.(runner.ValidationErrors); ok && jsonErrors {
+			printValidationErrors(verrs)
+			os.Exit(1)
+		}
+		log.Errorf("Failed to preview the parameters of APB [%v]: %v", args[0], err)
+		os.Exit(1)
+	}
+}
+
+// addBatchFlags adds the flags limiting how many runs of a batch go at once
+func addBatchFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&batchConcurrency, "batch-concurrency", 1, "How many namespaces of the batch to run at once")
+	cmd.Flags().IntVar(&namespaceConcurrency, "namespace-concurrency", 0, "Queue the run in a namespace while this many APB pods are running there. Zero never queues")
+}
+
+// executeBatch runs the bundle's action in each namespace of the batch
+// manifest
+func executeBatch(action string, args []string) {
+	if bundleNamespace != "" {
+		log.Errorf("--namespace can't be used with --batch. List the namespaces in the manifest instead.")
+		os.Exit(1)
+	}
+	if len(args) == 0 {
+		log.Errorf("An APB name is required with --batch")
+		os.Exit(1)
+	}
+	manifest, err := runner.LoadBatchManifest(batchManifest)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	opts := append(runOptions(), runner.WithBatchConcurrency(batchConcurrency), runner.WithNamespaceConcurrency(namespaceConcurrency))
+	if localBundle {
+		opts = append(opts, runner.WithLocalBundle(args[0]))
+	} else if specsConfigMap == "" && specsFile == "" {
+		refreshStaleRegistries()
+	}
+	results, err := runner.RunBatch(action, args[0], sandboxRole, bundleRegistry, printLogs, manifest, opts...)
+	created := "created"
+	if dryRun {
+		created = "generated"
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Printf("%v: failed\n", result.Namespace)
+		} else if result.Queued > 0 {
+			fmt.Printf("%v: %v pod [%v] after queueing for %v\n", result.Namespace, created, result.PodName, result.Queued.Round(time.Second))
+		} else {
+			fmt.Printf("%v: %v pod [%v]\n", result.Namespace, created, result.PodName)
+		}
+	}
+	if err != nil {
+		log.Errorf("Failed to execute bundle [%v]: %v", args[0], err)
+		os.Exit(1)
+	}
+}
+
+// connectCluster connects to the cluster named by --cluster, defaulting the
+// namespace to that of its context. It is a no-op without --cluster.
+func connectCluster() bool {
+	if clusterName == "" {
+		return true
+	}
+	cluster, err := config.LoadedDefaults.FindCluster(clusterName)
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	if err := util.UseCluster(cluster.Kubeconfig, cluster.Context); err != nil {
+		log.Errorf("Failed to connect to cluster [%v]: %v", clusterName, err)
+		return false
+	}
+	if bundleNamespace == "" {
+		bundleNamespace = util.GetContextNamespace(cluster.Kubeconfig, cluster.Context)
+	}
+	return true
+}
+
+// printValidationErrors prints the errors to stderr as a JSON array
+func printValidationErrors(verrs runner.ValidationErrors) {
+	out, err := json.MarshalIndent(verrs, "", "    ")
+	if err != nil {
+		log.Errorf("Failed to marshal validation errors: %v", err)
+		return
+	}
+	fmt.Fprintln(os.Stderr, string(out))
+}
+
+// selectBundleArgs lets the user choose a bundle when none was named
+func selectBundleArgs(args []string) []string {
+	if len(args) > 0 {
+		return args
+	}
+	if fromCR != "" {
+		// the custom resource names the APB
+		return []string{""}
+	}
+	if localBundle {
+		log.Errorf("A directory is required with --local")
+		return nil
+	}
+	if nonInteractive || paramsStdin || paramsFile != "" {
+		log.Errorf("An APB name is required when not running interactively")
+		return nil
+	}
+	bundleName, err := runner.SelectBundle(bundleRegistry, runner.NewSelector(noTUI))
+	if err != nil {
+		log.Errorf("Failed to select an APB: %v", err)
+		return nil
+	}
+	return []string{bundleName}
+}
+
+// addRunFlags adds the flags shared by the commands which run a bundle
+func addRunFlags(cmd *cobra.Command) {
+	addWaitFlags(cmd)
+	cmd.Flags().StringVar(&imagePullPolicy, "image-pull-policy", runner.AutoPullPolicy, "Pull policy of the APB image (Always, IfNotPresent, Never or auto, which pulls tags always and digests only when missing)")
+	cmd.Flags().StringVar(&dnsPolicy, "dns-policy", "", "DNS policy of the APB pod (ClusterFirst, ClusterFirstWithHostNet, Default, None)")
+	cmd.Flags().StringSliceVar(&dnsNameservers, "dns-nameserver", []string{}, "DNS nameservers for the APB pod")
+	cmd.Flags().StringSliceVar(&dnsSearches, "dns-search", []string{}, "DNS search domains for the APB pod")
+	cmd.Flags().StringSliceVar(&dnsOptions, "dns-option", []string{}, "DNS resolver options for the APB pod (e.g. 'ndots:2')")
+	cmd.Flags().BoolVar(&localBundle, "local", false, "Build and run the APB in the local directory given in place of the APB name")
+	cmd.Flags().StringVar(&clusterName, "cluster", "", "Name of a cluster from the Clusters in ~/.apb/defaults.json to run the APB on")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the APB pod instead of creating it")
+	cmd.Flags().BoolVar(&validateOnly, "validate-only", false, "Only collect and validate the parameters, without prompting, and exit non-zero if they are invalid. Never contacts the cluster")
+	cmd.Flags().BoolVar(&previewParams, "preview-params", false, "Only collect the parameters, prompting for them unless running non-interactively, and print them and the extra vars the APB would get. Never contacts the cluster")
+	cmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "With --preview-params, print the values of password parameters instead of redacting them")
+	cmd.Flags().BoolVar(&checkQuota, "check-quota", false, "With --dry-run, report whether the APB pod fits the namespace's resource quotas")
+	cmd.Flags().BoolVar(&diffPrevious, "diff", false, "With --dry-run, print how the APB pod differs from the last pod of the APB and action in the namespace instead of the pod")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "yaml", "Format of the --dry-run output (yaml, json or kustomize)")
+	cmd.Flags().StringVar(&outputDir, "output-dir", "", "Directory to write --dry-run output to instead of printing it. With --batch, each namespace's pod is written to a subdirectory named after it")
+	cmd.Flags().StringVar(&serviceClassID, "service-class-id", "", "Override the _apb_service_class_id passed to the APB, which defaults to the spec ID")
+	cmd.Flags().StringVar(&podNameTemplate, "pod-name-template", "", "Name of the APB pod, using ${bundle}, ${action}, ${namespace}, ${uuid} and ${short-uuid} tokens. Defaults to 'bundle-${uuid}'")
+	cmd.Flags().StringVar(&serviceAccount, "service-account", "", "Existing service account to run the APB pod as, instead of a sandbox one")
+	cmd.Flags().StringVar(&account, "account", "", "Account passed to the APB and labeling its pod, which defaults to the service account name")
+	cmd.Flags().BoolVar(&noTUI, "no-tui", false, "Choose APBs and plans by typing their names instead of from a menu")
+	cmd.Flags().BoolVar(&generateNamespace, "generate-namespace", false, "Run the APB in a new namespace named after it")
+	cmd.Flags().BoolVar(&createProject, "create-project", false, "Create the namespace to run the APB in, if it doesn't exist, as an OpenShift project. Creates a namespace on clusters without projects")
+	cmd.Flags().StringSliceVar(&namespaceLabels, "namespace-label", []string{}, "Label (key=value) to add to a generated namespace, on top of the configured defaults")
+	cmd.Flags().StringSliceVar(&namespaceAnnotations, "namespace-annotation", []string{}, "Annotation (key=value) to add to a generated namespace, on top of the configured defaults")
+	cmd.Flags().BoolVar(&storeParams, "store-params", false, "Store the parameters of a provision or update, passwords included, in a secret in the namespace, and run later actions on the instance with them")
+	cmd.Flags().BoolVar(&rememberParams, "remember-params", false, "Offer the parameters of the APB's last run as defaults and remember those entered. Sensitive parameters are never remembered")
+	cmd.Flags().StringSliceVar(&resourceRequests, "requests", []string{}, "Resource requests (name=quantity) of the APB pod, e.g. 'cpu=100m,memory=256Mi'. Defaults to the APB's recommendation")
+	cmd.Flags().StringVar(&ephemeralStorageRequest, "ephemeral-storage-request", "", "Ephemeral storage request of the APB pod, e.g. '1Gi'")
+	cmd.Flags().StringVar(&ephemeralStorageLimit, "ephemeral-storage-limit", "", "Ephemeral storage limit of the APB pod, e.g. '4Gi'. For APBs which download large artifacts")
+	cmd.Flags().StringSliceVar(&resourceLimits, "limits", []string{}, "Resource limits (name=quantity) of the APB pod. Defaults to the APB's recommendation")
+	cmd.Flags().StringVar(&specsConfigMap, "specs-configmap", "", "Find the APB's spec in a config map, given as namespace/name[:key], instead of the configured registries")
+	cmd.Flags().StringVar(&specsFile, "specs-file", "", "Find the APB's spec in a file holding a JSON or YAML list of specs, instead of the configured registries")
+	cmd.Flags().StringVar(&fromCR, "from-cr", "", "Run the APB, plan and parameters in the spec of a custom resource, given as group/version/kind/name, such as a service binding. The APB name may be left out")
+	cmd.Flags().StringVar(&extraVarsSecret, "extra-vars-secret", "", "Re-run with the extra vars of a previous run, read unchanged from a secret given as namespace/name[:key], instead of collecting parameters")
+	cmd.Flags().BoolVar(&checkLabels, "check-labels", false, "Check the APB image carries the APB spec label before running it")
+	cmd.Flags().BoolVar(&checkArch, "check-arch", false, "Check the APB image is built for the architecture of the cluster's nodes before running it")
+	cmd.Flags().Int64Var(&fsGroup, "fs-group", -1, "Group ID owning the volumes mounted into the APB pod")
+	cmd.Flags().StringSliceVar(&annotateParams, "annotate-param", []string{}, "Parameter whose value to copy into a 'bundle-param-<name>' annotation of the APB pod. Passwords are refused")
+	cmd.Flags().BoolVar(&annotateAllParams, "annotate-params", false, "Record the parameters as JSON in a 'bundle-parameters' annotation of the APB pod. Passwords are named without their values")
+	cmd.Flags().StringVar(&terminationMessagePath, "termination-message-path", "", "Path the APB container writes its result to. Defaults to /dev/termination-log")
+	cmd.Flags().StringVar(&terminationMessagePolicy, "termination-message-policy", "", "Termination message policy of the APB container, File or FallbackToLogsOnError")
+	cmd.Flags().StringVar(&runID, "run-id", "", "Correlation id, e.g. a ticket or pipeline run, to label the APB pod with. Generated when unset")
+	cmd.Flags().StringVar(&schedulerName, "scheduler-name", "", "Scheduler to assign the APB pod to instead of the default scheduler")
+	cmd.Flags().StringSliceVar(&transforms, "transform", []string{}, "Transform a parameter's value before it is validated, as parameter=transformer. Transformers: trim, lower, upper")
+	cmd.Flags().BoolVar(&Refresh, "refresh", false, "Fetch the specs of every registry again before running the APB")
+	cmd.Flags().StringVar(&planName, "plan", "", "Plan to run instead of choosing one")
+	cmd.Flags().StringVar(&defaultPlan, "default-plan", "", "Plan to run, when the APB has it and --plan isn't given, instead of choosing one. Defaults to DefaultPlan in the config")
+	cmd.Flags().StringVar(&scriptPath, "emit-script", "", "Write a shell script repeating this run, with sensitive values read from the environment")
+	cmd.Flags().BoolVar(&printCommand, "print-command", false, "Print the apb command repeating this run, with sensitive values read from the environment")
+	cmd.Flags().StringArrayVar(&parameterValues, "set", []string{}, "Parameter value (name=value) to use instead of prompting for it")
+	cmd.Flags().StringArrayVar(&jsonParameterValues, "set-json", []string{}, "Parameter value given as JSON (name=<json>), such as an object or array, to use instead of prompting for it")
+	cmd.Flags().StringVar(&paramsFile, "params-file", "", "Read parameter values from a file holding a JSON or YAML object, without prompting. --set values take precedence")
+	cmd.Flags().BoolVar(&paramsStdin, "params-stdin", false, "Read parameter values from a JSON object on stdin, without prompting. --set values take precedence")
+	cmd.Flags().BoolVar(&nonInteractive, "non-interactive", false, "Never prompt for parameters. Parameters not given with --set take their defaults")
+	cmd.Flags().BoolVar(&skipValidation, "skip-validation", false, "Don't check parameter values against the plan, only convert them to their types. For APBs with broken schemas")
+	cmd.Flags().BoolVar(&remoteSchemaRefs, "remote-schema-refs", false, "Fetch the remote schemas a plan's schema refers to with $ref over HTTP")
+	cmd.Flags().DurationVar(&remoteSchemaTimeout, "remote-schema-timeout", 5*time.Second, "How long to wait for each remote schema fetched with --remote-schema-refs")
+	cmd.Flags().StringVar(&planMessage, "plan-message", "always", "Whether to print the selected plan: 'always', including for APBs with a single plan, or 'never'")
+	cmd.Flags().IntVar(&extraVarsIndent, "extra-vars-indent", 0, "Indent the extra vars of the pod printed by --dry-run by this many spaces, e.g. to diff two runs' inputs")
+	cmd.Flags().BoolVar(&minimalExtraVars, "minimal-extra-vars", false, "Pass the APB only its parameters and namespace, without the reserved _apb_* and cluster keys. For images which reject unknown variables")
+	cmd.Flags().BoolVar(&traceRuns, "trace", false, "Export a trace of the run to the OTLP collector configured by the OTEL_EXPORTER_OTLP_* environment variables")
+	cmd.Flags().BoolVar(&jsonErrors, "json-errors", false, "Print parameter validation errors to stderr as JSON and exit non-zero")
+}
+
+// addWaitFlags adds the flags for following, waiting for and cleaning up
+// the APB pod to cmd
+func addWaitFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&cleanup, "cleanup", false, "Delete the APB pod once it has completed")
+	cmd.Flags().BoolVar(&cleanupOnInterrupt, "cleanup-on-interrupt", false, "Delete the APB pod when interrupted while following its logs or waiting for it. Implied by --cleanup")
+	cmd.Flags().BoolVar(&forceCleanup, "force-cleanup", false, "Delete the APB pod once it has completed, removing finalizers which block its deletion")
+	cmd.Flags().BoolVar(&wait, "wait", false, "Wait for the APB pod to complete, failing if it fails")
+	cmd.Flags().DurationVar(&waitTimeout, "timeout", 0, "How long to wait for the APB pod to complete. Zero waits as long as it runs")
+	cmd.Flags().StringVar(&resultsOut, "results-out", "", "File to copy the JSON results the APB writes to $APB_RESULTS_FILE to once it succeeds. Requires waiting for the APB pod and permission to exec into it (create on pods/exec)")
+	cmd.Flags().StringVar(&eventsOut, "events-out", "", "File to write the APB pod's events to as they occur while waiting for it, e.g. as a CI artifact")
+	cmd.Flags().DurationVar(&operationTimeout, "operation-timeout", 0, "How long the whole run may take, from finding the APB to waiting for its pod, leaving out time spent prompting. Zero doesn't bound it")
+	cmd.Flags().DurationVar(&podDeadline, "deadline", 0, "How long the APB pod may run, set as its activeDeadlineSeconds. Waits for the pod for no longer than this, deleting it and failing once it expires. Zero doesn't bound it")
+	cmd.Flags().DurationVar(&unschedulableTimeout, "unschedulable-timeout", runner.DefaultUnschedulableTimeout, "How long the APB pod may be pending with FailedScheduling events before waiting for it fails. Zero waits regardless")
+	cmd.Flags().DurationVar(&reconnectTimeout, "reconnect-timeout", runner.DefaultReconnectTimeout, "How long to keep waiting for the APB pod while the API server can't be reached, e.g. during its rollout. Zero fails at once")
+	cmd.Flags().StringSliceVar(&actionTimeouts, "action-timeout", []string{}, "Timeout (action=duration) which replaces --timeout for one action, e.g. 'provision=20m'")
+	cmd.Flags().DurationVar(&logSince, "since", 0, "Only print the logs followed with --follow which are newer than this, e.g. 10m")
+	cmd.Flags().Int64Var(&logTail, "tail", -1, "Only print the last lines of the logs followed with --follow. Negative prints them all")
+	cmd.Flags().IntVar(&retries, "retries", 0, "Run the APB pod again, up to this many times, when it exits with one of --retry-exit-codes. Implies --wait")
+	cmd.Flags().IntSliceVar(&retryExitCodes, "retry-exit-codes", []int{}, "Exit codes with which the APB reports a transient failure worth retrying")
+	cmd.Flags().DurationVar(&retryBackoff, "retry-backoff", 10*time.Second, "How long to wait before the first retry. Each retry after that waits twice as long")
+}
+
+// unreproducedFlags are left out of the command repeating a run, since the
+// runner sets them from what it resolved or they only apply to prompting or
+// to the one run
+var unreproducedFlags = map[string]bool{
+	"namespace":             true,
+	"registry":              true,
+	"local":                 true,
+	"plan":                  true,
+	"default-plan":          true,
+	"set":                   true,
+	"set-json":              true,
+	"params-stdin":          true,
+	"params-file":           true,
+	"non-interactive":       true,
+	"validate-only":         true,
+	"preview-params":        true,
+	"show-secrets":          true,
+	"from-cr":               true,
+	"remember-params":       true,
+	"no-tui":                true,
+	"emit-script":           true,
+	"print-command":         true,
+	"run-id":                true,
+	"batch":                 true,
+	"batch-concurrency":     true,
+	"namespace-concurrency": true,
+}
+
+// reproducedFlags returns the flags set for this run, other than those the
+// runner resolves itself, as the arguments which would set them again
+func reproducedFlags(flags *pflag.FlagSet) []string {
+	var args []string
+	flags.Visit(func(f *pflag.Flag) {
+		if unreproducedFlags[f.Name] {
+			return
+		}
+		switch f.Value.Type() {
+		case "bool":
+			if f.Value.String() == "true" {
+				args = append(args, "--"+f.Name)
+			} else {
+				args = append(args, "--"+f.Name+"=false")
+			}
+		case "stringSlice":
+			values, _ := flags.GetStringSlice(f.Name)
+			for _, v := range values {
+				args = append(args, "--"+f.Name+"="+v)
+			}
+		case "stringArray":
+			values, _ := flags.GetStringArray(f.Name)
+			for _, v := range values {
+				args = append(args, "--"+f.Name+"="+v)
+			}
+		default:
+			args = append(args, "--"+f.Name+"="+f.Value.String())
+		}
+	})
+	return args
+}
+
+// runOptions builds the runner options from the flags set by addRunFlags
+func runOptions() []runner.Option {
+	var opts []runner.Option
+	if imagePullPolicy != runner.AutoPullPolicy {
+		opts = append(opts, runner.WithImagePullPolicy(imagePullPolicy))
+	}
+	if dnsPolicy != "" {
+		opts = append(opts, runner.WithDNSPolicy(dnsPolicy))
+	}
+	opts = append(opts, runner.WithDNSConfig(dnsNameservers, dnsSearches, dnsOptions))
+	opts = append(opts, runner.WithSelector(runner.NewSelector(noTUI)))
+	opts = append(opts, waitOptions()...)
+	if dryRun {
+		opts = append(opts, runner.WithDryRun(outputFormat, outputDir))
+		if checkQuota {
+			opts = append(opts, runner.WithQuotaCheck())
+		}
+		if diffPrevious {
+			opts = append(opts, runner.WithPreviousPodDiff())
+		}
+		if extraVarsIndent != 0 {
+			opts = append(opts, runner.WithExtraVarsIndent(extraVarsIndent))
+		}
+	} else {
+		if checkQuota {
+			log.Warning("--check-quota only applies with --dry-run")
+		}
+		if diffPrevious {
+			log.Warning("--diff only applies with --dry-run")
+		}
+		if extraVarsIndent != 0 {
+			log.Warning("--extra-vars-indent only applies with --dry-run")
+		}
+	}
+	if serviceClassID != "" {
+		opts = append(opts, runner.WithServiceClassID(serviceClassID))
+	}
+	if generateNamespace {
+		opts = append(opts, runner.WithGeneratedNamespace())
+	}
+	if createProject {
+		opts = append(opts, runner.WithCreateProject())
+	}
+	opts = append(opts,
+		runner.WithResources(resourceRequests, resourceLimits),
+		runner.WithEphemeralStorage(ephemeralStorageRequest, ephemeralStorageLimit),
+	)
+	if fsGroup >= 0 {
+		opts = append(opts, runner.WithFSGroup(fsGroup))
+	}
+	if runID != "" {
+		opts = append(opts, runner.WithRunID(runID))
+	}
+	if schedulerName != "" {
+		opts = append(opts, runner.WithSchedulerName(schedulerName))
+	}
+	if paramsFile != "" {
+		opts = append(opts, runner.WithParameterFile(paramsFile))
+	}
+	if paramsStdin {
+		opts = append(opts, runner.WithParameterJSON(os.Stdin))
+	}
+	opts = append(opts, runner.WithJSONParameterValues(jsonParameterValues), runner.WithParameterValues(parameterValues))
+	if len(transforms) > 0 {
+		opts = append(opts, runner.WithTransforms(transforms))
+	}
+	if len(config.LoadedDefaults.ParameterRules) > 0 {
+		opts = append(opts, runner.WithParameterRules(config.LoadedDefaults.ParameterRules))
+	}
+	if planName != "" {
+		opts = append(opts, runner.WithPlan(planName))
+	}
+	if defaultPlan == "" {
+		defaultPlan = config.LoadedDefaults.DefaultPlan
+	}
+	if defaultPlan != "" {
+		opts = append(opts, runner.WithDefaultPlan(defaultPlan))
+	}
+	opts = append(opts, runner.WithPlanMetadataKeys(planMetadataKeys()))
+	if podNameTemplate != "" {
+		opts = append(opts, runner.WithPodNameTemplate(podNameTemplate))
+	}
+	if serviceAccount != "" {
+		opts = append(opts, runner.WithServiceAccount(serviceAccount))
+	}
+	if account != "" {
+		opts = append(opts, runner.WithAccount(account))
+	}
+	if scriptPath != "" {
+		opts = append(opts, runner.WithRunScript(scriptPath))
+	}
+	if printCommand {
+		opts = append(opts, runner.WithPrintCommand())
+	}
+	if specsConfigMap != "" {
+		opts = append(opts, runner.WithSpecsConfigMap(specsConfigMap))
+	}
+	if specsFile != "" {
+		opts = append(opts, runner.WithSpecProvider(runner.NewFileSpecProvider(specsFile)))
+	}
+	if terminationMessagePath != "" || terminationMessagePolicy != "" {
+		opts = append(opts, runner.WithTerminationMessage(terminationMessagePath, terminationMessagePolicy))
+	}
+	if len(annotateParams) > 0 {
+		opts = append(opts, runner.WithParameterAnnotations(annotateParams))
+	}
+	if storeParams {
+		opts = append(opts, runner.WithStoredParameters())
+	}
+	if annotateAllParams {
+		opts = append(opts, runner.WithParametersAnnotation())
+	}
+	if fromCR != "" {
+		opts = append(opts, runner.WithCustomResource(fromCR))
+	}
+	if extraVarsSecret != "" {
+		opts = append(opts, runner.WithExtraVarsSecret(extraVarsSecret))
+	}
+	if checkLabels {
+		opts = append(opts, runner.WithLabelCheck())
+	}
+	if checkArch {
+		opts = append(opts, runner.WithArchitectureCheck())
+	}
+	if nonInteractive {
+		opts = append(opts, runner.WithNonInteractive())
+	}
+	if skipValidation {
+		opts = append(opts, runner.WithSkipValidation())
+	}
+	if remoteSchemaRefs {
+		opts = append(opts, runner.WithRemoteSchemaRefs(remoteSchemaTimeout))
+	}
+	if planMessage != "always" {
+		opts = append(opts, runner.WithPlanMessage(planMessage))
+	}
+	if minimalExtraVars {
+		opts = append(opts, runner.WithMinimalExtraVars())
+	}
+	if traceRuns {
+		tracer, err := tracing.NewOTLPTracer()
+		if err != nil {
+			log.Warningf("Unable to trace the run: %v", err)
+		} else {
+			opts = append(opts, runner.WithTracer(tracer))
+		}
+	}
+	if rememberParams {
+		dir, err := config.ConfigPath(cfgDir)
+		if err != nil {
+			log.Warningf("Unable to remember parameters: %v", err)
+		} else {
+			opts = append(opts, runner.WithParameterCache(filepath.Join(dir, "params")))
+		}
+	}
+	opts = append(opts,
+		runner.WithNamespaceLabels(append(config.LoadedDefaults.NamespaceLabels, namespaceLabels...)),
+		runner.WithNamespaceAnnotations(append(config.LoadedDefaults.NamespaceAnnotations, namespaceAnnotations...)),
+	)
+	return opts
+}
+
+// waitOptions builds the runner options from the flags set by addWaitFlags
+func waitOptions() []runner.Option {
+	var opts []runner.Option
+	if cleanup || forceCleanup {
+		opts = append(opts, runner.WithCleanup(forceCleanup))
+	}
+	if cleanupOnInterrupt {
+		opts = append(opts, runner.WithInterruptCleanup())
+	}
+	if wait || waitTimeout > 0 || len(actionTimeouts) > 0 {
+		opts = append(opts, runner.WithWait(waitTimeout))
+	}
+	if resultsOut != "" {
+		opts = append(opts, runner.WithResultsOut(resultsOut))
+	}
+	if eventsOut != "" {
+		opts = append(opts, runner.WithEventsOut(eventsOut))
+	}
+	if operationTimeout > 0 {
+		opts = append(opts, runner.WithOperationTimeout(operationTimeout))
+	}
+	if podDeadline > 0 {
+		opts = append(opts, runner.WithDeadline(podDeadline))
+	}
+	if unschedulableTimeout != runner.DefaultUnschedulableTimeout {
+		opts = append(opts, runner.WithUnschedulableTimeout(unschedulableTimeout))
+	}
+	if reconnectTimeout != runner.DefaultReconnectTimeout {
+		opts = append(opts, runner.WithReconnectTimeout(reconnectTimeout))
+	}
+	opts = append(opts, runner.WithActionTimeouts(actionTimeouts))
+	if logSince != 0 || logTail >= 0 {
+		opts = append(opts, runner.WithLogLimits(logSince, logTail))
+	}
+	if retries != 0 {
+		opts = append(opts, runner.WithRetries(retries, retryExitCodes, retryBackoff))
+	}
+	return opts
+}
+
+// resumeBundle waits for the APB pod named in args, or of the instance given
+// with --instance, as the run which created it would have
+func resumeBundle(args []string) {
+	if (len(args) == 0) == (resumeInstanceID == "") {
+		log.Errorf("Give either the name of an APB pod or --instance")
+		os.Exit(1)
+	}
+	if !connectCluster() {
+		os.Exit(1)
+	}
+	opts := append(waitOptions(), runner.WithWait(waitTimeout))
+	if resumeInstanceID != "" {
+		if _, err := runner.ResumeInstance(resumeInstanceID, printLogs, opts...); err != nil {
+			log.Errorf("Failed to resume instance [%v]: %v", resumeInstanceID, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if bundleNamespace == "" {
+		bundleNamespace = util.GetCurrentNamespace(kubeConfig)
+		if bundleNamespace == "" {
+			log.Errorf("Failed to get current namespace. Try supplying it with --namespace.")
+			os.Exit(1)
+		}
+	}
+	if err := runner.ResumePod(bundleNamespace, args[0], printLogs, opts...); err != nil {
+		log.Errorf("Failed to resume pod [%v]: %v", args[0], err)
+		os.Exit(1)
+	}
+}
+
 // Check running pod if it has succeeded or not
 func checkTestSucceeded(podName string, namespace string) bool {
 	log.Infof("Monitoring test pod [%v] for status every 5 seconds...", podName)
@@ -319,6 +1221,15 @@ func printRegConfigSpecs(regConfigs []config.Registry) {
 	util.PrintTable(tableToPrint)
 }
 
+// countSpecs returns the number of specs of all the registries
+func countSpecs(regConfigs []config.Registry) int {
+	n := 0
+	for _, r := range regConfigs {
+		n += len(r.Specs)
+	}
+	return n
+}
+
 func printBundleInfo(bundleSpec *bundle.Spec) {
 	fmt.Printf(" %-11s  |  %v\n", "NAME", bundleSpec.FQName)
 	fmt.Printf(" %-11s  |  %v\n", "DESCRIPTION", bundleSpec.Description)
@@ -331,6 +1242,9 @@ func printBundleInfo(bundleSpec *bundle.Spec) {
 
 	for i, plan := range bundleSpec.Plans {
 		fmt.Printf(" %-11s  |  %v\n", "PLAN", plan.Name)
+		for _, v := range runner.PlanMetadata(plan, planMetadataKeys()) {
+			fmt.Printf("   %-9s  |    %v\n", v.Key, v.Value)
+		}
 		for _, param := range plan.Parameters {
 			fmt.Printf("   %-9s  |    %v\n", "param", param.Name)
 		}
@@ -341,6 +1255,15 @@ func printBundleInfo(bundleSpec *bundle.Spec) {
 	fmt.Println()
 }
 
+// planMetadataKeys returns the plan metadata keys configured to be shown,
+// or the runner's defaults
+func planMetadataKeys() []string {
+	if len(config.LoadedDefaults.PlanMetadataKeys) > 0 {
+		return config.LoadedDefaults.PlanMetadataKeys
+	}
+	return runner.DefaultPlanMetadataKeys
+}
+
 func showBundleInfo(bundleName string, registryName string) {
 	var regConfigs []config.Registry
 
@@ -378,6 +1301,197 @@ func showBundleInfo(bundleName string, registryName string) {
 	return
 }
 
+// printCatalog prints the catalog of the cached specs. Specs are not
+// fetched, so nothing but the catalog is printed to stdout.
+func printCatalog() {
+	var regConfigs []config.Registry
+	if err := config.Registries.UnmarshalKey("Registries", &regConfigs); err != nil {
+		log.Error("Error unmarshalling config: ", err)
+		return
+	}
+	catalog, err := runner.BuildCatalog(regConfigs)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	out, err := json.MarshalIndent(catalog, "", "    ")
+	if err != nil {
+		log.Errorf("Failed to encode the catalog: %v", err)
+		return
+	}
+	fmt.Printf("%s\n", out)
+}
+
+// writeBundleSchema prints the schema of the plan, or writes those of every
+// plan into the output directory. It returns whether it succeeded.
+func writeBundleSchema(bundleName string) bool {
+	if schemaOutputDir == "" {
+		if err := runner.WriteSchema(bundleName, schemaPlan, schemaAction, os.Stdout); err != nil {
+			log.Errorf("Failed to write the schema of APB [%v]: %v", bundleName, err)
+			return false
+		}
+		return true
+	}
+	if schemaPlan != "" {
+		log.Errorf("--plan can't be used with --output-dir, which writes the schema of every plan")
+		return false
+	}
+	files, err := runner.WriteSchemas(bundleName, schemaAction, schemaOutputDir)
+	for _, file := range files {
+		fmt.Printf("Wrote [%v]\n", file)
+	}
+	if err != nil {
+		log.Errorf("Failed to write the schemas of APB [%v]: %v", bundleName, err)
+		return false
+	}
+	return true
+}
+
+func showPlanConstraints(bundleName string, registryName string, planName string) {
+	spec, err := runner.FindSpec(bundleName, registryName)
+	if err != nil {
+		log.Errorf("Failed to list constraints of APB [%v]: %v", bundleName, err)
+		return
+	}
+	found := false
+	for _, plan := range spec.Plans {
+		if planName != "" && plan.Name != planName {
+			continue
+		}
+		found = true
+		summary, err := runner.PlanConstraints(plan)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		fmt.Printf("\nPlan: %v\n", plan.Name)
+		printPlanConstraints(summary)
+	}
+	if !found {
+		log.Errorf("Did not find plan [%v] in APB [%v]", planName, bundleName)
+	}
+}
+
+func printPlanConstraints(summary []runner.ParameterConstraints) {
+	colName := &util.TableColumn{Header: "PARAMETER"}
+	colType := &util.TableColumn{Header: "TYPE"}
+	colRequired := &util.TableColumn{Header: "REQUIRED"}
+	colDefault := &util.TableColumn{Header: "DEFAULT"}
+	colConstraints := &util.TableColumn{Header: "CONSTRAINTS"}
+
+	for _, param := range summary {
+		defaultValue := ""
+		if param.Default != nil {
+			defaultValue = fmt.Sprint(param.Default)
+		}
+		colName.Data = append(colName.Data, param.Name)
+		colType.Data = append(colType.Data, param.Type)
+		colRequired.Data = append(colRequired.Data, fmt.Sprint(param.Required))
+		colDefault.Data = append(colDefault.Data, defaultValue)
+		colConstraints.Data = append(colConstraints.Data, strings.Join(param.Constraints, "; "))
+	}
+
+	tableToPrint := []*util.TableColumn{colName, colType, colRequired, colDefault, colConstraints}
+	util.PrintTable(tableToPrint)
+}
+
+// validateBundleSpec prints the problems found in the APB's spec, reporting
+// whether there were none
+func validateBundleSpec(bundleName string, registryName string, local bool) bool {
+	var spec *bundle.Spec
+	var err error
+	if local {
+		spec, err = runner.LoadLocalSpec(bundleName)
+	} else {
+		spec, err = runner.FindSpec(bundleName, registryName)
+	}
+	if err != nil {
+		log.Errorf("Failed to load APB [%v]: %v", bundleName, err)
+		return false
+	}
+	verrs := runner.ValidateSpec(spec)
+	if len(verrs) == 0 {
+		fmt.Printf("No problems found in APB [%v]\n", spec.FQName)
+		return true
+	}
+	if jsonErrors {
+		printValidationErrors(verrs)
+		return false
+	}
+	for _, verr := range verrs {
+		log.Error(verr.Message)
+	}
+	return false
+}
+
+func showInstance(id string) {
+	instance, err := runner.DescribeInstance(id)
+	if err != nil {
+		log.Errorf("Failed to describe instance: %v", err)
+		return
+	}
+	fmt.Println()
+	printInstance(instance)
+}
+
+func printInstance(instance config.Instance) {
+	fmt.Printf(" %-13s  |  %v\n", "ID", instance.ID)
+	fmt.Printf(" %-13s  |  %v\n", "APB", instance.Bundle)
+	fmt.Printf(" %-13s  |  %v\n", "NAMESPACE", instance.Namespace)
+	fmt.Printf(" %-13s  |  %v\n", "PLAN", instance.Plan)
+	fmt.Printf(" %-13s  |  %v\n", "LAST ACTION", instance.LastAction)
+	fmt.Printf(" %-13s  |  %v\n", "OUTCOME", instance.LastOutcome)
+	if instance.LastResult != "" {
+		fmt.Printf(" %-13s  |  %v\n", "RESULT", instance.LastResult)
+	}
+	fmt.Printf(" %-13s  |  %v\n", "DEPROVISIONED", instance.Deprovisioned)
+	fmt.Printf(" %-13s  | \n", "")
+
+	var names []string
+	for name := range instance.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("   %-11s  |    %v: %v\n", "param", name, instance.Parameters[name])
+	}
+	fmt.Println()
+}
+
+func showBundleActions(bundleName string, registryName string) {
+	spec, err := runner.FindSpec(bundleName, registryName)
+	if err != nil {
+		log.Errorf("Failed to list actions for APB [%v]: %v", bundleName, err)
+		os.Exit(1)
+	}
+	fmt.Println()
+	printBundleActions(spec)
+}
+
+func printBundleActions(bundleSpec *bundle.Spec) {
+	colAction := &util.TableColumn{Header: "ACTION"}
+	colPlan := &util.TableColumn{Header: "PLAN"}
+	colParams := &util.TableColumn{Header: "PARAMETERS"}
+
+	for _, action := range runner.SupportedActions(bundleSpec) {
+		for _, plan := range bundleSpec.Plans {
+			if runner.CheckPlanAction(bundleSpec, plan, action) != nil {
+				continue
+			}
+			var paramNames []string
+			for _, param := range runner.ActionParameters(plan, action) {
+				paramNames = append(paramNames, param.Name)
+			}
+			colAction.Data = append(colAction.Data, action)
+			colPlan.Data = append(colPlan.Data, plan.Name)
+			colParams.Data = append(colParams.Data, strings.Join(paramNames, ", "))
+		}
+	}
+
+	tableToPrint := []*util.TableColumn{colAction, colPlan, colParams}
+	util.PrintTable(tableToPrint)
+}
+
 func stampBundleMetadata(bundleMetaFilename string, containerMetaFilename string, noLineBreaks bool) {
 	workingDir, err := os.Getwd()
 	if err != nil {
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestTestCommandPreview(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-cmd")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	defer os.RemoveAll(dir)
+	specs := `- name: postgresql-apb
+  image: docker.io/ansibleplaybookbundle/postgresql-apb:latest
+  plans:
+  - name: dev
+    parameters:
+    - name: db_name
+      type: string
+      required: true
+`
+	path := filepath.Join(dir, "specs.yaml")
+	if err := ioutil.WriteFile(path, []byte(specs), 0600); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+
+	savedPreview, savedSpecs, savedNamespace := previewParams, specsFile, bundleNamespace
+	savedNonInteractive, savedValues := nonInteractive, parameterValues
+	defer func() {
+		previewParams, specsFile, bundleNamespace = savedPreview, savedSpecs, savedNamespace
+		nonInteractive, parameterValues = savedNonInteractive, savedValues
+	}()
+	previewParams = true
+	specsFile = path
+	bundleNamespace = "web"
+	nonInteractive = true
+	parameterValues = []string{"db_name=wiki"}
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+	bundleTestCmd.Run(bundleTestCmd, []string{"postgresql-apb"})
+	if strings.Contains(logged.String(), "level=error") {
+		t.Fatalf("expected no error logged in preview mode, got:\n%v", logged.String())
+	}
+}
@@ -0,0 +1,83 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/automationbroker/apb/pkg/runner"
+	"github.com/automationbroker/apb/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var cancelSelector string
+var cancelConfirmed bool
+
+var bundleCancelCmd = &cobra.Command{
+	Use:   "cancel",
+	Short: "Cancel the APB runs matching a label selector",
+	Long: `Delete the APB pods in the namespace matching a label selector, e.g.
+bundle-action=provision, stopping those runs. Only pods labeled as APB pods
+are deleted`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cancelRuns()
+	},
+}
+
+func init() {
+	bundleCancelCmd.Flags().StringVarP(&bundleNamespace, "namespace", "n", "", "Namespace of the APB pods")
+	bundleCancelCmd.Flags().StringVarP(&cancelSelector, "selector", "l", "", "Label selector of the APB pods to delete, e.g. bundle-action=provision")
+	bundleCancelCmd.Flags().BoolVarP(&cancelConfirmed, "yes", "y", false, "Delete the pods without asking for confirmation")
+	rootCmd.AddCommand(createHiddenCmd(bundleCancelCmd, ""))
+	bundleCmd.AddCommand(bundleCancelCmd)
+}
+
+func cancelRuns() {
+	if cancelSelector == "" {
+		log.Errorf("A label selector is required. Give one with --selector.")
+		os.Exit(1)
+	}
+	if !connectCluster() {
+		os.Exit(1)
+	}
+	if bundleNamespace == "" {
+		bundleNamespace = util.GetCurrentNamespace(kubeConfig)
+		if bundleNamespace == "" {
+			log.Errorf("Failed to get current namespace. Try supplying it with --namespace.")
+			os.Exit(1)
+		}
+	}
+	if !cancelConfirmed {
+		var answer string
+		fmt.Printf("Delete the APB pods matching [%v] in namespace [%v]? [y/N]: ", cancelSelector, bundleNamespace)
+		fmt.Scanln(&answer)
+		if answer = strings.ToLower(answer); answer != "y" && answer != "yes" {
+			fmt.Println("Nothing was canceled")
+			return
+		}
+	}
+	canceled, err := runner.CancelRuns(bundleNamespace, cancelSelector)
+	fmt.Printf("Canceled %d APB runs in namespace [%v]\n", canceled, bundleNamespace)
+	if err != nil {
+		log.Errorf("Failed to cancel runs matching [%v]: %v", cancelSelector, err)
+		os.Exit(1)
+	}
+}
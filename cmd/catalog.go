@@ -25,8 +25,8 @@ import (
 	"net/http"
 
 	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/apb/pkg/util"
 
-	"github.com/automationbroker/bundle-lib/clients"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -70,7 +70,7 @@ func relistCatalog(brokerResourceURL string, clusterServiceBrokerName string) {
 	if brokerResourceName != "" {
 		clusterServiceBrokerName = brokerResourceName
 	}
-	kube, err := clients.Kubernetes()
+	kube, err := util.KubernetesClient()
 	if err != nil {
 		log.Errorf("Failed to connect to cluster: %v", err)
 		return
@@ -18,8 +18,13 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/apb/pkg/runner"
+	"github.com/automationbroker/apb/pkg/util"
+	"github.com/ghodss/yaml"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -32,10 +37,64 @@ var configCmd = &cobra.Command{
 	},
 }
 
+var configViewAction string
+
+var configViewCmd = &cobra.Command{
+	Use:   "view [apb-name]",
+	Short: "Show the effective settings of a run",
+	Long:  `Print, as YAML, the settings a run of the APB with the same flags would use, merged from the flags, ~/.apb/defaults.json and the defaults, with password parameters redacted. Without an APB, the settings which depend on it are left out.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		viewEffectiveConfig(args)
+	},
+}
+
 func init() {
+	configViewCmd.Flags().StringVarP(&bundleNamespace, "namespace", "n", "", "Namespace the APB would run in")
+	configViewCmd.Flags().StringVarP(&bundleRegistry, "registry", "r", "", "Registry to load the APB from")
+	configViewCmd.Flags().StringVar(&configViewAction, "action", "provision", "Action the APB would run")
+	addRunFlags(configViewCmd)
+	configCmd.AddCommand(configViewCmd)
 	rootCmd.AddCommand(configCmd)
 }
 
+// viewEffectiveConfig prints the settings a run with the flags would use,
+// resolving the namespace as a run does
+func viewEffectiveConfig(args []string) {
+	source := "--namespace"
+	if bundleNamespace == "" && clusterName != "" {
+		source = fmt.Sprintf("context of cluster [%v]", clusterName)
+	}
+	if !connectCluster() {
+		os.Exit(1)
+	}
+	if bundleNamespace == "" {
+		bundleNamespace = util.GetCurrentNamespace(kubeConfig)
+		source = "current context"
+	}
+	var bundleName string
+	opts := runOptions()
+	if len(args) > 0 {
+		bundleName = args[0]
+		if localBundle {
+			opts = append(opts, runner.WithLocalBundle(args[0]))
+		}
+	}
+	settings, err := runner.EffectiveSettings(configViewAction, bundleNamespace, bundleName, bundleRegistry, opts...)
+	if err != nil {
+		log.Errorf("Failed to resolve the effective settings: %v", err)
+		os.Exit(1)
+	}
+	if settings.NamespaceSource == "" && settings.Namespace != "" {
+		settings.NamespaceSource = source
+	}
+	out, err := yaml.Marshal(settings)
+	if err != nil {
+		log.Errorf("Failed to marshal the effective settings: %v", err)
+		os.Exit(1)
+	}
+	fmt.Print(string(out))
+}
+
 func gatherDefaultsConfig() {
 	defaultSettings := &config.DefaultSettings{
 		BrokerNamespace:          getUserInput("Broker namespace", config.InitialDefaultSettings().BrokerNamespace),
@@ -43,6 +102,13 @@ func gatherDefaultsConfig() {
 		BrokerRouteName:          getUserInput("Broker route name", config.InitialDefaultSettings().BrokerRouteName),
 		ClusterServiceBrokerName: getUserInput("clusterservicebroker resource name", config.InitialDefaultSettings().ClusterServiceBrokerName),
 		BrokerRouteSuffix:        getUserInput("Broker route suffix", config.InitialDefaultSettings().BrokerRouteSuffix),
+		NamespaceLabels:          config.LoadedDefaults.NamespaceLabels,
+		NamespaceAnnotations:     config.LoadedDefaults.NamespaceAnnotations,
+		SpecCacheTTL:             config.LoadedDefaults.SpecCacheTTL,
+		Clusters:                 config.LoadedDefaults.Clusters,
+		DefaultPlan:              config.LoadedDefaults.DefaultPlan,
+		PlanMetadataKeys:         config.LoadedDefaults.PlanMetadataKeys,
+		ParameterRules:           config.LoadedDefaults.ParameterRules,
 	}
 	fmt.Println("\nSaving new configuration....")
 	config.UpdateCachedDefaults(config.Defaults, defaultSettings)
@@ -0,0 +1,89 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/automationbroker/apb/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var contextCmd = &cobra.Command{
+	Use:     "context",
+	Aliases: []string{"whoami"},
+	Short:   "Print current cluster and user",
+	Long:    `Print the current kubeconfig context, server, authenticated user and namespace`,
+	Run: func(cmd *cobra.Command, args []string) {
+		showContext()
+	},
+}
+
+func init() {
+	contextCmd.Flags().StringVarP(&kubeConfig, "kubeconfig", "k", "", "Path to kubeconfig to use")
+	rootCmd.AddCommand(contextCmd)
+}
+
+func showContext() {
+	info, err := util.GetClusterInfo(kubeConfig)
+	if err != nil {
+		log.Errorf("Failed to load kubeconfig: %v", err)
+		os.Exit(1)
+	}
+
+	if kubeConfig != "" {
+		// the client reads the default kubeconfig unless pointed at the
+		// given one, which the server and user must come from too
+		if err := util.UseCluster(kubeConfig, ""); err != nil {
+			log.Errorf("Failed to connect to cluster: %v", err)
+			os.Exit(1)
+		}
+	}
+	kube, err := util.KubernetesClient()
+	if err != nil {
+		log.Errorf("Failed to connect to cluster: %v", err)
+		os.Exit(1)
+	}
+	if kube.ClientConfig.Host != "" {
+		info.Server = kube.ClientConfig.Host
+	}
+	// building the client doesn't contact the cluster, so ask it for its
+	// version to check it can be reached with these credentials
+	if _, err := kube.Client.Discovery().ServerVersion(); err != nil {
+		log.Errorf("Failed to connect to cluster [%v]: %v", info.Server, err)
+		os.Exit(1)
+	}
+	// SelfSubjectReview would tell any user who they are, but the vendored
+	// client predates it. A TokenReview needs create on tokenreviews across
+	// the cluster, which ordinary users lack, so the kubeconfig user is
+	// shown when it is refused.
+	if kube.ClientConfig.BearerToken != "" {
+		user, err := util.GetAuthenticatedUser(kube.Client.AuthenticationV1().TokenReviews(), kube.ClientConfig.BearerToken)
+		if err != nil {
+			log.Warningf("Failed to look up authenticated user, showing kubeconfig user: %v", err)
+		} else {
+			info.User = user
+		}
+	}
+
+	fmt.Printf(" %-9s  |  %v\n", "CONTEXT", info.Context)
+	fmt.Printf(" %-9s  |  %v\n", "SERVER", info.Server)
+	fmt.Printf(" %-9s  |  %v\n", "USER", info.User)
+	fmt.Printf(" %-9s  |  %v\n", "NAMESPACE", info.Namespace)
+}
@@ -53,6 +53,6 @@ func handleResourceInaccessibleErr(resourceType string, namespace string, restat
 			errMsg += fmt.Sprintf("Current 'oc' user unable to get '%s'. ", resourceType)
 		}
 	}
-	log.Errorf(errMsg + "Try again with a more privileged user.")
+	log.Error(errMsg + "Try again with a more privileged user.")
 	log.Info("Administrators can grant 'cluster-admin' privileges with:\n   oc adm policy add-cluster-role-to-user cluster-admin <oc-user>")
 }
@@ -20,6 +20,7 @@ import (
 	"os"
 
 	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/apb/pkg/util"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -36,6 +37,9 @@ var rootCmd = &cobra.Command{
 		if Verbose {
 			log.SetLevel(log.DebugLevel)
 		}
+		// logs go to stderr, which may be a terminal when stdout is not
+		color := util.Color(os.Stderr)
+		log.SetFormatter(&log.TextFormatter{DisableTimestamp: true, ForceColors: color, DisableColors: !color})
 	},
 }
 
@@ -46,6 +50,8 @@ func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.PersistentFlags().BoolVarP(&Verbose, "verbose", "v", false, "verbose output")
 	rootCmd.PersistentFlags().StringVar(&cfgDir, "config", "", "configuration directory (default is $HOME/.apb)")
+	rootCmd.PersistentFlags().BoolVar(&util.NoColor, "no-color", false, "disable colored output, as does setting NO_COLOR")
+	rootCmd.PersistentFlags().StringVar(&util.UserAgent, "user-agent", util.DefaultUserAgent(), "user agent sent with requests to the cluster")
 }
 
 func initConfig() {
@@ -53,6 +59,8 @@ func initConfig() {
 
 	// Load or create registries.json
 	config.Registries, _ = config.InitJSONConfig(cfgDir, "registries")
+	// Load or create instances.json
+	config.Instances, _ = config.InitJSONConfig(cfgDir, "instances")
 	// Load or create defaults.json
 	config.Defaults, isNewDefaultsConfig = config.InitJSONConfig(cfgDir, "defaults")
 	if isNewDefaultsConfig {
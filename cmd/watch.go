@@ -0,0 +1,136 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/apb/pkg/runner"
+	"github.com/automationbroker/apb/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var watchAction string
+var triggerDir string
+var triggerSelector string
+var watchInterval time.Duration
+
+var bundleWatchCmd = &cobra.Command{
+	Use:   "watch <apb-name>",
+	Short: "Run an APB whenever a trigger appears",
+	Long: `Watch a directory for JSON files of parameter values, or the namespace for
+config maps matching a label selector, and run the APB's action with those
+parameters for each new one`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		watchBundle(args[0])
+	},
+}
+
+func init() {
+	bundleWatchCmd.Flags().StringVarP(&bundleNamespace, "namespace", "n", "", "Namespace to run the APB and watch config maps in")
+	bundleWatchCmd.Flags().StringVarP(&sandboxRole, "sandbox-role", "s", "edit", "ClusterRole to be applied to APB sandbox")
+	bundleWatchCmd.Flags().StringVarP(&bundleRegistry, "registry", "r", "", "Registry to load APB from")
+	bundleWatchCmd.Flags().StringVarP(&watchAction, "action", "a", "provision", "Action to run for each trigger")
+	bundleWatchCmd.Flags().StringVar(&triggerDir, "trigger-dir", "", "Directory to watch for JSON files of parameter values")
+	bundleWatchCmd.Flags().StringVar(&triggerSelector, "trigger-configmap-selector", "", "Label selector of the config maps holding parameter values")
+	bundleWatchCmd.Flags().DurationVar(&watchInterval, "interval", 5*time.Second, "How often to look for new triggers")
+	addRunFlags(bundleWatchCmd)
+	bundleCmd.AddCommand(bundleWatchCmd)
+}
+
+func watchBundle(bundleName string) {
+	if (triggerDir == "") == (triggerSelector == "") {
+		log.Errorf("Exactly one of --trigger-dir and --trigger-configmap-selector is required")
+		os.Exit(1)
+	}
+	if !connectCluster() {
+		os.Exit(1)
+	}
+	if bundleNamespace == "" {
+		bundleNamespace = util.GetCurrentNamespace(kubeConfig)
+		if bundleNamespace == "" {
+			log.Errorf("Failed to get current namespace. Try supplying it with --namespace.")
+			os.Exit(1)
+		}
+	}
+
+	var source runner.TriggerSource
+	watched := triggerSelector
+	if triggerDir != "" {
+		source = &runner.DirectorySource{Dir: triggerDir}
+		watched = triggerDir
+		if dir, err := filepath.Abs(triggerDir); err == nil {
+			watched = dir
+		}
+	} else {
+		kube, err := util.KubernetesClient()
+		if err != nil {
+			log.Errorf("Failed to connect to cluster: %v", err)
+			os.Exit(1)
+		}
+		source = &runner.ConfigMapSource{
+			ConfigMaps: kube.Client.CoreV1().ConfigMaps(bundleNamespace),
+			Selector:   triggerSelector,
+		}
+	}
+	handled, err := loadHandledTriggers(bundleName, watched)
+	if err != nil {
+		log.Errorf("Failed to load handled triggers: %v", err)
+		os.Exit(1)
+	}
+
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+
+	fmt.Printf("Watching for triggers to %v [%v] in namespace [%v]\n", watchAction, bundleName, bundleNamespace)
+	runner.WatchTriggers(source, handled, watchInterval, stop, func(t runner.Trigger) error {
+		fmt.Printf("Handling trigger [%v]\n", t.ID)
+		var values []string
+		for name, value := range t.Parameters {
+			values = append(values, fmt.Sprintf("%s=%s", name, value))
+		}
+		opts := append(runOptions(), runner.WithParameterValues(values), runner.WithNonInteractive())
+		_, err := runner.RunBundle(watchAction, bundleNamespace, bundleName, sandboxRole, bundleRegistry, false, false, nil, opts...)
+		return err
+	})
+}
+
+// loadHandledTriggers reads the triggers a previous watch of the same
+// action, APB, namespace and triggers handled, kept in the config dir
+func loadHandledTriggers(bundleName string, watched string) (*runner.HandledTriggers, error) {
+	dir, err := config.ConfigPath(cfgDir)
+	if err != nil {
+		return nil, err
+	}
+	key := sha256.Sum256([]byte(strings.Join([]string{watchAction, bundleName, bundleNamespace, watched}, "\x00")))
+	return runner.LoadHandledTriggers(filepath.Join(dir, "triggers", fmt.Sprintf("%x.json", key[:8])))
+}
@@ -32,22 +32,20 @@ var LoadedDefaults DefaultSettings
 // Registries stores APB registry and spec data
 var Registries *viper.Viper
 
+// Instances stores the instances provisioned from this machine
+var Instances *viper.Viper
+
 // InitJSONConfig will load (or create if needed) a JSON config at ~/home/.apb/configName.json or configDir/configName.json
 func InitJSONConfig(configDir string, configName string) (config *viper.Viper, isNewConfig bool) {
-	var configPath string
-
 	viperConfig := viper.New()
 	viperConfig.SetConfigType("json")
 	if configDir != "" {
 		viperConfig.AddConfigPath(configDir)
-		configPath = configDir
-	} else {
-		home, err := homedir.Dir()
-		if err != nil {
-			log.Error(err)
-			os.Exit(1)
-		}
-		configPath = filepath.Join(home, ".apb")
+	}
+	configPath, err := ConfigPath(configDir)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
 	}
 	viperConfig.AddConfigPath(configPath)
 	viperConfig.SetConfigName(configName)
@@ -70,6 +68,18 @@ func InitJSONConfig(configDir string, configName string) (config *viper.Viper, i
 	return viperConfig, isNewConfig
 }
 
+// ConfigPath returns configDir, or ~/.apb when configDir is empty
+func ConfigPath(configDir string) (string, error) {
+	if configDir != "" {
+		return configDir, nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".apb"), nil
+}
+
 // UpdateCachedRegistries saves the contents of regList to a configuration file
 func UpdateCachedRegistries(viperConfig *viper.Viper, regList []Registry) error {
 	viperConfig.Set("Registries", regList)
@@ -77,6 +87,13 @@ func UpdateCachedRegistries(viperConfig *viper.Viper, regList []Registry) error
 	return nil
 }
 
+// UpdateCachedInstances saves the instances to a configuration file
+func UpdateCachedInstances(viperConfig *viper.Viper, instances []Instance) error {
+	viperConfig.Set("Instances", instances)
+	viperConfig.WriteConfig()
+	return nil
+}
+
 // UpdateCachedDefaults saves the contents of defaults to a configuration file
 func UpdateCachedDefaults(viperConfig *viper.Viper, defaults *DefaultSettings) error {
 	viperConfig.Set("Defaults", defaults)
@@ -1,9 +1,11 @@
 package config
 
 import (
+	"github.com/automationbroker/bundle-lib/bundle"
 	"github.com/automationbroker/bundle-lib/registries"
 	"os"
 	"testing"
+	"time"
 )
 
 const defaultConfigDir = "testdata/.apb"
@@ -173,3 +175,77 @@ func TestInitializeDefaultSettings(t *testing.T) {
 		})
 	}
 }
+
+func TestRegistrySpecsExpired(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name     string
+		fetched  time.Time
+		ttl      time.Duration
+		expected bool
+	}{
+		{
+			name:     "test no ttl never expires",
+			fetched:  now.Add(-1000 * time.Hour),
+			ttl:      0,
+			expected: false,
+		},
+		{
+			name:     "test specs within ttl",
+			fetched:  now.Add(-time.Hour),
+			ttl:      24 * time.Hour,
+			expected: false,
+		},
+		{
+			name:     "test specs past ttl",
+			fetched:  now.Add(-25 * time.Hour),
+			ttl:      24 * time.Hour,
+			expected: true,
+		},
+		{
+			name:     "test specs never fetched",
+			fetched:  time.Unix(0, 0),
+			ttl:      24 * time.Hour,
+			expected: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reg := Registry{SpecsFetched: tc.fetched.Unix()}
+			if expired := reg.SpecsExpired(tc.ttl, now); expired != tc.expected {
+				t.Fatalf("expected expired [%v], got [%v]", tc.expected, expired)
+			}
+		})
+	}
+}
+
+func TestRegistrySpecsValid(t *testing.T) {
+	valid := Registry{Specs: []*bundle.Spec{{FQName: "mediawiki-apb", Image: "docker.io/ansibleplaybookbundle/mediawiki-apb"}}}
+	if !valid.SpecsValid() {
+		t.Fatalf("expected specs to be valid")
+	}
+	for _, specs := range [][]*bundle.Spec{{nil}, {{FQName: "mediawiki-apb"}}, {{Image: "mediawiki-apb"}}} {
+		if (Registry{Specs: specs}).SpecsValid() {
+			t.Fatalf("expected specs %v to be invalid", specs)
+		}
+	}
+}
+
+func TestFindCluster(t *testing.T) {
+	defaults := DefaultSettings{
+		Clusters: []Cluster{
+			{Name: "dev", Context: "dev/api.example.com:443/leto"},
+			{Name: "prod", Kubeconfig: "/etc/apb/prod.kubeconfig"},
+		},
+	}
+	cluster, err := defaults.FindCluster("prod")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if cluster.Kubeconfig != "/etc/apb/prod.kubeconfig" {
+		t.Fatalf("expected prod cluster, got %+v", cluster)
+	}
+	if _, err := defaults.FindCluster("staging"); err == nil {
+		t.Fatalf("expected error for an undefined cluster")
+	}
+}
@@ -1,6 +1,9 @@
 package config
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/automationbroker/bundle-lib/bundle"
 	"github.com/automationbroker/bundle-lib/registries"
 )
@@ -9,6 +12,27 @@ import (
 type Registry struct {
 	Config registries.Config
 	Specs  []*bundle.Spec
+	// SpecsFetched is when Specs were fetched, in seconds since the epoch
+	SpecsFetched int64
+}
+
+// SpecsExpired reports whether the registry's specs were fetched longer
+// than ttl ago. A ttl of zero never expires them.
+func (r Registry) SpecsExpired(ttl time.Duration, now time.Time) bool {
+	if ttl <= 0 {
+		return false
+	}
+	return now.Sub(time.Unix(r.SpecsFetched, 0)) > ttl
+}
+
+// SpecsValid reports whether every cached spec can be run
+func (r Registry) SpecsValid() bool {
+	for _, s := range r.Specs {
+		if s == nil || s.FQName == "" || s.Image == "" {
+			return false
+		}
+	}
+	return true
 }
 
 // DefaultSettings stores default settings for APB tool operation
@@ -18,4 +42,65 @@ type DefaultSettings struct {
 	BrokerRouteName          string
 	ClusterServiceBrokerName string
 	BrokerRouteSuffix        string
+	// NamespaceLabels and NamespaceAnnotations are key=value pairs applied
+	// to namespaces created to run APBs in
+	NamespaceLabels      []string
+	NamespaceAnnotations []string
+	// SpecCacheTTL is how long, as a duration, fetched specs are used
+	// before being fetched again. Empty keeps them until refreshed.
+	SpecCacheTTL string
+	// Clusters are the clusters which can be chosen to run APBs on by name
+	Clusters []Cluster
+	// DefaultPlan is run when an APB has several plans, including it, and
+	// no plan is given
+	DefaultPlan string
+	// PlanMetadataKeys are the plan metadata, such as cost, shown when a
+	// plan is selected. Empty shows the runner's defaults.
+	PlanMetadataKeys []string
+	// ParameterRules are input policies which values of parameters, by
+	// name, must follow in every bundle
+	ParameterRules []ParameterRule
+}
+
+// ParameterRule requires string values of the named parameter to match the
+// regular expression Pattern. Description says what the rule enforces, e.g.
+// "names start with a team code", when it is broken.
+type ParameterRule struct {
+	Parameter   string
+	Pattern     string
+	Description string
+}
+
+// Cluster names a kubeconfig context to run APBs on. An empty Kubeconfig
+// is ~/.kube/config and an empty Context is its current context.
+type Cluster struct {
+	Name       string
+	Kubeconfig string
+	Context    string
+}
+
+// FindCluster returns the cluster with the name
+func (d DefaultSettings) FindCluster(name string) (Cluster, error) {
+	var names []string
+	for _, c := range d.Clusters {
+		if c.Name == name {
+			return c, nil
+		}
+		names = append(names, c.Name)
+	}
+	return Cluster{}, fmt.Errorf("cluster [%v] is not defined. Defined clusters: %v", name, names)
+}
+
+// Instance is a bundle provisioned from this machine, as last recorded
+type Instance struct {
+	ID            string
+	Bundle        string
+	Namespace     string
+	Plan          string
+	Parameters    map[string]interface{}
+	LastAction    string
+	LastOutcome   string
+	Deprovisioned bool
+	// LastResult is the termination message of the last action's pod
+	LastResult string
 }
@@ -0,0 +1,142 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+// actionsMetadataKey is the spec or plan metadata key listing the actions a
+// bundle or one of its plans supports
+const actionsMetadataKey = "actions"
+
+// SupportedActions returns the actions a bundle supports. Actions declared
+// in the spec metadata take precedence, otherwise they are inferred from
+// the spec's bindable and updatable settings.
+func SupportedActions(spec *bundle.Spec) []string {
+	if declared := metadataActions(spec.Metadata); len(declared) > 0 {
+		return declared
+	}
+	actions := []string{"provision", "deprovision"}
+	if spec.Bindable {
+		actions = append(actions, "bind", "unbind")
+	}
+	for _, plan := range spec.Plans {
+		if len(plan.UpdatesTo) > 0 || len(updatableParameters(plan)) > 0 {
+			actions = append(actions, "update")
+			break
+		}
+	}
+	return actions
+}
+
+// BundleFilter selects the bundles ListBundles returns
+type BundleFilter func(spec *bundle.Spec) bool
+
+// SupportingAction selects the bundles which support the action, as
+// SupportedActions reports them
+func SupportingAction(action string) BundleFilter {
+	return func(spec *bundle.Spec) bool {
+		return contains(SupportedActions(spec), action)
+	}
+}
+
+// ListBundles returns the registries with only the specs every filter
+// selects. Registries are kept when none of their specs are selected.
+func ListBundles(regConfigs []config.Registry, filters ...BundleFilter) []config.Registry {
+	listed := make([]config.Registry, 0, len(regConfigs))
+	for _, r := range regConfigs {
+		specs := []*bundle.Spec{}
+		for _, s := range r.Specs {
+			selected := true
+			for _, filter := range filters {
+				if !filter(s) {
+					selected = false
+					break
+				}
+			}
+			if selected {
+				specs = append(specs, s)
+			}
+		}
+		r.Specs = specs
+		listed = append(listed, r)
+	}
+	return listed
+}
+
+// PlanActions returns the actions declared as supported by the plan's
+// metadata, or else by the spec's. Unlike SupportedActions nothing is
+// inferred, so it is empty when neither declares them.
+func PlanActions(spec *bundle.Spec, plan bundle.Plan) []string {
+	if declared := metadataActions(plan.Metadata); len(declared) > 0 {
+		return declared
+	}
+	return metadataActions(spec.Metadata)
+}
+
+// CheckPlanAction fails when the plan declares the actions it supports and
+// the action is not one of them
+func CheckPlanAction(spec *bundle.Spec, plan bundle.Plan, action string) error {
+	actions := PlanActions(spec, plan)
+	if len(actions) == 0 || contains(actions, action) {
+		return nil
+	}
+	return fmt.Errorf("plan [%v] does not support action [%v]. Supported actions: %v", plan.Name, action, strings.Join(actions, ", "))
+}
+
+// ActionParameters returns the plan parameters which apply to the given action
+func ActionParameters(plan bundle.Plan, action string) []bundle.ParameterDescriptor {
+	switch action {
+	case "provision":
+		return plan.Parameters
+	case "update":
+		return updatableParameters(plan)
+	case "bind":
+		return plan.BindParameters
+	}
+	return nil
+}
+
+func updatableParameters(plan bundle.Plan) []bundle.ParameterDescriptor {
+	var params []bundle.ParameterDescriptor
+	for _, param := range plan.Parameters {
+		if param.Updatable {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+func metadataActions(metadata map[string]interface{}) []string {
+	var actions []string
+	switch declared := metadata[actionsMetadataKey].(type) {
+	case []string:
+		actions = declared
+	case []interface{}:
+		for _, a := range declared {
+			if action, ok := a.(string); ok {
+				actions = append(actions, action)
+			}
+		}
+	}
+	return actions
+}
@@ -0,0 +1,126 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+func TestCheckPlanAction(t *testing.T) {
+	testCases := []struct {
+		name         string
+		specMetadata map[string]interface{}
+		planMetadata map[string]interface{}
+		action       string
+		actions      []string
+		errMsg       string
+	}{
+		{
+			name:   "test nothing declared allows any action",
+			action: "bind",
+		},
+		{
+			name:         "test action declared by the plan",
+			planMetadata: map[string]interface{}{actionsMetadataKey: []interface{}{"provision", "deprovision", "bind"}},
+			action:       "bind",
+			actions:      []string{"provision", "deprovision", "bind"},
+		},
+		{
+			name:         "test action the plan does not support",
+			planMetadata: map[string]interface{}{actionsMetadataKey: []interface{}{"provision", "deprovision"}},
+			action:       "bind",
+			actions:      []string{"provision", "deprovision"},
+			errMsg:       "plan [default] does not support action [bind]. Supported actions: provision, deprovision",
+		},
+		{
+			name:         "test plan declaration overrides the spec's",
+			specMetadata: map[string]interface{}{actionsMetadataKey: []string{"provision", "deprovision", "update"}},
+			planMetadata: map[string]interface{}{actionsMetadataKey: []string{"provision", "deprovision"}},
+			action:       "update",
+			actions:      []string{"provision", "deprovision"},
+			errMsg:       "plan [default] does not support action [update]. Supported actions: provision, deprovision",
+		},
+		{
+			name:         "test falls back to the spec's declaration",
+			specMetadata: map[string]interface{}{actionsMetadataKey: []string{"provision", "deprovision"}},
+			action:       "test",
+			actions:      []string{"provision", "deprovision"},
+			errMsg:       "plan [default] does not support action [test]. Supported actions: provision, deprovision",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := &bundle.Spec{FQName: "hello-apb", Metadata: tc.specMetadata}
+			plan := bundle.Plan{Name: "default", Metadata: tc.planMetadata}
+			if actions := PlanActions(spec, plan); !reflect.DeepEqual(actions, tc.actions) {
+				t.Fatalf("expected actions %v, got %v", tc.actions, actions)
+			}
+			err := CheckPlanAction(spec, plan, tc.action)
+			if tc.errMsg == "" {
+				if err != nil {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.errMsg {
+				t.Fatalf("expected error [%v], got [%v]", tc.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestListBundles(t *testing.T) {
+	bindable := &bundle.Spec{FQName: "postgresql-apb", Bindable: true}
+	declared := &bundle.Spec{FQName: "mediawiki-apb", Metadata: map[string]interface{}{"actions": []interface{}{"provision", "bind"}}}
+	plain := &bundle.Spec{FQName: "hello-apb"}
+	regConfigs := []config.Registry{
+		{Specs: []*bundle.Spec{bindable, plain}},
+		{Specs: []*bundle.Spec{declared}},
+	}
+	testCases := []struct {
+		name     string
+		filters  []BundleFilter
+		expected [][]string
+	}{
+		{
+			name:     "test no filters",
+			expected: [][]string{{"postgresql-apb", "hello-apb"}, {"mediawiki-apb"}},
+		},
+		{
+			name:     "test bundles supporting bind",
+			filters:  []BundleFilter{SupportingAction("bind")},
+			expected: [][]string{{"postgresql-apb"}, {"mediawiki-apb"}},
+		},
+		{
+			name:     "test inferred actions overridden by metadata",
+			filters:  []BundleFilter{SupportingAction("deprovision")},
+			expected: [][]string{{"postgresql-apb", "hello-apb"}, {}},
+		},
+		{
+			name:     "test no bundle supporting the action",
+			filters:  []BundleFilter{SupportingAction("update")},
+			expected: [][]string{{}, {}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			listed := ListBundles(regConfigs, tc.filters...)
+			names := [][]string{}
+			for _, r := range listed {
+				registryNames := []string{}
+				for _, s := range r.Specs {
+					registryNames = append(registryNames, s.FQName)
+				}
+				names = append(names, registryNames)
+			}
+			if !reflect.DeepEqual(names, tc.expected) {
+				t.Fatalf("expected bundles %v, got %v", tc.expected, names)
+			}
+		})
+	}
+	if len(regConfigs[0].Specs) != 2 {
+		t.Fatalf("ListBundles changed the registries")
+	}
+}
@@ -0,0 +1,110 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+// parameterAnnotationPrefix prefixes the name of a parameter to get the
+// bundle pod annotation holding its value
+const parameterAnnotationPrefix = "bundle-param-"
+
+// parametersAnnotation is the bundle pod annotation holding the run's
+// parameters as JSON
+const parametersAnnotation = "bundle-parameters"
+
+// maxParametersAnnotationSize bounds the parametersAnnotation, well within
+// the 256KiB the API server allows for all of a pod's annotations
+const maxParametersAnnotationSize = 64 * 1024
+
+// truncatedMarker ends a parametersAnnotation cut short to fit
+const truncatedMarker = "...(truncated)"
+
+// auditedParameters are the parameters of a run as recorded on its pod.
+// Sensitive parameters are only named.
+type auditedParameters struct {
+	Parameters map[string]interface{} `json:"parameters"`
+	Redacted   []string               `json:"redacted,omitempty"`
+}
+
+// parametersAnnotationValue returns the parameters as JSON, leaving out the
+// values of those the plan marks as passwords. JSON longer than
+// maxParametersAnnotationSize is truncated and ends with truncatedMarker.
+func parametersAnnotationValue(plan bundle.Plan, params bundle.Parameters) (string, error) {
+	audited := auditedParameters{Parameters: recordableParameters(plan, params)}
+	for name := range params {
+		if _, ok := audited.Parameters[name]; !ok {
+			audited.Redacted = append(audited.Redacted, name)
+		}
+	}
+	sort.Strings(audited.Redacted)
+	b, err := json.Marshal(audited)
+	if err != nil {
+		return "", fmt.Errorf("failed to annotate parameters: %v", err)
+	}
+	if len(b) > maxParametersAnnotationSize {
+		b = append(b[:maxParametersAnnotationSize-len(truncatedMarker)], truncatedMarker...)
+	}
+	return string(b), nil
+}
+
+// checkAnnotatedParameters checks the plan declares each of the parameters
+// to annotate the bundle pod with and none of them are passwords. It runs
+// before prompting, so a refused parameter is never entered.
+func checkAnnotatedParameters(plan bundle.Plan, names []string) error {
+	for _, name := range names {
+		pd := plan.GetParameter(name)
+		if pd == nil {
+			return fmt.Errorf("plan [%v] has no parameter [%v] to annotate the APB pod with", plan.Name, name)
+		}
+		if isSensitive(*pd) {
+			return fmt.Errorf("parameter [%v] is a password and is never copied into an annotation", name)
+		}
+	}
+	return nil
+}
+
+// parameterAnnotations returns the annotations holding the values of the
+// named parameters. Strings are copied as is and other values as JSON.
+// Parameters without a value are left out.
+func parameterAnnotations(plan bundle.Plan, params bundle.Parameters, names []string) (map[string]string, error) {
+	if err := checkAnnotatedParameters(plan, names); err != nil {
+		return nil, err
+	}
+	annotations := map[string]string{}
+	for _, name := range names {
+		value, ok := params[name]
+		if !ok || value == nil {
+			continue
+		}
+		if s, ok := value.(string); ok {
+			annotations[parameterAnnotationPrefix+name] = s
+			continue
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to annotate parameter [%v]: %v", name, err)
+		}
+		annotations[parameterAnnotationPrefix+name] = string(b)
+	}
+	return annotations, nil
+}
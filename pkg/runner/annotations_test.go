@@ -0,0 +1,150 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/automationbroker/bundle-lib/runtime"
+)
+
+func TestParameterAnnotations(t *testing.T) {
+	plan := bundle.Plan{
+		Name: "default",
+		Parameters: []bundle.ParameterDescriptor{
+			{Name: "mysql_user", Type: "string"},
+			{Name: "mysql_password", Type: "string", DisplayType: "password"},
+			{Name: "replicas", Type: "int"},
+			{Name: "tags", Type: "array"},
+			{Name: "debug", Type: "boolean"},
+		},
+	}
+	params := bundle.Parameters{
+		"mysql_user":     "admin",
+		"mysql_password": "s3cret",
+		"replicas":       3,
+		"tags":           []interface{}{"web", "db"},
+	}
+	testCases := []struct {
+		name     string
+		names    []string
+		expected map[string]string
+		err      string
+	}{
+		{
+			name:     "no parameters",
+			expected: map[string]string{},
+		},
+		{
+			name:  "values",
+			names: []string{"mysql_user", "replicas", "tags"},
+			expected: map[string]string{
+				"bundle-param-mysql_user": "admin",
+				"bundle-param-replicas":   "3",
+				"bundle-param-tags":       `["web","db"]`,
+			},
+		},
+		{
+			name:     "parameter without a value",
+			names:    []string{"debug"},
+			expected: map[string]string{},
+		},
+		{
+			name:  "password",
+			names: []string{"mysql_user", "mysql_password"},
+			err:   "parameter [mysql_password] is a password",
+		},
+		{
+			name:  "unknown parameter",
+			names: []string{"mysql_host"},
+			err:   "plan [default] has no parameter [mysql_host]",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			annotations, err := parameterAnnotations(plan, params, tc.names)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing [%v], got [%v]", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if !reflect.DeepEqual(annotations, tc.expected) {
+				t.Fatalf("expected annotations %v, got %v", tc.expected, annotations)
+			}
+		})
+	}
+}
+
+func TestParameterAnnotationsOption(t *testing.T) {
+	if _, err := newOptions([]Option{WithParameterAnnotations([]string{"mysql user"})}); err == nil {
+		t.Fatalf("expected an invalid annotation name to be refused")
+	}
+	o, err := newOptions([]Option{WithParameterAnnotations([]string{"mysql_user"})})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if !reflect.DeepEqual(o.annotatedParameters, []string{"mysql_user"}) {
+		t.Fatalf("expected parameters [mysql_user], got %v", o.annotatedParameters)
+	}
+
+	ec := runtime.ExecutionContext{
+		BundleName: "bundle-1234",
+		Action:     "provision",
+		Image:      "docker.io/ansibleplaybookbundle/mediawiki-apb:latest",
+		Location:   "foo-ns",
+	}
+	pod, err := BuildPod(ec, WithRunID("JIRA-1234"), withPodAnnotations(map[string]string{"bundle-param-mysql_user": "admin"}))
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected := map[string]string{
+		runIDLabel:                "JIRA-1234",
+		"bundle-param-mysql_user": "admin",
+	}
+	if !reflect.DeepEqual(pod.Annotations, expected) {
+		t.Fatalf("expected annotations %v, got %v", expected, pod.Annotations)
+	}
+}
+
+func TestParametersAnnotationValue(t *testing.T) {
+	plan := bundle.Plan{
+		Name: "default",
+		Parameters: []bundle.ParameterDescriptor{
+			{Name: "mysql_user", Type: "string"},
+			{Name: "mysql_password", Type: "string", DisplayType: "password"},
+			{Name: "admin_password", Type: "string", DisplayType: "password"},
+			{Name: "replicas", Type: "int"},
+		},
+	}
+	params := bundle.Parameters{
+		"mysql_user":     "admin",
+		"mysql_password": "s3cret",
+		"admin_password": "t0psecret",
+		"replicas":       3,
+	}
+	value, err := parametersAnnotationValue(plan, params)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected := `{"parameters":{"mysql_user":"admin","replicas":3},"redacted":["admin_password","mysql_password"]}`
+	if value != expected {
+		t.Fatalf("expected annotation [%v], got [%v]", expected, value)
+	}
+	if strings.Contains(value, "s3cret") || strings.Contains(value, "t0psecret") {
+		t.Fatalf("expected password values to be left out, got [%v]", value)
+	}
+
+	large := bundle.Parameters{"mysql_user": strings.Repeat("a", maxParametersAnnotationSize)}
+	value, err = parametersAnnotationValue(plan, large)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if len(value) != maxParametersAnnotationSize || !strings.HasSuffix(value, truncatedMarker) {
+		t.Fatalf("expected the annotation to be truncated to %v bytes with a marker, got %v bytes", maxParametersAnnotationSize, len(value))
+	}
+}
@@ -0,0 +1,143 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// archLabels are the node labels holding a node's architecture, newest
+// first
+var archLabels = []string{"kubernetes.io/arch", "beta.kubernetes.io/arch"}
+
+// manifestTools are the commands tried, in order, to fetch the manifest of a
+// remote image
+var manifestTools = []struct {
+	name string
+	args func(image string) []string
+}{
+	{"skopeo", func(image string) []string { return []string{"inspect", "--raw", "docker://" + image} }},
+	{"docker", func(image string) []string { return []string{"manifest", "inspect", image} }},
+}
+
+var fetchManifest = func(image string) ([]byte, error) {
+	for _, tool := range manifestTools {
+		if _, err := lookPath(tool.name); err != nil {
+			continue
+		}
+		return exec.Command(tool.name, tool.args(image)...).Output()
+	}
+	return nil, errors.New("no tool to inspect image manifests (skopeo or docker) found in PATH")
+}
+
+// manifestArchitectures returns the architectures an image manifest is
+// built for. Manifests which don't record an architecture return none.
+func manifestArchitectures(data []byte) ([]string, error) {
+	manifest := struct {
+		Architecture string `json:"architecture"`
+		Manifests    []struct {
+			Platform struct {
+				Architecture string `json:"architecture"`
+			} `json:"platform"`
+		} `json:"manifests"`
+	}{}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse image manifest: %v", err)
+	}
+	var archs []string
+	if manifest.Architecture != "" {
+		archs = append(archs, manifest.Architecture)
+	}
+	for _, m := range manifest.Manifests {
+		if m.Platform.Architecture != "" && !contains(archs, m.Platform.Architecture) {
+			archs = append(archs, m.Platform.Architecture)
+		}
+	}
+	return archs, nil
+}
+
+// nodeArchitectures returns the architectures of the cluster's nodes
+func nodeArchitectures(nodes corev1.NodeInterface) ([]string, error) {
+	list, err := nodes.List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var archs []string
+	for _, node := range list.Items {
+		for _, label := range archLabels {
+			if arch, ok := node.Labels[label]; ok {
+				if !contains(archs, arch) {
+					archs = append(archs, arch)
+				}
+				break
+			}
+		}
+	}
+	sort.Strings(archs)
+	return archs, nil
+}
+
+// checkArchitectures fails when the image runs on none of the node
+// architectures, and warns when only some nodes can run it
+func checkArchitectures(image string, imageArchs []string, nodeArchs []string) error {
+	var unsupported []string
+	for _, arch := range nodeArchs {
+		if !contains(imageArchs, arch) {
+			unsupported = append(unsupported, arch)
+		}
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+	if len(unsupported) == len(nodeArchs) {
+		return fmt.Errorf("image [%v] is built for [%v], which none of the nodes (%v) can run",
+			image, strings.Join(imageArchs, ", "), strings.Join(nodeArchs, ", "))
+	}
+	log.Warningf("Image [%v] is built for [%v] and can't run on the cluster's %v nodes",
+		image, strings.Join(imageArchs, ", "), strings.Join(unsupported, ", "))
+	return nil
+}
+
+// preflightArchitectures checks the bundle image can run on the cluster's
+// nodes. The check is skipped when either architecture can't be found.
+func preflightArchitectures(nodes corev1.NodeInterface, image string) error {
+	manifest, err := fetchManifest(image)
+	if err != nil {
+		log.Warningf("Skipping architecture check, failed to inspect image [%v]: %v", image, err)
+		return nil
+	}
+	imageArchs, err := manifestArchitectures(manifest)
+	if err != nil || len(imageArchs) == 0 {
+		log.Warningf("Skipping architecture check, image [%v] does not list its architectures", image)
+		return nil
+	}
+	nodeArchs, err := nodeArchitectures(nodes)
+	if err != nil || len(nodeArchs) == 0 {
+		log.Warningf("Skipping architecture check, failed to find the architectures of the nodes: %v", err)
+		return nil
+	}
+	return checkArchitectures(image, imageArchs, nodeArchs)
+}
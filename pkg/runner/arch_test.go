@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestManifestArchitectures(t *testing.T) {
+	testCases := []struct {
+		name     string
+		manifest string
+		expected []string
+	}{
+		{
+			name: "test manifest list",
+			manifest: `{"schemaVersion": 2, "manifests": [
+				{"platform": {"architecture": "amd64", "os": "linux"}},
+				{"platform": {"architecture": "arm64", "os": "linux"}},
+				{"platform": {"architecture": "arm64", "os": "linux", "variant": "v8"}}]}`,
+			expected: []string{"amd64", "arm64"},
+		},
+		{
+			name:     "test schema 1 manifest",
+			manifest: `{"schemaVersion": 1, "architecture": "amd64"}`,
+			expected: []string{"amd64"},
+		},
+		{
+			name:     "test schema 2 manifest",
+			manifest: `{"schemaVersion": 2, "config": {"digest": "sha256:abc"}}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			archs, err := manifestArchitectures([]byte(tc.manifest))
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if !reflect.DeepEqual(archs, tc.expected) {
+				t.Fatalf("expected architectures [%v], got [%v]", tc.expected, archs)
+			}
+		})
+	}
+}
+
+func TestPreflightArchitectures(t *testing.T) {
+	defer func(orig func(string) ([]byte, error)) { fetchManifest = orig }(fetchManifest)
+	node := func(label string, arch string) v1.Node {
+		return v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{label: arch}}}
+	}
+	testCases := []struct {
+		name      string
+		manifest  string
+		fetchErr  error
+		nodes     []v1.Node
+		shouldErr bool
+	}{
+		{
+			name:     "test image runs on every node",
+			manifest: `{"manifests": [{"platform": {"architecture": "amd64"}}, {"platform": {"architecture": "arm64"}}]}`,
+			nodes:    []v1.Node{node("kubernetes.io/arch", "amd64"), node("beta.kubernetes.io/arch", "arm64")},
+		},
+		{
+			name:     "test image runs on some nodes",
+			manifest: `{"manifests": [{"platform": {"architecture": "amd64"}}]}`,
+			nodes:    []v1.Node{node("kubernetes.io/arch", "amd64"), node("kubernetes.io/arch", "arm64")},
+		},
+		{
+			name:      "test image runs on no nodes",
+			manifest:  `{"manifests": [{"platform": {"architecture": "amd64"}}]}`,
+			nodes:     []v1.Node{node("beta.kubernetes.io/arch", "arm64")},
+			shouldErr: true,
+		},
+		{
+			name:     "test image can't be inspected",
+			fetchErr: errors.New("unauthorized"),
+			nodes:    []v1.Node{node("kubernetes.io/arch", "arm64")},
+		},
+		{
+			name:     "test nodes without architecture labels",
+			manifest: `{"manifests": [{"platform": {"architecture": "amd64"}}]}`,
+			nodes:    []v1.Node{{}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fetchManifest = func(string) ([]byte, error) { return []byte(tc.manifest), tc.fetchErr }
+			err := preflightArchitectures(&fakeNodes{nodes: tc.nodes}, "docker.io/example/hello-apb")
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("expected error for an image no node can run")
+			}
+		})
+	}
+}
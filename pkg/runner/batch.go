@@ -0,0 +1,259 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/automationbroker/apb/pkg/util"
+	"github.com/ghodss/yaml"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// BatchManifest runs a bundle in several namespaces. The parameters of each
+// namespace are the manifest's parameters merged with the namespace's own,
+// which take precedence.
+type BatchManifest struct {
+	Parameters map[string]interface{} `json:"parameters"`
+	Namespaces []BatchTarget          `json:"namespaces"`
+}
+
+// BatchTarget is a namespace to run the bundle in with the parameters
+// overridden there
+type BatchTarget struct {
+	Namespace  string                 `json:"namespace"`
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+// BatchResult is the outcome of running the bundle in a namespace
+type BatchResult struct {
+	Namespace string
+	PodName   string
+	Err       error
+	// Queued is how long the run waited for the namespace to have fewer
+	// APB pods running than the namespace concurrency
+	Queued time.Duration
+}
+
+// LoadBatchManifest reads a batch manifest in YAML or JSON
+func LoadBatchManifest(path string) (*BatchManifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch manifest: %v", err)
+	}
+	m := &BatchManifest{}
+	if err := yaml.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse batch manifest [%v]: %v", path, err)
+	}
+	if len(m.Namespaces) == 0 {
+		return nil, fmt.Errorf("batch manifest [%v] has no namespaces", path)
+	}
+	seen := map[string]bool{}
+	for _, target := range m.Namespaces {
+		if target.Namespace == "" {
+			return nil, fmt.Errorf("batch manifest [%v] has a target without a namespace", path)
+		}
+		if seen[target.Namespace] {
+			return nil, fmt.Errorf("batch manifest [%v] lists namespace [%v] more than once", path, target.Namespace)
+		}
+		seen[target.Namespace] = true
+	}
+	return m, nil
+}
+
+// TargetParameters returns the parameter values of the target, its own
+// overriding the manifest's
+func (m *BatchManifest) TargetParameters(target BatchTarget) (map[string]string, error) {
+	merged := map[string]interface{}{}
+	for name, value := range m.Parameters {
+		merged[name] = value
+	}
+	for name, value := range target.Parameters {
+		merged[name] = value
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters for namespace [%v]: %v", target.Namespace, err)
+	}
+	return parameterStrings(data)
+}
+
+// withBatchParameters supplies the parameter values of a batch target.
+// Applied before the other options, values given with WithParameterValues
+// still take precedence.
+func withBatchParameters(values map[string]string) Option {
+	return func(o *options) error {
+		if o.parameterValues == nil {
+			o.parameterValues = map[string]string{}
+		}
+		for name, value := range values {
+			o.parameterValues[name] = value
+		}
+		o.nonInteractive = true
+		return nil
+	}
+}
+
+// withTargetOutputDir writes the dry-run output of a batch target into its
+// own subdirectory of the output directory, named after its namespace
+func withTargetOutputDir(ns string) Option {
+	return func(o *options) error {
+		o.outputDir = filepath.Join(o.outputDir, ns)
+		return nil
+	}
+}
+
+// RunBatch runs the bundle's action in each of the manifest's namespaces,
+// in turn or as many at once as WithBatchConcurrency allows, without
+// prompting. The parameters are validated for each namespace by its run, and
+// a failed namespace does not stop the others. The results are in the
+// manifest's order. A dry run with an output directory writes each
+// namespace's pod into a subdirectory named after it, without contacting the
+// cluster, and for kustomize output a kustomization.yaml referencing them
+// all.
+func RunBatch(action string, bundleName string, sandboxRole string, bundleRegistry string, printLogs bool, manifest *BatchManifest, opts ...Option) ([]BatchResult, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	var active func(ns string) (int, error)
+	if o.namespaceConcurrency > 0 && !o.dryRun {
+		k8scli, err := util.KubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		active = func(ns string) (int, error) {
+			return activeBundlePods(k8scli.Client.CoreV1().Pods(ns))
+		}
+	}
+	results := runBatch(manifest, o.batchConcurrency, o.namespaceConcurrency, active, func(target BatchTarget) (string, error) {
+		values, err := manifest.TargetParameters(target)
+		if err != nil {
+			return "", err
+		}
+		targetOpts := append([]Option{withBatchParameters(values)}, opts...)
+		if o.dryRun && o.outputDir != "" {
+			targetOpts = append(targetOpts, withTargetOutputDir(target.Namespace))
+		}
+		return RunBundle(action, target.Namespace, bundleName, sandboxRole, bundleRegistry, printLogs, false, nil, targetOpts...)
+	})
+	var failed, written []string
+	for _, result := range results {
+		if result.Err != nil {
+			log.Errorf("Failed to %v APB [%v] in namespace [%v]: %v", action, bundleName, result.Namespace, result.Err)
+			failed = append(failed, result.Namespace)
+		} else {
+			written = append(written, result.Namespace)
+		}
+	}
+	if o.dryRun && o.outputFormat == "kustomize" && len(written) > 0 {
+		if err := writeBatchKustomization(o.outputDir, written); err != nil {
+			return results, err
+		}
+	}
+	if len(failed) > 0 {
+		return results, fmt.Errorf("failed in namespaces [%v]", strings.Join(failed, ", "))
+	}
+	return results, nil
+}
+
+// runBatch launches run for each target in the manifest's order, with at most
+// concurrency of them running at once. With a namespace limit, a target is
+// queued until active reports fewer APB pods running in its namespace.
+// Queued targets hold their launch slot, so with a concurrency of one the
+// targets still run strictly in turn.
+func runBatch(manifest *BatchManifest, concurrency int, namespaceLimit int, active func(ns string) (int, error), run func(target BatchTarget) (string, error)) []BatchResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]BatchResult, len(manifest.Namespaces))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, target := range manifest.Namespaces {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int, target BatchTarget) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			result := BatchResult{Namespace: target.Namespace}
+			var err error
+			if namespaceLimit > 0 && active != nil {
+				result.Queued, err = waitForNamespaceSlot(target.Namespace, namespaceLimit, active)
+			}
+			if err == nil {
+				result.PodName, err = run(target)
+			}
+			result.Err = err
+			results[i] = result
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
+
+// waitForNamespaceSlot waits until fewer than limit APB pods are running in
+// the namespace. It returns how long the run was queued for, zero when it
+// was not.
+func waitForNamespaceSlot(ns string, limit int, active func(ns string) (int, error)) (time.Duration, error) {
+	var queued time.Time
+	for {
+		running, err := active(ns)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count the APB pods running in namespace [%v] [%v]", ns, err)
+		}
+		if running < limit {
+			if queued.IsZero() {
+				return 0, nil
+			}
+			waited := time.Since(queued)
+			log.Infof("Starting queued run in namespace [%v] after %v", ns, waited)
+			return waited, nil
+		}
+		if queued.IsZero() {
+			queued = time.Now()
+			log.Infof("Queueing run in namespace [%v]: %d APB pods running, the limit is %d", ns, running, limit)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// activeBundlePods counts the APB pods, of any bundle and action, which are
+// pending or running
+func activeBundlePods(pods corev1.PodInterface) (int, error) {
+	list, err := pods.List(metav1.ListOptions{LabelSelector: "bundle-action"})
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, pod := range list.Items {
+		if pod.Status.Phase == v1.PodPending || pod.Status.Phase == v1.PodRunning {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,233 @@
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLoadBatchManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-batch")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	defer os.RemoveAll(dir)
+	testCases := []struct {
+		name      string
+		manifest  string
+		expected  map[string]map[string]string
+		shouldErr bool
+	}{
+		{
+			name: "overrides merged per namespace",
+			manifest: `parameters:
+  replicas: 2
+  hostname: example.com
+namespaces:
+- namespace: dev
+  parameters:
+    hostname: dev.example.com
+- namespace: prod
+  parameters:
+    replicas: 5
+    debug: false
+`,
+			expected: map[string]map[string]string{
+				"dev":  {"replicas": "2", "hostname": "dev.example.com"},
+				"prod": {"replicas": "5", "hostname": "example.com", "debug": "false"},
+			},
+		},
+		{
+			name:     "json without base parameters",
+			manifest: `{"namespaces": [{"namespace": "dev", "parameters": {"hostname": "dev.example.com"}}]}`,
+			expected: map[string]map[string]string{
+				"dev": {"hostname": "dev.example.com"},
+			},
+		},
+		{
+			name:      "no namespaces",
+			manifest:  "parameters:\n  replicas: 2\n",
+			shouldErr: true,
+		},
+		{
+			name:      "target without a namespace",
+			manifest:  "namespaces:\n- parameters:\n    replicas: 2\n",
+			shouldErr: true,
+		},
+		{
+			name:      "duplicate namespace",
+			manifest:  "namespaces:\n- namespace: dev\n- namespace: dev\n",
+			shouldErr: true,
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, string(rune('a'+i))+".yaml")
+			if err := ioutil.WriteFile(path, []byte(tc.manifest), 0644); err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			m, err := LoadBatchManifest(path)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("expected error for manifest [%v]", tc.manifest)
+			}
+			if len(m.Namespaces) != len(tc.expected) {
+				t.Fatalf("expected %d namespaces, got [%v]", len(tc.expected), m.Namespaces)
+			}
+			for _, target := range m.Namespaces {
+				values, err := m.TargetParameters(target)
+				if err != nil {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				if !reflect.DeepEqual(values, tc.expected[target.Namespace]) {
+					t.Fatalf("expected parameters %v in namespace [%v], got %v", tc.expected[target.Namespace], target.Namespace, values)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchParametersPrecedence(t *testing.T) {
+	o, err := newOptions([]Option{
+		withBatchParameters(map[string]string{"hostname": "dev.example.com", "replicas": "2"}),
+		WithParameterValues([]string{"replicas=3"}),
+	})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected := map[string]string{"hostname": "dev.example.com", "replicas": "3"}
+	if !reflect.DeepEqual(o.parameterValues, expected) {
+		t.Fatalf("expected parameter values %v, got %v", expected, o.parameterValues)
+	}
+	if !o.nonInteractive {
+		t.Fatalf("expected batch runs to be non-interactive")
+	}
+}
+
+func TestRunBatchConcurrency(t *testing.T) {
+	manifest := &BatchManifest{}
+	for i := 0; i < 6; i++ {
+		manifest.Namespaces = append(manifest.Namespaces, BatchTarget{Namespace: fmt.Sprintf("ns-%d", i)})
+	}
+	testCases := []struct {
+		name        string
+		concurrency int
+	}{
+		{name: "in turn", concurrency: 1},
+		{name: "three at once", concurrency: 3},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var lock sync.Mutex
+			running, most := 0, 0
+			var order []string
+			results := runBatch(manifest, tc.concurrency, 0, nil, func(target BatchTarget) (string, error) {
+				lock.Lock()
+				running++
+				if running > most {
+					most = running
+				}
+				order = append(order, target.Namespace)
+				lock.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				lock.Lock()
+				running--
+				lock.Unlock()
+				if target.Namespace == "ns-2" {
+					return "", fmt.Errorf("failed")
+				}
+				return "pod-" + target.Namespace, nil
+			})
+			if most != tc.concurrency {
+				t.Fatalf("expected at most %d runs at once, got %d", tc.concurrency, most)
+			}
+			if tc.concurrency == 1 && !reflect.DeepEqual(order, []string{"ns-0", "ns-1", "ns-2", "ns-3", "ns-4", "ns-5"}) {
+				t.Fatalf("expected the targets to run in turn, got %v", order)
+			}
+			for i, result := range results {
+				if result.Namespace != manifest.Namespaces[i].Namespace {
+					t.Fatalf("expected result %d for namespace [%v], got [%v]", i, manifest.Namespaces[i].Namespace, result.Namespace)
+				}
+				if (result.Err != nil) != (result.Namespace == "ns-2") {
+					t.Fatalf("got unexpected error [%v] for namespace [%v]", result.Err, result.Namespace)
+				}
+			}
+		})
+	}
+}
+
+func TestRunBatchNamespaceLimit(t *testing.T) {
+	pollInterval = time.Millisecond
+	defer func() { pollInterval = 3 * time.Second }()
+
+	manifest := &BatchManifest{Namespaces: []BatchTarget{{Namespace: "busy"}, {Namespace: "idle"}}}
+	var lock sync.Mutex
+	busy := 2
+	active := func(ns string) (int, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if ns == "busy" {
+			busy--
+			return busy + 1, nil
+		}
+		return 0, nil
+	}
+	results := runBatch(manifest, 2, 2, active, func(target BatchTarget) (string, error) {
+		return "pod-" + target.Namespace, nil
+	})
+	if results[0].Err != nil || results[1].Err != nil {
+		t.Fatalf("got unexpected errors [%v] [%v]", results[0].Err, results[1].Err)
+	}
+	if results[0].Queued == 0 {
+		t.Fatalf("expected the run in the busy namespace to be queued")
+	}
+	if results[1].Queued != 0 {
+		t.Fatalf("expected the run in the idle namespace not to be queued, got %v", results[1].Queued)
+	}
+
+	failing := func(ns string) (int, error) { return 0, fmt.Errorf("forbidden") }
+	results = runBatch(manifest, 1, 1, failing, func(target BatchTarget) (string, error) {
+		t.Errorf("expected no run when the pods cannot be counted")
+		return "", nil
+	})
+	if results[0].Err == nil {
+		t.Fatalf("expected an error when the pods cannot be counted")
+	}
+}
+
+func TestActiveBundlePods(t *testing.T) {
+	pod := func(name string, phase v1.PodPhase, labels map[string]string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Status:     v1.PodStatus{Phase: phase},
+		}
+	}
+	apb := map[string]string{"bundle-action": "provision"}
+	pods := newFakePods(
+		pod("pending", v1.PodPending, apb),
+		pod("running", v1.PodRunning, apb),
+		pod("done", v1.PodSucceeded, apb),
+		pod("failed", v1.PodFailed, apb),
+		pod("app", v1.PodRunning, map[string]string{"app": "mediawiki"}),
+	)
+	count, err := activeBundlePods(pods)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 active APB pods, got %d", count)
+	}
+}
@@ -0,0 +1,86 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/automationbroker/apb/pkg/util"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// CancelRuns deletes the bundle pods in the namespace matching the label
+// selector, e.g. bundle-action=provision, and returns how many it deleted.
+// Only pods labeled with a bundle action are deleted, whatever the selector.
+func CancelRuns(ns string, selector string) (int, error) {
+	k8scli, err := util.KubernetesClient()
+	if err != nil {
+		return 0, err
+	}
+	return cancelRuns(k8scli.Client.CoreV1().Pods(ns), selector)
+}
+
+// cancelRuns deletes every bundle pod matching the selector. A pod which
+// fails to be deleted doesn't stop the others, and the failures are
+// returned together.
+func cancelRuns(pods corev1.PodInterface, selector string) (int, error) {
+	if strings.TrimSpace(selector) == "" {
+		return 0, errors.New("a label selector is required to cancel runs")
+	}
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return 0, fmt.Errorf("invalid label selector [%v]: %v", selector, err)
+	}
+	bundlePods, err := labels.NewRequirement("bundle-action", selection.Exists, nil)
+	if err != nil {
+		return 0, err
+	}
+	list, err := pods.List(metav1.ListOptions{LabelSelector: parsed.Add(*bundlePods).String()})
+	if err != nil {
+		return 0, err
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, pod := range list.Items {
+		names = append(names, pod.Name)
+	}
+	sort.Strings(names)
+	canceled := 0
+	var failed []string
+	for _, name := range names {
+		err := pods.Delete(name, &metav1.DeleteOptions{})
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("[%v]: %v", name, err))
+			continue
+		}
+		fmt.Printf("Deleted pod [%v]\n", name)
+		canceled++
+	}
+	if len(failed) > 0 {
+		return canceled, fmt.Errorf("failed to delete pods %v", strings.Join(failed, ", "))
+	}
+	return canceled, nil
+}
@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCancelRuns(t *testing.T) {
+	pods := func() *fakePods {
+		pod := func(name string, labels map[string]string) *v1.Pod {
+			return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+		}
+		return newFakePods(
+			pod("provision-wiki", map[string]string{"bundle-action": "provision", "bundle-fqname": "mediawiki-apb"}),
+			pod("provision-db", map[string]string{"bundle-action": "provision", "bundle-fqname": "postgresql-apb"}),
+			pod("deprovision-db", map[string]string{"bundle-action": "deprovision", "bundle-fqname": "postgresql-apb"}),
+			pod("postgresql", map[string]string{"bundle-fqname": "postgresql-apb"}),
+		)
+	}
+	testCases := []struct {
+		name      string
+		selector  string
+		canceled  int
+		remaining []string
+		expected  string
+	}{
+		{
+			name:      "test runs of an action are deleted",
+			selector:  "bundle-action=provision",
+			canceled:  2,
+			remaining: []string{"deprovision-db", "postgresql"},
+		},
+		{
+			name:      "test only bundle pods are deleted",
+			selector:  "bundle-fqname=postgresql-apb",
+			canceled:  2,
+			remaining: []string{"postgresql", "provision-wiki"},
+		},
+		{
+			name:      "test no matching runs",
+			selector:  "bundle-action=update",
+			remaining: []string{"deprovision-db", "postgresql", "provision-db", "provision-wiki"},
+		},
+		{
+			name:      "test empty selector",
+			selector:  " ",
+			remaining: []string{"deprovision-db", "postgresql", "provision-db", "provision-wiki"},
+			expected:  "a label selector is required",
+		},
+		{
+			name:      "test invalid selector",
+			selector:  "bundle-action in provision",
+			remaining: []string{"deprovision-db", "postgresql", "provision-db", "provision-wiki"},
+			expected:  "invalid label selector",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := pods()
+			canceled, err := cancelRuns(fake, tc.selector)
+			if tc.expected != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expected) {
+					t.Fatalf("expected error [%v], got [%v]", tc.expected, err)
+				}
+			} else if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if canceled != tc.canceled {
+				t.Fatalf("expected %d canceled runs, got %d", tc.canceled, canceled)
+			}
+			var remaining []string
+			for name := range fake.pods {
+				remaining = append(remaining, name)
+			}
+			sort.Strings(remaining)
+			if !reflect.DeepEqual(remaining, tc.remaining) {
+				t.Fatalf("expected remaining pods %v, got %v", tc.remaining, remaining)
+			}
+		})
+	}
+}
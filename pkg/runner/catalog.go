@@ -0,0 +1,162 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/bundle-lib/bundle"
+	log "github.com/sirupsen/logrus"
+)
+
+// Catalog lists what can be run from the configured registries, with what
+// a front-end needs to render a form for each plan
+type Catalog struct {
+	Bundles []CatalogBundle `json:"bundles"`
+}
+
+// CatalogBundle is a bundle of the catalog. Metadata holds the spec's
+// display metadata, e.g. displayName and imageUrl.
+type CatalogBundle struct {
+	Name        string                 `json:"name"`
+	Registry    string                 `json:"registry"`
+	Image       string                 `json:"image"`
+	Description string                 `json:"description"`
+	Bindable    bool                   `json:"bindable"`
+	Tags        []string               `json:"tags,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Plans       []CatalogPlan          `json:"plans"`
+}
+
+// CatalogPlan is a plan of a catalog bundle. Schemas are the JSON schemas
+// generated from the plan, which the parameters are validated against.
+type CatalogPlan struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Free        bool                   `json:"free"`
+	Bindable    bool                   `json:"bindable"`
+	UpdatesTo   []string               `json:"updatesTo,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Parameters  []CatalogParameter     `json:"parameters"`
+	Schemas     bundle.Schema          `json:"schemas"`
+}
+
+// CatalogParameter describes how to prompt for a parameter. Default is the
+// effective default, including the bundle's shared defaults, and is left
+// out for parameters displayed as passwords.
+type CatalogParameter struct {
+	Name         string              `json:"name"`
+	Title        string              `json:"title,omitempty"`
+	Description  string              `json:"description,omitempty"`
+	Type         string              `json:"type"`
+	DisplayType  string              `json:"displayType,omitempty"`
+	DisplayGroup string              `json:"displayGroup,omitempty"`
+	Required     bool                `json:"required"`
+	Updatable    bool                `json:"updatable"`
+	Default      interface{}         `json:"default,omitempty"`
+	Enum         []string            `json:"enum,omitempty"`
+	Constraints  []string            `json:"constraints,omitempty"`
+	Dependencies []bundle.Dependency `json:"dependencies,omitempty"`
+}
+
+// BuildCatalog assembles the catalog of the specs cached for the registries,
+// in the registries' order
+func BuildCatalog(registries []config.Registry) (*Catalog, error) {
+	catalog := &Catalog{Bundles: []CatalogBundle{}}
+	for _, registry := range registries {
+		for _, spec := range registry.Specs {
+			if spec == nil {
+				continue
+			}
+			b, err := catalogBundle(spec)
+			if err != nil {
+				return nil, fmt.Errorf("failed to add APB [%v] of registry [%v] to the catalog: %v", spec.FQName, registry.Config.Name, err)
+			}
+			b.Registry = registry.Config.Name
+			catalog.Bundles = append(catalog.Bundles, b)
+		}
+	}
+	return catalog, nil
+}
+
+func catalogBundle(spec *bundle.Spec) (CatalogBundle, error) {
+	b := CatalogBundle{
+		Name:        spec.FQName,
+		Image:       spec.Image,
+		Description: spec.Description,
+		Bindable:    spec.Bindable,
+		Tags:        spec.Tags,
+		Metadata:    spec.Metadata,
+		Plans:       []CatalogPlan{},
+	}
+	for _, plan := range spec.Plans {
+		plan, err := applyBundleDefaults(spec, plan)
+		if err != nil {
+			log.Warningf("Ignoring parameter defaults of APB [%v]: %v", spec.FQName, err)
+		}
+		p, err := catalogPlan(plan)
+		if err != nil {
+			return b, err
+		}
+		b.Plans = append(b.Plans, p)
+	}
+	return b, nil
+}
+
+func catalogPlan(plan bundle.Plan) (CatalogPlan, error) {
+	schemaPlans, err := bundle.ConvertPlansToSchema([]bundle.Plan{plan})
+	if err != nil {
+		return CatalogPlan{}, fmt.Errorf("failed to convert plan [%v] to JSON schema: %v", plan.Name, err)
+	}
+	constraints, err := PlanConstraints(plan)
+	if err != nil {
+		return CatalogPlan{}, err
+	}
+	byName := map[string]ParameterConstraints{}
+	for _, c := range constraints {
+		byName[c.Name] = c
+	}
+	p := CatalogPlan{
+		Name:        plan.Name,
+		Description: plan.Description,
+		Free:        plan.Free,
+		Bindable:    plan.Bindable,
+		UpdatesTo:   plan.UpdatesTo,
+		Metadata:    plan.Metadata,
+		Parameters:  []CatalogParameter{},
+		Schemas:     schemaPlans[0].Schemas,
+	}
+	for _, param := range plan.Parameters {
+		c := byName[param.Name]
+		p.Parameters = append(p.Parameters, CatalogParameter{
+			Name:         param.Name,
+			Title:        param.Title,
+			Description:  param.Description,
+			Type:         param.Type,
+			DisplayType:  param.DisplayType,
+			DisplayGroup: param.DisplayGroup,
+			Required:     c.Required,
+			Updatable:    param.Updatable,
+			Default:      c.Default,
+			Enum:         param.Enum,
+			Constraints:  c.Constraints,
+			Dependencies: param.Dependencies,
+		})
+	}
+	return p, nil
+}
@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/automationbroker/bundle-lib/registries"
+)
+
+func TestBuildCatalog(t *testing.T) {
+	spec := &bundle.Spec{
+		FQName:      "mediawiki-apb",
+		Image:       "docker.io/ansibleplaybookbundle/mediawiki-apb:latest",
+		Description: "Mediawiki APB",
+		Bindable:    true,
+		Metadata: map[string]interface{}{
+			"displayName":                "Mediawiki (APB)",
+			parameterDefaultsMetadataKey: map[string]interface{}{"site_name": "Wiki"},
+		},
+		Plans: []bundle.Plan{
+			{
+				Name:        "default",
+				Description: "Default plan",
+				Free:        true,
+				Parameters: []bundle.ParameterDescriptor{
+					{Name: "site_name", Title: "Site name", Type: "string", Required: true, MaxLength: 40},
+					{Name: "size", Type: "enum", Enum: []string{"small", "large"}, Default: "small", DisplayGroup: "Sizing"},
+					{Name: "admin_pass", Type: "string", DisplayType: "password", Default: "changeme"},
+				},
+			},
+		},
+	}
+	registries := []config.Registry{
+		{Config: registries.Config{Name: "docker"}, Specs: []*bundle.Spec{spec, nil}},
+	}
+	catalog, err := BuildCatalog(registries)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if len(catalog.Bundles) != 1 {
+		t.Fatalf("expected one bundle, got [%v]", catalog.Bundles)
+	}
+	b := catalog.Bundles[0]
+	if b.Name != spec.FQName || b.Registry != "docker" || b.Image != spec.Image || !b.Bindable {
+		t.Fatalf("unexpected bundle [%v]", b)
+	}
+	if b.Metadata["displayName"] != "Mediawiki (APB)" {
+		t.Fatalf("expected the spec's display metadata, got [%v]", b.Metadata)
+	}
+	if len(b.Plans) != 1 || len(b.Plans[0].Parameters) != 3 {
+		t.Fatalf("expected one plan with three parameters, got [%v]", b.Plans)
+	}
+	plan := b.Plans[0]
+	if plan.Schemas.ServiceInstance.Create["parameters"] == nil {
+		t.Fatalf("expected the plan's create schema, got [%v]", plan.Schemas)
+	}
+	expected := []CatalogParameter{
+		{Name: "site_name", Title: "Site name", Type: "string", Required: true, Default: "Wiki", Constraints: []string{"length at most 40"}},
+		{Name: "size", Type: "enum", DisplayGroup: "Sizing", Default: "small", Enum: []string{"small", "large"}, Constraints: []string{"one of: small, large"}},
+		{Name: "admin_pass", Type: "string", DisplayType: "password"},
+	}
+	for i, param := range plan.Parameters {
+		if !reflect.DeepEqual(param, expected[i]) {
+			t.Fatalf("expected parameter [%+v], got [%+v]", expected[i], param)
+		}
+	}
+	if _, err := json.Marshal(catalog); err != nil {
+		t.Fatalf("expected the catalog to encode as JSON, got [%v]", err)
+	}
+}
@@ -0,0 +1,149 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// safeFinalizers are the finalizers which --force-cleanup may remove from a
+// bundle pod. Bundle pods own no dependents, so these only hold up deletion.
+var safeFinalizers = []string{
+	metav1.FinalizerOrphanDependents,
+	metav1.FinalizerDeleteDependents,
+}
+
+var cleanupGracePeriod = 30 * time.Second
+var pollInterval = 3 * time.Second
+
+// waitForPodCompletion polls the pod until it has succeeded or failed. A
+// timeout of zero waits for as long as the pod runs. Closing stop ends the
+// wait with errInterrupted, and the check, when set, ends it with its error.
+func waitForPodCompletion(pods corev1.PodInterface, podName string, timeout time.Duration, check podCheck, stop <-chan struct{}) (v1.PodPhase, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	for {
+		pod, err := pods.Get(podName, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			return pod.Status.Phase, nil
+		}
+		if check != nil {
+			if err := check(pod); err != nil {
+				return pod.Status.Phase, err
+			}
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return pod.Status.Phase, fmt.Errorf("timed out after %v waiting for pod [%v] to complete", timeout, podName)
+		}
+		log.Debugf("Pod [%v] status: %v", podName, pod.Status.Phase)
+		select {
+		case <-stop:
+			return pod.Status.Phase, errInterrupted
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// cleanupPod deletes the bundle pod and waits for it to go away. When force
+// is set and the pod is still present after the grace period, safe
+// finalizers are removed so the pod can be collected.
+func cleanupPod(pods corev1.PodInterface, podName string, force bool) error {
+	err := pods.Delete(podName, &metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	gone, err := waitForPodDeletion(pods, podName)
+	if err != nil || gone {
+		return err
+	}
+	if !force {
+		return fmt.Errorf("pod [%v] was not deleted within %v. Retry with --force-cleanup to remove its finalizers", podName, cleanupGracePeriod)
+	}
+
+	pod, err := pods.Get(podName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	var remaining []string
+	var removed []string
+	for _, f := range pod.Finalizers {
+		if contains(safeFinalizers, f) {
+			removed = append(removed, f)
+		} else {
+			remaining = append(remaining, f)
+		}
+	}
+	if len(removed) == 0 {
+		return fmt.Errorf("pod [%v] is held by finalizers %v which are not safe to remove", podName, pod.Finalizers)
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"finalizers": remaining,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removing finalizers %v from pod [%v]\n", removed, podName)
+	_, err = pods.Patch(podName, types.MergePatchType, patch)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	gone, err = waitForPodDeletion(pods, podName)
+	if err != nil {
+		return err
+	}
+	if !gone {
+		return fmt.Errorf("pod [%v] is held by finalizers %v which are not safe to remove", podName, remaining)
+	}
+	return nil
+}
+
+// waitForPodDeletion reports whether the pod went away within the grace period
+func waitForPodDeletion(pods corev1.PodInterface, podName string) (bool, error) {
+	deadline := time.Now().Add(cleanupGracePeriod)
+	for {
+		_, err := pods.Get(podName, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		time.Sleep(pollInterval)
+	}
+}
@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCleanupPod(t *testing.T) {
+	savedGracePeriod, savedPollInterval := cleanupGracePeriod, pollInterval
+	defer func() { cleanupGracePeriod, pollInterval = savedGracePeriod, savedPollInterval }()
+	cleanupGracePeriod = 10 * time.Millisecond
+	pollInterval = time.Millisecond
+	testCases := []struct {
+		name       string
+		finalizers []string
+		force      bool
+		shouldErr  bool
+	}{
+		{
+			name:       "test pod without finalizers",
+			finalizers: nil,
+			force:      false,
+			shouldErr:  false,
+		},
+		{
+			name:       "test pod with finalizers without force",
+			finalizers: []string{metav1.FinalizerDeleteDependents},
+			force:      false,
+			shouldErr:  true,
+		},
+		{
+			name:       "test pod with safe finalizers removed by force",
+			finalizers: []string{metav1.FinalizerDeleteDependents, metav1.FinalizerOrphanDependents},
+			force:      true,
+			shouldErr:  false,
+		},
+		{
+			name:       "test pod with unknown finalizer kept by force",
+			finalizers: []string{metav1.FinalizerDeleteDependents, "example.com/protect"},
+			force:      true,
+			shouldErr:  true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pods := newFakePods(&v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "bundle-1234",
+					Finalizers: tc.finalizers,
+				},
+			})
+			err := cleanupPod(pods, "bundle-1234", tc.force)
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("got unexpected error [%v]", err)
+				return
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("expected error but pod was cleaned up")
+				return
+			}
+			_, exists := pods.pods["bundle-1234"]
+			if exists != tc.shouldErr {
+				t.Fatalf("expected pod to exist [%v], got [%v]", tc.shouldErr, exists)
+				return
+			}
+			if tc.force && exists {
+				for _, f := range pods.pods["bundle-1234"].Finalizers {
+					if contains(safeFinalizers, f) {
+						t.Fatalf("expected safe finalizer [%v] to be removed", f)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWaitForPodCompletion(t *testing.T) {
+	savedPollInterval := pollInterval
+	defer func() { pollInterval = savedPollInterval }()
+	pollInterval = time.Millisecond
+	pods := newFakePods(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "bundle-1234"},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	})
+	if _, err := waitForPodCompletion(pods, "bundle-1234", 10*time.Millisecond, nil, nil); err == nil {
+		t.Fatalf("expected a running pod to time out")
+	}
+
+	pods.pods["bundle-1234"].Status.Phase = v1.PodFailed
+	phase, err := waitForPodCompletion(pods, "bundle-1234", 0, nil, nil)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if phase != v1.PodFailed {
+		t.Fatalf("expected phase [%v], got [%v]", v1.PodFailed, phase)
+	}
+}
+
+func TestWaitForPodCompletionInterrupted(t *testing.T) {
+	savedPollInterval := pollInterval
+	defer func() { pollInterval = savedPollInterval }()
+	pollInterval = time.Millisecond
+	pods := newFakePods(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "bundle-1234"},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	})
+	stop := make(chan struct{})
+	close(stop)
+	phase, err := waitForPodCompletion(pods, "bundle-1234", 0, nil, stop)
+	if err != errInterrupted {
+		t.Fatalf("expected the wait to be interrupted, got [%v]", err)
+	}
+	if phase != v1.PodRunning {
+		t.Fatalf("expected phase [%v], got [%v]", v1.PodRunning, phase)
+	}
+}
@@ -0,0 +1,116 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/lestrrat/go-jsschema"
+)
+
+// ParameterConstraints summarizes what a plan accepts for one parameter
+type ParameterConstraints struct {
+	Name     string
+	Type     string
+	Required bool
+	// Default is nil when the parameter has no default, or is displayed as
+	// a password
+	Default interface{}
+	// Constraints describe the enum, bounds and pattern of the parameter,
+	// e.g. "one of: small, large" or "at least 1"
+	Constraints []string
+}
+
+// PlanConstraints returns the constraints on each of the plan's parameters,
+// in the plan's order, as the JSON schema generated from the plan enforces
+// them
+func PlanConstraints(plan bundle.Plan) ([]ParameterConstraints, error) {
+	schemaPlan, err := bundle.ConvertPlansToSchema([]bundle.Plan{plan})
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert plan [%v] to JSON schema: %v", plan.Name, err)
+	}
+	schemaParams := schemaPlan[0].Schemas.ServiceInstance.Create["parameters"]
+	var summary []ParameterConstraints
+	for _, param := range plan.Parameters {
+		property, ok := schemaParams.Properties[param.Name]
+		if !ok {
+			continue
+		}
+		constraints := ParameterConstraints{
+			Name:        param.Name,
+			Type:        schemaType(property.Type),
+			Required:    contains(schemaParams.Required, param.Name),
+			Default:     property.Default,
+			Constraints: describeConstraints(property),
+		}
+		if isSensitive(param) {
+			constraints.Default = nil
+		}
+		summary = append(summary, constraints)
+	}
+	return summary, nil
+}
+
+func schemaType(types schema.PrimitiveTypes) string {
+	var names []string
+	for _, t := range types {
+		names = append(names, t.String())
+	}
+	return strings.Join(names, " or ")
+}
+
+// describeConstraints returns a readable description of each constraint
+// the schema places on a value
+func describeConstraints(s *schema.Schema) []string {
+	var constraints []string
+	if len(s.Enum) > 0 {
+		var entries []string
+		for _, e := range s.Enum {
+			entries = append(entries, fmt.Sprint(e))
+		}
+		constraints = append(constraints, "one of: "+strings.Join(entries, ", "))
+	}
+	if s.Minimum.Initialized {
+		bound := "at least"
+		if s.ExclusiveMinimum.Val {
+			bound = "greater than"
+		}
+		constraints = append(constraints, fmt.Sprintf("%v %v", bound, s.Minimum.Val))
+	}
+	if s.Maximum.Initialized {
+		bound := "at most"
+		if s.ExclusiveMaximum.Val {
+			bound = "less than"
+		}
+		constraints = append(constraints, fmt.Sprintf("%v %v", bound, s.Maximum.Val))
+	}
+	if s.MultipleOf.Initialized {
+		constraints = append(constraints, fmt.Sprintf("multiple of %v", s.MultipleOf.Val))
+	}
+	if s.MinLength.Initialized {
+		constraints = append(constraints, fmt.Sprintf("length at least %d", s.MinLength.Val))
+	}
+	if s.MaxLength.Initialized {
+		constraints = append(constraints, fmt.Sprintf("length at most %d", s.MaxLength.Val))
+	}
+	if s.Pattern != nil {
+		constraints = append(constraints, fmt.Sprintf("matches %v", s.Pattern))
+	}
+	return constraints
+}
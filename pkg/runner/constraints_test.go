@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+func TestPlanConstraints(t *testing.T) {
+	one := bundle.NilableNumber(1)
+	ten := bundle.NilableNumber(10)
+	plan := bundle.Plan{
+		Name: "dev",
+		Parameters: []bundle.ParameterDescriptor{
+			{Name: "db_name", Type: "string", Required: true, Default: "wiki", MinLength: 1, MaxLength: 63, Pattern: "^[a-z]+$"},
+			{Name: "replicas", Type: "int", Minimum: &one, ExclusiveMaximum: &ten},
+			{Name: "ratio", Type: "number", MultipleOf: 0.5},
+			{Name: "size", Type: "enum", Enum: []string{"small", "large"}},
+			{Name: "password", Type: "string", Default: "changeme", DisplayType: "password"},
+		},
+	}
+	summary, err := PlanConstraints(plan)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected := []ParameterConstraints{
+		{
+			Name:        "db_name",
+			Type:        "string",
+			Required:    true,
+			Default:     "wiki",
+			Constraints: []string{"length at least 1", "length at most 63", "matches ^[a-z]+$"},
+		},
+		{
+			Name:        "replicas",
+			Type:        "integer",
+			Constraints: []string{"at least 1", "less than 10"},
+		},
+		{
+			Name:        "ratio",
+			Type:        "number",
+			Constraints: []string{"multiple of 0.5"},
+		},
+		{
+			Name:        "size",
+			Type:        "string",
+			Constraints: []string{"one of: small, large"},
+		},
+		{
+			Name: "password",
+			Type: "string",
+		},
+	}
+	if !reflect.DeepEqual(summary, expected) {
+		t.Fatalf("expected constraints\n%+v\ngot\n%+v", expected, summary)
+	}
+
+	if _, err := PlanConstraints(bundle.Plan{Name: "broken", Parameters: []bundle.ParameterDescriptor{{Name: "x", Type: "colour"}}}); err == nil {
+		t.Fatalf("expected error for a parameter of unknown type")
+	}
+}
@@ -0,0 +1,55 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// credentialsKey is the key of a bind secret holding the credentials as JSON
+const credentialsKey = "fields"
+
+// WaitForCredentials polls the bind secret until its credentials have been
+// written, checking every interval for up to timeout. Some bind flows write
+// them after the bind playbook finishes. A timeout of zero reads the secret
+// once.
+func WaitForCredentials(secrets corev1.SecretInterface, secretName string, interval time.Duration, timeout time.Duration) ([]byte, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		secret, err := secrets.Get(secretName, metav1.GetOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return nil, fmt.Errorf("unable to retrieve secret [%v]: %v", secretName, err)
+		}
+		if err == nil && len(secret.Data[credentialsKey]) > 0 {
+			return secret.Data[credentialsKey], nil
+		}
+		if !time.Now().Before(deadline) {
+			if err != nil {
+				return nil, fmt.Errorf("secret [%v] was not found within %v", secretName, timeout)
+			}
+			return nil, fmt.Errorf("secret [%v] had no credentials within %v", secretName, timeout)
+		}
+		log.Debugf("Waiting for credentials in secret [%v]", secretName)
+		time.Sleep(interval)
+	}
+}
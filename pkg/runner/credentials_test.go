@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWaitForCredentials(t *testing.T) {
+	bindSecret := func(data map[string][]byte) *v1.Secret {
+		return &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "bind-1234"}, Data: data}
+	}
+	ready := bindSecret(map[string][]byte{"fields": []byte(`{"DB_USER": "admin"}`)})
+	testCases := []struct {
+		name       string
+		secrets    map[string]*v1.Secret
+		pending    map[string]*v1.Secret
+		readyAfter int
+		timeout    time.Duration
+		gets       int
+		err        string
+	}{
+		{
+			name:    "test credentials already written",
+			secrets: map[string]*v1.Secret{"bind-1234": ready},
+			timeout: time.Second,
+			gets:    1,
+		},
+		{
+			name:       "test secret created later",
+			secrets:    map[string]*v1.Secret{},
+			pending:    map[string]*v1.Secret{"bind-1234": ready},
+			readyAfter: 3,
+			timeout:    time.Second,
+			gets:       4,
+		},
+		{
+			name:       "test credentials populated later",
+			secrets:    map[string]*v1.Secret{"bind-1234": bindSecret(nil)},
+			pending:    map[string]*v1.Secret{"bind-1234": ready},
+			readyAfter: 2,
+			timeout:    time.Second,
+			gets:       3,
+		},
+		{
+			name:       "test zero timeout reads once",
+			secrets:    map[string]*v1.Secret{},
+			pending:    map[string]*v1.Secret{"bind-1234": ready},
+			readyAfter: 1,
+			gets:       1,
+			err:        "was not found",
+		},
+		{
+			name:    "test timeout without credentials",
+			secrets: map[string]*v1.Secret{"bind-1234": bindSecret(map[string][]byte{"other": []byte("x")})},
+			timeout: 20 * time.Millisecond,
+			err:     "had no credentials",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			secrets := &fakeSecrets{secrets: tc.secrets, pending: tc.pending, readyAfter: tc.readyAfter}
+			creds, err := WaitForCredentials(secrets, "bind-1234", time.Millisecond, tc.timeout)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing [%v], got [%v]", tc.err, err)
+				}
+				if tc.gets > 0 && secrets.gets != tc.gets {
+					t.Fatalf("expected %d reads, got %d", tc.gets, secrets.gets)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if string(creds) != `{"DB_USER": "admin"}` || secrets.gets != tc.gets {
+				t.Fatalf("expected credentials after %d reads, got [%s] after %d", tc.gets, creds, secrets.gets)
+			}
+		})
+	}
+}
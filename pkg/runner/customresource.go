@@ -0,0 +1,176 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// crRef names a custom resource to run a bundle from, as
+// group/version/kind/name. A namespaced kind is read from the namespace of
+// the run.
+type crRef struct {
+	group   string
+	version string
+	kind    string
+	name    string
+}
+
+func (r crRef) String() string {
+	return strings.Join([]string{r.group, r.version, r.kind, r.name}, "/")
+}
+
+func (r crRef) groupVersion() string {
+	return r.group + "/" + r.version
+}
+
+// parseCRRef parses a reference given as group/version/kind/name
+func parseCRRef(ref string) (crRef, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 4 {
+		return crRef{}, fmt.Errorf("invalid custom resource [%v]. Expected group/version/kind/name", ref)
+	}
+	for _, p := range parts {
+		if p == "" {
+			return crRef{}, fmt.Errorf("invalid custom resource [%v]. Expected group/version/kind/name", ref)
+		}
+	}
+	return crRef{group: parts[0], version: parts[1], kind: parts[2], name: parts[3]}, nil
+}
+
+// customResources reads custom resources. No dynamic client is vendored,
+// so the API is called through the REST client.
+type customResources interface {
+	// Resource returns the resource serving the kind in the group version,
+	// and whether it is namespaced
+	Resource(groupVersion string, kind string) (string, bool, error)
+	// Get returns the named object of the resource. An empty namespace
+	// gets a cluster scoped object.
+	Get(groupVersion string, resource string, namespace string, name string) (map[string]interface{}, error)
+}
+
+type restCustomResources struct {
+	client kubernetes.Interface
+}
+
+func (c *restCustomResources) Resource(groupVersion string, kind string) (string, bool, error) {
+	resources, err := c.client.Discovery().ServerResourcesForGroupVersion(groupVersion)
+	if k8serrors.IsNotFound(err) {
+		return "", false, fmt.Errorf("the cluster does not serve [%v]. Is its CRD installed?", groupVersion)
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to discover the resources of [%v]: %v", groupVersion, err)
+	}
+	for _, r := range resources.APIResources {
+		// subresources, such as status, share the kind of their resource
+		if r.Kind == kind && !strings.Contains(r.Name, "/") {
+			return r.Name, r.Namespaced, nil
+		}
+	}
+	return "", false, fmt.Errorf("the cluster serves no kind [%v] in [%v]. Is its CRD installed?", kind, groupVersion)
+}
+
+func (c *restCustomResources) Get(groupVersion string, resource string, namespace string, name string) (map[string]interface{}, error) {
+	path := []string{"/apis", groupVersion}
+	if namespace != "" {
+		path = append(path, "namespaces", namespace)
+	}
+	path = append(path, resource, name)
+	data, err := c.client.Discovery().RESTClient().Get().AbsPath(path...).Do().Raw()
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// crRun is the run a custom resource asks for
+type crRun struct {
+	bundle     string
+	plan       string
+	parameters map[string]interface{}
+}
+
+// resolveCR reads the bundle, plan and parameters to run from the spec of
+// the custom resource, as its bundle, plan and parameters fields. Only the
+// bundle is required.
+func resolveCR(crs customResources, ref crRef, ns string) (crRun, error) {
+	resource, namespaced, err := crs.Resource(ref.groupVersion(), ref.kind)
+	if err != nil {
+		return crRun{}, err
+	}
+	if !namespaced {
+		ns = ""
+	}
+	object, err := crs.Get(ref.groupVersion(), resource, ns, ref.name)
+	if k8serrors.IsNotFound(err) {
+		return crRun{}, fmt.Errorf("custom resource [%v] not found", ref)
+	}
+	if err != nil {
+		return crRun{}, fmt.Errorf("failed to get custom resource [%v]: %v", ref, err)
+	}
+	spec, ok := object["spec"].(map[string]interface{})
+	if !ok {
+		return crRun{}, fmt.Errorf("custom resource [%v] has no spec", ref)
+	}
+	var run crRun
+	if run.bundle, ok = spec["bundle"].(string); !ok || run.bundle == "" {
+		return crRun{}, fmt.Errorf("custom resource [%v] names no bundle in spec.bundle", ref)
+	}
+	if plan, set := spec["plan"]; set {
+		if run.plan, ok = plan.(string); !ok {
+			return crRun{}, fmt.Errorf("spec.plan of custom resource [%v] is not a string", ref)
+		}
+	}
+	if params, set := spec["parameters"]; set {
+		if run.parameters, ok = params.(map[string]interface{}); !ok {
+			return crRun{}, fmt.Errorf("spec.parameters of custom resource [%v] is not an object", ref)
+		}
+	}
+	return run, nil
+}
+
+// withCRParameters supplies the parameters of the custom resource as JSON
+// values, unless they are given otherwise
+func withCRParameters(o *options, params map[string]interface{}) error {
+	for name, value := range params {
+		if _, given := o.parameterValues[name]; given {
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("invalid value of parameter [%v]: %v", name, err)
+		}
+		if o.parameterValues == nil {
+			o.parameterValues = map[string]string{}
+		}
+		if o.jsonParameters == nil {
+			o.jsonParameters = map[string]bool{}
+		}
+		o.parameterValues[name] = string(encoded)
+		o.jsonParameters[name] = true
+	}
+	return nil
+}
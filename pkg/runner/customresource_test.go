@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCRRef(t *testing.T) {
+	r, err := parseCRRef("binding.example.com/v1alpha1/BundleBinding/wiki")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected := crRef{group: "binding.example.com", version: "v1alpha1", kind: "BundleBinding", name: "wiki"}
+	if r != expected {
+		t.Fatalf("expected %+v, got %+v", expected, r)
+	}
+	for _, ref := range []string{"v1/BundleBinding/wiki", "binding.example.com/v1alpha1//wiki", "a/b/c/d/e"} {
+		if _, err := parseCRRef(ref); err == nil {
+			t.Fatalf("expected error for custom resource [%v]", ref)
+		}
+	}
+}
+
+func TestResolveCR(t *testing.T) {
+	ref := crRef{group: "binding.example.com", version: "v1alpha1", kind: "BundleBinding", name: "wiki"}
+	wiki := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"bundle":     "mediawiki-apb",
+			"plan":       "dev",
+			"parameters": map[string]interface{}{"mediawiki_site_name": "Wiki", "replicas": float64(2)},
+		},
+	}
+	testCases := []struct {
+		name       string
+		resources  map[string]string
+		namespaced bool
+		objects    map[string]map[string]interface{}
+		expected   crRun
+		shouldErr  bool
+	}{
+		{
+			name:       "test namespaced resource",
+			resources:  map[string]string{"binding.example.com/v1alpha1/BundleBinding": "bundlebindings"},
+			namespaced: true,
+			objects:    map[string]map[string]interface{}{"web/wiki": wiki},
+			expected: crRun{
+				bundle:     "mediawiki-apb",
+				plan:       "dev",
+				parameters: map[string]interface{}{"mediawiki_site_name": "Wiki", "replicas": float64(2)},
+			},
+		},
+		{
+			name:      "test cluster scoped resource without plan",
+			resources: map[string]string{"binding.example.com/v1alpha1/BundleBinding": "bundlebindings"},
+			objects: map[string]map[string]interface{}{
+				"/wiki": {"spec": map[string]interface{}{"bundle": "mediawiki-apb"}},
+			},
+			expected: crRun{bundle: "mediawiki-apb"},
+		},
+		{
+			name:      "test missing CRD",
+			resources: map[string]string{},
+			shouldErr: true,
+		},
+		{
+			name:       "test missing resource",
+			resources:  map[string]string{"binding.example.com/v1alpha1/BundleBinding": "bundlebindings"},
+			namespaced: true,
+			objects:    map[string]map[string]interface{}{"db/wiki": wiki},
+			shouldErr:  true,
+		},
+		{
+			name:      "test resource without bundle",
+			resources: map[string]string{"binding.example.com/v1alpha1/BundleBinding": "bundlebindings"},
+			objects: map[string]map[string]interface{}{
+				"/wiki": {"spec": map[string]interface{}{"plan": "dev"}},
+			},
+			shouldErr: true,
+		},
+		{
+			name:      "test parameters which are not an object",
+			resources: map[string]string{"binding.example.com/v1alpha1/BundleBinding": "bundlebindings"},
+			objects: map[string]map[string]interface{}{
+				"/wiki": {"spec": map[string]interface{}{"bundle": "mediawiki-apb", "parameters": "replicas=2"}},
+			},
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			crs := &fakeCustomResources{resources: tc.resources, namespaced: tc.namespaced, objects: tc.objects}
+			run, err := resolveCR(crs, ref, "web")
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("expected an error")
+			}
+			if !reflect.DeepEqual(run, tc.expected) {
+				t.Fatalf("expected %+v, got %+v", tc.expected, run)
+			}
+		})
+	}
+}
+
+func TestWithCRParameters(t *testing.T) {
+	o, err := newOptions([]Option{WithParameterValues([]string{"replicas=3"})})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if err := withCRParameters(o, map[string]interface{}{"replicas": float64(2), "tags": []interface{}{"a"}}); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected := map[string]string{"replicas": "3", "tags": `["a"]`}
+	if !reflect.DeepEqual(o.parameterValues, expected) || o.jsonParameters["replicas"] || !o.jsonParameters["tags"] {
+		t.Fatalf("expected values %v with only tags as JSON, got %v %v", expected, o.parameterValues, o.jsonParameters)
+	}
+}
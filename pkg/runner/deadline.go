@@ -0,0 +1,93 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"time"
+)
+
+// operationClock bounds a whole run by its timeout. Time spent prompting
+// is excluded by pausing the clock. There is no request context to cancel
+// client calls with, so the deadline is checked between the stages of the
+// run and ends the waits on the bundle pod through their stop channel.
+type operationClock struct {
+	timeout  time.Duration
+	deadline time.Time
+	pausedAt time.Time
+}
+
+// newOperationClock starts the clock. A timeout of zero never expires.
+func newOperationClock(timeout time.Duration) *operationClock {
+	c := &operationClock{timeout: timeout}
+	if timeout > 0 {
+		c.deadline = time.Now().Add(timeout)
+	}
+	return c
+}
+
+// pause stops the clock until resume is called
+func (c *operationClock) pause() {
+	if !c.deadline.IsZero() && c.pausedAt.IsZero() {
+		c.pausedAt = time.Now()
+	}
+}
+
+// resume restarts the clock, moving the deadline by the time it was paused
+func (c *operationClock) resume() {
+	if c.pausedAt.IsZero() {
+		return
+	}
+	c.deadline = c.deadline.Add(time.Since(c.pausedAt))
+	c.pausedAt = time.Time{}
+}
+
+// expired reports whether the deadline has passed
+func (c *operationClock) expired() bool {
+	return !c.deadline.IsZero() && c.pausedAt.IsZero() && !time.Now().Before(c.deadline)
+}
+
+// check returns an error naming the stage of the run once the deadline has
+// passed
+func (c *operationClock) check(stage string) error {
+	if !c.expired() {
+		return nil
+	}
+	return fmt.Errorf("operation timed out after %v while %v", c.timeout, stage)
+}
+
+// stop returns a channel which is closed when interrupt is closed or the
+// deadline passes, and a function to stop watching them
+func (c *operationClock) stop(interrupt <-chan struct{}) (<-chan struct{}, func()) {
+	if c.deadline.IsZero() {
+		return interrupt, func() {}
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	timer := time.NewTimer(time.Until(c.deadline))
+	go func() {
+		defer timer.Stop()
+		select {
+		case <-interrupt:
+		case <-timer.C:
+		case <-done:
+			return
+		}
+		close(stop)
+	}()
+	return stop, func() { close(done) }
+}
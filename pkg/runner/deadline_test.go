@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOperationClock(t *testing.T) {
+	unbounded := newOperationClock(0)
+	if err := unbounded.check("collecting parameters"); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+
+	clock := newOperationClock(20 * time.Millisecond)
+	if err := clock.check("finding the APB"); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	// time spent prompting doesn't count
+	clock.pause()
+	time.Sleep(30 * time.Millisecond)
+	if err := clock.check("collecting parameters"); err != nil {
+		t.Fatalf("expected a paused clock not to expire, got [%v]", err)
+	}
+	clock.resume()
+	if err := clock.check("collecting parameters"); err != nil {
+		t.Fatalf("expected the deadline to move by the time paused, got [%v]", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	err := clock.check("preparing namespace [foo-ns]")
+	if err == nil || !strings.Contains(err.Error(), "timed out after 20ms while preparing namespace [foo-ns]") {
+		t.Fatalf("expected the operation to time out, got [%v]", err)
+	}
+}
+
+func TestOperationClockStop(t *testing.T) {
+	interrupt := make(chan struct{})
+	stop, release := newOperationClock(0).stop(interrupt)
+	defer release()
+	if stop != (<-chan struct{})(interrupt) {
+		t.Fatalf("expected an unbounded clock to stop on interrupt only")
+	}
+
+	savedPollInterval := pollInterval
+	defer func() { pollInterval = savedPollInterval }()
+	pollInterval = time.Millisecond
+	pods := newFakePods(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "bundle-1234"},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	})
+	clock := newOperationClock(10 * time.Millisecond)
+	stop, release = clock.stop(nil)
+	defer release()
+	if _, err := waitForPodCompletion(pods, "bundle-1234", 0, nil, stop); err != errInterrupted {
+		t.Fatalf("expected the deadline to end the wait, got [%v]", err)
+	}
+	if !clock.expired() {
+		t.Fatalf("expected the clock to have expired")
+	}
+
+	interrupt = make(chan struct{})
+	stop, release = newOperationClock(time.Hour).stop(interrupt)
+	defer release()
+	close(interrupt)
+	select {
+	case <-stop:
+	case <-time.After(time.Second):
+		t.Fatalf("expected an interrupt to close the stop channel")
+	}
+}
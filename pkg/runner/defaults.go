@@ -0,0 +1,101 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+// parameterDefaultsMetadataKey is the spec metadata key holding defaults
+// shared by the parameters of every plan, e.g.
+//
+//	parameterDefaults:
+//	  region: us-east-1
+const parameterDefaultsMetadataKey = "parameterDefaults"
+
+var templateActions = regexp.MustCompile(`{{(.*?)}}`)
+var templateFields = regexp.MustCompile(`(?:^|[^\w.])\.(\w+)`)
+
+// applyBundleDefaults returns the plan with the bundle's shared defaults
+// given to parameters which have no default of their own. Defaults set by
+// the plan take precedence.
+func applyBundleDefaults(spec *bundle.Spec, plan bundle.Plan) (bundle.Plan, error) {
+	declared, ok := spec.Metadata[parameterDefaultsMetadataKey]
+	if !ok {
+		return plan, nil
+	}
+	defaults, err := stringKeyed(declared)
+	if err != nil {
+		return plan, fmt.Errorf("invalid %v metadata: %v", parameterDefaultsMetadataKey, err)
+	}
+	params := make([]bundle.ParameterDescriptor, len(plan.Parameters))
+	copy(params, plan.Parameters)
+	for i, param := range params {
+		if value, ok := defaults[param.Name]; ok && param.Default == nil {
+			params[i].Default = value
+		}
+	}
+	plan.Parameters = params
+	return plan, nil
+}
+
+// isTemplateDefault reports whether the default refers to other parameters,
+// e.g. "{{.app_name}}.example.com"
+func isTemplateDefault(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.Contains(s, "{{")
+}
+
+// templateReferences returns the names of the parameters the parameter's
+// template default refers to
+func templateReferences(param bundle.ParameterDescriptor) []string {
+	if !isTemplateDefault(param.Default) {
+		return nil
+	}
+	var refs []string
+	for _, action := range templateActions.FindAllStringSubmatch(param.Default.(string), -1) {
+		for _, field := range templateFields.FindAllStringSubmatch(action[1], -1) {
+			if !contains(refs, field[1]) {
+				refs = append(refs, field[1])
+			}
+		}
+	}
+	return refs
+}
+
+// renderDefault evaluates a template default against the parameters
+// collected so far. Other defaults are returned as they are.
+func renderDefault(param bundle.ParameterDescriptor, value interface{}, params bundle.Parameters) (interface{}, error) {
+	if !isTemplateDefault(value) {
+		return value, nil
+	}
+	tmpl, err := template.New(param.Name).Option("missingkey=error").Parse(value.(string))
+	if err != nil {
+		return nil, fmt.Errorf("invalid default for parameter [%v]: %v", param.Name, err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, map[string]interface{}(params)); err != nil {
+		return nil, fmt.Errorf("failed to evaluate default for parameter [%v]: %v", param.Name, err)
+	}
+	return out.String(), nil
+}
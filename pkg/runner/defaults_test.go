@@ -0,0 +1,162 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+func TestRenderDefault(t *testing.T) {
+	testCases := []struct {
+		name      string
+		value     interface{}
+		params    bundle.Parameters
+		expected  interface{}
+		shouldErr bool
+	}{
+		{
+			name:     "test template default",
+			value:    "{{.app_name}}.example.com",
+			params:   bundle.Parameters{"app_name": "wiki"},
+			expected: "wiki.example.com",
+		},
+		{
+			name:     "test template default with several references",
+			value:    "{{ .app_name }}-{{ .app_env }}",
+			params:   bundle.Parameters{"app_name": "wiki", "app_env": "dev"},
+			expected: "wiki-dev",
+		},
+		{
+			name:     "test plain default",
+			value:    5,
+			expected: 5,
+		},
+		{
+			name:      "test template default referring to a missing parameter",
+			value:     "{{.app_name}}.example.com",
+			params:    bundle.Parameters{},
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			param := bundle.ParameterDescriptor{Name: "app_host", Default: tc.value}
+			value, err := renderDefault(param, tc.value, tc.params)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("expected error but default was rendered as [%v]", value)
+			}
+			if value != tc.expected {
+				t.Fatalf("expected default [%v], got [%v]", tc.expected, value)
+			}
+		})
+	}
+}
+
+func TestTemplateDefaultOrdering(t *testing.T) {
+	params := []bundle.ParameterDescriptor{
+		{Name: "app_host", Type: "string", Default: "{{.app_name}}.{{.app_domain}}"},
+		{Name: "app_domain", Type: "string", Default: "example.com"},
+		{Name: "app_name", Type: "string", Default: "wiki"},
+	}
+	ordered, err := orderParameters(params)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	var names []string
+	for _, param := range ordered {
+		names = append(names, param.Name)
+	}
+	expected := []string{"app_domain", "app_name", "app_host"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected parameters %v, got %v", expected, names)
+	}
+
+	collected, err := collectParameters(bundle.Plan{Parameters: params}, nil, map[string]string{"app_name": "blog"}, nil, nil, nil, false)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if collected["app_host"] != "blog.example.com" {
+		t.Fatalf("expected app_host [blog.example.com], got [%v]", collected["app_host"])
+	}
+
+	cyclic := []bundle.ParameterDescriptor{
+		{Name: "a", Default: "{{.b}}"},
+		{Name: "b", Default: "{{.a}}"},
+	}
+	if _, err := orderParameters(cyclic); err == nil {
+		t.Fatalf("expected error for parameters whose defaults refer to each other")
+	}
+}
+
+func TestApplyBundleDefaults(t *testing.T) {
+	spec := &bundle.Spec{
+		FQName: "mediawiki-apb",
+		Metadata: map[string]interface{}{
+			"parameterDefaults": map[interface{}]interface{}{
+				"region":   "us-east-1",
+				"replicas": 2,
+			},
+		},
+		Plans: []bundle.Plan{
+			{
+				Name: "dev",
+				Parameters: []bundle.ParameterDescriptor{
+					{Name: "region", Type: "string"},
+					{Name: "replicas", Type: "int", Default: 1},
+					{Name: "site_name", Type: "string"},
+				},
+			},
+			{
+				Name: "prod",
+				Parameters: []bundle.ParameterDescriptor{
+					{Name: "region", Type: "string", Default: "eu-west-1"},
+					{Name: "replicas", Type: "int"},
+				},
+			},
+		},
+	}
+	testCases := []struct {
+		name     string
+		plan     bundle.Plan
+		expected map[string]interface{}
+	}{
+		{
+			name:     "test plan default takes precedence",
+			plan:     spec.Plans[0],
+			expected: map[string]interface{}{"region": "us-east-1", "replicas": 1, "site_name": nil},
+		},
+		{
+			name:     "test bundle default fills plan without one",
+			plan:     spec.Plans[1],
+			expected: map[string]interface{}{"region": "eu-west-1", "replicas": 2},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			plan, err := applyBundleDefaults(spec, tc.plan)
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			for _, param := range plan.Parameters {
+				if param.Default != tc.expected[param.Name] {
+					t.Fatalf("expected parameter [%v] default [%v], got [%v]", param.Name, tc.expected[param.Name], param.Default)
+				}
+			}
+		})
+	}
+	if spec.Plans[0].Parameters[0].Default != nil {
+		t.Fatalf("expected the spec's plans to be left unchanged")
+	}
+
+	spec.Metadata["parameterDefaults"] = "us-east-1"
+	if _, err := applyBundleDefaults(spec, spec.Plans[0]); err == nil {
+		t.Fatalf("expected error for parameter defaults which aren't a map")
+	}
+}
@@ -0,0 +1,153 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+// unmetDependency returns the first of the parameter's dependencies which
+// does not hold for the given parameters. A parameter with dependencies only
+// applies once every other parameter it depends on has the listed value, or
+// any value when the dependency lists none, e.g. to ask for a subnet only
+// after, and only if, a provider was chosen.
+func unmetDependency(param bundle.ParameterDescriptor, params bundle.Parameters) (bundle.Dependency, bool) {
+	for _, dep := range param.Dependencies {
+		value, ok := params[dep.Key]
+		if !ok || (dep.Value != nil && fmt.Sprint(value) != fmt.Sprint(dep.Value)) {
+			return dep, true
+		}
+	}
+	return bundle.Dependency{}, false
+}
+
+// orderParameters moves parameters after the parameters they depend on or
+// whose values their defaults are made from, so those are known by the time
+// they are prompted for. Otherwise the plan's order is kept.
+func orderParameters(params []bundle.ParameterDescriptor) ([]bundle.ParameterDescriptor, error) {
+	declared := map[string]bool{}
+	for _, param := range params {
+		declared[param.Name] = true
+	}
+	ready := func(param bundle.ParameterDescriptor, placed map[string]bool) bool {
+		for _, ref := range parameterReferences(param) {
+			if declared[ref] && !placed[ref] {
+				return false
+			}
+		}
+		return true
+	}
+
+	var ordered []bundle.ParameterDescriptor
+	placed := map[string]bool{}
+	remaining := params
+	for len(remaining) > 0 {
+		next := -1
+		for i, param := range remaining {
+			if ready(param, placed) {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			var names []string
+			for _, param := range remaining {
+				names = append(names, param.Name)
+			}
+			return nil, fmt.Errorf("parameters %v depend on each other", names)
+		}
+		ordered = append(ordered, remaining[next])
+		placed[remaining[next].Name] = true
+		remaining = append(remaining[:next:next], remaining[next+1:]...)
+	}
+	return ordered, nil
+}
+
+// parameterReferences returns the names of the other parameters the
+// parameter depends on
+func parameterReferences(param bundle.ParameterDescriptor) []string {
+	var refs []string
+	for _, dep := range param.Dependencies {
+		refs = append(refs, dep.Key)
+	}
+	for _, ref := range templateReferences(param) {
+		if ref != param.Name {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// activeParameters returns the plan with only the parameters whose
+// dependencies hold for the given parameters
+func activeParameters(plan bundle.Plan, params bundle.Parameters) bundle.Plan {
+	active := plan
+	active.Parameters = nil
+	for _, param := range plan.Parameters {
+		if _, unmet := unmetDependency(param, params); !unmet {
+			active.Parameters = append(active.Parameters, param)
+		}
+	}
+	return active
+}
+
+// validateDependencies checks that required parameters are set when their
+// dependencies hold, and that no parameter is set when they do not
+func validateDependencies(plan bundle.Plan, params bundle.Parameters) error {
+	for _, param := range plan.Parameters {
+		if len(param.Dependencies) == 0 {
+			continue
+		}
+		_, set := params[param.Name]
+		dep, unmet := unmetDependency(param, params)
+		if unmet && set {
+			return ValidationErrors{{
+				Parameter:  param.Name,
+				Constraint: "dependencies",
+				Message:    fmt.Sprintf("Parameter [%v] can only be set when %v", param.Name, describeDependency(dep)),
+			}}
+		}
+		if !unmet && param.Required && !set {
+			return ValidationErrors{{
+				Parameter:  param.Name,
+				Constraint: "dependencies",
+				Message:    fmt.Sprintf("Parameter [%v] is required when %v", param.Name, describeDependencies(param.Dependencies)),
+			}}
+		}
+	}
+	return nil
+}
+
+func describeDependencies(deps []bundle.Dependency) string {
+	description := ""
+	for i, dep := range deps {
+		if i > 0 {
+			description += " and "
+		}
+		description += describeDependency(dep)
+	}
+	return description
+}
+
+func describeDependency(dep bundle.Dependency) string {
+	if dep.Value == nil {
+		return fmt.Sprintf("[%v] is set", dep.Key)
+	}
+	return fmt.Sprintf("[%v] is [%v]", dep.Key, dep.Value)
+}
@@ -0,0 +1,160 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+var oauthPlan = bundle.Plan{
+	Name: "default",
+	Parameters: []bundle.ParameterDescriptor{
+		{
+			Name:         "client_id",
+			Type:         "string",
+			Required:     true,
+			Dependencies: []bundle.Dependency{{Key: "auth_type", Value: "oauth"}},
+		},
+		{Name: "auth_type", Type: "enum", Enum: []string{"basic", "oauth"}},
+		{
+			Name:         "password",
+			Type:         "string",
+			Dependencies: []bundle.Dependency{{Key: "auth_type", Value: "basic"}},
+		},
+	},
+}
+
+func TestOrderParameters(t *testing.T) {
+	ordered, err := orderParameters(oauthPlan.Parameters)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	var names []string
+	for _, param := range ordered {
+		names = append(names, param.Name)
+	}
+	expected := []string{"auth_type", "client_id", "password"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected parameters %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("expected parameters %v, got %v", expected, names)
+		}
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	testCases := []struct {
+		name      string
+		params    bundle.Parameters
+		shouldErr bool
+	}{
+		{
+			name:   "test dependent parameter set",
+			params: bundle.Parameters{"auth_type": "oauth", "client_id": "apb"},
+		},
+		{
+			name:      "test conditionally required parameter missing",
+			params:    bundle.Parameters{"auth_type": "oauth"},
+			shouldErr: true,
+		},
+		{
+			name:   "test optional dependent parameter missing",
+			params: bundle.Parameters{"auth_type": "basic"},
+		},
+		{
+			name:      "test exclusive parameter set",
+			params:    bundle.Parameters{"auth_type": "oauth", "client_id": "apb", "password": "secret"},
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateDependencies(oauthPlan, tc.params)
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("expected error but parameters were accepted")
+			}
+		})
+	}
+}
+
+func TestActiveParameters(t *testing.T) {
+	active := activeParameters(oauthPlan, bundle.Parameters{"auth_type": "basic"})
+	if len(active.Parameters) != 2 || active.GetParameter("client_id") != nil {
+		t.Fatalf("expected client_id to be inactive, got %v", active.Parameters)
+	}
+}
+
+var networkPlan = bundle.Plan{
+	Name: "default",
+	Parameters: []bundle.ParameterDescriptor{
+		{Name: "subnet", Type: "string", Dependencies: []bundle.Dependency{{Key: "provider"}}},
+		{Name: "monitoring_endpoint", Type: "string", Dependencies: []bundle.Dependency{{Key: "monitoring", Value: true}}},
+		{Name: "provider", Type: "enum", Enum: []string{"aws", "gce"}},
+		{Name: "monitoring", Type: "boolean"},
+	},
+}
+
+func TestSelectParametersDependencies(t *testing.T) {
+	savedReader := promptReader
+	defer func() { promptReader = savedReader }()
+	testCases := []struct {
+		name     string
+		input    string
+		expected bundle.Parameters
+	}{
+		{
+			name:     "test toggle off skips its parameter",
+			input:    "aws\n10.0.0.0/24\nfalse\n",
+			expected: bundle.Parameters{"provider": "aws", "subnet": "10.0.0.0/24", "monitoring": false},
+		},
+		{
+			name:     "test toggle on prompts for its parameter",
+			input:    "gce\n10.1.0.0/24\ntrue\nhttp://metrics\n",
+			expected: bundle.Parameters{"provider": "gce", "subnet": "10.1.0.0/24", "monitoring": true, "monitoring_endpoint": "http://metrics"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			promptReader = strings.NewReader(tc.input)
+			params, err := selectParameters(networkPlan, nil, nil, nil, nil, nil, nil, false)
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if !reflect.DeepEqual(params, tc.expected) {
+				t.Fatalf("expected parameters %v, got %v", tc.expected, params)
+			}
+		})
+	}
+}
+
+func TestUnmetDependencyWithoutValue(t *testing.T) {
+	subnet := networkPlan.GetParameter("subnet")
+	if _, unmet := unmetDependency(*subnet, bundle.Parameters{}); !unmet {
+		t.Fatalf("expected subnet to be skipped without a provider")
+	}
+	if _, unmet := unmetDependency(*subnet, bundle.Parameters{"provider": "aws"}); unmet {
+		t.Fatalf("expected subnet to apply once a provider is chosen")
+	}
+}
+
+func TestOrderParametersCycle(t *testing.T) {
+	params := []bundle.ParameterDescriptor{
+		{Name: "zone", Type: "string", Dependencies: []bundle.Dependency{{Key: "region"}}},
+		{Name: "region", Type: "string", Dependencies: []bundle.Dependency{{Key: "zone"}}},
+		{Name: "name", Type: "string"},
+	}
+	_, err := orderParameters(params)
+	if err == nil {
+		t.Fatalf("expected error for parameters which depend on each other")
+	}
+	if !strings.Contains(err.Error(), "zone") || !strings.Contains(err.Error(), "region") || strings.Contains(err.Error(), "name") {
+		t.Fatalf("expected the error to name only the cycle, got [%v]", err)
+	}
+}
@@ -0,0 +1,205 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/automationbroker/apb/pkg/util"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// diffLabels are the labels a previous pod of the same run shares with the
+// pod the run would create
+var diffLabels = []string{"bundle-fqname", "bundle-action"}
+
+// findPreviousPod returns the newest pod sharing the diffLabels of the pod
+func findPreviousPod(pods corev1.PodInterface, pod *v1.Pod) (*v1.Pod, error) {
+	set := labels.Set{}
+	for _, l := range diffLabels {
+		set[l] = pod.Labels[l]
+	}
+	previous, err := newestPod(pods, set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods of [%v] in namespace [%v]: %v", set, pod.Namespace, err)
+	}
+	if previous == nil {
+		return nil, fmt.Errorf("no pod of [%v] in namespace [%v] to compare against", set, pod.Namespace)
+	}
+	return previous, nil
+}
+
+// newestPod returns the most recently created pod with the labels, or nil
+// if there is none
+func newestPod(pods corev1.PodInterface, set labels.Set) (*v1.Pod, error) {
+	list, err := pods.List(metav1.ListOptions{LabelSelector: set.String()})
+	if err != nil {
+		return nil, err
+	}
+	var newest *v1.Pod
+	for i := range list.Items {
+		p := &list.Items[i]
+		if newest == nil || newest.CreationTimestamp.Before(&p.CreationTimestamp) {
+			newest = p
+		}
+	}
+	return newest, nil
+}
+
+// podDifferences compares the image, args, env and extra vars of the bundle
+// containers of the pods. The values of the sensitive extra vars are masked
+// and the ignored ones, which differ on every run, are left out.
+func podDifferences(previous *v1.Pod, pod *v1.Pod, sensitive map[string]bool, ignored map[string]bool) ([]string, error) {
+	if len(previous.Spec.Containers) == 0 || len(pod.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("pod [%v] has no containers to compare", previous.Name)
+	}
+	was, is := previous.Spec.Containers[0], pod.Spec.Containers[0]
+	diffs := []string{}
+	if was.Image != is.Image {
+		diffs = append(diffs, fmt.Sprintf("image: %v -> %v", was.Image, is.Image))
+	}
+
+	wasVars, wasArgs, err := splitExtraVars(was.Args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the extra vars of pod [%v]: %v", previous.Name, err)
+	}
+	isVars, isArgs, err := splitExtraVars(is.Args)
+	if err != nil {
+		return nil, err
+	}
+	if !reflect.DeepEqual(wasArgs, isArgs) {
+		diffs = append(diffs, fmt.Sprintf("args: %v -> %v", wasArgs, isArgs))
+	}
+
+	wasEnv, isEnv := envValues(was.Env), envValues(is.Env)
+	for _, name := range unionKeys(wasEnv, isEnv) {
+		if !reflect.DeepEqual(wasEnv[name], isEnv[name]) {
+			diffs = append(diffs, fmt.Sprintf("env [%v]: %v -> %v", name, diffValue(wasEnv[name], false), diffValue(isEnv[name], false)))
+		}
+	}
+
+	for _, name := range unionKeys(wasVars, isVars) {
+		if ignored[name] || reflect.DeepEqual(wasVars[name], isVars[name]) {
+			continue
+		}
+		diffs = append(diffs, fmt.Sprintf("extra var [%v]: %v -> %v", name, diffValue(wasVars[name], sensitive[name]), diffValue(isVars[name], sensitive[name])))
+	}
+	return diffs, nil
+}
+
+// printPodDifferences prints the differences of the pod from the previous
+// pod of the run
+func printPodDifferences(previous *v1.Pod, pod *v1.Pod, sensitive map[string]bool, ignored map[string]bool, w io.Writer) error {
+	diffs, err := podDifferences(previous, pod, sensitive, ignored)
+	if err != nil {
+		return err
+	}
+	if len(diffs) == 0 {
+		fmt.Fprintf(w, "No differences from pod [%v/%v]\n", previous.Namespace, previous.Name)
+		return nil
+	}
+	fmt.Fprintf(w, "Differences from pod [%v/%v]:\n", previous.Namespace, previous.Name)
+	for _, d := range diffs {
+		fmt.Fprintf(w, "  %v\n", d)
+	}
+	return nil
+}
+
+// diffPreviousPod prints the differences of the pod from the newest pod of
+// the same bundle and action in its namespace
+func diffPreviousPod(pod *v1.Pod, sensitive map[string]bool, ignored map[string]bool, w io.Writer) error {
+	k8scli, err := util.KubernetesClient()
+	if err != nil {
+		return err
+	}
+	previous, err := findPreviousPod(k8scli.Client.CoreV1().Pods(pod.Namespace), pod)
+	if err != nil {
+		return err
+	}
+	return printPodDifferences(previous, pod, sensitive, ignored, w)
+}
+
+// splitExtraVars returns the decoded extra vars passed in the args, and the
+// args without them
+func splitExtraVars(args []string) (map[string]interface{}, []string, error) {
+	vars := map[string]interface{}{}
+	rest := []string{}
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--extra-vars" && i+1 < len(args) {
+			if err := json.Unmarshal([]byte(args[i+1]), &vars); err != nil {
+				return nil, nil, err
+			}
+			i++
+			continue
+		}
+		rest = append(rest, args[i])
+	}
+	return vars, rest, nil
+}
+
+// envValues returns the value of each env var, or its source when it is
+// taken from elsewhere
+func envValues(env []v1.EnvVar) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, e := range env {
+		if e.ValueFrom != nil {
+			b, _ := json.Marshal(e.ValueFrom)
+			values[e.Name] = string(b)
+			continue
+		}
+		values[e.Name] = e.Value
+	}
+	return values
+}
+
+func unionKeys(a map[string]interface{}, b map[string]interface{}) []string {
+	keys := []string{}
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func diffValue(value interface{}, sensitive bool) string {
+	switch {
+	case value == nil:
+		return "<unset>"
+	case sensitive:
+		return redactedValue
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(b)
+}
@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/runtime"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func diffPod(t *testing.T, name string, image string, extraVars string, created time.Time) *v1.Pod {
+	pod, err := BuildPod(runtime.ExecutionContext{
+		BundleName: name,
+		Metadata:   map[string]string{"bundle-fqname": "mediawiki-apb", "bundle-action": "provision"},
+		Action:     "provision",
+		Image:      image,
+		Account:    name,
+		Location:   "wiki",
+		ExtraVars:  extraVars,
+	})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	pod.CreationTimestamp = metav1.NewTime(created)
+	return pod
+}
+
+func TestFindPreviousPod(t *testing.T) {
+	now := time.Now()
+	older := diffPod(t, "bundle-1", "mediawiki-apb:1", `{}`, now.Add(-2*time.Hour))
+	newer := diffPod(t, "bundle-2", "mediawiki-apb:1", `{}`, now.Add(-time.Hour))
+	other := diffPod(t, "bundle-3", "mediawiki-apb:1", `{}`, now)
+	other.Labels = map[string]string{"bundle-fqname": "mediawiki-apb", "bundle-action": "deprovision"}
+	pod := diffPod(t, "bundle-4", "mediawiki-apb:1", `{}`, now)
+
+	previous, err := findPreviousPod(newFakePods(older, newer, other), pod)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if previous.Name != newer.Name {
+		t.Fatalf("expected the newest pod [%v], got [%v]", newer.Name, previous.Name)
+	}
+	if _, err := findPreviousPod(newFakePods(other), pod); err == nil || !strings.Contains(err.Error(), "no pod") {
+		t.Fatalf("expected no pod to compare against, got [%v]", err)
+	}
+}
+
+func TestPodDifferences(t *testing.T) {
+	now := time.Now()
+	previous := diffPod(t, "bundle-1", "mediawiki-apb:1", `{"_apb_account":"bundle-1","mediawiki_admin_pass":"old","mediawiki_site_name":"Wiki","namespace":"wiki"}`, now)
+	testCases := []struct {
+		name     string
+		pod      *v1.Pod
+		ignored  map[string]bool
+		expected []string
+	}{
+		{
+			name:     "no differences",
+			pod:      diffPod(t, "bundle-2", "mediawiki-apb:1", `{"_apb_account":"bundle-2","mediawiki_admin_pass":"old","mediawiki_site_name":"Wiki","namespace":"wiki"}`, now),
+			ignored:  map[string]bool{"_apb_account": true},
+			expected: []string{},
+		},
+		{
+			name: "image and extra vars",
+			pod:  diffPod(t, "bundle-2", "mediawiki-apb:2", `{"_apb_account":"bundle-2","mediawiki_admin_pass":"new","replicas":2,"namespace":"wiki"}`, now),
+			expected: []string{
+				"image: mediawiki-apb:1 -> mediawiki-apb:2",
+				"extra var [_apb_account]: bundle-1 -> bundle-2",
+				"extra var [mediawiki_admin_pass]: *** -> ***",
+				"extra var [mediawiki_site_name]: Wiki -> <unset>",
+				"extra var [replicas]: <unset> -> 2",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			diffs, err := podDifferences(previous, tc.pod, map[string]bool{"mediawiki_admin_pass": true}, tc.ignored)
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if !reflect.DeepEqual(diffs, tc.expected) {
+				t.Fatalf("expected differences %v, got %v", tc.expected, diffs)
+			}
+		})
+	}
+
+	env := diffPod(t, "bundle-2", "mediawiki-apb:1", `{}`, now)
+	env.Spec.Containers[0].Env = append(env.Spec.Containers[0].Env, v1.EnvVar{Name: "DEBUG", Value: "true"})
+	env.Spec.Containers[0].Args = append([]string{"--verbose"}, env.Spec.Containers[0].Args...)
+	diffs, err := podDifferences(diffPod(t, "bundle-1", "mediawiki-apb:1", `{}`, now), env, nil, nil)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected := []string{
+		"args: [provision] -> [--verbose provision]",
+		"env [DEBUG]: <unset> -> true",
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Fatalf("expected differences %v, got %v", expected, diffs)
+	}
+
+	var out bytes.Buffer
+	if err := printPodDifferences(previous, previous, nil, nil, &out); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if out.String() != "No differences from pod [wiki/bundle-1]\n" {
+		t.Fatalf("unexpected output [%v]", out.String())
+	}
+}
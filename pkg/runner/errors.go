@@ -0,0 +1,54 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import "errors"
+
+// The kinds of failure of a run which callers may want to tell apart, e.g.
+// with errors.Is(err, ErrSpecNotFound). Invalid parameters are reported as
+// ValidationErrors instead.
+var (
+	// ErrSpecNotFound is a bundle missing from where specs were looked for
+	ErrSpecNotFound = errors.New("APB not found")
+	// ErrClientInit is a failure to build the Kubernetes client
+	ErrClientInit = errors.New("failed to create the Kubernetes client")
+	// ErrPodCreate is a failure to create the bundle pod
+	ErrPodCreate = errors.New("failed to create the APB pod")
+)
+
+// runError marks an error with the kind of failure it is, keeping its
+// message and what it wraps
+type runError struct {
+	kind error
+	err  error
+}
+
+func (e *runError) Error() string {
+	return e.err.Error()
+}
+
+func (e *runError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// markError marks the error as the kind of failure, leaving nil as it is
+func markError(kind error, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &runError{kind: kind, err: err}
+}
@@ -0,0 +1,30 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMarkError(t *testing.T) {
+	cause := fmt.Errorf("connection refused")
+	err := markError(ErrClientInit, cause)
+	if err.Error() != "connection refused" {
+		t.Fatalf("expected the message of the marked error, got [%v]", err)
+	}
+	if !errors.Is(err, ErrClientInit) {
+		t.Fatalf("expected [%v] to be an ErrClientInit", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Fatalf("expected [%v] to wrap its cause", err)
+	}
+	if errors.Is(err, ErrPodCreate) || errors.Is(err, ErrSpecNotFound) {
+		t.Fatalf("expected [%v] to only be an ErrClientInit", err)
+	}
+	if markError(ErrPodCreate, nil) != nil {
+		t.Fatalf("expected no error to stay nil")
+	}
+	if err := specNotFound("mysql-apb", "configured registries"); !errors.Is(err, ErrSpecNotFound) {
+		t.Fatalf("expected [%v] to be an ErrSpecNotFound", err)
+	}
+}
@@ -0,0 +1,144 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// maxFailureEvents is how many of a pod's most recent events are attached
+// to its failure
+const maxFailureEvents = 10
+
+// podEvents returns the events of the pod, oldest first
+func podEvents(events corev1.EventInterface, podName string) ([]v1.Event, error) {
+	selector := fields.Set{
+		"involvedObject.kind": "Pod",
+		"involvedObject.name": podName,
+	}.AsSelector().String()
+	list, err := events.List(metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(list.Items, func(i, j int) bool {
+		return list.Items[i].LastTimestamp.Before(&list.Items[j].LastTimestamp)
+	})
+	return list.Items, nil
+}
+
+// describeFailure returns the failure with why the pod's containers
+// terminated and its most recent events, so it can be diagnosed without
+// describing the pod. The pod may already be gone, leaving only events.
+func describeFailure(failure error, pods corev1.PodInterface, events corev1.EventInterface, podName string) error {
+	var details bytes.Buffer
+	if pod, err := pods.Get(podName, metav1.GetOptions{}); err == nil {
+		for _, status := range pod.Status.ContainerStatuses {
+			if t := status.State.Terminated; t != nil {
+				fmt.Fprintf(&details, "\n  container [%v] terminated: %v, exit code %v", status.Name, t.Reason, t.ExitCode)
+				// the message the bundle wrote or, with the
+				// FallbackToLogsOnError policy, the end of its logs
+				if message := strings.TrimSpace(t.Message); message != "" {
+					fmt.Fprintf(&details, "\n    %v", strings.Replace(message, "\n", "\n    ", -1))
+				}
+			} else if w := status.State.Waiting; w != nil && w.Reason != "" {
+				fmt.Fprintf(&details, "\n  container [%v] waiting: %v %v", status.Name, w.Reason, w.Message)
+			}
+		}
+	}
+
+	recent, err := podEvents(events, podName)
+	if err != nil {
+		log.Warningf("Failed to get events of pod [%v]: %v", podName, err)
+	}
+	if len(recent) > maxFailureEvents {
+		recent = recent[len(recent)-maxFailureEvents:]
+	}
+	for _, event := range recent {
+		fmt.Fprintf(&details, "\n  %v %v: %v", event.Type, event.Reason, event.Message)
+		if event.Count > 1 {
+			fmt.Fprintf(&details, " (x%v)", event.Count)
+		}
+	}
+	if details.Len() == 0 {
+		return failure
+	}
+	return fmt.Errorf("%v:%v", failure, details.String())
+}
+
+// eventRecorder writes the events of the bundle pod to a file as they occur,
+// so they can be looked at after the run. Each event is written once, and
+// again whenever it recurs.
+type eventRecorder struct {
+	events corev1.EventInterface
+	out    io.Writer
+	// seen is the count each event was last written with
+	seen map[string]int32
+}
+
+func newEventRecorder(events corev1.EventInterface, out io.Writer) *eventRecorder {
+	return &eventRecorder{events: events, out: out, seen: map[string]int32{}}
+}
+
+// record writes the events of the pod which are new since the last call.
+// Each is synced to disk once written, so a crash leaves those before it.
+func (r *eventRecorder) record(podName string) error {
+	recent, err := podEvents(r.events, podName)
+	if err != nil {
+		return err
+	}
+	for _, event := range recent {
+		key := event.Namespace + "/" + event.Name
+		if count, ok := r.seen[key]; ok && count == event.Count {
+			continue
+		}
+		r.seen[key] = event.Count
+		timestamp := event.LastTimestamp.Time
+		if timestamp.IsZero() {
+			timestamp = event.FirstTimestamp.Time
+		}
+		if _, err := fmt.Fprintf(r.out, "%v %v %v %v: %v\n", timestamp.UTC().Format(time.RFC3339), podName, event.Type, event.Reason, event.Message); err != nil {
+			return err
+		}
+		if f, ok := r.out.(*os.File); ok {
+			if err := f.Sync(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// check records the pod's events on each poll of a wait. Failing to record
+// them is logged rather than ending the wait.
+func (r *eventRecorder) check(pod *v1.Pod) error {
+	if err := r.record(pod.Name); err != nil {
+		log.Warningf("Failed to record events of pod [%v]: %v", pod.Name, err)
+	}
+	return nil
+}
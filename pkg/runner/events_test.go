@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func podEvent(podName string, reason string, message string, count int32, age time.Duration) v1.Event {
+	return v1.Event{
+		InvolvedObject: v1.ObjectReference{Kind: "Pod", Name: podName},
+		Type:           v1.EventTypeWarning,
+		Reason:         reason,
+		Message:        message,
+		Count:          count,
+		LastTimestamp:  metav1.NewTime(time.Now().Add(-age)),
+	}
+}
+
+func TestDescribeFailure(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "bundle-1234"},
+		Status: v1.PodStatus{
+			Phase: v1.PodFailed,
+			ContainerStatuses: []v1.ContainerStatus{{
+				Name:  "apb",
+				State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137, Message: "TASK [create database]\nfatal: out of memory\n"}},
+			}},
+		},
+	}
+	events := &fakeEvents{events: []v1.Event{
+		podEvent("bundle-1234", "BackOff", "Back-off restarting failed container", 3, time.Minute),
+		podEvent("bundle-1234", "FailedScheduling", "0/3 nodes are available: 3 Insufficient memory.", 1, time.Hour),
+		podEvent("bundle-5678", "Killing", "Stopping container apb", 1, time.Minute),
+	}}
+
+	err := describeFailure(errors.New("pod [bundle-1234] failed"), newFakePods(pod), events, "bundle-1234")
+	expected := "pod [bundle-1234] failed:" +
+		"\n  container [apb] terminated: OOMKilled, exit code 137" +
+		"\n    TASK [create database]" +
+		"\n    fatal: out of memory" +
+		"\n  Warning FailedScheduling: 0/3 nodes are available: 3 Insufficient memory." +
+		"\n  Warning BackOff: Back-off restarting failed container (x3)"
+	if err.Error() != expected {
+		t.Fatalf("expected error:\n%v\ngot:\n%v", expected, err)
+	}
+
+	// the pod is gone after cleanup, leaving its events
+	err = describeFailure(errors.New("pod [bundle-1234] failed"), newFakePods(), events, "bundle-1234")
+	if strings.Contains(err.Error(), "OOMKilled") || !strings.Contains(err.Error(), "BackOff") {
+		t.Fatalf("expected only events without the pod, got:\n%v", err)
+	}
+
+	failure := errors.New("timed out")
+	if err := describeFailure(failure, newFakePods(), &fakeEvents{}, "bundle-1234"); err != failure {
+		t.Fatalf("expected failure without details to be unchanged, got [%v]", err)
+	}
+}
+
+func TestDescribeFailureLimitsEvents(t *testing.T) {
+	events := &fakeEvents{}
+	for i := 0; i < maxFailureEvents+5; i++ {
+		events.events = append(events.events, podEvent("bundle-1234", fmt.Sprintf("Reason%d", i), "message", 1, time.Duration(100-i)*time.Second))
+	}
+	err := describeFailure(errors.New("pod [bundle-1234] failed"), newFakePods(), events, "bundle-1234")
+	if strings.Count(err.Error(), "\n") != maxFailureEvents || strings.Contains(err.Error(), "Reason4:") || !strings.Contains(err.Error(), "Reason14:") {
+		t.Fatalf("expected the %v most recent events, got:\n%v", maxFailureEvents, err)
+	}
+}
+
+func TestEventRecorder(t *testing.T) {
+	scheduling := podEvent("bundle-1234", "FailedScheduling", "0/3 nodes are available", 1, time.Minute)
+	scheduling.Name = "bundle-1234.1"
+	events := &fakeEvents{events: []v1.Event{scheduling}}
+	out := &bytes.Buffer{}
+	recorder := newEventRecorder(events, out)
+
+	if err := recorder.record("bundle-1234"); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	// a poll without new events writes nothing
+	if err := recorder.record("bundle-1234"); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 1 {
+		t.Fatalf("expected 1 line, got %v:\n%v", lines, out)
+	}
+
+	pulled := podEvent("bundle-1234", "Pulled", "Successfully pulled image", 1, 0)
+	pulled.Type = v1.EventTypeNormal
+	pulled.Name = "bundle-1234.2"
+	other := podEvent("bundle-5678", "Killing", "Stopping container apb", 1, 0)
+	other.Name = "bundle-5678.1"
+	events.events[0].Count = 2
+	events.events = append(events.events, pulled, other)
+	if err := recorder.check(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "bundle-1234"}}); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %v:\n%v", len(lines), out)
+	}
+	expected := []string{
+		"bundle-1234 Warning FailedScheduling: 0/3 nodes are available",
+		"bundle-1234 Warning FailedScheduling: 0/3 nodes are available",
+		"bundle-1234 Normal Pulled: Successfully pulled image",
+	}
+	for i, line := range lines {
+		fields := strings.SplitN(line, " ", 2)
+		if _, err := time.Parse(time.RFC3339, fields[0]); err != nil {
+			t.Errorf("line %v: got unexpected timestamp [%v]", i, err)
+		}
+		if fields[1] != expected[i] {
+			t.Errorf("line %v: expected [%v], got [%v]", i, expected[i], fields[1])
+		}
+	}
+}
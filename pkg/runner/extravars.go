@@ -0,0 +1,98 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/automationbroker/apb/pkg/util"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// planIDKey is the extra var holding the name of the plan the bundle ran
+const planIDKey = "_apb_plan_id"
+
+// secretRef names the key of a secret holding the extra vars of a run
+type secretRef struct {
+	namespace string
+	name      string
+	// key is empty to use the secret's only key
+	key string
+}
+
+func (r secretRef) String() string {
+	return r.namespace + "/" + r.name
+}
+
+// parseSecretRef parses a reference given as namespace/name[:key]
+func parseSecretRef(ref string) (secretRef, error) {
+	var r secretRef
+	var err error
+	r.namespace, r.name, r.key, err = parseKeyRef("secret", ref)
+	return r, err
+}
+
+// loadExtraVarsSecret reads the extra vars of a previous run from the key of
+// the secret. They must be a JSON object and are returned unchanged, along
+// with the object they decode to.
+func loadExtraVarsSecret(secrets corev1.SecretInterface, ref secretRef) (string, map[string]interface{}, error) {
+	secret, err := secrets.Get(ref.name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return "", nil, fmt.Errorf("secret [%v] not found", ref)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get secret [%v]: %v", ref, err)
+	}
+	keys := []string{}
+	for k := range secret.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	key := ref.key
+	if key == "" {
+		if len(keys) != 1 {
+			return "", nil, fmt.Errorf("secret [%v] has keys %v. Choose one with %v:<key>", ref, keys, ref)
+		}
+		key = keys[0]
+	}
+	data, ok := secret.Data[key]
+	if !ok {
+		return "", nil, fmt.Errorf("secret [%v] has no key [%v]. Found keys %v", ref, key, keys)
+	}
+	var vars map[string]interface{}
+	if err := json.Unmarshal(data, &vars); err != nil {
+		return "", nil, fmt.Errorf("extra vars in key [%v] of secret [%v] are not a JSON object: %v", key, ref, err)
+	}
+	if vars == nil {
+		return "", nil, fmt.Errorf("extra vars in key [%v] of secret [%v] are not a JSON object", key, ref)
+	}
+	return string(data), vars, nil
+}
+
+// readExtraVarsSecret reads the extra vars of a previous run from the secret
+// in the cluster
+func readExtraVarsSecret(ref secretRef) (string, map[string]interface{}, error) {
+	k8scli, err := util.KubernetesClient()
+	if err != nil {
+		return "", nil, err
+	}
+	return loadExtraVarsSecret(k8scli.Client.CoreV1().Secrets(ref.namespace), ref)
+}
@@ -0,0 +1,154 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseSecretRef(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ref      string
+		expected secretRef
+		err      string
+	}{
+		{
+			name:     "namespace and name",
+			ref:      "apb/run-vars",
+			expected: secretRef{namespace: "apb", name: "run-vars"},
+		},
+		{
+			name:     "with key",
+			ref:      "apb/run-vars:extra-vars",
+			expected: secretRef{namespace: "apb", name: "run-vars", key: "extra-vars"},
+		},
+		{
+			name: "missing namespace",
+			ref:  "run-vars",
+			err:  "Expected namespace/name[:key]",
+		},
+		{
+			name: "empty key",
+			ref:  "apb/run-vars:",
+			err:  "empty key",
+		},
+		{
+			name: "invalid name",
+			ref:  "apb/Run_Vars",
+			err:  "invalid secret name",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := parseSecretRef(tc.ref)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing [%v], got [%v]", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if r != tc.expected {
+				t.Fatalf("expected %#v, got %#v", tc.expected, r)
+			}
+		})
+	}
+}
+
+func TestLoadExtraVarsSecret(t *testing.T) {
+	extraVars := `{"_apb_plan_id":"prod","mysql_user":"admin","namespace":"apb"}`
+	secrets := &fakeSecrets{secrets: map[string]*v1.Secret{
+		"single": {
+			ObjectMeta: metav1.ObjectMeta{Name: "single"},
+			Data:       map[string][]byte{"extra-vars": []byte(extraVars)},
+		},
+		"multiple": {
+			ObjectMeta: metav1.ObjectMeta{Name: "multiple"},
+			Data: map[string][]byte{
+				"extra-vars": []byte(extraVars),
+				"other":      []byte("{}"),
+			},
+		},
+		"invalid": {
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid"},
+			Data:       map[string][]byte{"extra-vars": []byte(`{"mysql_user":`)},
+		},
+		"array": {
+			ObjectMeta: metav1.ObjectMeta{Name: "array"},
+			Data:       map[string][]byte{"extra-vars": []byte(`["admin"]`)},
+		},
+		"null": {
+			ObjectMeta: metav1.ObjectMeta{Name: "null"},
+			Data:       map[string][]byte{"extra-vars": []byte(`null`)},
+		},
+	}}
+	testCases := []struct {
+		name string
+		ref  secretRef
+		err  string
+	}{
+		{
+			name: "only key",
+			ref:  secretRef{namespace: "apb", name: "single"},
+		},
+		{
+			name: "named key",
+			ref:  secretRef{namespace: "apb", name: "multiple", key: "extra-vars"},
+		},
+		{
+			name: "ambiguous key",
+			ref:  secretRef{namespace: "apb", name: "multiple"},
+			err:  "has keys [extra-vars other]",
+		},
+		{
+			name: "missing key",
+			ref:  secretRef{namespace: "apb", name: "single", key: "vars"},
+			err:  "has no key [vars]",
+		},
+		{
+			name: "missing secret",
+			ref:  secretRef{namespace: "apb", name: "missing"},
+			err:  "secret [apb/missing] not found",
+		},
+		{
+			name: "malformed JSON",
+			ref:  secretRef{namespace: "apb", name: "invalid"},
+			err:  "not a JSON object",
+		},
+		{
+			name: "not an object",
+			ref:  secretRef{namespace: "apb", name: "array"},
+			err:  "not a JSON object",
+		},
+		{
+			name: "null",
+			ref:  secretRef{namespace: "apb", name: "null"},
+			err:  "not a JSON object",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, vars, err := loadExtraVarsSecret(secrets, tc.ref)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing [%v], got [%v]", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if data != extraVars {
+				t.Fatalf("expected extra vars [%v] unchanged, got [%v]", extraVars, data)
+			}
+			if vars[planIDKey] != "prod" {
+				t.Fatalf("expected plan [prod], got [%v]", vars[planIDKey])
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// fakePods is an in-memory PodInterface. Methods which aren't overridden
+// panic through the nil embedded interface.
+type fakePods struct {
+	corev1.PodInterface
+	pods map[string]*v1.Pod
+}
+
+func newFakePods(pods ...*v1.Pod) *fakePods {
+	f := &fakePods{pods: map[string]*v1.Pod{}}
+	for _, pod := range pods {
+		f.pods[pod.Name] = pod
+	}
+	return f
+}
+
+func (f *fakePods) Create(pod *v1.Pod) (*v1.Pod, error) {
+	if _, ok := f.pods[pod.Name]; ok {
+		return nil, k8serrors.NewAlreadyExists(schema.GroupResource{Resource: "pods"}, pod.Name)
+	}
+	f.pods[pod.Name] = pod
+	return pod, nil
+}
+
+func (f *fakePods) Get(name string, options metav1.GetOptions) (*v1.Pod, error) {
+	pod, ok := f.pods[name]
+	if !ok {
+		return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "pods"}, name)
+	}
+	return pod, nil
+}
+
+func (f *fakePods) List(options metav1.ListOptions) (*v1.PodList, error) {
+	selector, err := labels.Parse(options.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	list := &v1.PodList{}
+	for _, pod := range f.pods {
+		if selector.Matches(labels.Set(pod.Labels)) {
+			list.Items = append(list.Items, *pod)
+		}
+	}
+	return list, nil
+}
+
+func (f *fakePods) Delete(name string, options *metav1.DeleteOptions) error {
+	pod, ok := f.pods[name]
+	if !ok {
+		return k8serrors.NewNotFound(schema.GroupResource{Resource: "pods"}, name)
+	}
+	if len(pod.Finalizers) > 0 {
+		now := metav1.Now()
+		pod.DeletionTimestamp = &now
+		return nil
+	}
+	delete(f.pods, name)
+	return nil
+}
+
+func (f *fakePods) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (*v1.Pod, error) {
+	pod, ok := f.pods[name]
+	if !ok {
+		return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "pods"}, name)
+	}
+	patch := struct {
+		Metadata struct {
+			Finalizers []string `json:"finalizers"`
+		} `json:"metadata"`
+	}{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, err
+	}
+	pod.Finalizers = patch.Metadata.Finalizers
+	if pod.DeletionTimestamp != nil && len(pod.Finalizers) == 0 {
+		delete(f.pods, name)
+	}
+	return pod, nil
+}
+
+// fakeNodes is an in-memory NodeInterface which only lists nodes
+type fakeNodes struct {
+	corev1.NodeInterface
+	nodes []v1.Node
+}
+
+func (f *fakeNodes) List(opts metav1.ListOptions) (*v1.NodeList, error) {
+	return &v1.NodeList{Items: f.nodes}, nil
+}
+
+// fakeQuotas is an in-memory ResourceQuotaInterface which only lists quotas
+type fakeQuotas struct {
+	corev1.ResourceQuotaInterface
+	quotas []v1.ResourceQuota
+}
+
+func (f *fakeQuotas) List(opts metav1.ListOptions) (*v1.ResourceQuotaList, error) {
+	return &v1.ResourceQuotaList{Items: f.quotas}, nil
+}
+
+// fakeEvents is an in-memory EventInterface which lists the events of the
+// object named in the field selector
+type fakeEvents struct {
+	corev1.EventInterface
+	events []v1.Event
+}
+
+func (f *fakeEvents) List(opts metav1.ListOptions) (*v1.EventList, error) {
+	selector, err := fields.ParseSelector(opts.FieldSelector)
+	if err != nil {
+		return nil, err
+	}
+	list := &v1.EventList{}
+	for _, event := range f.events {
+		if selector.Matches(fields.Set{"involvedObject.kind": event.InvolvedObject.Kind, "involvedObject.name": event.InvolvedObject.Name}) {
+			list.Items = append(list.Items, event)
+		}
+	}
+	return list, nil
+}
+
+// fakeSecrets is an in-memory SecretInterface. A secret in pending is only
+// returned by Get once it has been asked for readyAfter times, as if a
+// controller wrote it in the meantime.
+type fakeSecrets struct {
+	corev1.SecretInterface
+	secrets    map[string]*v1.Secret
+	pending    map[string]*v1.Secret
+	readyAfter int
+	gets       int
+}
+
+func (f *fakeSecrets) Create(secret *v1.Secret) (*v1.Secret, error) {
+	if _, ok := f.secrets[secret.Name]; ok {
+		return nil, k8serrors.NewAlreadyExists(schema.GroupResource{Resource: "secrets"}, secret.Name)
+	}
+	f.secrets[secret.Name] = secret
+	return secret, nil
+}
+
+func (f *fakeSecrets) Update(secret *v1.Secret) (*v1.Secret, error) {
+	if _, ok := f.secrets[secret.Name]; !ok {
+		return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, secret.Name)
+	}
+	f.secrets[secret.Name] = secret
+	return secret, nil
+}
+
+func (f *fakeSecrets) List(options metav1.ListOptions) (*v1.SecretList, error) {
+	selector, err := labels.Parse(options.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	list := &v1.SecretList{}
+	for _, secret := range f.secrets {
+		if selector.Matches(labels.Set(secret.Labels)) {
+			list.Items = append(list.Items, *secret)
+		}
+	}
+	return list, nil
+}
+
+func (f *fakeSecrets) Delete(name string, options *metav1.DeleteOptions) error {
+	if _, ok := f.secrets[name]; !ok {
+		return k8serrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+	}
+	delete(f.secrets, name)
+	return nil
+}
+
+func (f *fakeSecrets) Get(name string, options metav1.GetOptions) (*v1.Secret, error) {
+	f.gets++
+	if pending, ok := f.pending[name]; ok && f.gets > f.readyAfter {
+		f.secrets[name] = pending
+		delete(f.pending, name)
+	}
+	secret, ok := f.secrets[name]
+	if !ok {
+		return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+	}
+	return secret, nil
+}
+
+// fakeConfigMaps is an in-memory ConfigMapInterface which only gets and
+// lists config maps, ignoring label selectors
+type fakeConfigMaps struct {
+	corev1.ConfigMapInterface
+	configMaps map[string]*v1.ConfigMap
+}
+
+func (f *fakeConfigMaps) List(options metav1.ListOptions) (*v1.ConfigMapList, error) {
+	var names []string
+	for name := range f.configMaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := &v1.ConfigMapList{}
+	for _, name := range names {
+		list.Items = append(list.Items, *f.configMaps[name])
+	}
+	return list, nil
+}
+
+func (f *fakeConfigMaps) Get(name string, options metav1.GetOptions) (*v1.ConfigMap, error) {
+	configMap, ok := f.configMaps[name]
+	if !ok {
+		return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, name)
+	}
+	return configMap, nil
+}
+
+// fakeNamespaces is an in-memory NamespaceInterface which only gets and
+// creates namespaces
+type fakeNamespaces struct {
+	corev1.NamespaceInterface
+	namespaces map[string]*v1.Namespace
+}
+
+func (f *fakeNamespaces) Get(name string, options metav1.GetOptions) (*v1.Namespace, error) {
+	ns, ok := f.namespaces[name]
+	if !ok {
+		return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, name)
+	}
+	return ns, nil
+}
+
+func (f *fakeNamespaces) Create(ns *v1.Namespace) (*v1.Namespace, error) {
+	if _, ok := f.namespaces[ns.Name]; ok {
+		return nil, k8serrors.NewAlreadyExists(schema.GroupResource{Resource: "namespaces"}, ns.Name)
+	}
+	f.namespaces[ns.Name] = ns
+	return ns, nil
+}
+
+// fakeProjects stands in for the OpenShift project API, served when
+// available is set
+type fakeProjects struct {
+	available   bool
+	projects    map[string]bool
+	labels      map[string]string
+	annotations map[string]string
+}
+
+func (f *fakeProjects) Available() (bool, error) {
+	return f.available, nil
+}
+
+func (f *fakeProjects) Create(name string) error {
+	if f.projects[name] {
+		return k8serrors.NewAlreadyExists(schema.GroupResource{Group: projectGroup, Resource: "projectrequests"}, name)
+	}
+	f.projects[name] = true
+	return nil
+}
+
+func (f *fakeProjects) Label(name string, labels map[string]string, annotations map[string]string) error {
+	f.labels = labels
+	f.annotations = annotations
+	return nil
+}
+
+// fakeCustomResources serves custom resources of the kinds it has
+// resources for, keyed by group version and kind, with objects keyed by
+// namespace/name
+type fakeCustomResources struct {
+	resources  map[string]string
+	namespaced bool
+	objects    map[string]map[string]interface{}
+}
+
+func (f *fakeCustomResources) Resource(groupVersion string, kind string) (string, bool, error) {
+	resource, ok := f.resources[groupVersion+"/"+kind]
+	if !ok {
+		return "", false, fmt.Errorf("the cluster does not serve [%v]. Is its CRD installed?", groupVersion)
+	}
+	return resource, f.namespaced, nil
+}
+
+func (f *fakeCustomResources) Get(groupVersion string, resource string, namespace string, name string) (map[string]interface{}, error) {
+	object, ok := f.objects[namespace+"/"+name]
+	if !ok {
+		return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: resource}, name)
+	}
+	return object, nil
+}
@@ -0,0 +1,73 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+// format is a check for the structure of a string parameter. The parameter
+// descriptor carries no JSON schema format, so it is named by the
+// parameter's display type.
+type format struct {
+	description string
+	valid       func(string) bool
+}
+
+var formats = map[string]format{
+	"uri":   {description: "URI", valid: isURI},
+	"url":   {description: "URI", valid: isURI},
+	"email": {description: "email address", valid: isEmail},
+	"ipv4":  {description: "IPv4 address", valid: isIPv4},
+}
+
+// checkFormat reports whether the input matches the parameter's format
+func checkFormat(param bundle.ParameterDescriptor, input string) *ValidationError {
+	f, ok := formats[param.DisplayType]
+	if !ok || input == "" || f.valid(input) {
+		return nil
+	}
+	return &ValidationError{
+		Parameter:  param.Name,
+		Constraint: "format",
+		Message:    fmt.Sprintf("[%v] is not a valid %v for parameter [%v]", input, f.description, param.Name),
+	}
+}
+
+func isURI(input string) bool {
+	u, err := url.Parse(input)
+	if err != nil || u.Scheme == "" {
+		return false
+	}
+	return u.Host != "" || u.Opaque != "" || u.Path != ""
+}
+
+func isEmail(input string) bool {
+	addr, err := mail.ParseAddress(input)
+	return err == nil && addr.Address == input
+}
+
+func isIPv4(input string) bool {
+	ip := net.ParseIP(input)
+	return ip != nil && ip.To4() != nil && !strings.Contains(input, ":")
+}
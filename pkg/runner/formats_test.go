@@ -0,0 +1,44 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+func TestCheckFormat(t *testing.T) {
+	testCases := []struct {
+		name      string
+		format    string
+		input     string
+		shouldErr bool
+	}{
+		{name: "test valid uri", format: "uri", input: "https://example.com/path?q=1"},
+		{name: "test valid urn", format: "uri", input: "urn:isbn:0451450523"},
+		{name: "test uri without scheme", format: "uri", input: "example.com/path", shouldErr: true},
+		{name: "test uri with only a scheme", format: "url", input: "https:", shouldErr: true},
+		{name: "test valid email", format: "email", input: "admin@example.com"},
+		{name: "test email without domain", format: "email", input: "admin", shouldErr: true},
+		{name: "test email with display name", format: "email", input: "Admin <admin@example.com>", shouldErr: true},
+		{name: "test valid ipv4", format: "ipv4", input: "10.0.0.10"},
+		{name: "test ipv4 out of range", format: "ipv4", input: "10.0.0.256", shouldErr: true},
+		{name: "test ipv6 is not ipv4", format: "ipv4", input: "::ffff:10.0.0.10", shouldErr: true},
+		{name: "test empty input is left to required", format: "email", input: ""},
+		{name: "test unknown format", format: "password", input: "anything"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			param := bundle.ParameterDescriptor{Name: "value", Type: "string", DisplayType: tc.format}
+			verr := checkFormat(param, tc.input)
+			if verr != nil && !tc.shouldErr {
+				t.Fatalf("got unexpected error [%v]", verr.Message)
+			}
+			if verr == nil && tc.shouldErr {
+				t.Fatalf("expected [%v] to be rejected as %v", tc.input, tc.format)
+			}
+			if verr != nil && verr.Constraint != "format" {
+				t.Fatalf("expected constraint [format], got [%v]", verr.Constraint)
+			}
+		})
+	}
+}
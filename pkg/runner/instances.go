@@ -0,0 +1,205 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/pborman/uuid"
+)
+
+// instancesLock serializes updates to the instance records, which batch runs
+// make concurrently
+var instancesLock sync.Mutex
+
+// outcomes recorded for the last action run on an instance
+const (
+	outcomeStarted   = "started"
+	outcomeSucceeded = "succeeded"
+	outcomeFailed    = "failed"
+)
+
+func loadInstances() []config.Instance {
+	var instances []config.Instance
+	if config.Instances == nil {
+		return instances
+	}
+	config.Instances.UnmarshalKey("Instances", &instances)
+	for i, instance := range instances {
+		// instances recorded before they had IDs get one derived from
+		// where they run, so it stays the same until they are recorded again
+		if instance.ID == "" {
+			instances[i].ID = legacyInstanceID(instance.Bundle, instance.Namespace)
+		}
+	}
+	return instances
+}
+
+func legacyInstanceID(bundleName string, ns string) string {
+	return uuid.NewSHA1(uuid.NameSpace_URL, []byte(bundleName+"/"+ns)).String()
+}
+
+// findInstance returns the bundle's instance in the namespace which has not
+// been deprovisioned
+func findInstance(instances []config.Instance, bundleName string, ns string) (int, bool) {
+	for i, instance := range instances {
+		if instance.Bundle == bundleName && instance.Namespace == ns && !instance.Deprovisioned {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// findInstanceByID returns the instance whose ID is, or uniquely starts
+// with, id
+func findInstanceByID(instances []config.Instance, id string) (int, error) {
+	match := -1
+	for i, instance := range instances {
+		if instance.ID == id {
+			return i, nil
+		}
+		if id != "" && strings.HasPrefix(instance.ID, id) {
+			if match >= 0 {
+				return -1, fmt.Errorf("instance ID [%v] is ambiguous", id)
+			}
+			match = i
+		}
+	}
+	if match < 0 {
+		return -1, fmt.Errorf("no instance with ID [%v] is recorded", id)
+	}
+	return match, nil
+}
+
+// DescribeInstance returns what is recorded about the instance with the
+// given ID, or a unique prefix of it
+func DescribeInstance(id string) (config.Instance, error) {
+	instances := loadInstances()
+	i, err := findInstanceByID(instances, id)
+	if err != nil {
+		return config.Instance{}, err
+	}
+	return instances[i], nil
+}
+
+// previousParameters returns the parameters recorded for the bundle's
+// instance in the namespace, if it was last run with the same plan
+func previousParameters(bundleName string, ns string, planName string) bundle.Parameters {
+	instances := loadInstances()
+	i, found := findInstance(instances, bundleName, ns)
+	if !found || instances[i].Plan != planName {
+		return nil
+	}
+	return bundle.Parameters(instances[i].Parameters)
+}
+
+// recordInstance stores the non-sensitive parameters used to provision or
+// update a bundle, and marks the instance deprovisioned once it is
+// deprovisioned. It returns the ID of the instance the action ran on, or an
+// empty string when nothing was recorded.
+func recordInstance(action string, bundleName string, ns string, plan bundle.Plan, params bundle.Parameters) (string, error) {
+	if config.Instances == nil {
+		return "", nil
+	}
+	instancesLock.Lock()
+	defer instancesLock.Unlock()
+	instances := loadInstances()
+	i, found := findInstance(instances, bundleName, ns)
+	switch action {
+	case "provision", "update":
+		instance := config.Instance{
+			ID:         uuid.New(),
+			Bundle:     bundleName,
+			Namespace:  ns,
+			Plan:       plan.Name,
+			Parameters: recordableParameters(plan, params),
+		}
+		if found {
+			instance.ID = instances[i].ID
+		} else {
+			instances = append(instances, instance)
+			i = len(instances) - 1
+		}
+		instances[i] = instance
+	case "deprovision":
+		if !found {
+			return "", nil
+		}
+		instances[i].Deprovisioned = true
+	default:
+		return "", nil
+	}
+	instances[i].LastAction = action
+	instances[i].LastOutcome = outcomeStarted
+	instances[i].LastResult = ""
+	return instances[i].ID, config.UpdateCachedInstances(config.Instances, instances)
+}
+
+// recordOutcome stores how the last action on the instance ended, and the
+// result its pod reported in its termination message. An instance whose
+// deprovision failed is still considered to exist.
+func recordOutcome(id string, outcome string, result string) error {
+	if config.Instances == nil || id == "" {
+		return nil
+	}
+	instancesLock.Lock()
+	defer instancesLock.Unlock()
+	instances := loadInstances()
+	i, err := findInstanceByID(instances, id)
+	if err != nil {
+		return err
+	}
+	instances[i].LastOutcome = outcome
+	instances[i].LastResult = result
+	if instances[i].LastAction == "deprovision" && outcome == outcomeFailed {
+		instances[i].Deprovisioned = false
+	}
+	return config.UpdateCachedInstances(config.Instances, instances)
+}
+
+// recordableParameters returns the parameters which are safe to store
+// locally, leaving out any the plan marks as passwords
+func recordableParameters(plan bundle.Plan, params bundle.Parameters) map[string]interface{} {
+	recordable := map[string]interface{}{}
+	for name, value := range params {
+		if pd := plan.GetParameter(name); pd != nil && isSensitive(*pd) {
+			continue
+		}
+		recordable[name] = value
+	}
+	return recordable
+}
+
+// sensitiveParameters returns the names of the parameters the plan marks as
+// passwords
+func sensitiveParameters(plan bundle.Plan) map[string]bool {
+	sensitive := map[string]bool{}
+	for _, pd := range plan.Parameters {
+		if isSensitive(pd) {
+			sensitive[pd.Name] = true
+		}
+	}
+	return sensitive
+}
+
+func isSensitive(param bundle.ParameterDescriptor) bool {
+	return param.DisplayType == "password"
+}
@@ -0,0 +1,110 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+func TestDescribeInstance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-instances")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	defer os.RemoveAll(dir)
+	saved := config.Instances
+	defer func() { config.Instances = saved }()
+	config.Instances, _ = config.InitJSONConfig(dir, "instances")
+
+	plan := bundle.Plan{
+		Name: "dev",
+		Parameters: []bundle.ParameterDescriptor{
+			{Name: "user", Type: "string"},
+			{Name: "password", Type: "string", DisplayType: "password"},
+		},
+	}
+	params := bundle.Parameters{"user": "admin", "password": "secret"}
+
+	id, err := recordInstance("provision", "hello-apb", "web", plan, params)
+	if err != nil || id == "" {
+		t.Fatalf("got unexpected error [%v] recording instance [%v]", err, id)
+	}
+	if err := recordOutcome(id, outcomeSucceeded, `{"url":"http://wiki.example.com"}`); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if instance, _ := DescribeInstance(id); instance.LastResult != `{"url":"http://wiki.example.com"}` {
+		t.Fatalf("expected the result to be recorded, got %+v", instance)
+	}
+	updated, err := recordInstance("update", "hello-apb", "web", plan, params)
+	if err != nil || updated != id {
+		t.Fatalf("expected update to keep instance [%v], got [%v] [%v]", id, updated, err)
+	}
+
+	instance, err := DescribeInstance(id[:8])
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if instance.Bundle != "hello-apb" || instance.Namespace != "web" || instance.Plan != "dev" {
+		t.Fatalf("got unexpected instance %+v", instance)
+	}
+	if instance.LastAction != "update" || instance.LastOutcome != outcomeStarted || instance.LastResult != "" || instance.Deprovisioned {
+		t.Fatalf("got unexpected last action %+v", instance)
+	}
+	if _, ok := instance.Parameters["password"]; ok || instance.Parameters["user"] != "admin" {
+		t.Fatalf("got unexpected parameters %v", instance.Parameters)
+	}
+
+	// a failed deprovision leaves the instance in place
+	if _, err := recordInstance("deprovision", "hello-apb", "web", plan, nil); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if err := recordOutcome(id, outcomeFailed, ""); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if instance, _ := DescribeInstance(id); instance.Deprovisioned || instance.LastOutcome != outcomeFailed {
+		t.Fatalf("expected failed deprovision to leave the instance, got %+v", instance)
+	}
+
+	if _, err := recordInstance("deprovision", "hello-apb", "web", plan, nil); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if instance, _ := DescribeInstance(id); !instance.Deprovisioned || instance.LastAction != "deprovision" {
+		t.Fatalf("expected instance to be deprovisioned, got %+v", instance)
+	}
+	if previous := previousParameters("hello-apb", "web", "dev"); previous != nil {
+		t.Fatalf("expected no parameters for a deprovisioned instance, got %v", previous)
+	}
+
+	// provisioning again creates a new instance
+	second, err := recordInstance("provision", "hello-apb", "web", plan, params)
+	if err != nil || second == id {
+		t.Fatalf("expected a new instance, got [%v] [%v]", second, err)
+	}
+	if _, err := DescribeInstance(""); err == nil {
+		t.Fatalf("expected an empty ID to fail")
+	}
+	if _, err := DescribeInstance("missing"); err == nil {
+		t.Fatalf("expected an unknown ID to fail")
+	}
+}
+
+func TestLegacyInstanceID(t *testing.T) {
+	saved := config.Instances
+	defer func() { config.Instances = saved }()
+	dir, err := ioutil.TempDir("", "apb-instances")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	defer os.RemoveAll(dir)
+	config.Instances, _ = config.InitJSONConfig(dir, "instances")
+	config.Instances.Set("Instances", []map[string]interface{}{{"Bundle": "hello-apb", "Namespace": "web"}})
+
+	id := legacyInstanceID("hello-apb", "web")
+	instance, err := DescribeInstance(id)
+	if err != nil || instance.Bundle != "hello-apb" {
+		t.Fatalf("expected legacy instance under [%v], got %+v [%v]", id, instance, err)
+	}
+}
@@ -0,0 +1,77 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// errInterrupted ends a wait on the bundle pod which was interrupted
+var errInterrupted = errors.New("interrupted")
+
+// notifyInterrupt returns a channel which is closed on the first SIGINT or
+// SIGTERM, and a function to stop listening for them. Signals after the
+// first are handled as usual, so interrupting again exits at once.
+func notifyInterrupt() (<-chan struct{}, func()) {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			fmt.Printf("\nReceived %v. Stopping...\n", sig)
+			close(stop)
+		case <-done:
+		}
+	}()
+	return stop, func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// interrupted reports whether stop has been closed
+func interrupted(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// cleanupInterrupted deletes the pod of an interrupted run when cleanup is
+// set, or else says how to, and returns the error ending the run
+func cleanupInterrupted(pods corev1.PodInterface, podName string, ns string, cleanup bool, force bool) error {
+	if !cleanup {
+		fmt.Printf("Left pod [%v] running in namespace [%v]. Delete it with `oc delete pod %v -n %v`\n", podName, ns, podName, ns)
+		return fmt.Errorf("interrupted while running pod [%v]", podName)
+	}
+	if err := cleanupPod(pods, podName, force); err != nil {
+		return fmt.Errorf("interrupted while running pod [%v], and failed to delete it: %v", podName, err)
+	}
+	fmt.Printf("Deleted pod [%v]\n", podName)
+	return fmt.Errorf("interrupted while running pod [%v]", podName)
+}
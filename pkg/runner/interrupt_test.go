@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCleanupInterrupted(t *testing.T) {
+	savedGracePeriod, savedPollInterval := cleanupGracePeriod, pollInterval
+	defer func() { cleanupGracePeriod, pollInterval = savedGracePeriod, savedPollInterval }()
+	cleanupGracePeriod = 10 * time.Millisecond
+	pollInterval = time.Millisecond
+	testCases := []struct {
+		name    string
+		cleanup bool
+	}{
+		{name: "test pod left running", cleanup: false},
+		{name: "test pod deleted", cleanup: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pods := newFakePods(&v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "bundle-1234"},
+				Status:     v1.PodStatus{Phase: v1.PodRunning},
+			})
+			if err := cleanupInterrupted(pods, "bundle-1234", "foo-ns", tc.cleanup, false); err == nil {
+				t.Fatalf("expected an interrupted run to fail")
+			}
+			if _, exists := pods.pods["bundle-1234"]; exists == tc.cleanup {
+				t.Fatalf("expected pod to exist [%v], got [%v]", !tc.cleanup, exists)
+			}
+		})
+	}
+}
+
+func TestInterrupted(t *testing.T) {
+	stop := make(chan struct{})
+	if interrupted(stop) || interrupted(nil) {
+		t.Fatalf("expected an open stop channel not to be interrupted")
+	}
+	close(stop)
+	if !interrupted(stop) {
+		t.Fatalf("expected a closed stop channel to be interrupted")
+	}
+}
+
+func TestInterruptCleanupOption(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     []Option
+		expected bool
+	}{
+		{name: "test default", expected: false},
+		{name: "test interrupt cleanup", opts: []Option{WithInterruptCleanup()}, expected: true},
+		{name: "test cleanup", opts: []Option{WithCleanup(false)}, expected: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := newOptions(tc.opts)
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if o.cleanupOnInterrupt != tc.expected {
+				t.Fatalf("expected cleanup on interrupt [%v], got [%v]", tc.expected, o.cleanupOnInterrupt)
+			}
+		})
+	}
+}
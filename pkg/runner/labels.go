@@ -0,0 +1,95 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"github.com/automationbroker/bundle-lib/registries/adapters"
+	log "github.com/sirupsen/logrus"
+)
+
+// labelTools are the commands tried, in order, to read the labels of an
+// image, each printing them as JSON. docker finds images built locally,
+// skopeo those in a registry.
+var labelTools = []struct {
+	name   string
+	args   func(image string) []string
+	labels func(output []byte) (map[string]string, error)
+}{
+	{
+		"docker",
+		func(image string) []string {
+			return []string{"image", "inspect", "--format", "{{json .Config.Labels}}", image}
+		},
+		func(output []byte) (map[string]string, error) {
+			labels := map[string]string{}
+			err := json.Unmarshal(output, &labels)
+			return labels, err
+		},
+	},
+	{
+		"skopeo",
+		func(image string) []string { return []string{"inspect", "docker://" + image} },
+		func(output []byte) (map[string]string, error) {
+			inspected := struct {
+				Labels map[string]string `json:"Labels"`
+			}{}
+			err := json.Unmarshal(output, &inspected)
+			return inspected.Labels, err
+		},
+	},
+}
+
+var inspectLabels = func(image string) (map[string]string, error) {
+	err := errors.New("no tool to inspect image labels (docker or skopeo) found in PATH")
+	for _, tool := range labelTools {
+		if _, lookErr := lookPath(tool.name); lookErr != nil {
+			continue
+		}
+		output, runErr := exec.Command(tool.name, tool.args(image)...).Output()
+		if runErr != nil {
+			err = fmt.Errorf("%v: %v", tool.name, runErr)
+			continue
+		}
+		return tool.labels(output)
+	}
+	return nil, err
+}
+
+// checkBundleLabels fails when the image lacks the label holding the APB's
+// spec, which every bundle image carries
+func checkBundleLabels(image string, labels map[string]string) error {
+	if labels[adapters.BundleSpecLabel] == "" {
+		return fmt.Errorf("image [%v] is not a valid APB (missing spec label [%v])", image, adapters.BundleSpecLabel)
+	}
+	return nil
+}
+
+// preflightLabels checks the bundle image is an APB before it is run. The
+// check is skipped when the image's labels can't be read.
+func preflightLabels(image string) error {
+	labels, err := inspectLabels(image)
+	if err != nil {
+		log.Warningf("Skipping APB label check, failed to inspect image [%v]: %v", image, err)
+		return nil
+	}
+	return checkBundleLabels(image, labels)
+}
@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPreflightLabels(t *testing.T) {
+	defer func(orig func(string) (map[string]string, error)) { inspectLabels = orig }(inspectLabels)
+	testCases := []struct {
+		name       string
+		labels     map[string]string
+		inspectErr error
+		shouldErr  bool
+	}{
+		{
+			name:   "test APB image",
+			labels: map[string]string{"com.redhat.apb.spec": "dmVyc2lvbjogMS4w", "com.redhat.apb.runtime": "2"},
+		},
+		{
+			name:      "test image without spec label",
+			labels:    map[string]string{"maintainer": "someone"},
+			shouldErr: true,
+		},
+		{
+			name:      "test image without labels",
+			shouldErr: true,
+		},
+		{
+			name:       "test image can't be inspected",
+			inspectErr: errors.New("no such image"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			inspectLabels = func(string) (map[string]string, error) { return tc.labels, tc.inspectErr }
+			err := preflightLabels("docker.io/library/nginx")
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("expected error for an image which is not an APB")
+			}
+			if err != nil && !strings.Contains(err.Error(), "is not a valid APB (missing spec label") {
+				t.Fatalf("got unclear error [%v]", err)
+			}
+		})
+	}
+}
+
+func TestLabelTools(t *testing.T) {
+	outputs := map[string]string{
+		"docker": `{"com.redhat.apb.spec": "c3BlYw=="}`,
+		"skopeo": `{"Name": "docker.io/example/hello-apb", "Labels": {"com.redhat.apb.spec": "c3BlYw=="}}`,
+	}
+	for _, tool := range labelTools {
+		labels, err := tool.labels([]byte(outputs[tool.name]))
+		if err != nil || labels["com.redhat.apb.spec"] != "c3BlYw==" {
+			t.Fatalf("expected [%v] output to give the spec label, got %v [%v]", tool.name, labels, err)
+		}
+	}
+}
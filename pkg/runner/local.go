@@ -0,0 +1,94 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// buildTools are the commands tried, in order, to build a local bundle image
+var buildTools = [][]string{
+	{"docker", "build"},
+	{"podman", "build"},
+	{"buildah", "bud"},
+}
+
+var lookPath = exec.LookPath
+
+// LoadLocalSpec reads the apb.yml of a bundle directory
+func LoadLocalSpec(dir string) (*bundle.Spec, error) {
+	specFile, err := ioutil.ReadFile(filepath.Join(dir, "apb.yml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read APB metadata from [%v]: %v", dir, err)
+	}
+	spec := &bundle.Spec{}
+	if err := yaml.Unmarshal(specFile, spec); err != nil {
+		return nil, fmt.Errorf("failed to parse APB metadata from [%v]: %v", dir, err)
+	}
+	if spec.FQName == "" || len(spec.Plans) == 0 {
+		return nil, fmt.Errorf("APB metadata in [%v] must have a name and at least one plan", dir)
+	}
+	return spec, nil
+}
+
+// findBuildTool returns the command used to build an image, preferring the
+// first of buildTools found in the PATH
+func findBuildTool() ([]string, error) {
+	for _, tool := range buildTools {
+		if _, err := lookPath(tool[0]); err == nil {
+			return tool, nil
+		}
+	}
+	return nil, errors.New("no container build tool (docker, podman or buildah) found in PATH. " +
+		"Build and push the APB image, then run it from a registry")
+}
+
+// localImage is the reference of the image built for a bundle directory
+func localImage(spec *bundle.Spec) string {
+	return fmt.Sprintf("apb-dev/%v:latest", spec.FQName)
+}
+
+// buildLocalImage builds the image of a bundle directory and returns its
+// reference. The image is only available to clusters sharing the local
+// container storage, such as 'oc cluster up' or minishift.
+func buildLocalImage(dir string, spec *bundle.Spec) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "Dockerfile")); err != nil {
+		return "", fmt.Errorf("failed to find Dockerfile in [%v]", dir)
+	}
+	tool, err := findBuildTool()
+	if err != nil {
+		return "", err
+	}
+	image := localImage(spec)
+	args := []string{tool[1], "-t", image, dir}
+	fmt.Printf("Building image [%v] with %v\n", image, tool[0])
+	build := exec.Command(tool[0], args...)
+	build.Stdout = os.Stdout
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		return "", fmt.Errorf("failed to build image from [%v]: %v", dir, err)
+	}
+	return image, nil
+}
@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLoadLocalSpec(t *testing.T) {
+	testCases := []struct {
+		name      string
+		dir       string
+		fqname    string
+		shouldErr bool
+	}{
+		{
+			name:   "test loading local APB",
+			dir:    "testdata/local-apb",
+			fqname: "local-apb",
+		},
+		{
+			name:      "test directory without apb.yml",
+			dir:       "testdata/path/that/doesnt/exist",
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec, err := LoadLocalSpec(tc.dir)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("expected error but loaded spec [%v]", spec.FQName)
+				return
+			}
+			if spec.FQName != tc.fqname {
+				t.Fatalf("expected APB name [%v], got [%v]", tc.fqname, spec.FQName)
+				return
+			}
+			if len(spec.Plans) != 1 || spec.Plans[0].Parameters[0].Default != "hello" {
+				t.Fatalf("unexpected plans in local spec: %v", spec.Plans)
+			}
+		})
+	}
+}
+
+func TestFindBuildTool(t *testing.T) {
+	defer func(orig func(string) (string, error)) { lookPath = orig }(lookPath)
+	testCases := []struct {
+		name      string
+		available []string
+		tool      string
+	}{
+		{
+			name:      "test docker preferred",
+			available: []string{"podman", "docker"},
+			tool:      "docker",
+		},
+		{
+			name:      "test podman fallback",
+			available: []string{"buildah", "podman"},
+			tool:      "podman",
+		},
+		{
+			name:      "test no build tool",
+			available: []string{},
+			tool:      "",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lookPath = func(file string) (string, error) {
+				if contains(tc.available, file) {
+					return "/usr/bin/" + file, nil
+				}
+				return "", errors.New("not found")
+			}
+			tool, err := findBuildTool()
+			if tc.tool == "" {
+				if err == nil {
+					t.Fatalf("expected error but found build tool %v", tool)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+				return
+			}
+			if tool[0] != tc.tool {
+				t.Fatalf("expected build tool [%v], got [%v]", tc.tool, tool[0])
+			}
+		})
+	}
+}
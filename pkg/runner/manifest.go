@@ -0,0 +1,164 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/api/core/v1"
+)
+
+const podManifestFilename = "pod.yaml"
+const podJSONManifestFilename = "pod.json"
+const kustomizationFilename = "kustomization.yaml"
+
+// redactedValue replaces the values of password parameters in dry-run output
+const redactedValue = "***"
+
+type kustomization struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Resources  []string `json:"resources"`
+}
+
+// writeManifest writes the pod in the given format to w, or to a pod.yaml or
+// pod.json file in dir when one is given. The kustomize format instead writes
+// the pod and a kustomization.yaml into dir.
+func writeManifest(pod *v1.Pod, format string, dir string, w io.Writer) error {
+	manifest := pod.DeepCopy()
+	manifest.APIVersion = "v1"
+	manifest.Kind = "Pod"
+
+	var out []byte
+	var err error
+	filename := podManifestFilename
+	switch format {
+	case "json":
+		out, err = json.MarshalIndent(manifest, "", "    ")
+		out = append(out, '\n')
+		filename = podJSONManifestFilename
+	case "kustomize":
+		return writeKustomization(manifest, dir)
+	default:
+		out, err = yaml.Marshal(manifest)
+	}
+	if err != nil {
+		return err
+	}
+	if dir == "" {
+		fmt.Fprintf(w, "%s", out)
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, filename), out, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote [%v] to [%v]\n", filename, dir)
+	return nil
+}
+
+// writeBatchKustomization writes a kustomization.yaml into dir referencing
+// the kustomization written for each namespace of a batch into its
+// subdirectory, so the whole batch can be applied at once
+func writeBatchKustomization(dir string, namespaces []string) error {
+	k, err := yaml.Marshal(kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  namespaces,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, kustomizationFilename), k, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote [%v] to [%v]\n", kustomizationFilename, dir)
+	return nil
+}
+
+// formatExtraVars returns a copy of the pod whose extra vars have the values
+// of the sensitive parameters replaced by redactedValue and, when indent is
+// set, are indented with it
+func formatExtraVars(pod *v1.Pod, sensitive map[string]bool, indent string) (*v1.Pod, error) {
+	redacted := pod.DeepCopy()
+	if len(sensitive) == 0 && indent == "" {
+		return redacted, nil
+	}
+	for c := range redacted.Spec.Containers {
+		args := redacted.Spec.Containers[c].Args
+		for i := 0; i+1 < len(args); i++ {
+			if args[i] != "--extra-vars" {
+				continue
+			}
+			vars := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(args[i+1]), &vars); err != nil {
+				return nil, fmt.Errorf("failed to parse the extra vars of pod [%v]: %v", pod.Name, err)
+			}
+			for name := range vars {
+				if sensitive[name] {
+					vars[name] = redactedValue
+				}
+			}
+			b, err := json.Marshal(vars)
+			if indent != "" {
+				b, err = json.MarshalIndent(vars, "", indent)
+			}
+			if err != nil {
+				return nil, err
+			}
+			args[i+1] = string(b)
+		}
+	}
+	return redacted, nil
+}
+
+func writeKustomization(pod *v1.Pod, dir string) error {
+	podManifest, err := yaml.Marshal(pod)
+	if err != nil {
+		return err
+	}
+	k, err := yaml.Marshal(kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  []string{podManifestFilename},
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, podManifestFilename), podManifest, 0644); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, kustomizationFilename), k, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote [%v] and [%v] to [%v]\n", podManifestFilename, kustomizationFilename, dir)
+	return nil
+}
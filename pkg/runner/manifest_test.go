@@ -0,0 +1,213 @@
+package runner
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWriteManifest(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "bundle-1234",
+			Namespace: "foo-ns",
+		},
+	}
+	testCases := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{
+			name:     "test yaml output",
+			format:   "yaml",
+			expected: "kind: Pod",
+		},
+		{
+			name:     "test json output",
+			format:   "json",
+			expected: `"kind": "Pod"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := writeManifest(pod, tc.format, "", &out); err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+				return
+			}
+			if !strings.Contains(out.String(), tc.expected) || !strings.Contains(out.String(), "bundle-1234") {
+				t.Fatalf("expected [%v] in output, got:\n%v", tc.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestWriteKustomization(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-kustomize")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "bundle-1234",
+			Namespace: "foo-ns",
+		},
+	}
+	if err := writeManifest(pod, "kustomize", dir, ioutil.Discard); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+
+	podManifest, err := ioutil.ReadFile(filepath.Join(dir, "pod.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read pod manifest: %v", err)
+	}
+	written := &v1.Pod{}
+	if err := yaml.Unmarshal(podManifest, written); err != nil {
+		t.Fatalf("failed to parse pod manifest: %v", err)
+	}
+	if written.Kind != "Pod" || written.APIVersion != "v1" || written.Name != "bundle-1234" || written.Namespace != "foo-ns" {
+		t.Fatalf("unexpected pod manifest:\n%s", podManifest)
+	}
+
+	k, err := ioutil.ReadFile(filepath.Join(dir, "kustomization.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read kustomization: %v", err)
+	}
+	kust := kustomization{}
+	if err := yaml.Unmarshal(k, &kust); err != nil {
+		t.Fatalf("failed to parse kustomization: %v", err)
+	}
+	if kust.Kind != "Kustomization" || len(kust.Resources) != 1 || kust.Resources[0] != "pod.yaml" {
+		t.Fatalf("unexpected kustomization:\n%s", k)
+	}
+}
+
+func TestWithDryRun(t *testing.T) {
+	if _, err := newOptions([]Option{WithDryRun("kustomize", "")}); err == nil {
+		t.Fatalf("expected error for kustomize output without a directory")
+	}
+	if _, err := newOptions([]Option{WithDryRun("xml", "")}); err == nil {
+		t.Fatalf("expected error for unrecognized output format")
+	}
+	o, err := newOptions([]Option{WithDryRun("kustomize", "out")})
+	if err != nil || !o.dryRun || o.outputDir != "out" {
+		t.Fatalf("expected kustomize dry run options, got [%+v] [%v]", o, err)
+	}
+}
+
+func TestRedactExtraVars(t *testing.T) {
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{
+		Args: []string{"provision", "--extra-vars", `{"admin_pass":"s3cret","site_name":"Team B"}`},
+	}}}}
+	redacted, err := formatExtraVars(pod, map[string]bool{"admin_pass": true}, "")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected := `{"admin_pass":"***","site_name":"Team B"}`
+	if redacted.Spec.Containers[0].Args[2] != expected {
+		t.Fatalf("expected extra vars [%v], got [%v]", expected, redacted.Spec.Containers[0].Args[2])
+	}
+	if !strings.Contains(pod.Spec.Containers[0].Args[2], "s3cret") {
+		t.Fatalf("redacting changed the pod: %v", pod.Spec.Containers[0].Args)
+	}
+}
+
+func TestIndentExtraVars(t *testing.T) {
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{
+		Args: []string{"provision", "--extra-vars", `{"site_name":"Team B","admin_user":"admin"}`},
+	}}}}
+	formatted, err := formatExtraVars(pod, nil, "  ")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected := "{\n  \"admin_user\": \"admin\",\n  \"site_name\": \"Team B\"\n}"
+	if formatted.Spec.Containers[0].Args[2] != expected {
+		t.Fatalf("expected extra vars:\n%v\ngot:\n%v", expected, formatted.Spec.Containers[0].Args[2])
+	}
+	if strings.Contains(pod.Spec.Containers[0].Args[2], "\n") {
+		t.Fatalf("formatting changed the pod: %v", pod.Spec.Containers[0].Args)
+	}
+}
+
+func TestWriteManifestToDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-manifest")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "bundle-1234",
+			Namespace: "team-a",
+		},
+	}
+	for format, filename := range map[string]string{"yaml": "pod.yaml", "json": "pod.json"} {
+		var out bytes.Buffer
+		if err := writeManifest(pod, format, filepath.Join(dir, "team-a"), &out); err != nil {
+			t.Fatalf("got unexpected error [%v]", err)
+		}
+		if out.Len() != 0 {
+			t.Fatalf("expected nothing printed when writing to a directory, got:\n%v", out.String())
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, "team-a", filename))
+		if err != nil {
+			t.Fatalf("failed to read pod manifest: %v", err)
+		}
+		written := &v1.Pod{}
+		if err := yaml.Unmarshal(data, written); err != nil {
+			t.Fatalf("failed to parse pod manifest: %v", err)
+		}
+		if written.Kind != "Pod" || written.Name != "bundle-1234" || written.Namespace != "team-a" {
+			t.Fatalf("unexpected pod manifest:\n%s", data)
+		}
+	}
+}
+
+func TestWriteBatchKustomization(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-batch")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, ns := range []string{"team-a", "team-b"} {
+		o, err := newOptions([]Option{WithDryRun("kustomize", dir), withTargetOutputDir(ns)})
+		if err != nil {
+			t.Fatalf("got unexpected error [%v]", err)
+		}
+		if o.outputDir != filepath.Join(dir, ns) {
+			t.Fatalf("expected output directory [%v], got [%v]", filepath.Join(dir, ns), o.outputDir)
+		}
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "bundle-1234", Namespace: ns}}
+		if err := writeManifest(pod, "kustomize", o.outputDir, ioutil.Discard); err != nil {
+			t.Fatalf("got unexpected error [%v]", err)
+		}
+	}
+	if err := writeBatchKustomization(dir, []string{"team-a", "team-b"}); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	k, err := ioutil.ReadFile(filepath.Join(dir, "kustomization.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read kustomization: %v", err)
+	}
+	kust := kustomization{}
+	if err := yaml.Unmarshal(k, &kust); err != nil {
+		t.Fatalf("failed to parse kustomization: %v", err)
+	}
+	if kust.Kind != "Kustomization" || len(kust.Resources) != 2 || kust.Resources[0] != "team-a" || kust.Resources[1] != "team-b" {
+		t.Fatalf("unexpected kustomization:\n%s", k)
+	}
+	for _, resource := range kust.Resources {
+		if _, err := os.Stat(filepath.Join(dir, resource, "kustomization.yaml")); err != nil {
+			t.Fatalf("expected a kustomization for [%v]: %v", resource, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pborman/uuid"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+var invalidNamespaceChars = regexp.MustCompile("[^a-z0-9-]+")
+
+// generateNamespaceName returns a new namespace name for the bundle, made up
+// of its name and a random suffix
+func generateNamespaceName(bundleName string) string {
+	suffix := uuid.New()[:8]
+	name := invalidNamespaceChars.ReplaceAllString(strings.ToLower(bundleName), "-")
+	// leave room for the prefix and suffix within the 63 character limit
+	if max := validation.DNS1123LabelMaxLength - len("apb--") - len(suffix); len(name) > max {
+		name = name[:max]
+	}
+	name = strings.Trim(name, "-")
+	return fmt.Sprintf("apb-%s-%s", name, suffix)
+}
+
+// newNamespace returns the namespace the runner creates, carrying the
+// configured labels and annotations
+func newNamespace(name string, o *options) *v1.Namespace {
+	return &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      o.namespaceLabels,
+			Annotations: o.namespaceAnnotations,
+		},
+	}
+}
+
+// createNamespace creates the named namespace
+func createNamespace(namespaces corev1.NamespaceInterface, name string, o *options) error {
+	_, err := namespaces.Create(newNamespace(name, o))
+	if err != nil {
+		return fmt.Errorf("failed to create namespace [%v]: %v", name, err)
+	}
+	fmt.Printf("Created namespace [%v]\n", name)
+	return nil
+}
+
+// parseKeyValues parses key=value pairs, checking each with the given
+// validation functions
+func parseKeyValues(pairs []string, validKey func(string) []string, validValue func(string) []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	values := map[string]string{}
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid pair [%v]. Expected key=value", pair)
+		}
+		if errs := validKey(kv[0]); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid key [%v]: %v", kv[0], strings.Join(errs, "; "))
+		}
+		if validValue != nil {
+			if errs := validValue(kv[1]); len(errs) > 0 {
+				return nil, fmt.Errorf("invalid value [%v] for key [%v]: %v", kv[1], kv[0], strings.Join(errs, "; "))
+			}
+		}
+		values[kv[0]] = kv[1]
+	}
+	return values, nil
+}
@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestGenerateNamespaceName(t *testing.T) {
+	names := []string{
+		"dh-postgresql-apb",
+		"Registry.Example.com/My_APB",
+		strings.Repeat("very-long-apb-name", 10),
+	}
+	for _, bundleName := range names {
+		name := generateNamespaceName(bundleName)
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			t.Fatalf("generated invalid namespace name [%v] for [%v]: %v", name, bundleName, errs)
+		}
+		if !strings.HasPrefix(name, "apb-") {
+			t.Fatalf("expected generated namespace name [%v] to start with apb-", name)
+		}
+	}
+	if generateNamespaceName("foo") == generateNamespaceName("foo") {
+		t.Fatalf("expected generated namespace names to differ")
+	}
+}
+
+func TestNamespaceOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		opts        []Option
+		labels      map[string]string
+		annotations map[string]string
+		shouldErr   bool
+	}{
+		{
+			name: "test labels and annotations",
+			opts: []Option{
+				WithNamespaceLabels([]string{"team=broker", "ttl=24h"}),
+				WithNamespaceAnnotations([]string{"example.com/purpose=apb testing"}),
+			},
+			labels:      map[string]string{"team": "broker", "ttl": "24h"},
+			annotations: map[string]string{"example.com/purpose": "apb testing"},
+		},
+		{
+			name:   "test later labels override earlier ones",
+			opts:   []Option{WithNamespaceLabels([]string{"team=broker", "team=catalog"})},
+			labels: map[string]string{"team": "catalog"},
+		},
+		{
+			name:      "test label without a value",
+			opts:      []Option{WithNamespaceLabels([]string{"team"})},
+			shouldErr: true,
+		},
+		{
+			name:      "test invalid label value",
+			opts:      []Option{WithNamespaceLabels([]string{"purpose=apb testing"})},
+			shouldErr: true,
+		},
+		{
+			name:      "test invalid annotation key",
+			opts:      []Option{WithNamespaceAnnotations([]string{"bad key=value"})},
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := newOptions(tc.opts)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("expected error but options were accepted")
+			}
+			ns := newNamespace("apb-foo", o)
+			if len(ns.Labels) != len(tc.labels) || len(ns.Annotations) != len(tc.annotations) {
+				t.Fatalf("unexpected namespace metadata [%v] [%v]", ns.Labels, ns.Annotations)
+			}
+			for k, v := range tc.labels {
+				if ns.Labels[k] != v {
+					t.Fatalf("expected label [%v=%v], got [%v]", k, v, ns.Labels[k])
+				}
+			}
+			for k, v := range tc.annotations {
+				if ns.Annotations[k] != v {
+					t.Fatalf("expected annotation [%v=%v], got [%v]", k, v, ns.Annotations[k])
+				}
+			}
+		})
+	}
+}
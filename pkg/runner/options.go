@@ -0,0 +1,1301 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/apb/pkg/tracing"
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/ghodss/yaml"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Option configures optional behavior of RunBundle
+type Option func(*options) error
+
+type options struct {
+	dnsPolicy    v1.DNSPolicy
+	dnsConfig    *v1.PodDNSConfig
+	cleanup      bool
+	forceCleanup bool
+	localDir     string
+	pullPolicy   v1.PullPolicy // empty picks it from the image reference
+	dryRun       bool
+	// validateOnly stops the run once its parameters are validated
+	validateOnly bool
+	// previewOut is where the run's resolved parameters and extra vars are
+	// written instead of running the bundle
+	previewOut     io.Writer
+	previewSecrets bool
+
+	outputFormat string
+	outputDir    string
+	diffPrevious bool
+
+	// cleanupOnInterrupt deletes the bundle pod when the run is interrupted
+	// while following its logs or waiting for it
+	cleanupOnInterrupt bool
+
+	// specProvider is where to find the bundle's spec instead of the
+	// configured registries
+	specProvider SpecProvider
+
+	// extraVarsSecret is the secret holding the extra vars of a previous
+	// run, passed to the bundle instead of collected parameters
+	extraVarsSecret *secretRef
+
+	serviceClassID string
+	selector       Selector
+
+	generateNamespace    bool
+	createProject        bool
+	namespaceLabels      map[string]string
+	namespaceAnnotations map[string]string
+
+	fsGroup       *int64
+	schedulerName string
+	runID         string
+	// annotatedParameters are the parameters whose values annotate the
+	// bundle pod, resolved into podAnnotations once they are collected
+	annotatedParameters []string
+	podAnnotations      map[string]string
+	// annotateParameters records all the parameters, redacted, in the
+	// parametersAnnotation
+	annotateParameters bool
+	// terminationMessagePath and terminationMessagePolicy are where the
+	// bundle container writes its result. Empty leaves the defaults.
+	terminationMessagePath   string
+	terminationMessagePolicy v1.TerminationMessagePolicy
+
+	wait           bool
+	waitTimeout    time.Duration
+	actionTimeouts map[string]time.Duration
+	retry          retryPolicy
+	// operationTimeout bounds the whole run, leaving out time spent
+	// prompting. Zero doesn't bound it.
+	operationTimeout time.Duration
+	// podDeadline is how long the bundle pod may run, both as its
+	// ActiveDeadlineSeconds and as the longest wait for it before it is
+	// deleted. Zero doesn't bound it.
+	podDeadline time.Duration
+	// resultsOut is the file the results the bundle writes are copied to
+	resultsOut string
+	// eventsOut is the file the bundle pod's events are written to while
+	// waiting for it
+	eventsOut string
+	// unschedulableTimeout is how long the bundle pod may be pending with
+	// FailedScheduling events while waiting for it. Zero waits regardless.
+	unschedulableTimeout time.Duration
+	// reconnectTimeout is how long waiting for the bundle pod rides out
+	// failures to reach the API server. Zero fails on the first.
+	reconnectTimeout time.Duration
+	// batchConcurrency is how many namespaces of a batch run at once, and
+	// namespaceConcurrency how many APB pods may be running in a namespace
+	// before a batch run there is queued. Zero leaves it unlimited.
+	batchConcurrency     int
+	namespaceConcurrency int
+
+	// logSince and logTail bound the logs printed with printLogs. Zero
+	// and a negative tail print all of them.
+	logSince time.Duration
+	logTail  int64
+
+	parameterValues map[string]string
+	nonInteractive  bool
+	transformers    *transformers
+	// parameterRules are the config's input policies for parameter values
+	parameterRules parameterRules
+	// jsonParameters are the parameterValues given as JSON
+	jsonParameters map[string]bool
+	// customResource names the resource the bundle, plan and parameters
+	// are read from
+	customResource *crRef
+
+	planName   string
+	scriptPath string
+	// defaultPlan is run, instead of asking, when the bundle has it
+	defaultPlan string
+
+	account        string
+	serviceAccount string
+
+	parameterCacheDir string
+	storeParameters   bool
+	printCommand      bool
+	checkArchitecture bool
+	checkLabels       bool
+	checkQuota        bool
+	podNameTemplate   string
+	skipValidation    bool
+	minimalExtraVars  bool
+	quietPlan         bool
+	// planMetadataKeys are the plan metadata shown with the selected plan,
+	// DefaultPlanMetadataKeys when nil
+	planMetadataKeys []string
+	// extraVarsIndent indents the extra vars of the pod printed by a dry run
+	extraVarsIndent string
+	// remoteSchemaTimeout is how long to wait for each remote schema a
+	// plan's schema refers to. Zero doesn't fetch them.
+	remoteSchemaTimeout time.Duration
+	runFlags            []string
+	tracer              tracing.Tracer
+
+	resources            v1.ResourceRequirements
+	recommendedResources v1.ResourceRequirements
+}
+
+// AutoPullPolicy picks the pull policy of the bundle image from its reference
+const AutoPullPolicy = "auto"
+
+var pullPolicies = []v1.PullPolicy{
+	v1.PullAlways,
+	v1.PullIfNotPresent,
+	v1.PullNever,
+}
+
+var dnsPolicies = []v1.DNSPolicy{
+	v1.DNSClusterFirst,
+	v1.DNSClusterFirstWithHostNet,
+	v1.DNSDefault,
+	v1.DNSNone,
+}
+
+func newOptions(opts []Option) (*options, error) {
+	o := &options{logTail: -1, podNameTemplate: defaultPodNameTemplate, tracer: tracing.Noop, unschedulableTimeout: DefaultUnschedulableTimeout, reconnectTimeout: DefaultReconnectTimeout}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+	if o.nonInteractive {
+		o.selector = nonInteractiveSelector{}
+	} else if o.selector == nil {
+		o.selector = NewSelector(false)
+	}
+	if o.dnsPolicy == v1.DNSNone && (o.dnsConfig == nil || len(o.dnsConfig.Nameservers) == 0) {
+		return nil, errors.New("DNS policy [None] requires at least one DNS nameserver")
+	}
+	return o, nil
+}
+
+// WithDNSPolicy sets the DNS policy of the bundle pod
+func WithDNSPolicy(policy string) Option {
+	return func(o *options) error {
+		for _, p := range dnsPolicies {
+			if string(p) == policy {
+				o.dnsPolicy = p
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid DNS policy [%v]. Allowed policies: %v", policy, dnsPolicies)
+	}
+}
+
+// WithImagePullPolicy sets the pull policy of the bundle image. AutoPullPolicy,
+// the default, pulls an image given by tag on every run, since the tag may
+// have moved, and one pinned by digest only when it is not present.
+func WithImagePullPolicy(policy string) Option {
+	return func(o *options) error {
+		if policy == AutoPullPolicy {
+			o.pullPolicy = ""
+			return nil
+		}
+		for _, p := range pullPolicies {
+			if string(p) == policy {
+				o.pullPolicy = p
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid image pull policy [%v]. Allowed policies: %v or %v", policy, pullPolicies, AutoPullPolicy)
+	}
+}
+
+// WithDNSConfig sets the nameservers, search domains and resolver options
+// of the bundle pod. Resolver options are given as "name" or "name:value".
+func WithDNSConfig(nameservers []string, searches []string, resolverOptions []string) Option {
+	return func(o *options) error {
+		if len(nameservers) == 0 && len(searches) == 0 && len(resolverOptions) == 0 {
+			return nil
+		}
+		dnsConfig := &v1.PodDNSConfig{
+			Nameservers: nameservers,
+			Searches:    searches,
+		}
+		for _, opt := range resolverOptions {
+			kv := strings.SplitN(opt, ":", 2)
+			if kv[0] == "" {
+				return fmt.Errorf("invalid DNS option [%v]. Expected name or name:value", opt)
+			}
+			dnsOption := v1.PodDNSConfigOption{Name: kv[0]}
+			if len(kv) == 2 {
+				dnsOption.Value = &kv[1]
+			}
+			dnsConfig.Options = append(dnsConfig.Options, dnsOption)
+		}
+		o.dnsConfig = dnsConfig
+		return nil
+	}
+}
+
+// WithCleanup deletes the bundle pod once it has completed, or when the run
+// is interrupted. With force set, safe finalizers are removed from a pod
+// whose deletion does not complete.
+func WithCleanup(force bool) Option {
+	return func(o *options) error {
+		o.cleanup = true
+		o.forceCleanup = force
+		o.cleanupOnInterrupt = true
+		return nil
+	}
+}
+
+// WithInterruptCleanup deletes the bundle pod when the run is interrupted by
+// SIGINT or SIGTERM while following its logs or waiting for it, instead of
+// leaving it running
+func WithInterruptCleanup() Option {
+	return func(o *options) error {
+		o.cleanupOnInterrupt = true
+		return nil
+	}
+}
+
+// WithLocalBundle runs the bundle in a local directory instead of one from a
+// registry. The directory's image is built locally and never pulled, whatever
+// WithImagePullPolicy asked for.
+func WithLocalBundle(dir string) Option {
+	return func(o *options) error {
+		o.localDir = dir
+		return nil
+	}
+}
+
+// WithSpecsConfigMap finds the bundle's spec in a config map, given as
+// namespace/name[:key], instead of the configured registries. The key holds
+// a JSON or YAML list of specs and may be left out when it is the only one.
+func WithSpecsConfigMap(ref string) Option {
+	return func(o *options) error {
+		r, err := parseConfigMapRef(ref)
+		if err != nil {
+			return err
+		}
+		o.specProvider = &configMapSpecs{ref: r}
+		return nil
+	}
+}
+
+// WithSpecProvider finds the bundle's spec with the provider instead of in
+// the configured registries
+func WithSpecProvider(p SpecProvider) Option {
+	return func(o *options) error {
+		o.specProvider = p
+		return nil
+	}
+}
+
+// WithExtraVarsSecret passes the bundle the extra vars of a previous run,
+// read unchanged from a secret given as namespace/name[:key], instead of
+// collecting parameters. The key may be left out when it is the only one.
+func WithExtraVarsSecret(ref string) Option {
+	return func(o *options) error {
+		r, err := parseSecretRef(ref)
+		if err != nil {
+			return err
+		}
+		o.extraVarsSecret = &r
+		return nil
+	}
+}
+
+// WithCustomResource runs the bundle, plan and parameters named in the spec
+// of a custom resource, given as group/version/kind/name, such as one a
+// service binding operator reconciles. Values given otherwise replace the
+// resource's parameters, and the run doesn't prompt for the others.
+func WithCustomResource(ref string) Option {
+	return func(o *options) error {
+		r, err := parseCRRef(ref)
+		if err != nil {
+			return err
+		}
+		o.customResource = &r
+		o.nonInteractive = true
+		return nil
+	}
+}
+
+// WithDryRun prints the bundle pod instead of creating it. The format is
+// yaml (the default) or json, written to a file in the output directory when
+// one is given, or kustomize to write the pod and a kustomization.yaml
+// referencing it into the output directory.
+func WithDryRun(format string, dir string) Option {
+	return func(o *options) error {
+		switch format {
+		case "", "yaml", "json":
+		case "kustomize":
+			if dir == "" {
+				return errors.New("an output directory is required for kustomize output")
+			}
+		default:
+			return fmt.Errorf("unrecognized output format [%v]. Acceptable formats: 'yaml', 'json', 'kustomize'", format)
+		}
+		o.dryRun = true
+		o.outputFormat = format
+		o.outputDir = dir
+		return nil
+	}
+}
+
+// WithValidateOnly collects the parameters without prompting and validates
+// them, then ends the run without building or creating the bundle pod.
+// Nothing is read from the cluster, so a local bundle's image isn't built
+// and stored parameters aren't read.
+func WithValidateOnly() Option {
+	return func(o *options) error {
+		o.validateOnly = true
+		o.nonInteractive = true
+		return nil
+	}
+}
+
+// WithParameterPreview collects the parameters, prompting for them unless
+// the run is non-interactive, and writes them and the extra vars they would
+// be passed as to w, then ends the run without building or creating the
+// bundle pod. Password values are redacted unless showSecrets is set.
+func WithParameterPreview(w io.Writer, showSecrets bool) Option {
+	return func(o *options) error {
+		if w == nil {
+			return errors.New("invalid parameter preview writer [nil]")
+		}
+		o.previewOut = w
+		o.previewSecrets = showSecrets
+		return nil
+	}
+}
+
+// offline reports whether the run ends before contacting the cluster, so
+// nothing may be read from it
+func (o *options) offline() bool {
+	return o.validateOnly || o.previewOut != nil
+}
+
+// WithServiceClassID overrides the _apb_service_class_id passed to the
+// bundle, which is otherwise derived from the spec
+func WithServiceClassID(id string) Option {
+	return func(o *options) error {
+		o.serviceClassID = id
+		return nil
+	}
+}
+
+// WithSelector sets how the user chooses between the bundle's plans
+func WithSelector(selector Selector) Option {
+	return func(o *options) error {
+		o.selector = selector
+		return nil
+	}
+}
+
+// WithCreateProject creates the namespace the bundle runs in, if it doesn't
+// exist, as an OpenShift project. On clusters without the project API a
+// namespace is created instead.
+func WithCreateProject() Option {
+	return func(o *options) error {
+		o.createProject = true
+		return nil
+	}
+}
+
+// WithGeneratedNamespace runs the bundle in a new namespace named after it
+// instead of the given one
+func WithGeneratedNamespace() Option {
+	return func(o *options) error {
+		o.generateNamespace = true
+		return nil
+	}
+}
+
+// WithNamespaceLabels sets the labels, given as key=value, of the namespaces
+// the runner creates
+func WithNamespaceLabels(labels []string) Option {
+	return func(o *options) error {
+		parsed, err := parseKeyValues(labels, validation.IsQualifiedName, validation.IsValidLabelValue)
+		if err != nil {
+			return fmt.Errorf("invalid namespace label: %v", err)
+		}
+		o.namespaceLabels = parsed
+		return nil
+	}
+}
+
+// WithNamespaceAnnotations sets the annotations, given as key=value, of the
+// namespaces the runner creates
+func WithNamespaceAnnotations(annotations []string) Option {
+	return func(o *options) error {
+		parsed, err := parseKeyValues(annotations, validation.IsQualifiedName, nil)
+		if err != nil {
+			return fmt.Errorf("invalid namespace annotation: %v", err)
+		}
+		o.namespaceAnnotations = parsed
+		return nil
+	}
+}
+
+// WithFSGroup sets the supplemental group which owns the volumes mounted
+// into the bundle pod
+func WithFSGroup(gid int64) Option {
+	return func(o *options) error {
+		if errs := validation.IsValidGroupID(gid); len(errs) > 0 {
+			return fmt.Errorf("invalid fsGroup [%v]: %v", gid, strings.Join(errs, "; "))
+		}
+		o.fsGroup = &gid
+		return nil
+	}
+}
+
+// WithSchedulerName assigns the bundle pod to the named scheduler instead
+// of the default one
+func WithSchedulerName(name string) Option {
+	return func(o *options) error {
+		if name == "" {
+			return errors.New("scheduler name must not be empty")
+		}
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return fmt.Errorf("invalid scheduler name [%v]: %v", name, strings.Join(errs, "; "))
+		}
+		o.schedulerName = name
+		return nil
+	}
+}
+
+// WithRunID stamps the id on the bundle pod as the runIDLabel label and
+// annotation, correlating the run with e.g. a ticket or pipeline run. The id
+// must be a valid label value. RunBundle generates one when it isn't set.
+func WithRunID(id string) Option {
+	return func(o *options) error {
+		if id == "" {
+			return errors.New("run id must not be empty")
+		}
+		if errs := validation.IsValidLabelValue(id); len(errs) > 0 {
+			return fmt.Errorf("invalid run id [%v]: %v", id, strings.Join(errs, "; "))
+		}
+		o.runID = id
+		return nil
+	}
+}
+
+// WithParameterAnnotations copies the values of the named parameters into
+// annotations of the bundle pod, prefixed with parameterAnnotationPrefix.
+// Running fails if the plan marks one of them as a password.
+func WithParameterAnnotations(names []string) Option {
+	return func(o *options) error {
+		for _, name := range names {
+			if errs := validation.IsQualifiedName(parameterAnnotationPrefix + name); len(errs) > 0 {
+				return fmt.Errorf("invalid parameter [%v] to annotate: %v", name, strings.Join(errs, "; "))
+			}
+			o.annotatedParameters = append(o.annotatedParameters, name)
+		}
+		return nil
+	}
+}
+
+// WithTerminationMessage sets the path the bundle container writes its
+// result to, and the policy of its termination message. The policy is File
+// or FallbackToLogsOnError, which reports the end of its logs when it fails
+// without writing one. Empty values leave the defaults.
+func WithTerminationMessage(path string, policy string) Option {
+	return func(o *options) error {
+		if path != "" && !filepath.IsAbs(path) {
+			return fmt.Errorf("termination message path [%v] must be absolute", path)
+		}
+		switch p := v1.TerminationMessagePolicy(policy); p {
+		case "", v1.TerminationMessageReadFile, v1.TerminationMessageFallbackToLogsOnError:
+			o.terminationMessagePolicy = p
+		default:
+			return fmt.Errorf("unrecognized termination message policy [%v]. Acceptable policies: '%v', '%v'", policy, v1.TerminationMessageReadFile, v1.TerminationMessageFallbackToLogsOnError)
+		}
+		o.terminationMessagePath = path
+		return nil
+	}
+}
+
+// WithParametersAnnotation records the run's parameters as JSON in the
+// parametersAnnotation of the bundle pod, naming the parameters the plan
+// marks as passwords without their values
+func WithParametersAnnotation() Option {
+	return func(o *options) error {
+		o.annotateParameters = true
+		return nil
+	}
+}
+
+// withPodAnnotations adds the annotations to the bundle pod
+func withPodAnnotations(annotations map[string]string) Option {
+	return func(o *options) error {
+		if o.podAnnotations == nil {
+			o.podAnnotations = map[string]string{}
+		}
+		for k, v := range annotations {
+			o.podAnnotations[k] = v
+		}
+		return nil
+	}
+}
+
+// WithWait waits for the bundle pod to complete and fails if the pod fails.
+// A timeout of zero waits for as long as the pod runs.
+func WithWait(timeout time.Duration) Option {
+	return func(o *options) error {
+		if timeout < 0 {
+			return fmt.Errorf("invalid timeout [%v]", timeout)
+		}
+		o.wait = true
+		o.waitTimeout = timeout
+		return nil
+	}
+}
+
+// WithDeadline bounds how long the bundle pod may run. The pod gets the
+// deadline as its ActiveDeadlineSeconds, and the run waits for it for no
+// longer than the deadline, deleting the pod and failing once it expires.
+func WithDeadline(deadline time.Duration) Option {
+	return func(o *options) error {
+		if deadline < 0 {
+			return fmt.Errorf("invalid deadline [%v]", deadline)
+		}
+		o.podDeadline = deadline
+		return nil
+	}
+}
+
+// WithOperationTimeout fails the run once it has taken longer than the
+// timeout, from finding the bundle to waiting for its pod. Time spent
+// prompting for the plan and parameters is left out.
+func WithOperationTimeout(timeout time.Duration) Option {
+	return func(o *options) error {
+		if timeout < 0 {
+			return fmt.Errorf("invalid operation timeout [%v]", timeout)
+		}
+		o.operationTimeout = timeout
+		return nil
+	}
+}
+
+// WithResultsOut gives the bundle pod a file, named by the APB_RESULTS_FILE
+// env var, to write its results to as JSON, and copies them to the path once
+// the bundle succeeds. It requires waiting for the pod.
+func WithResultsOut(path string) Option {
+	return func(o *options) error {
+		o.resultsOut = path
+		return nil
+	}
+}
+
+// WithEventsOut writes the events of the bundle pod to the file, replacing
+// it, as they occur while waiting for the pod
+func WithEventsOut(path string) Option {
+	return func(o *options) error {
+		o.eventsOut = path
+		return nil
+	}
+}
+
+// WithUnschedulableTimeout fails waiting for the bundle pod once it has been
+// pending for the timeout and the scheduler reported it could not place it,
+// instead of waiting for the full wait timeout. Zero never fails the wait
+// for it. It defaults to DefaultUnschedulableTimeout.
+func WithUnschedulableTimeout(timeout time.Duration) Option {
+	return func(o *options) error {
+		if timeout < 0 {
+			return fmt.Errorf("invalid unschedulable timeout [%v]", timeout)
+		}
+		o.unschedulableTimeout = timeout
+		return nil
+	}
+}
+
+// WithReconnectTimeout keeps waiting for the bundle pod through transient
+// failures to reach the API server, such as during its rollout, and only
+// fails once they have gone on for the timeout. Zero fails on the first. It
+// defaults to DefaultReconnectTimeout.
+func WithReconnectTimeout(timeout time.Duration) Option {
+	return func(o *options) error {
+		if timeout < 0 {
+			return fmt.Errorf("invalid reconnect timeout [%v]", timeout)
+		}
+		o.reconnectTimeout = timeout
+		return nil
+	}
+}
+
+// WithBatchConcurrency runs the namespaces of a batch up to n at once
+// instead of one after the other
+func WithBatchConcurrency(n int) Option {
+	return func(o *options) error {
+		if n < 1 {
+			return fmt.Errorf("invalid batch concurrency [%v]", n)
+		}
+		o.batchConcurrency = n
+		return nil
+	}
+}
+
+// WithNamespaceConcurrency queues a batch run in a namespace while n or more
+// APB pods are running there, whether started by the batch or not, so runs
+// stay within the namespace's quota. Zero never queues them.
+func WithNamespaceConcurrency(n int) Option {
+	return func(o *options) error {
+		if n < 0 {
+			return fmt.Errorf("invalid namespace concurrency [%v]", n)
+		}
+		o.namespaceConcurrency = n
+		return nil
+	}
+}
+
+// WithLogLimits prints only the bundle logs newer than since, and at most
+// the last tail lines of them, like kubectl logs --since and --tail. Zero
+// and a negative tail leave the logs unbounded.
+func WithLogLimits(since time.Duration, tail int64) Option {
+	return func(o *options) error {
+		if since < 0 {
+			return fmt.Errorf("invalid log duration [%v]", since)
+		}
+		o.logSince = since
+		o.logTail = tail
+		return nil
+	}
+}
+
+// podLogOptions returns the options with which the bundle logs are followed
+func (o *options) podLogOptions() *v1.PodLogOptions {
+	logOpts := &v1.PodLogOptions{Follow: true}
+	if o.logSince > 0 {
+		seconds := int64(o.logSince.Seconds())
+		if seconds < 1 {
+			seconds = 1
+		}
+		logOpts.SinceSeconds = &seconds
+	}
+	if o.logTail >= 0 {
+		tail := o.logTail
+		logOpts.TailLines = &tail
+	}
+	return logOpts
+}
+
+// WithParameterTransformer transforms the value of the named parameter
+// after it is converted to its type and before it is validated
+func WithParameterTransformer(name string, transformer Transformer) Option {
+	return func(o *options) error {
+		o.registerTransformer(name, "", transformer)
+		return nil
+	}
+}
+
+// WithTypeTransformer transforms the values of parameters declared with the
+// type, e.g. "string", unless a transformer is registered for their name
+func WithTypeTransformer(paramType string, transformer Transformer) Option {
+	return func(o *options) error {
+		o.registerTransformer("", paramType, transformer)
+		return nil
+	}
+}
+
+// WithTransforms transforms parameter values with the built in transformers,
+// given as parameter=transformer, e.g. "region=lower". The transformers are
+// trim, lower and upper.
+func WithTransforms(transforms []string) Option {
+	return func(o *options) error {
+		for _, transform := range transforms {
+			kv := strings.SplitN(transform, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return fmt.Errorf("invalid transform [%v]. Expected parameter=transformer", transform)
+			}
+			transformer, ok := builtinTransformers[kv[1]]
+			if !ok {
+				return fmt.Errorf("unknown transformer [%v]. Available transformers: trim, lower, upper", kv[1])
+			}
+			o.registerTransformer(kv[0], "", transformer)
+		}
+		return nil
+	}
+}
+
+// WithParameterRules checks parameter values against the input policies
+// from the config after the bundle's own checks, refusing values of the
+// rules' parameters which don't match their patterns
+func WithParameterRules(rules []config.ParameterRule) Option {
+	return func(o *options) error {
+		compiled, err := compileParameterRules(rules)
+		if err != nil {
+			return err
+		}
+		o.parameterRules = append(o.parameterRules, compiled...)
+		return nil
+	}
+}
+
+func (o *options) registerTransformer(name string, paramType string, transformer Transformer) {
+	if o.transformers == nil {
+		o.transformers = &transformers{byName: map[string]Transformer{}, byType: map[string]Transformer{}}
+	}
+	if name != "" {
+		o.transformers.byName[name] = transformer
+	} else {
+		o.transformers.byType[paramType] = transformer
+	}
+}
+
+// WithRetries waits for the bundle pod and runs it again, up to retries
+// times, when it exits with one of the exit codes. The first retry waits
+// for backoff, and each one after that twice as long as the last.
+func WithRetries(retries int, exitCodes []int, backoff time.Duration) Option {
+	return func(o *options) error {
+		if retries < 0 {
+			return fmt.Errorf("invalid number of retries [%v]", retries)
+		}
+		if retries > 0 && len(exitCodes) == 0 {
+			return errors.New("retries require at least one retryable exit code")
+		}
+		if backoff < 0 {
+			return fmt.Errorf("invalid retry backoff [%v]", backoff)
+		}
+		policy := retryPolicy{retries: retries, backoff: backoff}
+		for _, code := range exitCodes {
+			if code <= 0 || code > 255 {
+				return fmt.Errorf("invalid retryable exit code [%v]. Exit codes are between 1 and 255", code)
+			}
+			policy.exitCodes = append(policy.exitCodes, int32(code))
+		}
+		o.retry = policy
+		return nil
+	}
+}
+
+// WithActionTimeouts sets wait timeouts, given as action=duration, which
+// take the place of the WithWait timeout for the named actions
+func WithActionTimeouts(timeouts []string) Option {
+	return func(o *options) error {
+		for _, t := range timeouts {
+			kv := strings.SplitN(t, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return fmt.Errorf("invalid action timeout [%v]. Expected action=duration", t)
+			}
+			d, err := time.ParseDuration(kv[1])
+			if err != nil || d < 0 {
+				return fmt.Errorf("invalid timeout [%v] for action [%v]", kv[1], kv[0])
+			}
+			if o.actionTimeouts == nil {
+				o.actionTimeouts = map[string]time.Duration{}
+			}
+			o.actionTimeouts[kv[0]] = d
+		}
+		return nil
+	}
+}
+
+// waiting is whether the run waits for the bundle pod to complete
+func (o *options) waiting() bool {
+	return o.wait || o.cleanup || o.retry.retries > 0 || o.podDeadline > 0
+}
+
+// resultsSidecarLifetime is how long the results sidecar may wait to be
+// told its results were collected: the longest wait for the pod, with time
+// to collect them, or zero when the wait is unbounded
+func (o *options) resultsSidecarLifetime() time.Duration {
+	if o.podDeadline > 0 {
+		// no wait outlasts the deadline
+		return o.podDeadline + resultsGracePeriod
+	}
+	if o.waitTimeout == 0 {
+		return 0
+	}
+	longest := o.waitTimeout
+	for _, d := range o.actionTimeouts {
+		if d > longest {
+			longest = d
+		}
+	}
+	return longest + resultsGracePeriod
+}
+
+// timeout returns how long to wait for the action's pod to complete
+func (o *options) timeout(action string) time.Duration {
+	timeout := o.waitTimeout
+	if d, ok := o.actionTimeouts[action]; ok {
+		timeout = d
+	}
+	// the wait ends with the pod's deadline
+	if o.podDeadline > 0 && (timeout == 0 || timeout > o.podDeadline) {
+		return o.podDeadline
+	}
+	return timeout
+}
+
+// WithParameterValues supplies parameter values, given as name=value, so
+// they are not prompted for
+func WithParameterValues(values []string) Option {
+	return func(o *options) error {
+		for _, v := range values {
+			kv := strings.SplitN(v, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return fmt.Errorf("invalid parameter value [%v]. Expected name=value", v)
+			}
+			if o.parameterValues == nil {
+				o.parameterValues = map[string]string{}
+			}
+			o.parameterValues[kv[0]] = kv[1]
+			delete(o.jsonParameters, kv[0])
+		}
+		return nil
+	}
+}
+
+// WithJSONParameterValues supplies parameter values given as name=<json>,
+// such as objects and arrays. The values are decoded as JSON instead of
+// converted to their parameter's type, then validated as usual.
+func WithJSONParameterValues(values []string) Option {
+	return func(o *options) error {
+		for _, v := range values {
+			kv := strings.SplitN(v, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return fmt.Errorf("invalid parameter value [%v]. Expected name=<json>", v)
+			}
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(kv[1]), &decoded); err != nil {
+				return fmt.Errorf("invalid JSON value for parameter [%v]: %v", kv[0], err)
+			}
+			if o.parameterValues == nil {
+				o.parameterValues = map[string]string{}
+			}
+			if o.jsonParameters == nil {
+				o.jsonParameters = map[string]bool{}
+			}
+			o.parameterValues[kv[0]] = kv[1]
+			o.jsonParameters[kv[0]] = true
+		}
+		return nil
+	}
+}
+
+// WithParameterJSON reads parameter values from a JSON object, such as one
+// piped to stdin. Since the reader is used up, the run is non-interactive.
+func WithParameterJSON(r io.Reader) Option {
+	return func(o *options) error {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("failed to read parameters: %v", err)
+		}
+		return o.addParameterStrings(data)
+	}
+}
+
+// WithParameterFile reads parameter values from a file holding a JSON or
+// YAML object mapping their names to their values, such as one checked in
+// for CI. The run is non-interactive, so a required parameter which the
+// file leaves out and which has no default fails the run.
+func WithParameterFile(path string) Option {
+	return func(o *options) error {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read parameters: %v", err)
+		}
+		data, err = yaml.YAMLToJSON(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse parameters in file [%v]: %v", path, err)
+		}
+		if err := o.addParameterStrings(data); err != nil {
+			return fmt.Errorf("invalid parameters in file [%v]: %v", path, err)
+		}
+		return nil
+	}
+}
+
+// addParameterStrings adds the parameter values of a JSON object, making
+// the run non-interactive. Strings are converted to their parameter's type
+// as typed values would be, and other values are decoded as JSON, so that
+// objects and arrays keep their structure.
+func (o *options) addParameterStrings(data []byte) error {
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("expected a JSON object of parameter values: %v", err)
+	}
+	if o.parameterValues == nil {
+		o.parameterValues = map[string]string{}
+	}
+	if o.jsonParameters == nil {
+		o.jsonParameters = map[string]bool{}
+	}
+	for name, value := range values {
+		if s, ok := value.(string); ok {
+			o.parameterValues[name] = s
+			delete(o.jsonParameters, name)
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("invalid value of parameter [%v]: %v", name, err)
+		}
+		o.parameterValues[name] = string(encoded)
+		o.jsonParameters[name] = true
+	}
+	o.nonInteractive = true
+	return nil
+}
+
+// WithSkipValidation only converts parameter values to their types,
+// without checking them against the plan's constraints and schema
+func WithSkipValidation() Option {
+	return func(o *options) error {
+		o.skipValidation = true
+		return nil
+	}
+}
+
+// WithPlanMessage sets whether the selected plan is printed: always (the
+// default), whatever the number of plans the bundle has, or never
+func WithPlanMessage(mode string) Option {
+	return func(o *options) error {
+		switch mode {
+		case "", "always":
+			o.quietPlan = false
+		case "never":
+			o.quietPlan = true
+		default:
+			return fmt.Errorf("unrecognized plan message mode [%v]. Acceptable modes: 'always', 'never'", mode)
+		}
+		return nil
+	}
+}
+
+// WithExtraVarsIndent indents the extra vars of the pod printed by a dry run
+// by the number of spaces, e.g. to diff the inputs of two runs. The extra
+// vars passed to the bundle stay compact.
+func WithExtraVarsIndent(spaces int) Option {
+	return func(o *options) error {
+		if spaces < 0 {
+			return fmt.Errorf("invalid extra vars indent [%v]", spaces)
+		}
+		o.extraVarsIndent = strings.Repeat(" ", spaces)
+		return nil
+	}
+}
+
+// WithMinimalExtraVars passes the bundle only its parameters and namespace,
+// leaving out the reserved keys like _apb_plan_id and cluster
+func WithMinimalExtraVars() Option {
+	return func(o *options) error {
+		o.minimalExtraVars = true
+		return nil
+	}
+}
+
+// WithTracer records the stages of the run as spans of the tracer
+func WithTracer(tracer tracing.Tracer) Option {
+	return func(o *options) error {
+		if tracer == nil {
+			return errors.New("tracer must not be nil")
+		}
+		o.tracer = tracer
+		return nil
+	}
+}
+
+// WithRemoteSchemaRefs fetches the remote schemas which a plan's schema
+// refers to with $ref over HTTP, waiting up to timeout for each
+func WithRemoteSchemaRefs(timeout time.Duration) Option {
+	return func(o *options) error {
+		if timeout <= 0 {
+			return fmt.Errorf("invalid remote schema timeout [%v]. Expected a positive duration", timeout)
+		}
+		o.remoteSchemaTimeout = timeout
+		return nil
+	}
+}
+
+// WithNonInteractive never prompts for a plan or parameters. Parameters
+// which were not supplied take their defaults, and invalid values are
+// returned together as ValidationErrors.
+func WithNonInteractive() Option {
+	return func(o *options) error {
+		o.nonInteractive = true
+		return nil
+	}
+}
+
+// WithPlan runs the named plan instead of asking which plan to run
+func WithPlan(name string) Option {
+	return func(o *options) error {
+		o.planName = name
+		return nil
+	}
+}
+
+// WithPlanMetadataKeys sets the plan metadata printed with the selected plan
+// and annotated on the bundle pod, e.g. its cost. It defaults to
+// DefaultPlanMetadataKeys, and an empty list shows none.
+func WithPlanMetadataKeys(keys []string) Option {
+	return func(o *options) error {
+		o.planMetadataKeys = keys
+		if o.planMetadataKeys == nil {
+			o.planMetadataKeys = []string{}
+		}
+		return nil
+	}
+}
+
+// WithDefaultPlan runs the named plan, when a bundle with several plans has
+// it and no plan is given, instead of asking which plan to run
+func WithDefaultPlan(name string) Option {
+	return func(o *options) error {
+		o.defaultPlan = name
+		return nil
+	}
+}
+
+// WithRunScript writes a shell script repeating the run, once its plan and
+// parameters are known, to the given path
+func WithRunScript(path string) Option {
+	return func(o *options) error {
+		o.scriptPath = path
+		return nil
+	}
+}
+
+// WithAccount sets the account the bundle runs as, which is passed to the
+// bundle and labels its pod. It defaults to the service account name.
+func WithAccount(account string) Option {
+	return func(o *options) error {
+		if errs := validation.IsValidLabelValue(account); len(errs) > 0 {
+			return fmt.Errorf("invalid account [%v]: %v", account, strings.Join(errs, "; "))
+		}
+		o.account = account
+		return nil
+	}
+}
+
+// WithServiceAccount runs the bundle pod with an existing service account
+// instead of creating a sandbox service account for it
+func WithServiceAccount(name string) Option {
+	return func(o *options) error {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return fmt.Errorf("invalid service account name [%v]: %v", name, strings.Join(errs, "; "))
+		}
+		o.serviceAccount = name
+		return nil
+	}
+}
+
+// WithArchitectureCheck checks the bundle image is built for the
+// architecture of the cluster's nodes before creating its pod, failing when
+// no node can run it
+func WithArchitectureCheck() Option {
+	return func(o *options) error {
+		o.checkArchitecture = true
+		return nil
+	}
+}
+
+// WithLabelCheck checks the bundle image carries the APB spec label before
+// running it, failing when it does not
+func WithLabelCheck() Option {
+	return func(o *options) error {
+		o.checkLabels = true
+		return nil
+	}
+}
+
+// WithPodNameTemplate names the bundle pod from a template of ${bundle},
+// ${action}, ${namespace}, ${uuid} and ${short-uuid} tokens, e.g.
+// "${bundle}-${action}-${short-uuid}"
+func WithPodNameTemplate(template string) Option {
+	return func(o *options) error {
+		if _, err := renderPodName(template, podNameTokens("bundle", "provision", "default")); err != nil {
+			return err
+		}
+		o.podNameTemplate = template
+		return nil
+	}
+}
+
+// WithQuotaCheck reports whether the dry run pod fits the resource quotas
+// of its namespace, failing the dry run if it does not
+func WithQuotaCheck() Option {
+	return func(o *options) error {
+		o.checkQuota = true
+		return nil
+	}
+}
+
+// WithPreviousPodDiff prints how the dry run pod differs from the newest pod
+// of the same bundle and action in its namespace, instead of the pod
+func WithPreviousPodDiff() Option {
+	return func(o *options) error {
+		o.diffPrevious = true
+		return nil
+	}
+}
+
+// WithStoredParameters stores all the parameters of a provision or update,
+// passwords included, in a secret labeled with the instance ID, and runs
+// later actions on the instance with them. The secret is deleted once the
+// instance is deprovisioned. Instances are only known when they are
+// recorded locally.
+func WithStoredParameters() Option {
+	return func(o *options) error {
+		o.storeParameters = true
+		return nil
+	}
+}
+
+// WithPrintCommand prints the apb command which repeats the run. Sensitive
+// parameters are read from environment variables instead of printed.
+func WithPrintCommand() Option {
+	return func(o *options) error {
+		o.printCommand = true
+		return nil
+	}
+}
+
+// WithRunFlags sets the arguments, as --name=value, which the command or
+// script repeating the run passes on top of the resolved bundle, namespace,
+// plan and parameters
+func WithRunFlags(flags []string) Option {
+	return func(o *options) error {
+		o.runFlags = flags
+		return nil
+	}
+}
+
+// WithParameterCache caches the parameters of each interactive run in dir,
+// offering them as defaults the next time the bundle is run. Sensitive
+// parameters are never cached.
+func WithParameterCache(dir string) Option {
+	return func(o *options) error {
+		o.parameterCacheDir = dir
+		return nil
+	}
+}
+
+// WithResources sets the resource requests and limits, given as
+// name=quantity, of the bundle container. They take precedence over the
+// resources the bundle recommends.
+func WithResources(requests []string, limits []string) Option {
+	return func(o *options) error {
+		var err error
+		if o.resources.Requests, err = parseResourceList(requests); err != nil {
+			return err
+		}
+		if o.resources.Limits, err = parseResourceList(limits); err != nil {
+			return err
+		}
+		return nil
+	}
+}
+
+// WithEphemeralStorage sets the ephemeral-storage request and limit of the
+// bundle container, e.g. "2Gi", in addition to those set by WithResources.
+// Empty values are left unset.
+func WithEphemeralStorage(request string, limit string) Option {
+	return func(o *options) error {
+		var err error
+		if request != "" {
+			if o.resources.Requests, err = setQuantity(o.resources.Requests, v1.ResourceEphemeralStorage, request); err != nil {
+				return err
+			}
+		}
+		if limit != "" {
+			if o.resources.Limits, err = setQuantity(o.resources.Limits, v1.ResourceEphemeralStorage, limit); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// WithRecommendedResources uses the resources recommended in the spec
+// metadata for the bundle container where WithResources does not set them.
+// Recommendations which can't be read are ignored.
+func WithRecommendedResources(spec *bundle.Spec) Option {
+	return func(o *options) error {
+		recommended, err := recommendedResources(spec.Metadata)
+		if err != nil {
+			log.Warningf("Ignoring resources recommended by APB [%v]: %v", spec.FQName, err)
+			return nil
+		}
+		o.recommendedResources = recommended
+		return nil
+	}
+}
+
+func applyPodOptions(pod *v1.Pod, o *options) {
+	pod.Spec.DNSPolicy = o.dnsPolicy
+	pod.Spec.DNSConfig = o.dnsConfig
+	pod.Spec.SchedulerName = o.schedulerName
+	if o.podDeadline > 0 {
+		// round up, so the pod is never stopped before its deadline
+		seconds := int64((o.podDeadline + time.Second - 1) / time.Second)
+		pod.Spec.ActiveDeadlineSeconds = &seconds
+	}
+	if o.runID != "" {
+		// copy the labels, which are the execution context's metadata
+		labels := map[string]string{runIDLabel: o.runID}
+		for k, v := range pod.Labels {
+			if k != runIDLabel {
+				labels[k] = v
+			}
+		}
+		pod.Labels = labels
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[runIDLabel] = o.runID
+	}
+	if len(o.podAnnotations) > 0 {
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		for k, v := range o.podAnnotations {
+			pod.Annotations[k] = v
+		}
+	}
+	resources := mergeResources(o.recommendedResources, o.resources)
+	for i := range pod.Spec.Containers {
+		pod.Spec.Containers[i].Resources = resources
+		pod.Spec.Containers[i].TerminationMessagePath = o.terminationMessagePath
+		pod.Spec.Containers[i].TerminationMessagePolicy = o.terminationMessagePolicy
+	}
+	if o.fsGroup != nil {
+		if pod.Spec.SecurityContext == nil {
+			pod.Spec.SecurityContext = &v1.PodSecurityContext{}
+		}
+		pod.Spec.SecurityContext.FSGroup = o.fsGroup
+	}
+	if o.resultsOut != "" {
+		addResultsSidecar(pod, o.resultsSidecarLifetime())
+	}
+}
@@ -0,0 +1,293 @@
+package runner
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+func TestDNSOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		opts        []Option
+		policy      v1.DNSPolicy
+		nameservers []string
+		options     int
+		shouldErr   bool
+	}{
+		{
+			name:   "test unset DNS options are omitted",
+			opts:   []Option{WithDNSConfig(nil, nil, nil)},
+			policy: "",
+		},
+		{
+			name:   "test valid DNS policy",
+			opts:   []Option{WithDNSPolicy("Default")},
+			policy: v1.DNSDefault,
+		},
+		{
+			name:      "test invalid DNS policy",
+			opts:      []Option{WithDNSPolicy("ClusterLast")},
+			shouldErr: true,
+		},
+		{
+			name: "test DNS config with options",
+			opts: []Option{
+				WithDNSPolicy("None"),
+				WithDNSConfig([]string{"10.0.0.10"}, []string{"corp.example.com"}, []string{"ndots:2", "edns0"}),
+			},
+			policy:      v1.DNSNone,
+			nameservers: []string{"10.0.0.10"},
+			options:     2,
+		},
+		{
+			name:      "test DNS policy None without nameservers",
+			opts:      []Option{WithDNSPolicy("None")},
+			shouldErr: true,
+		},
+		{
+			name:      "test invalid DNS option",
+			opts:      []Option{WithDNSConfig(nil, nil, []string{":2"})},
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := newOptions(tc.opts)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("expected error but options were accepted")
+				return
+			}
+			pod := &v1.Pod{}
+			applyPodOptions(pod, o)
+			if pod.Spec.DNSPolicy != tc.policy {
+				t.Fatalf("expected DNS policy [%v], got [%v]", tc.policy, pod.Spec.DNSPolicy)
+				return
+			}
+			if tc.nameservers == nil {
+				if pod.Spec.DNSConfig != nil {
+					t.Fatalf("expected no DNS config, got [%v]", pod.Spec.DNSConfig)
+				}
+				return
+			}
+			if len(pod.Spec.DNSConfig.Nameservers) != len(tc.nameservers) || len(pod.Spec.DNSConfig.Options) != tc.options {
+				t.Fatalf("unexpected DNS config [%v]", pod.Spec.DNSConfig)
+				return
+			}
+		})
+	}
+}
+
+func TestFSGroupOption(t *testing.T) {
+	o, err := newOptions([]Option{WithFSGroup(2000)})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	pod := &v1.Pod{}
+	applyPodOptions(pod, o)
+	if pod.Spec.SecurityContext == nil || pod.Spec.SecurityContext.FSGroup == nil || *pod.Spec.SecurityContext.FSGroup != 2000 {
+		t.Fatalf("expected fsGroup [2000], got [%v]", pod.Spec.SecurityContext)
+	}
+	var manifest bytes.Buffer
+	if err := writeManifest(pod, "yaml", "", &manifest); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if !strings.Contains(manifest.String(), "fsGroup: 2000") {
+		t.Fatalf("expected fsGroup in dry run output, got:\n%v", manifest.String())
+	}
+
+	o, err = newOptions(nil)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	pod = &v1.Pod{}
+	applyPodOptions(pod, o)
+	if pod.Spec.SecurityContext != nil {
+		t.Fatalf("expected no security context, got [%v]", pod.Spec.SecurityContext)
+	}
+
+	if _, err := newOptions([]Option{WithFSGroup(-5)}); err == nil {
+		t.Fatalf("expected error for a negative fsGroup")
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	o, err := newOptions([]Option{
+		WithWait(5 * time.Minute),
+		WithActionTimeouts([]string{"provision=20m", "deprovision=2m"}),
+		WithDeadline(10*time.Minute + 500*time.Millisecond),
+	})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected := map[string]time.Duration{
+		"provision":   10*time.Minute + 500*time.Millisecond,
+		"deprovision": 2 * time.Minute,
+		"test":        5 * time.Minute,
+	}
+	for action, timeout := range expected {
+		if o.timeout(action) != timeout {
+			t.Fatalf("expected [%v] timeout [%v], got [%v]", action, timeout, o.timeout(action))
+		}
+	}
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "apb"}}}}
+	applyPodOptions(pod, o)
+	if pod.Spec.ActiveDeadlineSeconds == nil || *pod.Spec.ActiveDeadlineSeconds != 601 {
+		t.Fatalf("expected active deadline of 601 seconds, got %v", pod.Spec.ActiveDeadlineSeconds)
+	}
+
+	o, err = newOptions([]Option{WithDeadline(time.Minute)})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if !o.waiting() || o.timeout("provision") != time.Minute {
+		t.Fatalf("expected a deadline to wait for a minute, got waiting [%v] timeout [%v]", o.waiting(), o.timeout("provision"))
+	}
+	if _, err := newOptions([]Option{WithDeadline(-time.Second)}); err == nil {
+		t.Fatalf("expected error for a negative deadline")
+	}
+}
+
+func TestActionTimeouts(t *testing.T) {
+	o, err := newOptions([]Option{
+		WithWait(5 * time.Minute),
+		WithActionTimeouts([]string{"provision=20m", "deprovision=2m"}),
+	})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected := map[string]time.Duration{
+		"provision":   20 * time.Minute,
+		"deprovision": 2 * time.Minute,
+		"test":        5 * time.Minute,
+	}
+	for action, timeout := range expected {
+		if o.timeout(action) != timeout {
+			t.Fatalf("expected [%v] timeout [%v], got [%v]", action, timeout, o.timeout(action))
+		}
+	}
+
+	for _, timeouts := range [][]string{{"provision"}, {"provision=soon"}, {"=20m"}} {
+		if _, err := newOptions([]Option{WithActionTimeouts(timeouts)}); err == nil {
+			t.Fatalf("expected error for action timeouts %v", timeouts)
+		}
+	}
+}
+
+func TestAccountOptions(t *testing.T) {
+	o, err := newOptions([]Option{WithAccount("deployer"), WithServiceAccount("apb-runner")})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if o.account != "deployer" || o.serviceAccount != "apb-runner" {
+		t.Fatalf("expected account [deployer] and service account [apb-runner], got [%v] [%v]", o.account, o.serviceAccount)
+	}
+	if _, err := newOptions([]Option{WithAccount("bob@example.com")}); err == nil {
+		t.Fatalf("expected error for an account which can't label the pod")
+	}
+	if _, err := newOptions([]Option{WithServiceAccount("APB_Runner")}); err == nil {
+		t.Fatalf("expected error for an invalid service account name")
+	}
+}
+
+func TestPlanMessageOption(t *testing.T) {
+	testCases := []struct {
+		mode       string
+		quiet      bool
+		shouldFail bool
+	}{
+		{mode: "", quiet: false},
+		{mode: "always", quiet: false},
+		{mode: "never", quiet: true},
+		{mode: "sometimes", shouldFail: true},
+	}
+	for _, tc := range testCases {
+		o, err := newOptions([]Option{WithPlanMessage(tc.mode)})
+		if err != nil {
+			if !tc.shouldFail {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			continue
+		}
+		if tc.shouldFail {
+			t.Fatalf("expected error for plan message mode [%v]", tc.mode)
+		}
+		if o.quietPlan != tc.quiet {
+			t.Fatalf("expected plan message mode [%v] to be quiet [%v]", tc.mode, tc.quiet)
+		}
+	}
+}
+
+func TestPodLogOptions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		opts    []Option
+		since   *int64
+		tail    *int64
+		invalid bool
+	}{
+		{
+			name: "test full log by default",
+		},
+		{
+			name:  "test since",
+			opts:  []Option{WithLogLimits(10*time.Minute, -1)},
+			since: int64Ptr(600),
+		},
+		{
+			name:  "test since rounds up to a second",
+			opts:  []Option{WithLogLimits(time.Millisecond, -1)},
+			since: int64Ptr(1),
+		},
+		{
+			name: "test tail",
+			opts: []Option{WithLogLimits(0, 50)},
+			tail: int64Ptr(50),
+		},
+		{
+			name: "test zero tail",
+			opts: []Option{WithLogLimits(0, 0)},
+			tail: int64Ptr(0),
+		},
+		{
+			name:    "test negative since",
+			opts:    []Option{WithLogLimits(-time.Minute, -1)},
+			invalid: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := newOptions(tc.opts)
+			if err != nil {
+				if !tc.invalid {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			if tc.invalid {
+				t.Fatalf("expected options to fail")
+			}
+			logOpts := o.podLogOptions()
+			if !logOpts.Follow {
+				t.Fatalf("expected logs to be followed")
+			}
+			if !reflect.DeepEqual(logOpts.SinceSeconds, tc.since) || !reflect.DeepEqual(logOpts.TailLines, tc.tail) {
+				t.Fatalf("expected since [%v] and tail [%v], got %+v", tc.since, tc.tail, logOpts)
+			}
+		})
+	}
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
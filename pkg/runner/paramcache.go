@@ -0,0 +1,62 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+// parameterCachePath returns the file in dir caching the parameters of
+// the bundle
+func parameterCachePath(dir string, fqName string) string {
+	return filepath.Join(dir, strings.Replace(fqName, string(filepath.Separator), "_", -1)+".json")
+}
+
+// loadCachedParameters returns the parameters cached for the bundle by its
+// last run, or none if it has not been cached
+func loadCachedParameters(dir string, fqName string) (bundle.Parameters, error) {
+	data, err := ioutil.ReadFile(parameterCachePath(dir, fqName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	params := bundle.Parameters{}
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// cacheParameters saves the non-sensitive parameters of the run so the
+// next run of the bundle can offer them as defaults
+func cacheParameters(dir string, fqName string, plan bundle.Plan, params bundle.Parameters) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(recordableParameters(plan, params), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(parameterCachePath(dir, fqName), data, 0600)
+}
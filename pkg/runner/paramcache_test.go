@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+func TestParameterCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-params")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cached, err := loadCachedParameters(dir, "hello-apb")
+	if err != nil || cached != nil {
+		t.Fatalf("expected no cached parameters, got [%v] [%v]", cached, err)
+	}
+
+	plan := bundle.Plan{
+		Parameters: []bundle.ParameterDescriptor{
+			{Name: "user", Type: "string"},
+			{Name: "replicas", Type: "int"},
+			{Name: "password", Type: "string", DisplayType: "password"},
+		},
+	}
+	params := bundle.Parameters{"user": "admin", "replicas": 3, "password": "secret"}
+	if err := cacheParameters(dir, "hello-apb", plan, params); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	data, err := ioutil.ReadFile(parameterCachePath(dir, "hello-apb"))
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Fatalf("expected sensitive parameter to be left out of the cache, got:\n%s", data)
+	}
+
+	cached, err = loadCachedParameters(dir, "hello-apb")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if len(cached) != 2 || cached["user"] != "admin" || cached["replicas"] != float64(3) {
+		t.Fatalf("unexpected cached parameters [%v]", cached)
+	}
+	if other, _ := loadCachedParameters(dir, "other-apb"); other != nil {
+		t.Fatalf("expected no cached parameters for another bundle, got [%v]", other)
+	}
+}
+
+func TestLastRunDefault(t *testing.T) {
+	testCases := []struct {
+		name        string
+		previous    bundle.Parameters
+		cached      bundle.Parameters
+		expected    interface{}
+		fromLastRun bool
+	}{
+		{
+			name:     "test declared default without cache",
+			expected: "small",
+		},
+		{
+			name:        "test cached value",
+			cached:      bundle.Parameters{"size": "medium"},
+			expected:    "medium",
+			fromLastRun: true,
+		},
+		{
+			name:     "test previous instance value over cached value",
+			previous: bundle.Parameters{"size": "large"},
+			cached:   bundle.Parameters{"size": "medium"},
+			expected: "large",
+		},
+	}
+	param := bundle.ParameterDescriptor{Name: "size", Default: "small"}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			value, fromLastRun := lastRunDefault(param, tc.previous, tc.cached)
+			if value != tc.expected || fromLastRun != tc.fromLastRun {
+				t.Fatalf("expected default [%v] (from last run: %v), got [%v] (%v)", tc.expected, tc.fromLastRun, value, fromLastRun)
+			}
+		})
+	}
+}
@@ -0,0 +1,372 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/lestrrat/go-jsschema"
+	"github.com/lestrrat/go-jsschema/validator"
+)
+
+// ValidationError describes a parameter value which breaks a constraint of
+// the plan
+type ValidationError struct {
+	Parameter  string `json:"parameter"`
+	Constraint string `json:"constraint"`
+	Message    string `json:"message"`
+}
+
+// ValidationErrors are all the problems found with a set of parameters
+type ValidationErrors []ValidationError
+
+func (v ValidationErrors) Error() string {
+	var messages []string
+	for _, e := range v {
+		messages = append(messages, e.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// checkInput converts the input for the parameter to its type, reporting the
+// constraint it breaks if it is not valid
+func checkInput(param bundle.ParameterDescriptor, input string) (interface{}, *ValidationError) {
+	return inputChecker(nil, nil, false)(param, input)
+}
+
+func checkRequired(param bundle.ParameterDescriptor, input string) *ValidationError {
+	if param.Required && input == "" {
+		return &ValidationError{
+			Parameter:  param.Name,
+			Constraint: "required",
+			Message:    fmt.Sprintf("Parameter [%v] is required", param.Name),
+		}
+	}
+	return nil
+}
+
+// checkValue reports the enum or format constraint the converted value of
+// the parameter breaks
+func checkValue(param bundle.ParameterDescriptor, value interface{}) *ValidationError {
+	if len(param.Enum) > 0 && !enumContains(param, value) {
+		return &ValidationError{
+			Parameter:  param.Name,
+			Constraint: "enum",
+			Message:    fmt.Sprintf("[%v] is not a valid option for parameter [%v]. Available options: %v", value, param.Name, param.Enum),
+		}
+	}
+	if s, ok := value.(string); ok {
+		return checkFormat(param, s)
+	}
+	return nil
+}
+
+// enumContains reports whether the converted value of the parameter is one
+// of its enum entries. The entries are converted to the parameter's type as
+// well, so e.g. 10.0 matches a number enum entry of 10. Entries which can't
+// be converted are compared as strings.
+func enumContains(param bundle.ParameterDescriptor, value interface{}) bool {
+	for _, entry := range param.Enum {
+		converted, err := pruneInput(entry, param)
+		if err != nil {
+			if entry == fmt.Sprint(value) {
+				return true
+			}
+			continue
+		}
+		if enumEqual(converted, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// enumEqual compares numbers by value, whatever their type, and other
+// values as they are
+func enumEqual(a interface{}, b interface{}) bool {
+	x, xNumber := toFloat(a)
+	y, yNumber := toFloat(b)
+	if xNumber && yNumber {
+		return x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// coerceInput converts the input for the parameter to its type without
+// checking the plan's other constraints
+func coerceInput(param bundle.ParameterDescriptor, input string) (interface{}, *ValidationError) {
+	value, err := pruneInput(input, param)
+	if err != nil {
+		return nil, &ValidationError{
+			Parameter:  param.Name,
+			Constraint: "type",
+			Message:    fmt.Sprintf("Invalid value [%v] for parameter [%v]: %v", input, param.Name, err),
+		}
+	}
+	return value, nil
+}
+
+// defaultInput returns the parameter default in the form it would be typed
+func defaultInput(paramDefault interface{}) string {
+	switch d := paramDefault.(type) {
+	case int:
+		return strconv.Itoa(d)
+	case string:
+		return d
+	case float64:
+		return strconv.FormatFloat(d, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(d)
+	case []interface{}:
+		var items []string
+		for _, item := range d {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, ",")
+	}
+	return ""
+}
+
+// parseItems reads the items of an array parameter, typed as a comma
+// separated list or as a JSON array
+func parseItems(input string) ([]interface{}, error) {
+	items := []interface{}{}
+	if strings.HasPrefix(strings.TrimSpace(input), "[") {
+		if err := json.Unmarshal([]byte(input), &items); err != nil {
+			return nil, fmt.Errorf("Input must be a JSON array or comma separated list: %v", err)
+		}
+		return items, nil
+	}
+	for _, item := range strings.Split(input, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// collectParameters takes the plan's parameters from the supplied values,
+// falling back to previous values and defaults, without prompting. Every
+// problem found is returned together as ValidationErrors. Supplied values
+// named in jsonKeys are decoded as JSON instead of converted to their
+// parameter's type. Values are transformed by t and checked against the
+// config's rules, and skipValidation only converts them to their types.
+func collectParameters(plan bundle.Plan, previous bundle.Parameters, supplied map[string]string, jsonKeys map[string]bool, t *transformers, rules parameterRules, skipValidation bool) (bundle.Parameters, error) {
+	check := inputChecker(t, rules, skipValidation)
+	var verrs ValidationErrors
+	for name := range supplied {
+		if plan.GetParameter(name) == nil {
+			verrs = append(verrs, ValidationError{
+				Parameter:  name,
+				Constraint: "defined",
+				Message:    fmt.Sprintf("Parameter [%v] is not defined by plan [%v]", name, plan.Name),
+			})
+		}
+	}
+
+	ordered, err := orderParameters(plan.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	params := bundle.Parameters{}
+	for _, param := range ordered {
+		input, ok := supplied[param.Name]
+		if dep, unmet := unmetDependency(param, params); unmet {
+			if ok {
+				verrs = append(verrs, ValidationError{
+					Parameter:  param.Name,
+					Constraint: "dependencies",
+					Message:    fmt.Sprintf("Parameter [%v] can only be set when %v", param.Name, describeDependency(dep)),
+				})
+			}
+			continue
+		}
+		if !ok {
+			paramDefault, err := renderDefault(param, effectiveDefault(param, previous), params)
+			if err != nil {
+				verrs = append(verrs, ValidationError{
+					Parameter:  param.Name,
+					Constraint: "default",
+					Message:    err.Error(),
+				})
+				continue
+			}
+			input = defaultInput(paramDefault)
+		}
+		if input == "" && !param.Required {
+			continue
+		}
+		var value interface{}
+		var verr *ValidationError
+		if ok && jsonKeys[param.Name] {
+			value, verr = checkJSONInput(param, input, t, rules, skipValidation)
+		} else {
+			value, verr = check(param, input)
+		}
+		if verr != nil {
+			verrs = append(verrs, *verr)
+			continue
+		}
+		params.Add(param.Name, value)
+	}
+	if len(verrs) > 0 {
+		return nil, verrs
+	}
+	if skipValidation {
+		return params, nil
+	}
+	if err := validateDependencies(plan, params); err != nil {
+		return nil, err
+	}
+	if err := validateSchema(plan, params); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// checkSettable checks the parameters only hold values the action may set.
+// Supplied values of read-only parameters are refused, and an update may
+// not change the value, known from the previous run, of a parameter which
+// isn't updatable, nor supply one whose previous value is unknown.
+func checkSettable(plan bundle.Plan, action string, readOnly map[string]bool, supplied map[string]string, previous bundle.Parameters, params bundle.Parameters) error {
+	var verrs ValidationErrors
+	for _, param := range plan.Parameters {
+		_, given := supplied[param.Name]
+		if readOnly[param.Name] && given {
+			verrs = append(verrs, ValidationError{
+				Parameter:  param.Name,
+				Constraint: "readOnly",
+				Message:    fmt.Sprintf("Parameter [%v] is read-only and cannot be set", param.Name),
+			})
+			continue
+		}
+		if action != "update" || param.Updatable {
+			continue
+		}
+		value, set := params[param.Name]
+		was, known := previous[param.Name]
+		switch {
+		case known && set && !enumEqual(was, value):
+			verrs = append(verrs, ValidationError{
+				Parameter:  param.Name,
+				Constraint: "updatable",
+				Message:    fmt.Sprintf("Parameter [%v] is not updatable and cannot be changed from [%v]", param.Name, was),
+			})
+		case !known && given:
+			verrs = append(verrs, ValidationError{
+				Parameter:  param.Name,
+				Constraint: "updatable",
+				Message:    fmt.Sprintf("Parameter [%v] is not updatable and cannot be set by an update", param.Name),
+			})
+		}
+	}
+	if len(verrs) > 0 {
+		return verrs
+	}
+	return nil
+}
+
+// ParametersFromJSON converts and validates a JSON object of parameter
+// values, such as a web form would post, against the plan. Values may be
+// given as strings or as JSON values. Parameters left out take their
+// defaults. Every problem found is returned together as ValidationErrors.
+func ParametersFromJSON(plan bundle.Plan, raw []byte) (bundle.Parameters, error) {
+	values, err := parameterStrings(raw)
+	if err != nil {
+		return nil, err
+	}
+	return collectParameters(plan, nil, values, nil, nil, nil, false)
+}
+
+// allowObjectProperties lets object parameters hold any properties. The
+// schema generated from the plan declares none for them, and the validator
+// takes a schema without additionalProperties to allow no others.
+func allowObjectProperties(s *schema.Schema) {
+	for _, property := range s.Properties {
+		if property.AdditionalProperties == nil && property.Type.Contains(schema.ObjectType) {
+			property.AdditionalProperties = &schema.AdditionalProperties{}
+		}
+	}
+}
+
+// checkJSONInput decodes the JSON input for the parameter, in place of
+// converting it to the parameter's type, then transforms and, unless
+// skipValidation is set, validates the value before checking the config's
+// rules. Integral numbers given for integer parameters are made integers,
+// as converted input would be.
+func checkJSONInput(param bundle.ParameterDescriptor, input string, t *transformers, rules parameterRules, skipValidation bool) (interface{}, *ValidationError) {
+	var value interface{}
+	if err := json.Unmarshal([]byte(input), &value); err != nil {
+		return nil, &ValidationError{
+			Parameter:  param.Name,
+			Constraint: "type",
+			Message:    fmt.Sprintf("Invalid JSON value [%v] for parameter [%v]: %v", input, param.Name, err),
+		}
+	}
+	if n, ok := value.(float64); ok && (param.Type == "integer" || param.Type == "int") && n == math.Trunc(n) {
+		value = int64(n)
+	}
+	value, verr := t.transform(param, value)
+	if verr != nil {
+		return nil, verr
+	}
+	if !skipValidation {
+		if verr := checkValue(param, value); verr != nil {
+			return nil, verr
+		}
+	}
+	if verr := rules.check(param, value); verr != nil {
+		return nil, verr
+	}
+	return value, nil
+}
+
+// validateSchema checks the parameters against the plan's JSON schema, as a
+// backstop to the checks made on each value
+func validateSchema(plan bundle.Plan, params bundle.Parameters) error {
+	// parameters whose dependencies do not hold are left out of the schema,
+	// so they are not required
+	schemaParams, err := actionSchema(activeParameters(plan, params), "provision")
+	if err != nil {
+		return err
+	}
+	if err := validator.New(schemaParams).Validate(params); err != nil {
+		return ValidationErrors{{Constraint: "schema", Message: err.Error()}}
+	}
+	return nil
+}
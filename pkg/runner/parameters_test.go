@@ -0,0 +1,605 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+var databasePlan = bundle.Plan{
+	Name: "dev",
+	Parameters: []bundle.ParameterDescriptor{
+		{Name: "db_name", Type: "string", Required: true},
+		{Name: "db_size", Type: "int", Default: 5},
+		{Name: "db_version", Type: "enum", Enum: []string{"9.5", "9.6"}, Default: "9.6"},
+		{Name: "db_debug", Type: "boolean"},
+	},
+}
+
+var labelsPlan = bundle.Plan{
+	Name: "dev",
+	Parameters: []bundle.ParameterDescriptor{
+		{Name: "app", Type: "string", Required: true},
+		{Name: "labels", Type: "object"},
+	},
+}
+
+func TestCollectParameters(t *testing.T) {
+	testCases := []struct {
+		name           string
+		supplied       map[string]string
+		previous       bundle.Parameters
+		expected       bundle.Parameters
+		constraints    []string
+		skipValidation bool
+	}{
+		{
+			name:     "test supplied values and defaults",
+			supplied: map[string]string{"db_name": "mediawiki", "db_debug": "true"},
+			expected: bundle.Parameters{"db_name": "mediawiki", "db_size": int64(5), "db_version": "9.6", "db_debug": true},
+		},
+		{
+			name:     "test previous values replace defaults",
+			supplied: map[string]string{},
+			previous: bundle.Parameters{"db_name": "wiki", "db_size": 10},
+			expected: bundle.Parameters{"db_name": "wiki", "db_size": int64(10), "db_version": "9.6"},
+		},
+		{
+			name:        "test every problem is reported",
+			supplied:    map[string]string{"db_size": "big", "db_version": "10", "db_user": "admin"},
+			constraints: []string{"defined", "required", "type", "enum"},
+		},
+		{
+			name:           "test skipped validation still converts values",
+			supplied:       map[string]string{"db_name": "wiki", "db_size": "7", "db_version": "10"},
+			expected:       bundle.Parameters{"db_name": "wiki", "db_size": int64(7), "db_version": "10"},
+			skipValidation: true,
+		},
+		{
+			name:           "test skipped validation reports values which can't be converted",
+			supplied:       map[string]string{"db_name": "wiki", "db_size": "big"},
+			constraints:    []string{"type"},
+			skipValidation: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params, err := collectParameters(databasePlan, tc.previous, tc.supplied, nil, nil, nil, tc.skipValidation)
+			if tc.constraints == nil {
+				if err != nil {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				if len(params) != len(tc.expected) {
+					t.Fatalf("expected parameters [%v], got [%v]", tc.expected, params)
+				}
+				for k, v := range tc.expected {
+					if params[k] != v {
+						t.Fatalf("expected parameter [%v] to be [%#v], got [%#v]", k, v, params[k])
+					}
+				}
+				return
+			}
+			verrs, ok := err.(ValidationErrors)
+			if !ok {
+				t.Fatalf("expected ValidationErrors, got [%v]", err)
+			}
+			if len(verrs) != len(tc.constraints) {
+				t.Fatalf("expected constraints %v, got [%v]", tc.constraints, verrs)
+			}
+			for i, c := range tc.constraints {
+				if verrs[i].Constraint != c {
+					t.Fatalf("expected constraint [%v], got [%v]", c, verrs[i].Constraint)
+				}
+			}
+		})
+	}
+}
+
+func TestNumericEnums(t *testing.T) {
+	testCases := []struct {
+		name  string
+		param bundle.ParameterDescriptor
+		input string
+		valid bool
+	}{
+		{
+			name:  "test int enum",
+			param: bundle.ParameterDescriptor{Name: "size", Type: "int", Enum: []string{"10", "20", "30"}},
+			input: "20",
+			valid: true,
+		},
+		{
+			name:  "test int enum with another form of the value",
+			param: bundle.ParameterDescriptor{Name: "size", Type: "int", Enum: []string{"16", "32"}},
+			input: "0x10",
+			valid: true,
+		},
+		{
+			name:  "test int enum rejects other values",
+			param: bundle.ParameterDescriptor{Name: "size", Type: "int", Enum: []string{"10", "20", "30"}},
+			input: "25",
+		},
+		{
+			name:  "test number enum",
+			param: bundle.ParameterDescriptor{Name: "ratio", Type: "number", Enum: []string{"0.5", "1"}},
+			input: "1.0",
+			valid: true,
+		},
+		{
+			name:  "test number enum rejects other values",
+			param: bundle.ParameterDescriptor{Name: "ratio", Type: "number", Enum: []string{"0.5", "1"}},
+			input: "0.25",
+		},
+		{
+			name:  "test string enum",
+			param: bundle.ParameterDescriptor{Name: "db_version", Type: "enum", Enum: []string{"9.5", "9.6"}},
+			input: "9.6",
+			valid: true,
+		},
+		{
+			name:  "test string enum is not compared as numbers",
+			param: bundle.ParameterDescriptor{Name: "db_version", Type: "enum", Enum: []string{"9.5", "9.6"}},
+			input: "9.60",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, verr := checkInput(tc.param, tc.input)
+			if tc.valid && verr != nil {
+				t.Fatalf("got unexpected error [%v]", verr.Message)
+			}
+			if !tc.valid && (verr == nil || verr.Constraint != "enum") {
+				t.Fatalf("expected an enum error, got [%v]", verr)
+			}
+		})
+	}
+	// values given as JSON are compared by value too
+	param := bundle.ParameterDescriptor{Name: "size", Type: "int", Enum: []string{"10", "20"}}
+	if verr := checkValue(param, float64(20)); verr != nil {
+		t.Fatalf("got unexpected error [%v]", verr.Message)
+	}
+}
+
+func TestValidationErrorsJSON(t *testing.T) {
+	verrs := ValidationErrors{{Parameter: "db_name", Constraint: "required", Message: "Parameter [db_name] is required"}}
+	out, err := json.Marshal(verrs)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected := `[{"parameter":"db_name","constraint":"required","message":"Parameter [db_name] is required"}]`
+	if string(out) != expected {
+		t.Fatalf("expected [%v], got [%v]", expected, string(out))
+	}
+}
+
+func TestParametersFromStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	go func() {
+		w.Write([]byte(`{"db_name": "mediawiki", "db_size": 10, "db_debug": true}`))
+		w.Close()
+	}()
+	o, err := newOptions([]Option{WithParameterJSON(r), WithParameterValues([]string{"db_size=20"})})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if !o.nonInteractive {
+		t.Fatalf("expected parameters from stdin to be non-interactive")
+	}
+	if _, ok := o.selector.(nonInteractiveSelector); !ok {
+		t.Fatalf("expected plans not to be prompted for, got selector [%T]", o.selector)
+	}
+	params, err := collectParameters(databasePlan, nil, o.parameterValues, o.jsonParameters, nil, nil, false)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected := bundle.Parameters{"db_name": "mediawiki", "db_size": int64(20), "db_version": "9.6", "db_debug": true}
+	for k, v := range expected {
+		if params[k] != v {
+			t.Fatalf("expected parameter [%v] to be [%#v], got [%#v]", k, v, params[k])
+		}
+	}
+
+	o, err = newOptions([]Option{WithParameterJSON(strings.NewReader(`{"app": "wiki", "labels": {"tier": "db"}}`))})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	params, err = collectParameters(labelsPlan, nil, o.parameterValues, o.jsonParameters, nil, nil, false)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected = bundle.Parameters{"app": "wiki", "labels": map[string]interface{}{"tier": "db"}}
+	if !reflect.DeepEqual(params, expected) {
+		t.Fatalf("expected parameters %v, got %v", expected, params)
+	}
+
+	if _, err := newOptions([]Option{WithParameterJSON(strings.NewReader(`["db_name"]`))}); err == nil {
+		t.Fatalf("expected error for parameters which are not a JSON object")
+	}
+}
+
+func TestParametersFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-params")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	defer os.RemoveAll(dir)
+	testCases := []struct {
+		name      string
+		content   string
+		plan      *bundle.Plan
+		expected  bundle.Parameters
+		missing   string
+		shouldErr bool
+	}{
+		{
+			name:     "test YAML file",
+			content:  "db_name: mediawiki\ndb_size: 10\ndb_debug: true\n",
+			expected: bundle.Parameters{"db_name": "mediawiki", "db_size": int64(10), "db_version": "9.6", "db_debug": true},
+		},
+		{
+			name:     "test JSON file",
+			content:  `{"db_name": "mediawiki", "db_version": "9.5"}`,
+			expected: bundle.Parameters{"db_name": "mediawiki", "db_size": int64(5), "db_version": "9.5"},
+		},
+		{
+			name:     "test object values",
+			content:  "app: wiki\nlabels:\n  tier: db\n",
+			plan:     &labelsPlan,
+			expected: bundle.Parameters{"app": "wiki", "labels": map[string]interface{}{"tier": "db"}},
+		},
+		{
+			name:    "test file missing a required parameter",
+			content: "db_size: 10\n",
+			missing: "db_name",
+		},
+		{
+			name:      "test file which is not an object",
+			content:   "- db_name\n",
+			shouldErr: true,
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("params-%d.yaml", i))
+			if err := ioutil.WriteFile(path, []byte(tc.content), 0600); err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			o, err := newOptions([]Option{WithParameterFile(path)})
+			if tc.shouldErr {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if !o.nonInteractive {
+				t.Fatalf("expected parameters from a file to be non-interactive")
+			}
+			plan := databasePlan
+			if tc.plan != nil {
+				plan = *tc.plan
+			}
+			params, err := collectParameters(plan, nil, o.parameterValues, o.jsonParameters, nil, nil, false)
+			if tc.missing != "" {
+				verrs, ok := err.(ValidationErrors)
+				if !ok || len(verrs) != 1 || verrs[0].Constraint != "required" || verrs[0].Parameter != tc.missing {
+					t.Fatalf("expected parameter [%v] to be missing, got [%v]", tc.missing, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if !reflect.DeepEqual(params, tc.expected) {
+				t.Fatalf("expected parameters %v, got %v", tc.expected, params)
+			}
+		})
+	}
+	if _, err := newOptions([]Option{WithParameterFile(filepath.Join(dir, "missing.yaml"))}); err == nil {
+		t.Fatalf("expected error for a missing file")
+	}
+}
+
+func TestParseItems(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     string
+		expected  []interface{}
+		shouldErr bool
+	}{
+		{
+			name:     "test comma separated items",
+			input:    "web, db,cache",
+			expected: []interface{}{"web", "db", "cache"},
+		},
+		{
+			name:     "test empty input",
+			input:    "",
+			expected: []interface{}{},
+		},
+		{
+			name:     "test JSON array",
+			input:    `["a,b", 2]`,
+			expected: []interface{}{"a,b", float64(2)},
+		},
+		{
+			name:      "test invalid JSON array",
+			input:     `["a"`,
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			items, err := parseItems(tc.input)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("expected error but got [%v]", items)
+			}
+			if !reflect.DeepEqual(items, tc.expected) {
+				t.Fatalf("expected items [%v], got [%v]", tc.expected, items)
+			}
+		})
+	}
+	if input := defaultInput([]interface{}{"web", "db"}); input != "web,db" {
+		t.Fatalf("expected array default to be typed as [web,db], got [%v]", input)
+	}
+}
+
+func TestParametersFromJSON(t *testing.T) {
+	testCases := []struct {
+		name        string
+		raw         string
+		expected    bundle.Parameters
+		constraints []string
+		invalid     bool
+	}{
+		{
+			name:     "test JSON values",
+			raw:      `{"db_name": "wiki", "db_size": 10, "db_debug": true}`,
+			expected: bundle.Parameters{"db_name": "wiki", "db_size": int64(10), "db_version": "9.6", "db_debug": true},
+		},
+		{
+			name:     "test string values",
+			raw:      `{"db_name": "wiki", "db_size": "10", "db_version": "9.5", "db_debug": "false"}`,
+			expected: bundle.Parameters{"db_name": "wiki", "db_size": int64(10), "db_version": "9.5", "db_debug": false},
+		},
+		{
+			name:        "test invalid values",
+			raw:         `{"db_size": "big", "db_version": 10, "db_user": "admin"}`,
+			constraints: []string{"defined", "required", "type", "enum"},
+		},
+		{
+			name:    "test malformed JSON",
+			raw:     `{"db_name": "wiki"`,
+			invalid: true,
+		},
+		{
+			name:    "test JSON which is not an object",
+			raw:     `["wiki"]`,
+			invalid: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params, err := ParametersFromJSON(databasePlan, []byte(tc.raw))
+			if tc.invalid {
+				if _, ok := err.(ValidationErrors); err == nil || ok {
+					t.Fatalf("expected a JSON error, got [%v]", err)
+				}
+				return
+			}
+			if tc.constraints != nil {
+				verrs, ok := err.(ValidationErrors)
+				if !ok {
+					t.Fatalf("expected validation errors, got [%v]", err)
+				}
+				var constraints []string
+				for _, verr := range verrs {
+					constraints = append(constraints, verr.Constraint)
+				}
+				if !reflect.DeepEqual(constraints, tc.constraints) {
+					t.Fatalf("expected constraints %v, got %v", tc.constraints, constraints)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if !reflect.DeepEqual(params, tc.expected) {
+				t.Fatalf("expected parameters %v, got %v", tc.expected, params)
+			}
+		})
+	}
+}
+
+func TestJSONParameterValues(t *testing.T) {
+	plan := bundle.Plan{
+		Name: "default",
+		Parameters: []bundle.ParameterDescriptor{
+			{Name: "labels", Type: "object"},
+			{Name: "hosts", Type: "array"},
+			{Name: "replicas", Type: "int", Default: 1},
+		},
+	}
+	testCases := []struct {
+		name      string
+		opts      []Option
+		expected  bundle.Parameters
+		optsErr   bool
+		shouldErr bool
+	}{
+		{
+			name: "test object and array values",
+			opts: []Option{WithJSONParameterValues([]string{
+				`labels={"team": "web", "tier": {"name": "front"}}`,
+				`hosts=["a.example.com", "b.example.com"]`,
+				`replicas=3`,
+			})},
+			expected: bundle.Parameters{
+				"labels":   map[string]interface{}{"team": "web", "tier": map[string]interface{}{"name": "front"}},
+				"hosts":    []interface{}{"a.example.com", "b.example.com"},
+				"replicas": int64(3),
+			},
+		},
+		{
+			name: "test set overrides set-json",
+			opts: []Option{
+				WithJSONParameterValues([]string{`hosts=["a.example.com"]`}),
+				WithParameterValues([]string{"hosts=b.example.com,c.example.com"}),
+			},
+			expected: bundle.Parameters{
+				"hosts":    []interface{}{"b.example.com", "c.example.com"},
+				"replicas": int64(1),
+			},
+		},
+		{
+			name:    "test invalid JSON",
+			opts:    []Option{WithJSONParameterValues([]string{`labels={"team": web}`})},
+			optsErr: true,
+		},
+		{
+			name:    "test missing name",
+			opts:    []Option{WithJSONParameterValues([]string{`{"team": "web"}`})},
+			optsErr: true,
+		},
+		{
+			name:      "test value of the wrong type",
+			opts:      []Option{WithJSONParameterValues([]string{`labels=["web"]`})},
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := newOptions(tc.opts)
+			if tc.optsErr {
+				if err == nil {
+					t.Fatalf("expected error for options")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			params, err := collectParameters(plan, nil, o.parameterValues, o.jsonParameters, nil, nil, false)
+			if tc.shouldErr {
+				if err == nil {
+					t.Fatalf("expected error but got parameters [%v]", params)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if !reflect.DeepEqual(params, tc.expected) {
+				t.Fatalf("expected parameters [%#v], got [%#v]", tc.expected, params)
+			}
+		})
+	}
+}
+
+func TestCheckSettable(t *testing.T) {
+	plan := bundle.Plan{
+		Name: "dev",
+		Parameters: []bundle.ParameterDescriptor{
+			{Name: "db_name", Type: "string"},
+			{Name: "db_size", Type: "int", Updatable: true},
+			{Name: "db_host", Type: "string"},
+		},
+		Metadata: map[string]interface{}{"schema": map[string]interface{}{
+			"properties": map[string]interface{}{
+				"db_host": map[string]interface{}{"type": "string", "readOnly": true},
+			},
+		}},
+	}
+	s, err := loadPlanSchema(plan, 0)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	readOnly := readOnlyParameters(s)
+	if !reflect.DeepEqual(readOnly, map[string]bool{"db_host": true}) {
+		t.Fatalf("got unexpected read-only parameters %v", readOnly)
+	}
+
+	testCases := []struct {
+		name     string
+		action   string
+		supplied map[string]string
+		previous bundle.Parameters
+		params   bundle.Parameters
+		failed   []string
+	}{
+		{
+			name:     "test provision",
+			action:   "provision",
+			supplied: map[string]string{"db_name": "wiki", "db_size": "5"},
+			params:   bundle.Parameters{"db_name": "wiki", "db_size": int64(5)},
+		},
+		{
+			name:     "test read-only parameter supplied",
+			action:   "provision",
+			supplied: map[string]string{"db_host": "db.local"},
+			params:   bundle.Parameters{"db_host": "db.local"},
+			failed:   []string{"db_host"},
+		},
+		{
+			name:     "test update keeping a parameter which isn't updatable",
+			action:   "update",
+			supplied: map[string]string{"db_name": "wiki", "db_size": "10"},
+			previous: bundle.Parameters{"db_name": "wiki", "db_size": float64(5)},
+			params:   bundle.Parameters{"db_name": "wiki", "db_size": int64(10)},
+		},
+		{
+			name:     "test update changing a parameter which isn't updatable",
+			action:   "update",
+			previous: bundle.Parameters{"db_name": "wiki"},
+			params:   bundle.Parameters{"db_name": "blog"},
+			failed:   []string{"db_name"},
+		},
+		{
+			name:     "test update setting a parameter with no previous value",
+			action:   "update",
+			supplied: map[string]string{"db_name": "wiki"},
+			params:   bundle.Parameters{"db_name": "wiki"},
+			failed:   []string{"db_name"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkSettable(plan, tc.action, readOnly, tc.supplied, tc.previous, tc.params)
+			if len(tc.failed) == 0 {
+				if err != nil {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			verrs, ok := err.(ValidationErrors)
+			if !ok {
+				t.Fatalf("expected validation errors, got [%v]", err)
+			}
+			var failed []string
+			for _, verr := range verrs {
+				failed = append(failed, verr.Parameter)
+			}
+			if !reflect.DeepEqual(failed, tc.failed) {
+				t.Fatalf("expected errors for %v, got [%v]", tc.failed, err)
+			}
+		})
+	}
+}
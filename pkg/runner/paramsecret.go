@@ -0,0 +1,127 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/automationbroker/apb/pkg/util"
+	"github.com/automationbroker/bundle-lib/bundle"
+	"k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// instanceIDLabel labels the secret holding the parameters of an instance
+// with its ID
+const instanceIDLabel = "bundle-instance-id"
+
+// keys of the secret holding the parameters of an instance
+const (
+	parametersSecretKey = "parameters"
+	planSecretKey       = "plan"
+)
+
+func parametersSecretName(instanceID string) string {
+	return "bundle-params-" + instanceID
+}
+
+// storeParameters writes all the parameters of the instance, passwords
+// included, to a secret in its namespace labeled with the instance ID, so
+// later actions on it can be run with them
+func storeParameters(secrets corev1.SecretInterface, instanceID string, bundleName string, plan bundle.Plan, params bundle.Parameters) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: parametersSecretName(instanceID),
+			Labels: map[string]string{
+				instanceIDLabel: instanceID,
+				"bundle-fqname": bundleName,
+			},
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			parametersSecretKey: data,
+			planSecretKey:       []byte(plan.Name),
+		},
+	}
+	existing, err := secrets.Get(secret.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = secrets.Create(secret)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	existing.Labels = secret.Labels
+	existing.Data = secret.Data
+	_, err = secrets.Update(existing)
+	return err
+}
+
+// storedParameters returns the parameters stored for the instance, if they
+// were stored with the plan. It returns nil when none were stored.
+func storedParameters(secrets corev1.SecretInterface, instanceID string, planName string) (bundle.Parameters, error) {
+	selector := labels.Set{instanceIDLabel: instanceID}.String()
+	list, err := secrets.List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Items) == 0 {
+		return nil, nil
+	}
+	secret := list.Items[0]
+	if string(secret.Data[planSecretKey]) != planName {
+		return nil, nil
+	}
+	var params bundle.Parameters
+	if err := json.Unmarshal(secret.Data[parametersSecretKey], &params); err != nil {
+		return nil, fmt.Errorf("failed to parse the parameters in secret [%v]: %v", secret.Name, err)
+	}
+	return params, nil
+}
+
+// deleteStoredParameters removes the secret holding the parameters of the
+// instance
+func deleteStoredParameters(secrets corev1.SecretInterface, instanceID string) error {
+	err := secrets.Delete(parametersSecretName(instanceID), &metav1.DeleteOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// instanceParameters returns the parameters stored for the bundle's
+// instance in the namespace, found through the local instance records
+func instanceParameters(bundleName string, ns string, planName string) (bundle.Parameters, error) {
+	instances := loadInstances()
+	i, found := findInstance(instances, bundleName, ns)
+	if !found {
+		return nil, nil
+	}
+	k8scli, err := util.KubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+	return storedParameters(k8scli.Client.CoreV1().Secrets(ns), instances[i].ID, planName)
+}
@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"k8s.io/api/core/v1"
+)
+
+func TestStoredParameters(t *testing.T) {
+	plan := bundle.Plan{
+		Name: "dev",
+		Parameters: []bundle.ParameterDescriptor{
+			{Name: "user", Type: "string"},
+			{Name: "password", Type: "string", DisplayType: "password"},
+		},
+	}
+	id := "0f6e0a27-5b3c-4b1e-9d2a-6c1f0e8b7a11"
+	secrets := &fakeSecrets{secrets: map[string]*v1.Secret{}}
+
+	params, err := storedParameters(secrets, id, plan.Name)
+	if err != nil || params != nil {
+		t.Fatalf("expected no stored parameters, got %v [%v]", params, err)
+	}
+
+	if err := storeParameters(secrets, id, "hello-apb", plan, bundle.Parameters{"user": "admin", "password": "secret"}); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	secret := secrets.secrets[parametersSecretName(id)]
+	if secret == nil || secret.Labels[instanceIDLabel] != id || secret.Labels["bundle-fqname"] != "hello-apb" {
+		t.Fatalf("expected a secret labeled with the instance ID, got %+v", secret)
+	}
+
+	// an update stores its parameters over the provision's
+	if err := storeParameters(secrets, id, "hello-apb", plan, bundle.Parameters{"user": "root", "password": "changed"}); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	params, err = storedParameters(secrets, id, plan.Name)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected := bundle.Parameters{"user": "root", "password": "changed"}
+	if !reflect.DeepEqual(params, expected) {
+		t.Fatalf("expected parameters %v, got %v", expected, params)
+	}
+
+	if params, err := storedParameters(secrets, id, "prod"); err != nil || params != nil {
+		t.Fatalf("expected parameters of another plan to be ignored, got %v [%v]", params, err)
+	}
+
+	if err := deleteStoredParameters(secrets, id); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if len(secrets.secrets) != 0 {
+		t.Fatalf("expected the secret to be deleted, got %v", secrets.secrets)
+	}
+	if err := deleteStoredParameters(secrets, id); err != nil {
+		t.Fatalf("expected deleting a missing secret to succeed, got [%v]", err)
+	}
+}
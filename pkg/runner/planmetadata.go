@@ -0,0 +1,83 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+// DefaultPlanMetadataKeys are the plan metadata shown when a plan is
+// selected, unless other keys are configured
+var DefaultPlanMetadataKeys = []string{"cost", "supportTier"}
+
+// planMetadataAnnotation is the bundle pod annotation holding the shown
+// metadata of the plan as a JSON object
+const planMetadataAnnotation = "bundle-plan-metadata"
+
+// PlanMetadataValue is the value of a plan metadata key
+type PlanMetadataValue struct {
+	Key   string
+	Value interface{}
+}
+
+// PlanMetadata returns the value of each of the keys in the plan's metadata,
+// in the keys' order. Keys the plan has no metadata for are left out.
+func PlanMetadata(plan bundle.Plan, keys []string) []PlanMetadataValue {
+	var values []PlanMetadataValue
+	for _, key := range keys {
+		value, ok := plan.Metadata[key]
+		if !ok || value == nil {
+			continue
+		}
+		values = append(values, PlanMetadataValue{Key: key, Value: jsonValue(value)})
+	}
+	return values
+}
+
+// printPlanMetadata prints a line for each of the values, under the plan's
+// name. Strings are printed as they are and other values as JSON.
+func printPlanMetadata(w io.Writer, values []PlanMetadataValue) {
+	for _, v := range values {
+		text, ok := v.Value.(string)
+		if !ok {
+			b, err := json.Marshal(v.Value)
+			if err != nil {
+				text = fmt.Sprint(v.Value)
+			} else {
+				text = string(b)
+			}
+		}
+		fmt.Fprintf(w, "  %v: %v\n", v.Key, text)
+	}
+}
+
+// planMetadataAnnotationValue returns the values as a JSON object
+func planMetadataAnnotationValue(values []PlanMetadataValue) (string, error) {
+	m := map[string]interface{}{}
+	for _, v := range values {
+		m[v.Key] = v.Value
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("failed to annotate plan metadata: %v", err)
+	}
+	return string(b), nil
+}
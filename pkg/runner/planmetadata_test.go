@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+func TestPlanMetadata(t *testing.T) {
+	plan := bundle.Plan{
+		Name: "prod",
+		Metadata: map[string]interface{}{
+			"cost":        "$0.40/hr",
+			"supportTier": "gold",
+			"sla":         map[interface{}]interface{}{"uptime": 99.9},
+			"displayName": "Production",
+		},
+	}
+	testCases := []struct {
+		name        string
+		plan        bundle.Plan
+		keys        []string
+		expected    []PlanMetadataValue
+		printed     string
+		annotations string
+	}{
+		{
+			name:        "test default keys",
+			plan:        plan,
+			keys:        DefaultPlanMetadataKeys,
+			expected:    []PlanMetadataValue{{Key: "cost", Value: "$0.40/hr"}, {Key: "supportTier", Value: "gold"}},
+			printed:     "  cost: $0.40/hr\n  supportTier: gold\n",
+			annotations: `{"cost":"$0.40/hr","supportTier":"gold"}`,
+		},
+		{
+			name:        "test structured value",
+			plan:        plan,
+			keys:        []string{"sla", "missing"},
+			expected:    []PlanMetadataValue{{Key: "sla", Value: map[string]interface{}{"uptime": 99.9}}},
+			printed:     "  sla: {\"uptime\":99.9}\n",
+			annotations: `{"sla":{"uptime":99.9}}`,
+		},
+		{
+			name: "test plan without metadata",
+			plan: bundle.Plan{Name: "dev"},
+			keys: DefaultPlanMetadataKeys,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			values := PlanMetadata(tc.plan, tc.keys)
+			if !reflect.DeepEqual(values, tc.expected) {
+				t.Fatalf("expected metadata %v, got %v", tc.expected, values)
+			}
+			var out bytes.Buffer
+			printPlanMetadata(&out, values)
+			if out.String() != tc.printed {
+				t.Fatalf("expected to print %q, got %q", tc.printed, out.String())
+			}
+			if len(values) == 0 {
+				return
+			}
+			annotation, err := planMetadataAnnotationValue(values)
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if annotation != tc.annotations {
+				t.Fatalf("expected annotation %v, got %v", tc.annotations, annotation)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/lestrrat/go-jsref"
+	"github.com/lestrrat/go-jsref/provider"
+	"github.com/lestrrat/go-jsschema"
+	"github.com/lestrrat/go-jsschema/validator"
+)
+
+// planSchemaMetadataKey is the plan metadata key holding a JSON schema the
+// parameters must also satisfy. Unlike the schema generated from the
+// parameter descriptors it can use $ref, to definitions within it or, when
+// enabled, to schemas served over HTTP.
+const planSchemaMetadataKey = "schema"
+
+// loadPlanSchema returns the plan's metadata schema with its $refs
+// resolved, or nil if it has none. Remote refs are fetched only with a
+// timeout greater than zero.
+func loadPlanSchema(plan bundle.Plan, remoteTimeout time.Duration) (*schema.Schema, error) {
+	declared, ok := plan.Metadata[planSchemaMetadataKey]
+	if !ok {
+		return nil, nil
+	}
+	doc := jsonValue(declared)
+	if _, ok := doc.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("%v metadata of plan [%v] is not a JSON schema", planSchemaMetadataKey, plan.Name)
+	}
+
+	resolver := jsref.New()
+	if remoteTimeout > 0 {
+		remote := provider.NewHTTP()
+		remote.Client.Timeout = remoteTimeout
+		resolver.AddProvider(remote)
+	}
+	resolved, err := resolver.Resolve(doc, "#", jsref.WithRecursiveResolution(true))
+	if err != nil {
+		hint := ""
+		if remoteTimeout <= 0 {
+			hint = ". Refs to remote schemas are only fetched with --remote-schema-refs"
+		}
+		return nil, fmt.Errorf("failed to resolve $ref in the schema of plan [%v]: %v%v", plan.Name, err, hint)
+	}
+	data, err := json.Marshal(resolved)
+	if err != nil {
+		return nil, err
+	}
+	s, err := schema.Read(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema in plan [%v]: %v", plan.Name, err)
+	}
+	return s, nil
+}
+
+// validatePlanSchema checks the parameters against the plan's metadata
+// schema, if it has one
+func validatePlanSchema(s *schema.Schema, params bundle.Parameters) error {
+	if s == nil {
+		return nil
+	}
+	if err := validator.New(s).Validate(map[string]interface{}(params)); err != nil {
+		return ValidationErrors{{Constraint: "schema", Message: err.Error()}}
+	}
+	return nil
+}
+
+// readOnlyParameters returns the names of the properties the schema marks
+// readOnly. Those are set by the bundle, never by the user.
+func readOnlyParameters(s *schema.Schema) map[string]bool {
+	readOnly := map[string]bool{}
+	if s == nil {
+		return readOnly
+	}
+	for name, property := range s.Properties {
+		if property.Extras["readOnly"] == true {
+			readOnly[name] = true
+		}
+	}
+	return readOnly
+}
+
+// jsonValue converts metadata decoded from YAML to the types decoded from
+// JSON
+func jsonValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, item := range value {
+			m[fmt.Sprint(k)] = jsonValue(item)
+		}
+		return m
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for k, item := range value {
+			m[k] = jsonValue(item)
+		}
+		return m
+	case []interface{}:
+		items := make([]interface{}, len(value))
+		for i, item := range value {
+			items[i] = jsonValue(item)
+		}
+		return items
+	}
+	return v
+}
@@ -0,0 +1,122 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+func TestPlanSchemaRefs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"definitions": {"port": {"type": "integer", "minimum": 1024}}}`)
+	}))
+	defer server.Close()
+
+	planWithSchema := func(s map[string]interface{}) bundle.Plan {
+		return bundle.Plan{Name: "dev", Metadata: map[string]interface{}{"schema": s}}
+	}
+	localRef := planWithSchema(map[string]interface{}{
+		"definitions": map[string]interface{}{
+			"name": map[string]interface{}{"type": "string", "pattern": "^[a-z]+$"},
+		},
+		"properties": map[string]interface{}{
+			"db_name": map[string]interface{}{"$ref": "#/definitions/name"},
+		},
+	})
+	remoteRef := planWithSchema(map[string]interface{}{
+		"properties": map[string]interface{}{
+			"db_port": map[string]interface{}{"$ref": server.URL + "/common.json#/definitions/port"},
+		},
+	})
+	testCases := []struct {
+		name          string
+		plan          bundle.Plan
+		remoteTimeout time.Duration
+		params        bundle.Parameters
+		loadErr       string
+		shouldFail    bool
+	}{
+		{
+			name:   "test plan without a schema",
+			plan:   bundle.Plan{Name: "dev"},
+			params: bundle.Parameters{"db_name": "Wiki"},
+		},
+		{
+			name:   "test local ref",
+			plan:   localRef,
+			params: bundle.Parameters{"db_name": "wiki"},
+		},
+		{
+			name:       "test local ref rejects value",
+			plan:       localRef,
+			params:     bundle.Parameters{"db_name": "Wiki"},
+			shouldFail: true,
+		},
+		{
+			name:    "test remote ref not fetched by default",
+			plan:    remoteRef,
+			loadErr: "--remote-schema-refs",
+		},
+		{
+			name:          "test remote ref",
+			plan:          remoteRef,
+			remoteTimeout: time.Second,
+			params:        bundle.Parameters{"db_port": int64(8080)},
+		},
+		{
+			name:          "test remote ref rejects value",
+			plan:          remoteRef,
+			remoteTimeout: time.Second,
+			params:        bundle.Parameters{"db_port": int64(80)},
+			shouldFail:    true,
+		},
+		{
+			name: "test unresolvable ref",
+			plan: planWithSchema(map[string]interface{}{
+				"properties": map[string]interface{}{
+					"db_name": map[string]interface{}{"$ref": "#/definitions/missing"},
+				},
+			}),
+			loadErr: "failed to resolve $ref in the schema of plan [dev]",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := loadPlanSchema(tc.plan, tc.remoteTimeout)
+			if err != nil {
+				if tc.loadErr == "" || !strings.Contains(err.Error(), tc.loadErr) {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			if tc.loadErr != "" {
+				t.Fatalf("expected error containing [%v]", tc.loadErr)
+			}
+			err = validatePlanSchema(s, tc.params)
+			if err != nil && !tc.shouldFail {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if err == nil && tc.shouldFail {
+				t.Fatalf("expected parameters %v to fail validation", tc.params)
+			}
+		})
+	}
+}
+
+func TestJSONValue(t *testing.T) {
+	v := jsonValue(map[interface{}]interface{}{
+		"items": []interface{}{map[interface{}]interface{}{"type": "string"}},
+	})
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected string keyed map, got %T", v)
+	}
+	if _, ok := m["items"].([]interface{})[0].(map[string]interface{}); !ok {
+		t.Fatalf("expected nested maps to be string keyed, got %v", m)
+	}
+}
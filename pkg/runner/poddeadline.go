@@ -0,0 +1,90 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// deadlineExceededReason is the reason a pod fails with once it has run
+// past its ActiveDeadlineSeconds
+const deadlineExceededReason = "DeadlineExceeded"
+
+// expirePod deletes the bundle pod once its deadline has passed. That is
+// when the pod was stopped for running past its ActiveDeadlineSeconds, or
+// when the wait for it failed, whether it timed out or lost the API server,
+// after the deadline had elapsed since the pod started. A wait which failed
+// sooner, e.g. because the pod can't be scheduled, isn't an expiry and is
+// left to the caller. It reports whether the deadline expired and the
+// error the run fails with. Deleting the pod rides out failures to reach
+// the API server for up to the reconnect timeout, so the pod doesn't
+// outlive its deadline because the wait lost the API server.
+func expirePod(pods corev1.PodInterface, podName string, deadline time.Duration, created time.Time, phase v1.PodPhase, waitErr error, force bool, reconnectTimeout time.Duration) (bool, error) {
+	if waitErr == nil {
+		if phase != v1.PodFailed {
+			return false, nil
+		}
+		pod, err := pods.Get(podName, metav1.GetOptions{})
+		if err != nil || pod.Status.Reason != deadlineExceededReason {
+			return false, nil
+		}
+	} else if !deadlinePassed(pods, podName, deadline, created) {
+		return false, nil
+	}
+
+	var err error
+	for start := time.Now(); ; {
+		err = cleanupPod(pods, podName, force)
+		if err == nil || !transientError(err) || time.Since(start) > reconnectTimeout {
+			break
+		}
+		time.Sleep(pollInterval)
+	}
+	if err != nil {
+		return true, fmt.Errorf("pod [%v] did not complete within its deadline of %v and failed to be deleted: %v", podName, deadline, err)
+	}
+	fmt.Printf("Deleted pod [%v]\n", podName)
+	if waitErr != nil {
+		return true, fmt.Errorf("pod [%v] did not complete within its deadline of %v: %v", podName, deadline, waitErr)
+	}
+	return true, fmt.Errorf("pod [%v] did not complete within its deadline of %v", podName, deadline)
+}
+
+// deadlinePassed reports whether the pod was stopped for exceeding its
+// deadline or has been running for longer than it. The deadline counts from
+// the pod's start, or its creation while it hasn't started, and from when
+// the run created it when the pod can't be read.
+func deadlinePassed(pods corev1.PodInterface, podName string, deadline time.Duration, created time.Time) bool {
+	start := created
+	pod, err := pods.Get(podName, metav1.GetOptions{})
+	if err == nil {
+		if pod.Status.Reason == deadlineExceededReason {
+			return true
+		}
+		if pod.Status.StartTime != nil {
+			start = pod.Status.StartTime.Time
+		} else if !pod.CreationTimestamp.IsZero() {
+			start = pod.CreationTimestamp.Time
+		}
+	}
+	return time.Since(start) >= deadline
+}
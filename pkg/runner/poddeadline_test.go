@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// unreachablePods fails the first drops deletes, as the API server would
+// while unavailable
+type unreachablePods struct {
+	*fakePods
+	drops int
+}
+
+func (u *unreachablePods) Delete(name string, options *metav1.DeleteOptions) error {
+	if u.drops > 0 {
+		u.drops--
+		return errors.New("dial tcp 10.0.0.1:6443: connect: connection refused")
+	}
+	return u.fakePods.Delete(name, options)
+}
+
+func TestExpirePod(t *testing.T) {
+	savedGracePeriod, savedPollInterval := cleanupGracePeriod, pollInterval
+	defer func() { cleanupGracePeriod, pollInterval = savedGracePeriod, savedPollInterval }()
+	cleanupGracePeriod = 10 * time.Millisecond
+	pollInterval = time.Millisecond
+	timedOut := errors.New("timed out after 1m0s waiting for pod [apb-1] to complete")
+	testCases := []struct {
+		name          string
+		phase         v1.PodPhase
+		reason        string
+		waitErr       error
+		running       time.Duration
+		drops         int
+		expectExpired bool
+		expectDeleted bool
+		shouldErr     bool
+	}{
+		{
+			name:          "test wait timed out at the deadline",
+			phase:         v1.PodRunning,
+			waitErr:       timedOut,
+			running:       time.Minute,
+			expectExpired: true,
+			expectDeleted: true,
+			shouldErr:     true,
+		},
+		{
+			name:          "test pod stopped for exceeding its deadline",
+			phase:         v1.PodFailed,
+			reason:        deadlineExceededReason,
+			expectExpired: true,
+			expectDeleted: true,
+			shouldErr:     true,
+		},
+		{
+			name:          "test watch lost the API server near the deadline",
+			phase:         v1.PodRunning,
+			waitErr:       errors.New("failed to reconnect to the API server within 1m0s"),
+			running:       time.Minute,
+			drops:         3,
+			expectExpired: true,
+			expectDeleted: true,
+			shouldErr:     true,
+		},
+		{
+			name:    "test wait failed before the deadline",
+			phase:   v1.PodPending,
+			waitErr: errors.New("pod [apb-1] could not be scheduled"),
+			running: 10 * time.Second,
+		},
+		{
+			name:    "test watch lost the API server before the deadline",
+			phase:   v1.PodRunning,
+			waitErr: errors.New("failed to reconnect to the API server within 1m0s"),
+			running: 10 * time.Second,
+		},
+		{
+			name:  "test pod which succeeded",
+			phase: v1.PodSucceeded,
+		},
+		{
+			name:   "test pod which failed for another reason",
+			phase:  v1.PodFailed,
+			reason: "Evicted",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			created := time.Now().Add(-tc.running)
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "apb-1", CreationTimestamp: metav1.NewTime(created)},
+				Status:     v1.PodStatus{Phase: tc.phase, Reason: tc.reason},
+			}
+			fake := newFakePods(pod)
+			pods := &unreachablePods{fakePods: fake, drops: tc.drops}
+			expired, err := expirePod(pods, pod.Name, time.Minute, created, tc.phase, tc.waitErr, false, time.Minute)
+			if expired != tc.expectExpired {
+				t.Fatalf("expected expired [%v], got [%v]", tc.expectExpired, expired)
+			}
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("expected an error")
+			}
+			_, getErr := fake.Get(pod.Name, metav1.GetOptions{})
+			if deleted := getErr != nil; deleted != tc.expectDeleted {
+				t.Fatalf("expected deleted [%v], got [%v]", tc.expectDeleted, deleted)
+			}
+		})
+	}
+}
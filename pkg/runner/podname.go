@@ -0,0 +1,89 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pborman/uuid"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// defaultPodNameTemplate names pods bundle-<uuid>
+const defaultPodNameTemplate = "bundle-${uuid}"
+
+// trimmedPodNameTokens are shortened, longest first, when a pod name is
+// too long. The uuid and action are kept whole.
+var trimmedPodNameTokens = []string{"bundle", "namespace"}
+
+// podNameTokens returns the values of the tokens a pod name template can
+// use, each safe for a DNS label
+func podNameTokens(bundleName string, action string, ns string) map[string]string {
+	id := uuid.New()
+	sanitize := func(s string) string {
+		return strings.Trim(invalidNamespaceChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	}
+	return map[string]string{
+		"bundle":     sanitize(strings.TrimSuffix(bundleName, "-apb")),
+		"action":     sanitize(action),
+		"namespace":  sanitize(ns),
+		"uuid":       id,
+		"short-uuid": id[:8],
+	}
+}
+
+// renderPodName expands the ${token}s of the template, shortening the
+// bundle and namespace tokens until the name fits in a DNS label
+func renderPodName(template string, tokens map[string]string) (string, error) {
+	values := map[string]string{}
+	for k, v := range tokens {
+		values[k] = v
+	}
+	var unknown []string
+	expand := func() string {
+		return os.Expand(template, func(token string) string {
+			value, ok := values[token]
+			if !ok && !contains(unknown, token) {
+				unknown = append(unknown, token)
+			}
+			return value
+		})
+	}
+	name := expand()
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown pod name tokens %v. Available tokens: bundle, action, namespace, uuid, short-uuid", unknown)
+	}
+	for len(name) > validation.DNS1123LabelMaxLength {
+		longest := ""
+		for _, token := range trimmedPodNameTokens {
+			if strings.Contains(template, "${"+token+"}") && len(values[token]) > len(values[longest]) {
+				longest = token
+			}
+		}
+		if longest == "" {
+			break
+		}
+		values[longest] = strings.TrimRight(values[longest][:len(values[longest])-1], "-")
+		name = expand()
+	}
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return "", fmt.Errorf("invalid pod name [%v] from template [%v]: %v", name, template, strings.Join(errs, ", "))
+	}
+	return name, nil
+}
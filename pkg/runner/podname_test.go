@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderPodName(t *testing.T) {
+	tokens := map[string]string{
+		"bundle":     "mediawiki",
+		"action":     "provision",
+		"namespace":  "wiki",
+		"uuid":       "0f6e0a27-4b6b-4d39-9a3e-5a7f3f2e8e11",
+		"short-uuid": "0f6e0a27",
+	}
+	testCases := []struct {
+		name      string
+		template  string
+		tokens    map[string]string
+		expected  string
+		shouldErr bool
+	}{
+		{
+			name:     "test default template",
+			template: defaultPodNameTemplate,
+			tokens:   tokens,
+			expected: "bundle-0f6e0a27-4b6b-4d39-9a3e-5a7f3f2e8e11",
+		},
+		{
+			name:     "test self-describing template",
+			template: "${bundle}-${action}-${short-uuid}",
+			tokens:   tokens,
+			expected: "mediawiki-provision-0f6e0a27",
+		},
+		{
+			name:     "test long tokens are trimmed",
+			template: "${namespace}-${bundle}-${action}-${short-uuid}",
+			tokens: map[string]string{
+				"bundle":     strings.Repeat("b", 40),
+				"action":     "deprovision",
+				"namespace":  strings.Repeat("n", 30),
+				"short-uuid": "0f6e0a27",
+			},
+			expected: strings.Repeat("n", 21) + "-" + strings.Repeat("b", 20) + "-deprovision-0f6e0a27",
+		},
+		{
+			name:      "test unknown token",
+			template:  "${bundle}-${user}",
+			tokens:    tokens,
+			shouldErr: true,
+		},
+		{
+			name:      "test invalid literal",
+			template:  "APB_${uuid}",
+			tokens:    tokens,
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, err := renderPodName(tc.template, tc.tokens)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("expected error but got pod name [%v]", name)
+			}
+			if name != tc.expected {
+				t.Fatalf("expected pod name [%v], got [%v]", tc.expected, name)
+			}
+		})
+	}
+}
+
+func TestPodNameTokens(t *testing.T) {
+	tokens := podNameTokens("dh-MediaWiki_apb", "provision", "my.project")
+	if tokens["bundle"] != "dh-mediawiki-apb" || tokens["namespace"] != "my-project" {
+		t.Fatalf("expected DNS label safe tokens, got %v", tokens)
+	}
+	if tokens = podNameTokens("mediawiki-apb", "provision", "wiki"); tokens["bundle"] != "mediawiki" {
+		t.Fatalf("expected bundle short name [mediawiki], got [%v]", tokens["bundle"])
+	}
+	if len(tokens["short-uuid"]) != 8 || !strings.HasPrefix(tokens["uuid"], tokens["short-uuid"]) {
+		t.Fatalf("expected short-uuid to be the start of uuid, got %v", tokens)
+	}
+}
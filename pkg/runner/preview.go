@@ -0,0 +1,66 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+// writeParameterPreview writes the parameters resolved for a run and the
+// extra vars they would be passed to the bundle as, in the plan's order.
+// Values the plan's defaults supplied are marked, and the values of password
+// parameters are redacted unless showSecrets is set.
+func writeParameterPreview(w io.Writer, plan bundle.Plan, params bundle.Parameters, supplied map[string]string, extraVars string, showSecrets bool) error {
+	sensitive := map[string]bool{}
+	if !showSecrets {
+		sensitive = sensitiveParameters(plan)
+	}
+	fmt.Fprintf(w, "Parameters of plan [%v]:\n", plan.Name)
+	for _, param := range plan.Parameters {
+		value, ok := params[param.Name]
+		if !ok {
+			continue
+		}
+		line := fmt.Sprintf("  %v: %v", param.Name, value)
+		if sensitive[param.Name] {
+			line = fmt.Sprintf("  %v: %v", param.Name, redactedValue)
+		}
+		if _, given := supplied[param.Name]; !given && param.Default != nil && enumEqual(param.Default, value) {
+			line += " (default)"
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	vars := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(extraVars), &vars); err != nil {
+		return fmt.Errorf("failed to decode extra vars: %v", err)
+	}
+	for name := range vars {
+		if sensitive[name] {
+			vars[name] = redactedValue
+		}
+	}
+	fmt.Fprintln(w, "Extra vars:")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(vars)
+}
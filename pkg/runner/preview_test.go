@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+func TestParameterPreview(t *testing.T) {
+	plan := databasePlan
+	plan.Parameters = append([]bundle.ParameterDescriptor{
+		{Name: "admin_pass", Type: "string", DisplayType: "password"},
+	}, databasePlan.Parameters...)
+	specs := staticSpecs{{
+		ID:     "4f6e6cf6ffdd3c7b",
+		FQName: "postgresql-apb",
+		Image:  "docker.io/ansibleplaybookbundle/postgresql-apb:latest",
+		Plans:  []bundle.Plan{plan},
+	}}
+	testCases := []struct {
+		name        string
+		showSecrets bool
+		expected    string
+	}{
+		{
+			name: "test defaults are marked and passwords redacted",
+			expected: `Parameters of plan [dev]:
+  admin_pass: ***
+  db_name: wiki
+  db_size: 5 (default)
+  db_version: 9.6 (default)
+Extra vars:
+{
+  "_apb_account": "<sandbox named after the pod>",
+  "_apb_plan_id": "dev",
+  "_apb_service_class_id": "4f6e6cf6ffdd3c7b",
+  "_apb_service_instance_id": "1234",
+  "admin_pass": "***",
+  "cluster": "openshift",
+  "db_name": "wiki",
+  "db_size": 5,
+  "db_version": "9.6",
+  "namespace": "web"
+}
+`,
+		},
+		{
+			name:        "test passwords are shown when asked",
+			showSecrets: true,
+			expected: `Parameters of plan [dev]:
+  admin_pass: s3cret
+  db_name: wiki
+  db_size: 5 (default)
+  db_version: 9.6 (default)
+Extra vars:
+{
+  "_apb_account": "<sandbox named after the pod>",
+  "_apb_plan_id": "dev",
+  "_apb_service_class_id": "4f6e6cf6ffdd3c7b",
+  "_apb_service_instance_id": "1234",
+  "admin_pass": "s3cret",
+  "cluster": "openshift",
+  "db_name": "wiki",
+  "db_size": 5,
+  "db_version": "9.6",
+  "namespace": "web"
+}
+`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			// there is no cluster, so the run fails if it reaches for one
+			podName, err := RunBundle("provision", "web", "postgresql-apb", "edit", "", false, false, nil,
+				WithSpecProvider(specs), WithNonInteractive(),
+				WithParameterValues([]string{"db_name=wiki", "admin_pass=s3cret"}),
+				WithParameterPreview(&out, tc.showSecrets))
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if podName != "" {
+				t.Fatalf("expected no pod, got [%v]", podName)
+			}
+			if out.String() != tc.expected {
+				t.Fatalf("expected preview:\n%v\ngot:\n%v", tc.expected, out.String())
+			}
+		})
+	}
+}
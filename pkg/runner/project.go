@@ -0,0 +1,126 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// projectGroup is the API group serving OpenShift projects. Its presence
+// is how the runner tells it is on OpenShift.
+const projectGroup = "project.openshift.io"
+
+// projects requests OpenShift projects. No OpenShift project client is
+// vendored, so the API is called through the REST client.
+type projects interface {
+	// Available reports whether the cluster serves the project API
+	Available() (bool, error)
+	// Create requests the project
+	Create(name string) error
+	// Label adds the labels and annotations to the project
+	Label(name string, labels map[string]string, annotations map[string]string) error
+}
+
+type restProjects struct {
+	client kubernetes.Interface
+}
+
+func (p *restProjects) Available() (bool, error) {
+	groups, err := p.client.Discovery().ServerGroups()
+	if err != nil {
+		return false, err
+	}
+	for _, group := range groups.Groups {
+		if group.Name == projectGroup {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (p *restProjects) Create(name string) error {
+	request, err := json.Marshal(map[string]interface{}{
+		"apiVersion": projectGroup + "/v1",
+		"kind":       "ProjectRequest",
+		"metadata":   map[string]string{"name": name},
+	})
+	if err != nil {
+		return err
+	}
+	return p.client.Discovery().RESTClient().Post().
+		AbsPath("/apis", projectGroup, "v1", "projectrequests").
+		SetHeader("Content-Type", "application/json").
+		Body(request).
+		Do().Error()
+}
+
+func (p *restProjects) Label(name string, labels map[string]string, annotations map[string]string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": labels, "annotations": annotations},
+	})
+	if err != nil {
+		return err
+	}
+	return p.client.Discovery().RESTClient().Patch(types.MergePatchType).
+		AbsPath("/apis", projectGroup, "v1", "projects", name).
+		Body(patch).
+		Do().Error()
+}
+
+// createProject creates the namespace as an OpenShift project, carrying the
+// configured labels and annotations. Without the project API it creates a
+// bare namespace instead. An existing project or namespace is used as is.
+func createProject(p projects, namespaces corev1.NamespaceInterface, name string, o *options) error {
+	available, err := p.Available()
+	if err != nil {
+		log.Warningf("Unable to tell whether the cluster serves OpenShift projects: %v", err)
+	}
+	if !available {
+		log.Debugf("Creating namespace [%v] since the cluster does not serve OpenShift projects", name)
+		if _, err := namespaces.Get(name, metav1.GetOptions{}); err == nil {
+			fmt.Printf("Using existing namespace [%v]\n", name)
+			return nil
+		}
+		return createNamespace(namespaces, name, o)
+	}
+	err = p.Create(name)
+	if k8serrors.IsAlreadyExists(err) {
+		fmt.Printf("Using existing project [%v]\n", name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create project [%v]: %v", name, err)
+	}
+	fmt.Printf("Created project [%v]\n", name)
+	// a project request can't carry labels or annotations, so they are
+	// added to the project it created
+	if len(o.namespaceLabels) == 0 && len(o.namespaceAnnotations) == 0 {
+		return nil
+	}
+	if err := p.Label(name, o.namespaceLabels, o.namespaceAnnotations); err != nil {
+		return fmt.Errorf("failed to label project [%v]: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestCreateProject(t *testing.T) {
+	labels := map[string]string{"team": "broker"}
+	annotations := map[string]string{"example.com/purpose": "apb testing"}
+	testCases := []struct {
+		name         string
+		openshift    bool
+		existing     bool
+		projects     []string
+		namespaces   []string
+		labeled      bool
+		shouldCreate bool
+	}{
+		{
+			name:         "test project on openshift",
+			openshift:    true,
+			projects:     []string{"apb-foo"},
+			labeled:      true,
+			shouldCreate: true,
+		},
+		{
+			name:      "test existing project on openshift",
+			openshift: true,
+			existing:  true,
+			projects:  []string{"apb-foo"},
+		},
+		{
+			name:         "test namespace on kubernetes",
+			namespaces:   []string{"apb-foo"},
+			shouldCreate: true,
+		},
+		{
+			name:       "test existing namespace on kubernetes",
+			existing:   true,
+			namespaces: []string{"apb-foo"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			projects := &fakeProjects{available: tc.openshift, projects: map[string]bool{}}
+			namespaces := &fakeNamespaces{namespaces: map[string]*v1.Namespace{}}
+			if tc.existing {
+				if tc.openshift {
+					projects.projects["apb-foo"] = true
+				} else {
+					namespaces.namespaces["apb-foo"] = &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apb-foo"}}
+				}
+			}
+			o, err := newOptions([]Option{
+				WithCreateProject(),
+				WithNamespaceLabels([]string{"team=broker"}),
+				WithNamespaceAnnotations([]string{"example.com/purpose=apb testing"}),
+			})
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if err := createProject(projects, namespaces, "apb-foo", o); err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			var gotProjects, gotNamespaces []string
+			for name := range projects.projects {
+				gotProjects = append(gotProjects, name)
+			}
+			for name := range namespaces.namespaces {
+				gotNamespaces = append(gotNamespaces, name)
+			}
+			if !reflect.DeepEqual(gotProjects, tc.projects) || !reflect.DeepEqual(gotNamespaces, tc.namespaces) {
+				t.Fatalf("expected projects %v and namespaces %v, got %v and %v", tc.projects, tc.namespaces, gotProjects, gotNamespaces)
+			}
+			if tc.labeled && (!reflect.DeepEqual(projects.labels, labels) || !reflect.DeepEqual(projects.annotations, annotations)) {
+				t.Fatalf("expected project labels %v and annotations %v, got %v and %v", labels, annotations, projects.labels, projects.annotations)
+			}
+			if !tc.labeled && projects.labels != nil {
+				t.Fatalf("expected the project not to be labeled, got %v", projects.labels)
+			}
+			if tc.shouldCreate && !tc.openshift {
+				ns := namespaces.namespaces["apb-foo"]
+				if !reflect.DeepEqual(ns.Labels, labels) || !reflect.DeepEqual(ns.Annotations, annotations) {
+					t.Fatalf("expected namespace labels %v and annotations %v, got %v and %v", labels, annotations, ns.Labels, ns.Annotations)
+				}
+			}
+		})
+	}
+}
+
+func TestRESTProjects(t *testing.T) {
+	var requests []string
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		bodies = append(bodies, string(body))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api":
+			fmt.Fprint(w, `{"kind": "APIVersions", "versions": ["v1"]}`)
+		case "/apis":
+			fmt.Fprint(w, `{"kind": "APIGroupList", "groups": [{"name": "project.openshift.io", "versions": [{"groupVersion": "project.openshift.io/v1", "version": "v1"}]}]}`)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer server.Close()
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	p := &restProjects{client: client}
+	available, err := p.Available()
+	if err != nil || !available {
+		t.Fatalf("expected the project API to be available, got [%v] [%v]", available, err)
+	}
+	requests, bodies = nil, nil
+	if err := p.Create("apb-foo"); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if err := p.Label("apb-foo", map[string]string{"team": "broker"}, nil); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected := []string{
+		"POST /apis/project.openshift.io/v1/projectrequests",
+		"PATCH /apis/project.openshift.io/v1/projects/apb-foo",
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("expected requests %v, got %v", expected, requests)
+	}
+	if !strings.Contains(bodies[0], `"kind":"ProjectRequest"`) || !strings.Contains(bodies[0], `"name":"apb-foo"`) {
+		t.Fatalf("unexpected project request [%v]", bodies[0])
+	}
+	if !strings.Contains(bodies[1], `"labels":{"team":"broker"}`) {
+		t.Fatalf("unexpected project patch [%v]", bodies[1])
+	}
+}
@@ -0,0 +1,144 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// multilineSentinel ends multi-line input when entered on a line of its own
+const multilineSentinel = "EOF"
+
+// confirmMetadataKey is the plan metadata key listing the parameters whose
+// values must be entered twice when prompted for, e.g.
+//
+//	confirm:
+//	- admin_password
+const confirmMetadataKey = "confirm"
+
+// promptReader is where prompted values are read from
+var promptReader io.Reader = os.Stdin
+
+// readPassword reads a value without echoing it
+var readPassword = func() (string, error) {
+	input, err := terminal.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	return string(input), err
+}
+
+// confirmedParameters returns the names of the plan's parameters which must
+// be confirmed
+func confirmedParameters(plan bundle.Plan) (map[string]bool, error) {
+	declared, ok := plan.Metadata[confirmMetadataKey]
+	if !ok {
+		return nil, nil
+	}
+	names, ok := declared.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid %v metadata of plan [%v]: expected a list of parameter names, got [%v]", confirmMetadataKey, plan.Name, declared)
+	}
+	confirmed := map[string]bool{}
+	for _, name := range names {
+		confirmed[fmt.Sprint(name)] = true
+	}
+	return confirmed, nil
+}
+
+// readInput reads the parameter's value as its display type asks for:
+// several lines for textareas, without echo for passwords or else a line
+func readInput(param bundle.ParameterDescriptor, r io.Reader) (string, error) {
+	if isMultiline(param) {
+		fmt.Printf("\n(enter %v on its own line or press Ctrl-D to finish)\n", multilineSentinel)
+		return readMultiline(r)
+	}
+	if param.DisplayType == "password" {
+		return readPassword()
+	}
+	var input string
+	fmt.Fscanln(r, &input)
+	return input, nil
+}
+
+// promptInput reads the parameter's value, and when confirm is set reads it
+// again. matched is false when the two values differ.
+func promptInput(param bundle.ParameterDescriptor, confirm bool, r io.Reader) (input string, matched bool, err error) {
+	input, err = readInput(param, r)
+	if err != nil || !confirm {
+		return input, err == nil, err
+	}
+	fmt.Printf("Confirm value for parameter [%v]: ", param.Name)
+	again, err := readInput(param, r)
+	if err != nil {
+		return "", false, err
+	}
+	return input, input == again, nil
+}
+
+// isMultiline reports whether the parameter takes multi-line input, which
+// the schema marks with the textarea display type
+func isMultiline(param bundle.ParameterDescriptor) bool {
+	return param.DisplayType == "textarea"
+}
+
+// readMultiline reads lines until the sentinel line or the end of input and
+// returns them joined by newlines
+func readMultiline(r io.Reader) (string, error) {
+	var lines []string
+	for {
+		line, err := readLine(r)
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		if strings.TrimSpace(line) == multilineSentinel {
+			break
+		}
+		if err == io.EOF {
+			if line != "" {
+				lines = append(lines, line)
+			}
+			break
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// readLine reads a single line a byte at a time, so nothing past the line is
+// consumed from the reader that later prompts still read from
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				return strings.TrimSuffix(string(line), "\r"), nil
+			}
+			line = append(line, b[0])
+		}
+		if err != nil {
+			return strings.TrimSuffix(string(line), "\r"), err
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package runner
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+func TestReadMultiline(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     string
+		expected  string
+		remaining string
+	}{
+		{
+			name:      "test input ended by the sentinel",
+			input:     "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\nEOF\nnext\n",
+			expected:  "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----",
+			remaining: "next\n",
+		},
+		{
+			name:     "test input ended by end of input",
+			input:    "line one\nline two",
+			expected: "line one\nline two",
+		},
+		{
+			name:     "test windows line endings",
+			input:    "line one\r\nline two\r\nEOF\r\n",
+			expected: "line one\nline two",
+		},
+		{
+			name:     "test empty input",
+			input:    "EOF\n",
+			expected: "",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := strings.NewReader(tc.input)
+			value, err := readMultiline(r)
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if value != tc.expected {
+				t.Fatalf("expected [%q], got [%q]", tc.expected, value)
+			}
+			if r.Len() != len(tc.remaining) {
+				t.Fatalf("expected [%q] left unread, [%d] bytes remain", tc.remaining, r.Len())
+			}
+		})
+	}
+}
+
+func TestPromptInputConfirm(t *testing.T) {
+	savedPassword := readPassword
+	defer func() { readPassword = savedPassword }()
+	testCases := []struct {
+		name      string
+		param     bundle.ParameterDescriptor
+		confirm   bool
+		input     string
+		passwords []string
+		expected  string
+		matched   bool
+	}{
+		{
+			name:     "test unconfirmed value read once",
+			param:    bundle.ParameterDescriptor{Name: "user"},
+			input:    "admin\nnext\n",
+			expected: "admin",
+			matched:  true,
+		},
+		{
+			name:     "test matching values",
+			param:    bundle.ParameterDescriptor{Name: "user"},
+			confirm:  true,
+			input:    "admin\nadmin\n",
+			expected: "admin",
+			matched:  true,
+		},
+		{
+			name:     "test mismatched values",
+			param:    bundle.ParameterDescriptor{Name: "user"},
+			confirm:  true,
+			input:    "admin\nadmn\n",
+			expected: "admin",
+		},
+		{
+			name:      "test matching passwords",
+			param:     bundle.ParameterDescriptor{Name: "admin_password", DisplayType: "password"},
+			confirm:   true,
+			passwords: []string{"s3cret", "s3cret"},
+			expected:  "s3cret",
+			matched:   true,
+		},
+		{
+			name:      "test mismatched passwords",
+			param:     bundle.ParameterDescriptor{Name: "admin_password", DisplayType: "password"},
+			confirm:   true,
+			passwords: []string{"s3cret", "secret"},
+			expected:  "s3cret",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			passwords := tc.passwords
+			readPassword = func() (string, error) {
+				if len(passwords) == 0 {
+					return "", io.EOF
+				}
+				next := passwords[0]
+				passwords = passwords[1:]
+				return next, nil
+			}
+			input, matched, err := promptInput(tc.param, tc.confirm, strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if matched != tc.matched || (matched && input != tc.expected) {
+				t.Fatalf("expected [%v] matched [%v], got [%v] matched [%v]", tc.expected, tc.matched, input, matched)
+			}
+			if len(passwords) != 0 {
+				t.Fatalf("expected every password to be read, %v left", passwords)
+			}
+		})
+	}
+}
+
+func TestSelectParametersConfirm(t *testing.T) {
+	savedReader := promptReader
+	defer func() { promptReader = savedReader }()
+	// the first pair doesn't match, so the value is prompted for again
+	promptReader = strings.NewReader("hunter2\nhunter3\nhunter2\nhunter2\n")
+	plan := bundle.Plan{
+		Name:       "default",
+		Metadata:   map[string]interface{}{confirmMetadataKey: []interface{}{"admin_key"}},
+		Parameters: []bundle.ParameterDescriptor{{Name: "admin_key", Type: "string", Required: true}},
+	}
+	params, err := selectParameters(plan, nil, nil, nil, nil, nil, nil, false)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if params["admin_key"] != "hunter2" {
+		t.Fatalf("expected the confirmed value [hunter2], got [%v]", params["admin_key"])
+	}
+}
+
+func TestConfirmedParameters(t *testing.T) {
+	plan := bundle.Plan{Name: "default", Metadata: map[string]interface{}{confirmMetadataKey: []interface{}{"admin_password"}}}
+	confirmed, err := confirmedParameters(plan)
+	if err != nil || !confirmed["admin_password"] || len(confirmed) != 1 {
+		t.Fatalf("expected [admin_password] to be confirmed, got [%v] [%v]", confirmed, err)
+	}
+	plan.Metadata[confirmMetadataKey] = "admin_password"
+	if _, err := confirmedParameters(plan); err == nil {
+		t.Fatalf("expected error for metadata which is not a list")
+	}
+}
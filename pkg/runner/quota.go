@@ -0,0 +1,166 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/automationbroker/apb/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// quotaCheck compares what a pod would use of a quota'd resource with what
+// is left of the quota
+type quotaCheck struct {
+	Quota     string
+	Resource  v1.ResourceName
+	Requested resource.Quantity
+	Remaining resource.Quantity
+}
+
+// Fits is true when the pod's usage is within what is left of the quota
+func (q quotaCheck) Fits() bool {
+	return q.Requested.Cmp(q.Remaining) <= 0
+}
+
+// podQuotaUsage returns how much of each quota'd resource a pod counts
+// against. Containers without a request are charged their limit, as the
+// API server defaults the request to it.
+func podQuotaUsage(pod *v1.Pod) v1.ResourceList {
+	usage := v1.ResourceList{v1.ResourcePods: resource.MustParse("1")}
+	add := func(name v1.ResourceName, q resource.Quantity) {
+		total := usage[name]
+		total.Add(q)
+		usage[name] = total
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory, v1.ResourceEphemeralStorage} {
+			request, ok := c.Resources.Requests[name]
+			if !ok {
+				request, ok = c.Resources.Limits[name]
+			}
+			if ok {
+				add(name, request)
+				add(v1.ResourceName("requests."+string(name)), request)
+			}
+			if limit, ok := c.Resources.Limits[name]; ok {
+				add(v1.ResourceName("limits."+string(name)), limit)
+			}
+		}
+	}
+	return usage
+}
+
+// hasResources is true when a container of the pod requests or limits
+// resources
+func hasResources(pod *v1.Pod) bool {
+	for _, c := range pod.Spec.Containers {
+		if len(c.Resources.Requests) > 0 || len(c.Resources.Limits) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkQuotas compares the pod's usage with what is left of each quota in
+// its namespace. Scoped quotas are skipped since whether they apply
+// depends on more than the pod's resources.
+func checkQuotas(quotas corev1.ResourceQuotaInterface, pod *v1.Pod) ([]quotaCheck, error) {
+	list, err := quotas.List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource quotas: %v", err)
+	}
+	usage := podQuotaUsage(pod)
+	var checks []quotaCheck
+	for _, quota := range list.Items {
+		if len(quota.Spec.Scopes) > 0 {
+			log.Debugf("Skipping scoped resource quota [%v]", quota.Name)
+			continue
+		}
+		var names []string
+		for name := range quota.Status.Hard {
+			names = append(names, string(name))
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			name := v1.ResourceName(n)
+			requested, ok := usage[name]
+			if !ok {
+				continue
+			}
+			remaining := quota.Status.Hard[name]
+			remaining.Sub(quota.Status.Used[name])
+			checks = append(checks, quotaCheck{
+				Quota:     quota.Name,
+				Resource:  name,
+				Requested: requested,
+				Remaining: remaining,
+			})
+		}
+	}
+	return checks, nil
+}
+
+// reportQuota writes to stderr whether the pod fits the quotas of its
+// namespace, keeping stdout for the dry run manifest
+func reportQuota(pod *v1.Pod, ns string, generated bool) (bool, error) {
+	if generated {
+		fmt.Fprintln(os.Stderr, "Quota: skipped, the generated namespace has no resource quotas yet")
+		return true, nil
+	}
+	if !hasResources(pod) {
+		log.Warning("The APB pod requests no resources, so only its pod count is checked against quotas. Set them with --requests and --limits")
+	}
+	k8scli, err := util.KubernetesClient()
+	if err != nil {
+		return false, err
+	}
+	checks, err := checkQuotas(k8scli.Client.CoreV1().ResourceQuotas(ns), pod)
+	if err != nil {
+		return false, err
+	}
+	return writeQuotaReport(checks, os.Stderr), nil
+}
+
+// writeQuotaReport writes whether the pod fits each quota and the headroom
+// it would leave, returning false if it does not fit one
+func writeQuotaReport(checks []quotaCheck, w io.Writer) bool {
+	if len(checks) == 0 {
+		fmt.Fprintln(w, "Quota: no resource quotas limit the APB pod")
+		return true
+	}
+	fits := true
+	for _, check := range checks {
+		headroom := check.Remaining.DeepCopy()
+		headroom.Sub(check.Requested)
+		status := "PASS"
+		if !check.Fits() {
+			status = "FAIL"
+			fits = false
+		}
+		fmt.Fprintf(w, "Quota: %v %v/%v: requested %v of %v remaining, headroom %v\n",
+			status, check.Quota, check.Resource, check.Requested.String(), check.Remaining.String(), headroom.String())
+	}
+	return fits
+}
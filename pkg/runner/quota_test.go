@@ -0,0 +1,129 @@
+package runner
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func resourceList(pairs ...string) v1.ResourceList {
+	list, err := parseResourceList(pairs)
+	if err != nil {
+		panic(err)
+	}
+	return list
+}
+
+func TestPodQuotaUsage(t *testing.T) {
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{
+		Resources: v1.ResourceRequirements{
+			Requests: resourceList("cpu=100m", "ephemeral-storage=1Gi"),
+			Limits:   resourceList("cpu=500m", "memory=512Mi", "ephemeral-storage=4Gi"),
+		},
+	}}}}
+	usage := podQuotaUsage(pod)
+	expected := map[string]string{
+		"pods":                       "1",
+		"cpu":                        "100m",
+		"requests.cpu":               "100m",
+		"limits.cpu":                 "500m",
+		"memory":                     "512Mi",
+		"requests.memory":            "512Mi",
+		"limits.memory":              "512Mi",
+		"ephemeral-storage":          "1Gi",
+		"requests.ephemeral-storage": "1Gi",
+		"limits.ephemeral-storage":   "4Gi",
+	}
+	if len(usage) != len(expected) {
+		t.Fatalf("expected usage %v, got %v", expected, usage)
+	}
+	for name, value := range expected {
+		if q := usage[v1.ResourceName(name)]; q.Cmp(resource.MustParse(value)) != 0 {
+			t.Fatalf("expected [%v] usage [%v], got [%v]", name, value, q.String())
+		}
+	}
+}
+
+func TestCheckQuotas(t *testing.T) {
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{
+		Resources: v1.ResourceRequirements{
+			Requests: resourceList("cpu=500m", "memory=1Gi"),
+			Limits:   resourceList("memory=1Gi"),
+		},
+	}}}}
+	quotas := &fakeQuotas{quotas: []v1.ResourceQuota{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "compute"},
+			Status: v1.ResourceQuotaStatus{
+				Hard: resourceList("requests.cpu=2", "requests.memory=4Gi", "secrets=10"),
+				Used: resourceList("requests.cpu=1", "requests.memory=3500Mi", "secrets=2"),
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "best-effort"},
+			Spec:       v1.ResourceQuotaSpec{Scopes: []v1.ResourceQuotaScope{v1.ResourceQuotaScopeBestEffort}},
+			Status: v1.ResourceQuotaStatus{
+				Hard: resourceList("pods=0"),
+			},
+		},
+	}}
+	checks, err := checkQuotas(quotas, pod)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if len(checks) != 2 {
+		t.Fatalf("expected checks of requests.cpu and requests.memory, got %v", checks)
+	}
+	if !checks[0].Fits() || checks[0].Resource != v1.ResourceRequestsCPU {
+		t.Fatalf("expected requests.cpu to fit, got %+v", checks[0])
+	}
+	if checks[1].Fits() || checks[1].Resource != v1.ResourceRequestsMemory {
+		t.Fatalf("expected requests.memory not to fit, got %+v", checks[1])
+	}
+
+	var report bytes.Buffer
+	if writeQuotaReport(checks, &report) {
+		t.Fatalf("expected report to fail, got:\n%v", report.String())
+	}
+	for _, e := range []string{
+		"Quota: PASS compute/requests.cpu: requested 500m of 1 remaining, headroom 500m",
+		"Quota: FAIL compute/requests.memory: requested 1Gi of 596Mi remaining, headroom -428Mi",
+	} {
+		if !strings.Contains(report.String(), e) {
+			t.Fatalf("expected report to contain [%v], got:\n%v", e, report.String())
+		}
+	}
+}
+
+func TestCheckQuotasEphemeralStorage(t *testing.T) {
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{
+		Resources: v1.ResourceRequirements{
+			Requests: resourceList("ephemeral-storage=2Gi"),
+			Limits:   resourceList("ephemeral-storage=8Gi"),
+		},
+	}}}}
+	quotas := &fakeQuotas{quotas: []v1.ResourceQuota{{
+		ObjectMeta: metav1.ObjectMeta{Name: "storage"},
+		Status: v1.ResourceQuotaStatus{
+			Hard: resourceList("requests.ephemeral-storage=10Gi", "limits.ephemeral-storage=20Gi"),
+			Used: resourceList("requests.ephemeral-storage=4Gi", "limits.ephemeral-storage=16Gi"),
+		},
+	}}}
+	checks, err := checkQuotas(quotas, pod)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if len(checks) != 2 {
+		t.Fatalf("expected checks of both ephemeral-storage quotas, got %v", checks)
+	}
+	if checks[0].Fits() || checks[0].Resource != v1.ResourceLimitsEphemeralStorage {
+		t.Fatalf("expected limits.ephemeral-storage not to fit, got %+v", checks[0])
+	}
+	if !checks[1].Fits() || checks[1].Resource != v1.ResourceRequestsEphemeralStorage {
+		t.Fatalf("expected requests.ephemeral-storage to fit, got %+v", checks[1])
+	}
+}
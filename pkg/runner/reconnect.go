@@ -0,0 +1,85 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// DefaultReconnectTimeout is how long waiting for the bundle pod rides out
+// failures to reach the API server unless told otherwise
+const DefaultReconnectTimeout = time.Minute
+
+// reconnectingPods polls for pods through transient failures to reach the
+// API server, such as while it restarts during a rollout, until they have
+// gone on for the timeout. The wait polls rather than watches, so the pod
+// is simply got again once the API server answers.
+type reconnectingPods struct {
+	corev1.PodInterface
+	timeout time.Duration
+	stop    <-chan struct{}
+	// failingSince is when the current run of failures began
+	failingSince time.Time
+}
+
+func (p *reconnectingPods) Get(name string, options metav1.GetOptions) (*v1.Pod, error) {
+	for {
+		pod, err := p.PodInterface.Get(name, options)
+		if err == nil && !p.failingSince.IsZero() {
+			log.Infof("Reconnected to the API server after %v", time.Since(p.failingSince).Round(time.Second))
+			p.failingSince = time.Time{}
+		}
+		if err == nil || p.timeout <= 0 || !transientError(err) {
+			return pod, err
+		}
+		if p.failingSince.IsZero() {
+			p.failingSince = time.Now()
+			log.Warningf("Lost the API server while waiting for pod [%v], reconnecting for up to %v: %v", name, p.timeout, err)
+		}
+		if time.Since(p.failingSince) > p.timeout {
+			return nil, fmt.Errorf("failed to reconnect to the API server within %v: %v", p.timeout, err)
+		}
+		select {
+		case <-p.stop:
+			return nil, errInterrupted
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// transientError is whether the error is one the API server gives, or the
+// connection to it fails with, while it is unavailable, rather than an
+// answer about the pod
+func transientError(err error) bool {
+	if _, ok := err.(k8serrors.APIStatus); !ok {
+		// the API server could not be reached
+		return true
+	}
+	return k8serrors.IsServerTimeout(err) ||
+		k8serrors.IsTimeout(err) ||
+		k8serrors.IsTooManyRequests(err) ||
+		k8serrors.IsInternalError(err) ||
+		k8serrors.IsServiceUnavailable(err) ||
+		k8serrors.IsUnexpectedServerError(err)
+}
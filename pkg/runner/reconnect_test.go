@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// droppingPods fails the first drops gets with err, as a dropped
+// connection to the API server would
+type droppingPods struct {
+	*fakePods
+	drops int
+	err   error
+}
+
+func (d *droppingPods) Get(name string, options metav1.GetOptions) (*v1.Pod, error) {
+	if d.drops > 0 {
+		d.drops--
+		return nil, d.err
+	}
+	return d.fakePods.Get(name, options)
+}
+
+func TestReconnectingPods(t *testing.T) {
+	savedPollInterval := pollInterval
+	defer func() { pollInterval = savedPollInterval }()
+	pollInterval = time.Millisecond
+	refused := errors.New("dial tcp 10.0.0.1:6443: connect: connection refused")
+	testCases := []struct {
+		name       string
+		drops      int
+		err        error
+		timeout    time.Duration
+		shouldFail bool
+	}{
+		{
+			name:    "test reconnect after dropped connections",
+			drops:   3,
+			err:     refused,
+			timeout: time.Minute,
+		},
+		{
+			name:       "test reconnecting for longer than the timeout",
+			drops:      1000,
+			err:        refused,
+			timeout:    20 * time.Millisecond,
+			shouldFail: true,
+		},
+		{
+			name:       "test zero timeout",
+			drops:      1,
+			err:        refused,
+			shouldFail: true,
+		},
+		{
+			name:       "test pod which is gone",
+			drops:      1,
+			err:        k8serrors.NewNotFound(schema.GroupResource{Resource: "pods"}, "bundle-1234"),
+			timeout:    time.Minute,
+			shouldFail: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "bundle-1234"},
+				Status:     v1.PodStatus{Phase: v1.PodSucceeded},
+			}
+			pods := &reconnectingPods{
+				PodInterface: &droppingPods{fakePods: newFakePods(pod), drops: tc.drops, err: tc.err},
+				timeout:      tc.timeout,
+			}
+			phase, err := waitForPodCompletion(pods, "bundle-1234", 0, nil, nil)
+			if tc.shouldFail {
+				if err == nil {
+					t.Fatalf("expected error, got phase [%v]", phase)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if phase != v1.PodSucceeded || !pods.failingSince.IsZero() {
+				t.Fatalf("expected the wait to reconnect and see the pod succeed, got phase [%v]", phase)
+			}
+		})
+	}
+}
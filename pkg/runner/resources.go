@@ -0,0 +1,144 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// resourcesMetadataKey is the spec metadata key holding the requests and
+// limits a bundle recommends for its pod, e.g.
+//
+//	resources:
+//	  requests: {cpu: 100m, memory: 256Mi}
+//	  limits: {memory: 512Mi}
+const resourcesMetadataKey = "resources"
+
+// recommendedResources reads the resources recommended in spec metadata
+func recommendedResources(metadata map[string]interface{}) (v1.ResourceRequirements, error) {
+	var requirements v1.ResourceRequirements
+	declared, ok := metadata[resourcesMetadataKey]
+	if !ok {
+		return requirements, nil
+	}
+	resources, err := stringKeyed(declared)
+	if err != nil {
+		return requirements, fmt.Errorf("invalid %v metadata: %v", resourcesMetadataKey, err)
+	}
+	for kind, list := range resources {
+		values, err := stringKeyed(list)
+		if err != nil {
+			return requirements, fmt.Errorf("invalid %v %v: %v", resourcesMetadataKey, kind, err)
+		}
+		var pairs []string
+		for name, value := range values {
+			pairs = append(pairs, fmt.Sprintf("%v=%v", name, value))
+		}
+		parsed, err := parseResourceList(pairs)
+		if err != nil {
+			return requirements, err
+		}
+		switch kind {
+		case "requests":
+			requirements.Requests = parsed
+		case "limits":
+			requirements.Limits = parsed
+		default:
+			return requirements, fmt.Errorf("unknown %v [%v]. Expected requests or limits", resourcesMetadataKey, kind)
+		}
+	}
+	return requirements, nil
+}
+
+// stringKeyed returns metadata decoded from JSON or YAML as a map with
+// string keys
+func stringKeyed(v interface{}) (map[string]interface{}, error) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, nil
+	case map[interface{}]interface{}:
+		converted := map[string]interface{}{}
+		for k, value := range m {
+			converted[fmt.Sprint(k)] = value
+		}
+		return converted, nil
+	}
+	return nil, fmt.Errorf("expected a map, got [%v]", v)
+}
+
+// parseResourceList parses resource quantities given as name=quantity
+func parseResourceList(pairs []string) (v1.ResourceList, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	list := v1.ResourceList{}
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid resource [%v]. Expected name=quantity", pair)
+		}
+		quantity, err := resource.ParseQuantity(fmt.Sprint(kv[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity [%v] for resource [%v]: %v", kv[1], kv[0], err)
+		}
+		list[v1.ResourceName(kv[0])] = quantity
+	}
+	return list, nil
+}
+
+// setQuantity parses the quantity of the resource into the list, which is
+// created when nil
+func setQuantity(list v1.ResourceList, name v1.ResourceName, value string) (v1.ResourceList, error) {
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return list, fmt.Errorf("invalid quantity [%v] for resource [%v]: %v", value, name, err)
+	}
+	if quantity.Sign() < 0 {
+		return list, fmt.Errorf("invalid quantity [%v] for resource [%v]: must not be negative", value, name)
+	}
+	if list == nil {
+		list = v1.ResourceList{}
+	}
+	list[name] = quantity
+	return list, nil
+}
+
+// mergeResources returns the recommended resources with those the user set
+// taking their place
+func mergeResources(recommended v1.ResourceRequirements, user v1.ResourceRequirements) v1.ResourceRequirements {
+	merge := func(base v1.ResourceList, overrides v1.ResourceList) v1.ResourceList {
+		if len(base) == 0 && len(overrides) == 0 {
+			return nil
+		}
+		merged := v1.ResourceList{}
+		for name, quantity := range base {
+			merged[name] = quantity
+		}
+		for name, quantity := range overrides {
+			merged[name] = quantity
+		}
+		return merged
+	}
+	return v1.ResourceRequirements{
+		Requests: merge(recommended.Requests, user.Requests),
+		Limits:   merge(recommended.Limits, user.Limits),
+	}
+}
@@ -0,0 +1,178 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"k8s.io/api/core/v1"
+)
+
+func TestRecommendedResources(t *testing.T) {
+	testCases := []struct {
+		name      string
+		metadata  map[string]interface{}
+		requests  map[v1.ResourceName]string
+		limits    map[v1.ResourceName]string
+		shouldErr bool
+	}{
+		{
+			name:     "test no recommendation",
+			metadata: map[string]interface{}{"displayName": "hello"},
+		},
+		{
+			name: "test recommendation decoded from JSON",
+			metadata: map[string]interface{}{
+				"resources": map[string]interface{}{
+					"requests": map[string]interface{}{"cpu": "100m", "memory": "256Mi"},
+					"limits":   map[string]interface{}{"memory": "512Mi"},
+				},
+			},
+			requests: map[v1.ResourceName]string{v1.ResourceCPU: "100m", v1.ResourceMemory: "256Mi"},
+			limits:   map[v1.ResourceName]string{v1.ResourceMemory: "512Mi"},
+		},
+		{
+			name: "test recommendation decoded from YAML",
+			metadata: map[string]interface{}{
+				"resources": map[interface{}]interface{}{
+					"requests": map[interface{}]interface{}{"cpu": 1},
+				},
+			},
+			requests: map[v1.ResourceName]string{v1.ResourceCPU: "1"},
+		},
+		{
+			name: "test invalid quantity",
+			metadata: map[string]interface{}{
+				"resources": map[string]interface{}{
+					"requests": map[string]interface{}{"cpu": "lots"},
+				},
+			},
+			shouldErr: true,
+		},
+		{
+			name: "test unknown kind",
+			metadata: map[string]interface{}{
+				"resources": map[string]interface{}{
+					"minimums": map[string]interface{}{"cpu": "1"},
+				},
+			},
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resources, err := recommendedResources(tc.metadata)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("expected error but got [%v]", resources)
+			}
+			checkResourceList(t, "requests", resources.Requests, tc.requests)
+			checkResourceList(t, "limits", resources.Limits, tc.limits)
+		})
+	}
+}
+
+func TestResourceOptions(t *testing.T) {
+	spec := &bundle.Spec{
+		FQName: "hello-apb",
+		Metadata: map[string]interface{}{
+			"resources": map[string]interface{}{
+				"requests": map[string]interface{}{"cpu": "100m", "memory": "256Mi"},
+				"limits":   map[string]interface{}{"memory": "512Mi"},
+			},
+		},
+	}
+	o, err := newOptions([]Option{
+		WithRecommendedResources(spec),
+		WithResources([]string{"memory=1Gi"}, nil),
+	})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "apb"}}}}
+	applyPodOptions(pod, o)
+	resources := pod.Spec.Containers[0].Resources
+	checkResourceList(t, "requests", resources.Requests, map[v1.ResourceName]string{v1.ResourceCPU: "100m", v1.ResourceMemory: "1Gi"})
+	checkResourceList(t, "limits", resources.Limits, map[v1.ResourceName]string{v1.ResourceMemory: "512Mi"})
+
+	spec.Metadata["resources"] = "plenty"
+	if _, err := newOptions([]Option{WithRecommendedResources(spec)}); err != nil {
+		t.Fatalf("expected invalid recommendation to be ignored, got [%v]", err)
+	}
+	if _, err := newOptions([]Option{WithResources([]string{"cpu"}, nil)}); err == nil {
+		t.Fatalf("expected error for a resource without a quantity")
+	}
+}
+
+func checkResourceList(t *testing.T, kind string, got v1.ResourceList, expected map[v1.ResourceName]string) {
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v [%v], got [%v]", kind, expected, got)
+	}
+	for name, value := range expected {
+		quantity, ok := got[name]
+		if !ok || quantity.String() != value {
+			t.Fatalf("expected %v [%v], got [%v]", kind, expected, got)
+		}
+	}
+}
+
+func TestEphemeralStorageOption(t *testing.T) {
+	testCases := []struct {
+		name     string
+		request  string
+		limit    string
+		requests map[v1.ResourceName]string
+		limits   map[v1.ResourceName]string
+		err      bool
+	}{
+		{
+			name:     "request and limit",
+			request:  "1Gi",
+			limit:    "4Gi",
+			requests: map[v1.ResourceName]string{v1.ResourceMemory: "1Gi", v1.ResourceEphemeralStorage: "1Gi"},
+			limits:   map[v1.ResourceName]string{v1.ResourceEphemeralStorage: "4Gi"},
+		},
+		{
+			name:     "limit only",
+			limit:    "500M",
+			requests: map[v1.ResourceName]string{v1.ResourceMemory: "1Gi"},
+			limits:   map[v1.ResourceName]string{v1.ResourceEphemeralStorage: "500M"},
+		},
+		{
+			name:    "invalid quantity",
+			request: "lots",
+			err:     true,
+		},
+		{
+			name:  "negative quantity",
+			limit: "-1Gi",
+			err:   true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := newOptions([]Option{
+				WithResources([]string{"memory=1Gi"}, nil),
+				WithEphemeralStorage(tc.request, tc.limit),
+			})
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error for request [%v] and limit [%v]", tc.request, tc.limit)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "apb"}}}}
+			applyPodOptions(pod, o)
+			resources := pod.Spec.Containers[0].Resources
+			checkResourceList(t, "requests", resources.Requests, tc.requests)
+			checkResourceList(t, "limits", resources.Limits, tc.limits)
+		})
+	}
+}
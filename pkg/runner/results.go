@@ -0,0 +1,195 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// A bundle reports its results by writing them as JSON to resultsFile, named
+// to it by the resultsEnvVar env var, in an emptyDir shared with a sidecar.
+// The sidecar keeps the pod, and so the file, around until the results are
+// copied out of it, when asked for, and collectedMarker is written. So that
+// the pod completes even when no one collects the results, the sidecar also
+// exits resultsGracePeriod after the results file appears, and once the
+// longest wait for the pod has passed.
+const (
+	resultsVolume       = "apb-results"
+	resultsContainer    = "apb-results"
+	resultsDir          = "/var/run/apb-results"
+	resultsFile         = resultsDir + "/results.json"
+	resultsEnvVar       = "APB_RESULTS_FILE"
+	collectedMarker     = resultsDir + "/.collected"
+	resultsSidecarImage = "busybox"
+	resultsGracePeriod  = 5 * time.Minute
+)
+
+// addResultsSidecar mounts the results volume into the bundle container and
+// adds the sidecar holding it. A lifetime of zero leaves the sidecar running
+// until the results are collected or resultsGracePeriod after they appear.
+func addResultsSidecar(pod *v1.Pod, lifetime time.Duration) {
+	mount := v1.VolumeMount{Name: resultsVolume, MountPath: resultsDir}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{
+		Name:         resultsVolume,
+		VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+	})
+	for i := range pod.Spec.Containers {
+		pod.Spec.Containers[i].VolumeMounts = append(pod.Spec.Containers[i].VolumeMounts, mount)
+		pod.Spec.Containers[i].Env = append(pod.Spec.Containers[i].Env, v1.EnvVar{Name: resultsEnvVar, Value: resultsFile})
+	}
+	pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{
+		Name:         resultsContainer,
+		Image:        resultsSidecarImage,
+		Command:      []string{"sh", "-c", resultsSidecarScript(lifetime)},
+		VolumeMounts: []v1.VolumeMount{mount},
+	})
+}
+
+// resultsSidecarScript waits for the results to be collected, for at most
+// resultsGracePeriod after the results file appears and, unless it is zero,
+// for the lifetime
+func resultsSidecarScript(lifetime time.Duration) string {
+	expired := fmt.Sprintf("[ $written -ge %d ]", int64(resultsGracePeriod/time.Second))
+	if lifetime > 0 {
+		expired += fmt.Sprintf(" || [ $waited -ge %d ]", int64((lifetime+time.Second-1)/time.Second))
+	}
+	return fmt.Sprintf("waited=0; written=0; until [ -e %v ]; do sleep 1; waited=$((waited+1)); if [ -e %v ]; then written=$((written+1)); fi; if %v; then exit 0; fi; done",
+		collectedMarker, resultsFile, expired)
+}
+
+// hasResultsSidecar is whether the pod has the sidecar, which only exits
+// once told to
+func hasResultsSidecar(pod *v1.Pod) bool {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == resultsContainer {
+			return true
+		}
+	}
+	return false
+}
+
+// podExecutor runs a command in a container of a pod and returns its output
+type podExecutor interface {
+	exec(podName string, container string, command []string) ([]byte, error)
+}
+
+// restExecutor runs commands through the exec subresource of the pods in a
+// namespace
+type restExecutor struct {
+	client    *clients.KubernetesClient
+	namespace string
+}
+
+func (e restExecutor) exec(podName string, container string, command []string) ([]byte, error) {
+	req := e.client.Client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(e.namespace).
+		Name(podName).
+		SubResource("exec").
+		VersionedParams(&v1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(e.client.ClientConfig, "POST", req.URL())
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	if err := executor.Stream(remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr}); err != nil {
+		return nil, fmt.Errorf("%v %v", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// resultsCollector copies the results of the bundle out of the pod once the
+// bundle container has terminated, then lets the sidecar exit so the pod
+// completes
+type resultsCollector struct {
+	executor podExecutor
+	path     string
+	// released are the pods whose sidecar has been told to exit. A retry
+	// creates the pod again under a new UID.
+	released map[types.UID]bool
+}
+
+func newResultsCollector(executor podExecutor, path string) *resultsCollector {
+	return &resultsCollector{executor: executor, path: path, released: map[types.UID]bool{}}
+}
+
+// check collects the results, once, on the first poll of a wait to see the
+// bundle container terminated. Only a bundle which succeeded has its results
+// written to the path, and none are when it is empty.
+func (c *resultsCollector) check(pod *v1.Pod) error {
+	if c.released[pod.UID] || len(pod.Spec.Containers) == 0 {
+		return nil
+	}
+	var terminated *v1.ContainerStateTerminated
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == pod.Spec.Containers[0].Name {
+			terminated = status.State.Terminated
+		}
+	}
+	if terminated == nil {
+		return nil
+	}
+	c.released[pod.UID] = true
+	if terminated.ExitCode == 0 && c.path != "" {
+		if err := c.collect(pod.Name); err != nil {
+			log.Warningf("Failed to collect the results of pod [%v]: %v", pod.Name, err)
+		}
+	}
+	if _, err := c.executor.exec(pod.Name, resultsContainer, []string{"touch", collectedMarker}); err != nil {
+		return fmt.Errorf("failed to stop container [%v] of pod [%v]: %v", resultsContainer, pod.Name, err)
+	}
+	return nil
+}
+
+// collect writes the results of the pod to the path. Nothing is written
+// when the bundle wrote no results.
+func (c *resultsCollector) collect(podName string) error {
+	out, err := c.executor.exec(podName, resultsContainer, []string{"sh", "-c", fmt.Sprintf("[ ! -e %[1]v ] || cat %[1]v", resultsFile)})
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		fmt.Printf("APB wrote no results to [%v]\n", resultsFile)
+		return nil
+	}
+	var results interface{}
+	if err := json.Unmarshal(out, &results); err != nil {
+		return fmt.Errorf("results are not JSON: %v", err)
+	}
+	if err := ioutil.WriteFile(c.path, out, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote results to [%v]\n", c.path)
+	return nil
+}
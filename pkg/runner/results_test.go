@@ -0,0 +1,176 @@
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+// fakeExecutor returns the output set for the first word of each command
+// and records the commands run
+type fakeExecutor struct {
+	outputs map[string]string
+	run     []string
+}
+
+func (f *fakeExecutor) exec(podName string, container string, command []string) ([]byte, error) {
+	if container != resultsContainer {
+		return nil, fmt.Errorf("unexpected container [%v]", container)
+	}
+	f.run = append(f.run, command[0])
+	return []byte(f.outputs[command[0]]), nil
+}
+
+func TestResultsSidecar(t *testing.T) {
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "bundle-1234"}}}}
+	if hasResultsSidecar(pod) {
+		t.Fatalf("expected no results sidecar")
+	}
+	applyPodOptions(pod, &options{resultsOut: "results.json"})
+	if !hasResultsSidecar(pod) || len(pod.Spec.Containers) != 2 || len(pod.Spec.Volumes) != 1 {
+		t.Fatalf("expected the results sidecar and volume, got %+v", pod.Spec)
+	}
+	bundle := pod.Spec.Containers[0]
+	if len(bundle.VolumeMounts) != 1 || bundle.VolumeMounts[0].MountPath != resultsDir {
+		t.Fatalf("expected the results volume mounted at [%v], got %v", resultsDir, bundle.VolumeMounts)
+	}
+	if len(bundle.Env) != 1 || bundle.Env[0].Name != resultsEnvVar || bundle.Env[0].Value != resultsFile {
+		t.Fatalf("expected [%v] in the env, got %v", resultsEnvVar, bundle.Env)
+	}
+}
+
+func TestResultsSidecarBound(t *testing.T) {
+	grace := fmt.Sprintf("[ $written -ge %d ]", int64(resultsGracePeriod/time.Second))
+	testCases := []struct {
+		name     string
+		o        *options
+		lifetime string
+	}{
+		{
+			name: "test unbounded wait",
+			o:    &options{resultsOut: "results.json", wait: true},
+		},
+		{
+			name:     "test wait timeout",
+			o:        &options{resultsOut: "results.json", wait: true, waitTimeout: 10 * time.Minute},
+			lifetime: "[ $waited -ge 900 ]",
+		},
+		{
+			name: "test longer action timeout",
+			o: &options{resultsOut: "results.json", wait: true, waitTimeout: 10 * time.Minute,
+				actionTimeouts: map[string]time.Duration{"deprovision": 20 * time.Minute}},
+			lifetime: "[ $waited -ge 1500 ]",
+		},
+		{
+			name:     "test deadline",
+			o:        &options{resultsOut: "results.json", waitTimeout: time.Hour, podDeadline: 30 * time.Minute},
+			lifetime: "[ $waited -ge 2100 ]",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "bundle-1234"}}}}
+			applyPodOptions(pod, tc.o)
+			script := pod.Spec.Containers[1].Command[2]
+			if !strings.Contains(script, grace) {
+				t.Fatalf("expected the sidecar to exit after the grace period, got %v", script)
+			}
+			if tc.lifetime == "" && strings.Contains(script, "$waited -ge") {
+				t.Fatalf("expected no lifetime, got %v", script)
+			}
+			if tc.lifetime != "" && !strings.Contains(script, tc.lifetime) {
+				t.Fatalf("expected lifetime %v, got %v", tc.lifetime, script)
+			}
+		})
+	}
+}
+
+func TestResultsCollector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-results")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	defer os.RemoveAll(dir)
+
+	bundlePod := func(uid string, terminated *v1.ContainerStateTerminated) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "bundle-1234", UID: k8stypes.UID(uid)},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "bundle-1234"}, {Name: resultsContainer}}},
+			Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{
+				{Name: "bundle-1234", State: v1.ContainerState{Terminated: terminated}},
+			}},
+		}
+	}
+	testCases := []struct {
+		name     string
+		pod      *v1.Pod
+		output   string
+		run      []string
+		expected string
+	}{
+		{
+			name: "test bundle still running",
+			pod:  bundlePod("a", nil),
+			run:  nil,
+		},
+		{
+			name:     "test bundle succeeded",
+			pod:      bundlePod("b", &v1.ContainerStateTerminated{ExitCode: 0}),
+			output:   `{"url": "http://wiki.example.com"}`,
+			run:      []string{"sh", "touch"},
+			expected: `{"url": "http://wiki.example.com"}`,
+		},
+		{
+			name: "test bundle wrote no results",
+			pod:  bundlePod("c", &v1.ContainerStateTerminated{ExitCode: 0}),
+			run:  []string{"sh", "touch"},
+		},
+		{
+			name:   "test results which are not JSON",
+			pod:    bundlePod("d", &v1.ContainerStateTerminated{ExitCode: 0}),
+			output: "url=http://wiki.example.com",
+			run:    []string{"sh", "touch"},
+		},
+		{
+			name:   "test bundle failed",
+			pod:    bundlePod("e", &v1.ContainerStateTerminated{ExitCode: 1}),
+			output: `{"url": "http://wiki.example.com"}`,
+			run:    []string{"touch"},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("results-%d.json", i))
+			executor := &fakeExecutor{outputs: map[string]string{"sh": tc.output}}
+			collector := newResultsCollector(executor, path)
+			// later polls of the same pod collect nothing more
+			for poll := 0; poll < 2; poll++ {
+				if err := collector.check(tc.pod); err != nil {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+			}
+			if !reflect.DeepEqual(executor.run, tc.run) {
+				t.Fatalf("expected commands %v, got %v", tc.run, executor.run)
+			}
+			written, err := ioutil.ReadFile(path)
+			if tc.expected == "" {
+				if !os.IsNotExist(err) {
+					t.Fatalf("expected no results file, got [%s] [%v]", written, err)
+				}
+				return
+			}
+			if err != nil || strings.TrimSpace(string(written)) != tc.expected {
+				t.Fatalf("expected results [%v], got [%s] [%v]", tc.expected, written, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/apb/pkg/util"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ResumePod takes over a bundle pod created by an earlier run, e.g. one whose
+// process died while waiting for it, and follows it as that run would have:
+// printing its logs with printLogs and, as the options ask, waiting for it,
+// recording the outcome of its instance and cleaning it up
+func ResumePod(ns string, podName string, printLogs bool, opts ...Option) error {
+	return resumePod(ns, podName, "", printLogs, opts)
+}
+
+// ResumeInstance resumes the newest pod of the last action run on the
+// instance, found by its bundle-fqname and bundle-action labels. It returns
+// the name of the pod.
+func ResumeInstance(id string, printLogs bool, opts ...Option) (string, error) {
+	instances := loadInstances()
+	i, err := findInstanceByID(instances, id)
+	if err != nil {
+		return "", err
+	}
+	instance := instances[i]
+	k8scli, err := util.KubernetesClient()
+	if err != nil {
+		return "", err
+	}
+	set := labels.Set{"bundle-fqname": instance.Bundle, "bundle-action": instance.LastAction}
+	pod, err := newestPod(k8scli.Client.CoreV1().Pods(instance.Namespace), set)
+	if err != nil {
+		return "", fmt.Errorf("failed to list pods of [%v] in namespace [%v]: %v", set, instance.Namespace, err)
+	}
+	if pod == nil {
+		return "", fmt.Errorf("no pod of [%v] in namespace [%v] to resume", set, instance.Namespace)
+	}
+	return pod.Name, resumePod(instance.Namespace, pod.Name, instance.ID, printLogs, opts)
+}
+
+func resumePod(ns string, podName string, instanceID string, printLogs bool, opts []Option) (err error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return err
+	}
+	clock := newOperationClock(o.operationTimeout)
+	trace := o.tracer.Start("apb.resume", nil)
+	defer func() { trace.End(err) }()
+
+	k8scli, err := util.KubernetesClient()
+	if err != nil {
+		return err
+	}
+	existing, err := k8scli.Client.CoreV1().Pods(ns).Get(podName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get pod [%v]: %v", podName, err)
+	}
+	action := existing.Labels["bundle-action"]
+	if action == "" {
+		return fmt.Errorf("pod [%v] does not run an APB", podName)
+	}
+	trace.SetAttribute("apb.action", action)
+	trace.SetAttribute("apb.bundle", existing.Labels["bundle-fqname"])
+	trace.SetAttribute("apb.pod", podName)
+	if runID := existing.Labels[runIDLabel]; runID != "" {
+		trace.SetAttribute("apb.run_id", runID)
+	}
+	if instanceID == "" {
+		instanceID = podInstanceID(loadInstances(), existing)
+	}
+	fmt.Printf("Resuming pod [%v] to %s [%v] in namespace [%v]\n", podName, action, existing.Labels["bundle-fqname"], ns)
+	if instanceID != "" {
+		fmt.Printf("Instance: %v\n", instanceID)
+	}
+	return followPod(k8scli, resumablePod(existing), action, instanceID, printLogs, o, clock, trace)
+}
+
+// podInstanceID returns the ID of the instance the pod's action was run on,
+// or an empty string when none is recorded. A deprovisioned instance is only
+// chosen when no other matches, since its record is kept.
+func podInstanceID(instances []config.Instance, pod *v1.Pod) string {
+	id := ""
+	for _, instance := range instances {
+		if instance.Bundle != pod.Labels["bundle-fqname"] || instance.Namespace != pod.Namespace || instance.LastAction != pod.Labels["bundle-action"] {
+			continue
+		}
+		if !instance.Deprovisioned {
+			return instance.ID
+		}
+		id = instance.ID
+	}
+	return id
+}
+
+// resumablePod returns the pod as it was created, for a retry to create it
+// again
+func resumablePod(existing *v1.Pod) *v1.Pod {
+	spec := existing.Spec.DeepCopy()
+	spec.NodeName = ""
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        existing.Name,
+			Namespace:   existing.Namespace,
+			Labels:      existing.Labels,
+			Annotations: existing.Annotations,
+		},
+		Spec: *spec,
+	}
+}
@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestNewestPod(t *testing.T) {
+	bundlePod := func(name string, action string, age time.Duration) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Labels:            map[string]string{"bundle-fqname": "hello-apb", "bundle-action": action},
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+		}}
+	}
+	pods := newFakePods(
+		bundlePod("bundle-old", "provision", time.Hour),
+		bundlePod("bundle-new", "provision", time.Minute),
+		bundlePod("bundle-deprovision", "deprovision", 0),
+	)
+	pod, err := newestPod(pods, labels.Set{"bundle-fqname": "hello-apb", "bundle-action": "provision"})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if pod == nil || pod.Name != "bundle-new" {
+		t.Fatalf("expected pod [bundle-new], got %v", pod)
+	}
+	if pod, _ := newestPod(pods, labels.Set{"bundle-fqname": "other-apb"}); pod != nil {
+		t.Fatalf("expected no pod, got [%v]", pod.Name)
+	}
+}
+
+func TestPodInstanceID(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "bundle-1234",
+		Namespace: "web",
+		Labels:    map[string]string{"bundle-fqname": "hello-apb", "bundle-action": "deprovision"},
+	}}
+	instances := []config.Instance{
+		{ID: "other-ns", Bundle: "hello-apb", Namespace: "db", LastAction: "deprovision"},
+		{ID: "earlier", Bundle: "hello-apb", Namespace: "web", LastAction: "deprovision", Deprovisioned: true},
+		{ID: "latest", Bundle: "hello-apb", Namespace: "web", LastAction: "deprovision", Deprovisioned: true},
+	}
+	if id := podInstanceID(instances, pod); id != "latest" {
+		t.Fatalf("expected instance [latest], got [%v]", id)
+	}
+	instances = append(instances, config.Instance{ID: "live", Bundle: "hello-apb", Namespace: "web", LastAction: "deprovision"})
+	if id := podInstanceID(instances, pod); id != "live" {
+		t.Fatalf("expected instance [live], got [%v]", id)
+	}
+	pod.Labels["bundle-action"] = "update"
+	if id := podInstanceID(instances, pod); id != "" {
+		t.Fatalf("expected no instance, got [%v]", id)
+	}
+}
+
+func TestResumablePod(t *testing.T) {
+	existing := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "bundle-1234",
+			Namespace:       "web",
+			Labels:          map[string]string{"bundle-action": "provision"},
+			ResourceVersion: "42",
+			UID:             "b1e2",
+		},
+		Spec:   v1.PodSpec{NodeName: "node-1", Containers: []v1.Container{{Name: "apb", Image: "hello-apb"}}},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+	pod := resumablePod(existing)
+	if pod.ResourceVersion != "" || pod.UID != "" || pod.Status.Phase != "" {
+		t.Fatalf("expected a pod which can be created again, got %+v", pod)
+	}
+	if pod.Name != "bundle-1234" || pod.Namespace != "web" || pod.Labels["bundle-action"] != "provision" {
+		t.Fatalf("expected the pod's name and labels to be kept, got %+v", pod.ObjectMeta)
+	}
+	if pod.Spec.NodeName != "" || pod.Spec.Containers[0].Image != "hello-apb" {
+		t.Fatalf("expected the spec without its node, got %+v", pod.Spec)
+	}
+	if existing.Spec.NodeName != "node-1" {
+		t.Fatalf("resumablePod changed the existing pod")
+	}
+}
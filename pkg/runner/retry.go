@@ -0,0 +1,102 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// retryPolicy reruns a bundle pod which exits with one of the exit codes
+// the bundle uses to report transient failures
+type retryPolicy struct {
+	retries   int
+	exitCodes []int32
+	backoff   time.Duration
+}
+
+// delay returns how long to wait before the attempt, doubling the backoff
+// after each retry
+func (r retryPolicy) delay(attempt int) time.Duration {
+	return r.backoff * time.Duration(1<<uint(attempt-1))
+}
+
+// retryable reports whether the exit code is one of the policy's
+func (r retryPolicy) retryable(code int32) bool {
+	for _, c := range r.exitCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// podExitCode returns the exit code of the pod's first container which
+// terminated with a non-zero code
+func podExitCode(pod *v1.Pod) (int32, bool) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if t := status.State.Terminated; t != nil && t.ExitCode != 0 {
+			return t.ExitCode, true
+		}
+	}
+	return 0, false
+}
+
+// waitWithRetries waits for the pod to complete. While it fails with a
+// retryable exit code and retries remain, the pod is deleted and created
+// again after the policy's delay, and restarted is called. It returns the
+// last phase of the pod and the number of times it was run. Closing stop
+// ends the wait with errInterrupted.
+func waitWithRetries(pods corev1.PodInterface, pod *v1.Pod, timeout time.Duration, check podCheck, policy retryPolicy, force bool, stop <-chan struct{}, restarted func()) (v1.PodPhase, int, error) {
+	attempts := 1
+	for {
+		phase, err := waitForPodCompletion(pods, pod.Name, timeout, check, stop)
+		if err != nil || phase != v1.PodFailed || attempts > policy.retries {
+			return phase, attempts, err
+		}
+		completed, err := pods.Get(pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return phase, attempts, err
+		}
+		code, exited := podExitCode(completed)
+		if !exited || !policy.retryable(code) {
+			return phase, attempts, nil
+		}
+		delay := policy.delay(attempts)
+		log.Warningf("Pod [%v] exited with retryable code [%v]. Retrying in %v (retry %d of %d)", pod.Name, code, delay, attempts, policy.retries)
+		if err := cleanupPod(pods, pod.Name, force); err != nil {
+			return phase, attempts, err
+		}
+		select {
+		case <-stop:
+			return phase, attempts, errInterrupted
+		case <-time.After(delay):
+		}
+		if _, err := pods.Create(pod); err != nil {
+			return phase, attempts, fmt.Errorf("failed to create pod [%v]: %v", pod.Name, err)
+		}
+		attempts++
+		if restarted != nil {
+			restarted()
+		}
+	}
+}
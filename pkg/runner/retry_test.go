@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// scriptedPods completes each pod it creates with the next of its exit codes
+type scriptedPods struct {
+	*fakePods
+	exitCodes []int32
+}
+
+func (f *scriptedPods) Create(pod *v1.Pod) (*v1.Pod, error) {
+	created := pod.DeepCopy()
+	created.Status = completedStatus(f.exitCodes[0])
+	f.exitCodes = f.exitCodes[1:]
+	return f.fakePods.Create(created)
+}
+
+func completedStatus(code int32) v1.PodStatus {
+	phase := v1.PodSucceeded
+	if code != 0 {
+		phase = v1.PodFailed
+	}
+	return v1.PodStatus{
+		Phase: phase,
+		ContainerStatuses: []v1.ContainerStatus{
+			{State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: code}}},
+		},
+	}
+}
+
+func TestWaitWithRetries(t *testing.T) {
+	policy := retryPolicy{retries: 2, exitCodes: []int32{75}}
+	testCases := []struct {
+		name      string
+		exitCodes []int32
+		phase     v1.PodPhase
+		attempts  int
+	}{
+		{
+			name:      "test success without retry",
+			exitCodes: []int32{0},
+			phase:     v1.PodSucceeded,
+			attempts:  1,
+		},
+		{
+			name:      "test retry until success",
+			exitCodes: []int32{75, 75, 0},
+			phase:     v1.PodSucceeded,
+			attempts:  3,
+		},
+		{
+			name:      "test retries exhausted",
+			exitCodes: []int32{75, 75, 75},
+			phase:     v1.PodFailed,
+			attempts:  3,
+		},
+		{
+			name:      "test non-retryable exit code",
+			exitCodes: []int32{1},
+			phase:     v1.PodFailed,
+			attempts:  1,
+		},
+		{
+			name:      "test non-retryable exit code after retry",
+			exitCodes: []int32{75, 2},
+			phase:     v1.PodFailed,
+			attempts:  2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pods := &scriptedPods{fakePods: newFakePods(), exitCodes: tc.exitCodes}
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "bundle-1234"}}
+			if _, err := pods.Create(pod); err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			restarts := 0
+			phase, attempts, err := waitWithRetries(pods, pod, 0, nil, policy, false, nil, func() { restarts++ })
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if phase != tc.phase || attempts != tc.attempts || restarts != tc.attempts-1 {
+				t.Fatalf("expected phase [%v] after [%v] attempts, got [%v] after [%v] with [%v] restarts", tc.phase, tc.attempts, phase, attempts, restarts)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := retryPolicy{backoff: 10 * time.Second}
+	for attempt, expected := range map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 3: 40 * time.Second} {
+		if delay := policy.delay(attempt); delay != expected {
+			t.Fatalf("expected retry [%v] to wait [%v], got [%v]", attempt, expected, delay)
+		}
+	}
+}
+
+func TestRetriesOption(t *testing.T) {
+	o, err := newOptions([]Option{WithRetries(3, []int{75, 111}, time.Second)})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if o.retry.retries != 3 || !o.retry.retryable(111) || o.retry.retryable(1) {
+		t.Fatalf("got unexpected retry policy %+v", o.retry)
+	}
+	invalid := []Option{
+		WithRetries(-1, []int{75}, time.Second),
+		WithRetries(3, nil, time.Second),
+		WithRetries(3, []int{0}, time.Second),
+		WithRetries(3, []int{256}, time.Second),
+		WithRetries(3, []int{75}, -time.Second),
+	}
+	for i, opt := range invalid {
+		if _, err := newOptions([]Option{opt}); err == nil {
+			t.Fatalf("expected error for retry option [%d]", i)
+		}
+	}
+}
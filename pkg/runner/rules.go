@@ -0,0 +1,76 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+// parameterRule is a compiled config.ParameterRule
+type parameterRule struct {
+	parameter   string
+	pattern     *regexp.Regexp
+	description string
+}
+
+// parameterRules are the input policies from the config, which apply to
+// every bundle on top of its own checks. Nil has no rules.
+type parameterRules []parameterRule
+
+// compileParameterRules compiles the patterns of the rules
+func compileParameterRules(rules []config.ParameterRule) (parameterRules, error) {
+	var compiled parameterRules
+	for _, r := range rules {
+		if r.Parameter == "" {
+			return nil, fmt.Errorf("parameter rule [%v] names no parameter", r.Pattern)
+		}
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern [%v] of the rule for parameter [%v]: %v", r.Pattern, r.Parameter, err)
+		}
+		compiled = append(compiled, parameterRule{parameter: r.Parameter, pattern: pattern, description: r.Description})
+	}
+	return compiled, nil
+}
+
+// check reports the first rule the value of the parameter breaks. Rules
+// only apply to string values.
+func (r parameterRules) check(param bundle.ParameterDescriptor, value interface{}) *ValidationError {
+	s, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	for _, rule := range r {
+		if rule.parameter != param.Name || rule.pattern.MatchString(s) {
+			continue
+		}
+		message := fmt.Sprintf("[%v] breaks the rule [%v] for parameter [%v] from the config", s, rule.pattern, param.Name)
+		if rule.description != "" {
+			message = fmt.Sprintf("%v: %v", message, rule.description)
+		}
+		return &ValidationError{
+			Parameter:  param.Name,
+			Constraint: "rule",
+			Message:    message,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+var teamRules = []config.ParameterRule{
+	{Parameter: "db_name", Pattern: "^ops-", Description: "names start with a team code"},
+	{Parameter: "db_version", Pattern: `^9\.6$`},
+}
+
+func TestParameterRules(t *testing.T) {
+	testCases := []struct {
+		name           string
+		supplied       map[string]string
+		skipValidation bool
+		constraint     string
+		message        string
+	}{
+		{
+			name:     "test values which follow the rules",
+			supplied: map[string]string{"db_name": "ops-wiki"},
+		},
+		{
+			name:       "test value which breaks a rule",
+			supplied:   map[string]string{"db_name": "wiki"},
+			constraint: "rule",
+			message:    "[wiki] breaks the rule [^ops-] for parameter [db_name] from the config: names start with a team code",
+		},
+		{
+			name:       "test default which breaks a rule",
+			supplied:   map[string]string{"db_name": "ops-wiki", "db_version": "9.5"},
+			constraint: "rule",
+			message:    `[9.5] breaks the rule [^9\.6$] for parameter [db_version] from the config`,
+		},
+		{
+			name:       "test bundle checks come first",
+			supplied:   map[string]string{"db_name": "ops-wiki", "db_version": "9.4"},
+			constraint: "enum",
+		},
+		{
+			name:           "test rules apply when skipping validation",
+			supplied:       map[string]string{"db_name": "wiki"},
+			skipValidation: true,
+			constraint:     "rule",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := newOptions([]Option{WithParameterRules(teamRules)})
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			_, err = collectParameters(databasePlan, nil, tc.supplied, nil, nil, o.parameterRules, tc.skipValidation)
+			if tc.constraint == "" {
+				if err != nil {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			verrs, ok := err.(ValidationErrors)
+			if !ok || len(verrs) != 1 || verrs[0].Constraint != tc.constraint {
+				t.Fatalf("expected [%v] error, got [%v]", tc.constraint, err)
+			}
+			if tc.message != "" && verrs[0].Message != tc.message {
+				t.Fatalf("expected message [%v], got [%v]", tc.message, verrs[0].Message)
+			}
+		})
+	}
+}
+
+func TestSelectParametersRules(t *testing.T) {
+	savedReader := promptReader
+	defer func() { promptReader = savedReader }()
+	// the supplied name breaks the rule, and so does the first one entered
+	promptReader = strings.NewReader("wiki\nops-wiki\n")
+	rules, err := compileParameterRules(teamRules)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	plan := bundle.Plan{
+		Name:       "default",
+		Parameters: []bundle.ParameterDescriptor{{Name: "db_name", Type: "string", Required: true}},
+	}
+	params, err := selectParameters(plan, nil, nil, map[string]string{"db_name": "blog"}, nil, nil, rules, false)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if params["db_name"] != "ops-wiki" {
+		t.Fatalf("expected the value following the rule [ops-wiki], got [%v]", params["db_name"])
+	}
+}
+
+func TestParameterRulesOption(t *testing.T) {
+	for _, rules := range [][]config.ParameterRule{{{Parameter: "db_name", Pattern: "("}}, {{Pattern: "^ops-"}}} {
+		if _, err := newOptions([]Option{WithParameterRules(rules)}); err == nil {
+			t.Fatalf("expected error for rules %v", rules)
+		}
+	}
+}
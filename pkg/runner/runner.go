@@ -22,94 +22,287 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strconv"
-	"syscall"
+	"strings"
 	"time"
 
 	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/apb/pkg/tracing"
+	"github.com/automationbroker/apb/pkg/util"
 	"github.com/automationbroker/bundle-lib/bundle"
 	"github.com/automationbroker/bundle-lib/clients"
 	"github.com/automationbroker/bundle-lib/runtime"
-	"github.com/lestrrat/go-jsschema/validator"
+	"github.com/lestrrat/go-jsschema"
 	"github.com/pborman/uuid"
-	"golang.org/x/crypto/ssh/terminal"
 	"k8s.io/api/core/v1"
 
 	log "github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// runIDLabel is the label and annotation of the bundle pod holding the run's
+// correlation id
+const runIDLabel = "bundle-run-id"
+
 // RunBundle will run the bundle's action in the given namespace
-func RunBundle(action string, ns string, bundleName string, sandboxRole string, bundleRegistry string, printLogs bool, skipParams bool, args []string) (podName string, err error) {
-	reg := []config.Registry{}
+func RunBundle(action string, ns string, bundleName string, sandboxRole string, bundleRegistry string, printLogs bool, skipParams bool, args []string, opts ...Option) (podName string, err error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return "", err
+	}
+	if o.resultsOut != "" && !o.waiting() && !o.dryRun {
+		// the results sidecar only exits once the wait collects them
+		return "", errors.New("collecting results requires waiting for the APB pod")
+	}
+	if o.validateOnly && o.previewOut != nil {
+		return "", errors.New("parameters can't be both validated only and previewed")
+	}
+	if o.offline() && o.extraVarsSecret != nil {
+		return "", errors.New("extra vars from a secret can't be read without contacting the cluster")
+	}
+	if o.offline() && o.customResource != nil {
+		return "", errors.New("parameters from a custom resource can't be read without contacting the cluster")
+	}
+	clock := newOperationClock(o.operationTimeout)
+	trace := o.tracer.Start("apb.run", nil)
+	defer func() { trace.End(err) }()
+	trace.SetAttribute("apb.action", action)
+	runID := o.runID
+	if runID == "" {
+		runID = uuid.New()
+		opts = append(opts, WithRunID(runID))
+	}
+	trace.SetAttribute("apb.run_id", runID)
+	if o.customResource != nil {
+		k8scli, err := util.KubernetesClient()
+		if err != nil {
+			return "", markError(ErrClientInit, err)
+		}
+		run, err := resolveCR(&restCustomResources{client: k8scli.Client}, *o.customResource, ns)
+		if err != nil {
+			return "", err
+		}
+		if bundleName == "" {
+			bundleName = run.bundle
+		} else if bundleName != run.bundle {
+			return "", fmt.Errorf("custom resource [%v] runs APB [%v], not [%v]", o.customResource, run.bundle, bundleName)
+		}
+		if o.planName == "" {
+			o.planName = run.plan
+		}
+		if err := withCRParameters(o, run.parameters); err != nil {
+			return "", err
+		}
+	}
 	var targetSpec *bundle.Spec
-	var candidateSpecs []*bundle.Spec
-	podName = fmt.Sprintf("bundle-%s", uuid.New())
-	config.Registries.UnmarshalKey("Registries", &reg)
-	for _, r := range reg {
-		if len(bundleRegistry) > 0 && r.Config.Name != bundleRegistry {
-			continue
+	if o.localDir != "" {
+		targetSpec, err = LoadLocalSpec(o.localDir)
+		if err != nil {
+			return "", err
 		}
-		for _, s := range r.Specs {
-			if s.FQName == bundleName {
-				candidateSpecs = append(candidateSpecs, s)
-				fmt.Printf("Found APB [%v] in registry [%v]\n", bundleName, r.Config.Name)
+		if !o.offline() {
+			targetSpec.Image, err = buildLocalImage(o.localDir, targetSpec)
+			if err != nil {
+				return "", err
 			}
 		}
+		bundleName = targetSpec.FQName
+	} else {
+		provider := o.specProvider
+		if provider == nil {
+			provider = NewRegistrySpecProvider(bundleRegistry)
+		}
+		targetSpec, err = provider.Get(bundleName)
+		if err != nil {
+			return "", err
+		}
 	}
-	if len(candidateSpecs) == 0 {
-		if len(bundleRegistry) > 0 {
-			return "", errors.New(fmt.Sprintf("failed to find APB [%v] in registry [%v]", bundleName, bundleRegistry))
+
+	if err := clock.check("finding the APB"); err != nil {
+		return "", err
+	}
+
+	if o.checkLabels {
+		// fail before prompting for parameters an image which can't run
+		if err := preflightLabels(targetSpec.Image); err != nil {
+			return "", err
 		}
-		return "", errors.New(fmt.Sprintf("failed to find APB [%v] in configured registries", bundleName))
-		// TODO: return an ErrorBundleNotFound
 	}
-	if len(candidateSpecs) > 1 {
-		return "", errors.New(fmt.Sprintf("found multiple APBs with matching name [%v]. Specify a registry with --registry", bundleName))
+
+	opts = append([]Option{WithRecommendedResources(targetSpec)}, opts...)
+	trace.SetAttribute("apb.bundle", targetSpec.FQName)
+
+	// a re-run reuses the extra vars and, unless one is given, the plan of
+	// the previous run
+	var secretExtraVars string
+	planName := o.planName
+	if o.extraVarsSecret != nil {
+		if len(o.parameterValues) > 0 {
+			return "", errors.New("parameter values can't be given with extra vars from a secret")
+		}
+		var vars map[string]interface{}
+		secretExtraVars, vars, err = readExtraVarsSecret(*o.extraVarsSecret)
+		if err != nil {
+			return "", err
+		}
+		if name, ok := vars[planIDKey].(string); ok && planName == "" {
+			planName = name
+		}
 	}
 
-	targetSpec = candidateSpecs[0]
+	// time spent prompting doesn't count towards the operation timeout
+	if !o.nonInteractive {
+		clock.pause()
+	}
 
 	// determine the correct plan
-	plan := selectPlan(targetSpec)
+	stage := o.tracer.Start("apb.select_plan", trace)
+	plan, err := selectPlan(targetSpec, planName, o.defaultPlan, o.selector)
+	stage.End(err)
+	if err != nil {
+		return "", err
+	}
+	trace.SetAttribute("apb.plan", plan.Name)
+	metadataKeys := o.planMetadataKeys
+	if metadataKeys == nil {
+		metadataKeys = DefaultPlanMetadataKeys
+	}
+	planMetadata := PlanMetadata(plan, metadataKeys)
 	if plan.Name == "" {
 		log.Warning("Did not find a selected plan")
-	} else {
+	} else if !o.quietPlan {
 		fmt.Printf("Plan: %v\n", plan.Name)
+		printPlanMetadata(os.Stdout, planMetadata)
+	}
+	if err := CheckPlanAction(targetSpec, plan, action); err != nil {
+		return "", err
+	}
+	plan, err = applyBundleDefaults(targetSpec, plan)
+	if err != nil {
+		log.Warningf("Ignoring parameter defaults of APB [%v]: %v", targetSpec.FQName, err)
+	}
+	if err := checkAnnotatedParameters(plan, o.annotatedParameters); err != nil {
+		return "", err
 	}
 
+	if o.extraVarsSecret != nil {
+		// the previous run's extra vars already hold its parameters
+		skipParams = true
+	}
+	if o.skipValidation && !skipParams {
+		log.Warning("Parameter validation is skipped. Values are only converted to their types, not checked against the plan")
+	}
 	var params bundle.Parameters
 	if skipParams {
 		params = bundle.Parameters{}
 	} else {
-		params, err = selectParameters(plan)
+		stage = o.tracer.Start("apb.collect_parameters", trace)
+		params, err = runParameters(action, bundleName, ns, targetSpec, plan, o)
+		stage.End(err)
+		if err != nil {
+			return "", err
+		}
+	}
+	clock.resume()
+	if err := clock.check("collecting parameters"); err != nil {
+		return "", err
+	}
+	if o.validateOnly {
+		return "", nil
+	}
+
+	if len(o.annotatedParameters) > 0 {
+		annotations, err := parameterAnnotations(plan, params, o.annotatedParameters)
+		if err != nil {
+			return "", err
+		}
+		opts = append(opts, withPodAnnotations(annotations))
+	}
+	if len(planMetadata) > 0 {
+		value, err := planMetadataAnnotationValue(planMetadata)
+		if err != nil {
+			return "", err
+		}
+		opts = append(opts, withPodAnnotations(map[string]string{planMetadataAnnotation: value}))
+	}
+	if o.annotateParameters {
+		value, err := parametersAnnotationValue(plan, params)
 		if err != nil {
 			return "", err
 		}
+		opts = append(opts, withPodAnnotations(map[string]string{parametersAnnotation: value}))
 	}
 
-	extraVars, err := createExtraVars(ns, &params, plan)
+	if o.scriptPath != "" || o.printCommand {
+		run := runScript{
+			Action:    action,
+			Bundle:    bundleName,
+			Local:     o.localDir != "",
+			Registry:  bundleRegistry,
+			Namespace: ns,
+			Plan:      plan,
+			Params:    params,
+			Flags:     o.runFlags,
+		}
+		if run.Local {
+			run.Bundle = o.localDir
+		}
+		if o.scriptPath != "" {
+			if err := writeRunScript(o.scriptPath, run); err != nil {
+				return "", err
+			}
+		}
+		if o.printCommand {
+			fmt.Printf("Run again with:\n%v\n", run.commandLine())
+		}
+	}
+
+	classID := o.serviceClassID
+	if classID == "" {
+		classID = serviceClassID(targetSpec)
+	}
+	if o.generateNamespace {
+		ns = generateNamespaceName(bundleName)
+	}
+	trace.SetAttribute("apb.namespace", ns)
+	podName, err = renderPodName(o.podNameTemplate, podNameTokens(targetSpec.FQName, action, ns))
 	if err != nil {
 		return "", err
 	}
+	// the service account is the sandbox's unless one is given. The account
+	// identifies who the bundle runs as and defaults to the service account.
+	serviceAccount := podName
+	if o.serviceAccount != "" {
+		serviceAccount = o.serviceAccount
+	}
+	account := serviceAccount
+	if o.account != "" {
+		account = o.account
+	}
+	if o.previewOut != nil && o.serviceAccount == "" && o.account == "" {
+		// the sandbox is named after the pod the run would create
+		account = sandboxServiceAccount
+	}
+	extraVars := secretExtraVars
+	if o.extraVarsSecret == nil {
+		extraVars, err = createExtraVars(ns, &params, plan, classID, account, o.minimalExtraVars)
+		if err != nil {
+			return "", err
+		}
+	}
+	if o.previewOut != nil {
+		return "", writeParameterPreview(o.previewOut, plan, params, o.parameterValues, extraVars, o.previewSecrets)
+	}
 
 	labels := map[string]string{
 		"bundle-fqname":   targetSpec.FQName,
 		"bundle-action":   action,
 		"bundle-pod-name": podName,
+		"bundle-account":  account,
 	}
 
-	// TODO: using edit directly. The bundle code uses clusterConfig.SandboxRole
-	// which is defined by the template. So far we've been using edit.
-
-	runtime.NewRuntime(runtime.Configuration{})
 	targets := []string{ns}
-	serviceAccount, namespace, err := runtime.Provider.CreateSandbox(podName, ns, targets, sandboxRole, labels)
-	if err != nil {
-		fmt.Printf("\nProblem creating sandbox [%s] to run APB. Did you run `oc new-project %s` first?\n\n", podName, ns)
-		os.Exit(-1)
-	}
-
 	ec := runtime.ExecutionContext{
 		BundleName: podName,
 		Targets:    targets,
@@ -117,25 +310,345 @@ func RunBundle(action string, ns string, bundleName string, sandboxRole string,
 		Action:     action,
 		Image:      targetSpec.Image,
 		Account:    serviceAccount,
-		Location:   namespace,
+		Location:   ns,
 		ExtraVars:  extraVars,
 	}
 
-	k8scli, err := clients.Kubernetes()
+	if o.dryRun {
+		pod, err := BuildPod(ec, opts...)
+		if err != nil {
+			return "", err
+		}
+		fits := true
+		if o.checkQuota {
+			fits, err = reportQuota(pod, ns, o.generateNamespace)
+			if err != nil {
+				return "", err
+			}
+		}
+		if o.diffPrevious {
+			// the account of a sandbox is named after the pod, so it
+			// differs on every run
+			ignored := map[string]bool{"_apb_account": o.account == "" && o.serviceAccount == ""}
+			if err := diffPreviousPod(pod, sensitiveParameters(plan), ignored, os.Stdout); err != nil {
+				return podName, err
+			}
+		} else {
+			// the kustomization is written to be applied, so it keeps
+			// the values
+			sensitive := sensitiveParameters(plan)
+			if o.outputFormat == "kustomize" {
+				sensitive = nil
+			}
+			manifest, err := formatExtraVars(pod, sensitive, o.extraVarsIndent)
+			if err != nil {
+				return podName, err
+			}
+			if err := writeManifest(manifest, o.outputFormat, o.outputDir, os.Stdout); err != nil {
+				return podName, err
+			}
+		}
+		if !fits {
+			return podName, fmt.Errorf("pod [%v] would exceed the resource quota of namespace [%v]", podName, ns)
+		}
+		return podName, nil
+	}
+
+	// TODO: using edit directly. The bundle code uses clusterConfig.SandboxRole
+	// which is defined by the template. So far we've been using edit.
+
+	k8scli, err := util.KubernetesClient()
+	if err != nil {
+		return "", markError(ErrClientInit, err)
+	}
+	if o.checkArchitecture {
+		if err := preflightArchitectures(k8scli.Client.CoreV1().Nodes(), targetSpec.Image); err != nil {
+			return "", err
+		}
+	}
+	if o.createProject {
+		if err := createProject(&restProjects{client: k8scli.Client}, k8scli.Client.CoreV1().Namespaces(), ns, o); err != nil {
+			return "", err
+		}
+	} else if o.generateNamespace {
+		if err := createNamespace(k8scli.Client.CoreV1().Namespaces(), ns, o); err != nil {
+			return "", err
+		}
+	}
+
+	if o.serviceAccount == "" {
+		runtime.NewRuntime(runtime.Configuration{})
+		sandboxAccount, namespace, err := runtime.Provider.CreateSandbox(podName, ns, targets, sandboxRole, labels)
+		if err != nil {
+			return "", fmt.Errorf("failed to create sandbox [%v] to run APB. Did you run `oc new-project %v` first? %v", podName, ns, err)
+		}
+		ec.Account = sandboxAccount
+		ec.Location = namespace
+	}
+
+	if err := clock.check(fmt.Sprintf("preparing namespace [%v]", ns)); err != nil {
+		return "", err
+	}
+
+	pod, err := BuildPod(ec, opts...)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("Creating pod [%v] in namespace [%v]\n", podName, ns)
+	pods := k8scli.Client.CoreV1().Pods(ns)
+	stage = o.tracer.Start("apb.create_pod", trace)
+	stage.SetAttribute("apb.pod", podName)
+	_, err = pods.Create(pod)
+	stage.End(err)
+	if err != nil {
+		return podName, markError(ErrPodCreate, fmt.Errorf("failed to create pod [%v]: %v", podName, err))
+	}
+	fmt.Printf("Successfully created pod [%v] to %s [%v] in namespace [%v]\n", podName, ec.Action, bundleName, ns)
+	instanceID, err := recordInstance(action, bundleName, ns, plan, params)
+	if err != nil {
+		log.Warningf("Failed to record parameters for APB [%v]: %v", bundleName, err)
+	}
+	if instanceID != "" {
+		fmt.Printf("Instance: %v\n", instanceID)
+	}
+	if o.storeParameters && instanceID != "" && !skipParams && (action == "provision" || action == "update") {
+		if err := storeParameters(k8scli.Client.CoreV1().Secrets(ns), instanceID, targetSpec.FQName, plan, params); err != nil {
+			log.Warningf("Failed to store the parameters of instance [%v]: %v", instanceID, err)
+		} else {
+			fmt.Printf("Stored parameters in secret [%v]\n", parametersSecretName(instanceID))
+		}
+	}
+	fmt.Printf("Run ID: %v\n", runID)
+
+	return podName, followPod(k8scli, pod, action, instanceID, printLogs, o, clock, trace)
+}
+
+// followPod prints the logs of the bundle pod with printLogs and, when the
+// options ask to, waits for it to complete, records the outcome of the
+// instance and cleans the pod up
+func followPod(k8scli *clients.KubernetesClient, pod *v1.Pod, action string, instanceID string, printLogs bool, o *options, clock *operationClock, trace tracing.Span) error {
+	podName, ns := pod.Name, pod.Namespace
+	pods := k8scli.Client.CoreV1().Pods(ns)
+	// the pod's deadline counts from when it was created, which is now
+	// when the run has just created it
+	created := pod.CreationTimestamp.Time
+	if created.IsZero() {
+		created = time.Now()
+	}
+	waiting := o.waiting()
+	var stop <-chan struct{}
+	if printLogs || waiting {
+		var release func()
+		stop, release = notifyInterrupt()
+		defer release()
+		stop, release = clock.stop(stop)
+		defer release()
+	}
+
+	if printLogs {
+		printBundleLogs(podName, ns, action, o.podLogOptions(), stop)
+		if interrupted(stop) {
+			if err := clock.check(fmt.Sprintf("following the logs of pod [%v]", podName)); err != nil {
+				return err
+			}
+			return cleanupInterrupted(pods, podName, ns, o.cleanupOnInterrupt, o.forceCleanup)
+		}
+	}
+
+	if waiting {
+		events := k8scli.Client.CoreV1().Events(ns)
+		check := unschedulableCheck(events, o.unschedulableTimeout)
+		var recorder *eventRecorder
+		if o.eventsOut != "" {
+			f, err := os.Create(o.eventsOut)
+			if err != nil {
+				return fmt.Errorf("failed to create events file: %v", err)
+			}
+			defer f.Close()
+			recorder = newEventRecorder(events, f)
+			check = allChecks(recorder.check, check)
+		}
+		if hasResultsSidecar(pod) {
+			check = allChecks(check, newResultsCollector(restExecutor{client: k8scli, namespace: ns}, o.resultsOut).check)
+		}
+		stage := o.tracer.Start("apb.wait", trace)
+		waitPods := &reconnectingPods{PodInterface: pods, timeout: o.reconnectTimeout, stop: stop}
+		phase, attempts, err := waitWithRetries(waitPods, pod, o.timeout(action), check, o.retry, o.forceCleanup, stop, func() {
+			if printLogs {
+				printBundleLogs(podName, ns, action, o.podLogOptions(), stop)
+			}
+		})
+		stage.SetAttribute("apb.attempts", strconv.Itoa(attempts))
+		if recorder != nil {
+			// the events of the pod's last moments
+			if err := recorder.record(podName); err != nil {
+				log.Warningf("Failed to record events of pod [%v]: %v", podName, err)
+			}
+		}
+		if err == nil && phase == v1.PodFailed {
+			stage.End(fmt.Errorf("pod [%v] failed", podName))
+		} else {
+			stage.End(err)
+		}
+		if err == errInterrupted {
+			if err := clock.check(fmt.Sprintf("waiting for pod [%v]", podName)); err != nil {
+				return describeFailure(err, pods, events, podName)
+			}
+			return cleanupInterrupted(pods, podName, ns, o.cleanupOnInterrupt, o.forceCleanup)
+		}
+		if o.podDeadline > 0 {
+			waitErr := err
+			if o.timeout(action) < o.podDeadline {
+				// a shorter timeout ended the wait before the deadline
+				waitErr = nil
+			}
+			if expired, err := expirePod(pods, podName, o.podDeadline, created, phase, waitErr, o.forceCleanup, o.reconnectTimeout); expired {
+				if err := recordOutcome(instanceID, outcomeFailed, ""); err != nil {
+					log.Warningf("Failed to record the outcome of instance [%v]: %v", instanceID, err)
+				}
+				return err
+			}
+		}
+		if err != nil {
+			return describeFailure(err, pods, events, podName)
+		}
+		outcome := outcomeSucceeded
+		if phase == v1.PodFailed {
+			outcome = outcomeFailed
+		}
+		// read the result before cleanup removes the pod
+		var result string
+		if completed, err := pods.Get(podName, metav1.GetOptions{}); err == nil {
+			result = terminationMessage(completed)
+		}
+		// a failure's message is part of the error describing it
+		if result != "" && outcome == outcomeSucceeded {
+			fmt.Printf("Result: %v\n", result)
+		}
+		if err := recordOutcome(instanceID, outcome, result); err != nil {
+			log.Warningf("Failed to record the outcome of instance [%v]: %v", instanceID, err)
+		}
+		if o.storeParameters && instanceID != "" && action == "deprovision" && outcome == outcomeSucceeded {
+			if err := deleteStoredParameters(k8scli.Client.CoreV1().Secrets(ns), instanceID); err != nil {
+				log.Warningf("Failed to delete the stored parameters of instance [%v]: %v", instanceID, err)
+			}
+		}
+		var failure error
+		if phase == v1.PodFailed {
+			// describe the failure before cleanup removes the pod's status
+			failure = describeFailure(fmt.Errorf("pod [%v] failed", podName), pods, events, podName)
+		}
+		if o.cleanup {
+			if err := cleanupPod(pods, podName, o.forceCleanup); err != nil {
+				return err
+			}
+			fmt.Printf("Deleted pod [%v]\n", podName)
+		}
+		if failure != nil {
+			return failure
+		}
+	}
+
+	return nil
+}
+
+// runParameters collects the parameters of the run, from the options or by
+// prompting, and validates them against the plan
+func runParameters(action string, bundleName string, ns string, targetSpec *bundle.Spec, plan bundle.Plan, o *options) (bundle.Parameters, error) {
+	// resolve the plan's schema before prompting, so unresolvable refs
+	// fail the run early
+	var planSchema *schema.Schema
+	var err error
+	if !o.skipValidation {
+		planSchema, err = loadPlanSchema(plan, o.remoteSchemaTimeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, verr := range validateParameterNames(plan) {
+		log.Warningf("APB [%v]: %v", bundleName, verr.Message)
+	}
+	var previous bundle.Parameters
+	if o.storeParameters && action != "provision" && !o.offline() {
+		previous, err = instanceParameters(bundleName, ns, plan.Name)
+		if err != nil {
+			log.Warningf("Unable to read the stored parameters of APB [%v]: %v", bundleName, err)
+		}
+	}
+	if previous == nil && action == "update" {
+		previous = previousParameters(bundleName, ns, plan.Name)
+	}
+	var params bundle.Parameters
+	if o.nonInteractive {
+		params, err = collectParameters(plan, previous, o.parameterValues, o.jsonParameters, o.transformers, o.parameterRules, o.skipValidation)
+	} else {
+		var cached bundle.Parameters
+		if o.parameterCacheDir != "" {
+			cached, err = loadCachedParameters(o.parameterCacheDir, targetSpec.FQName)
+			if err != nil {
+				log.Warningf("Unable to read parameters from the last run: %v", err)
+			}
+		}
+		params, err = selectParameters(plan, previous, cached, o.parameterValues, o.jsonParameters, o.transformers, o.parameterRules, o.skipValidation)
+	}
 	if err != nil {
-		// TODO: return err
-		panic(err.Error())
+		return nil, err
 	}
+	if !o.skipValidation {
+		if err := checkSettable(plan, action, readOnlyParameters(planSchema), o.parameterValues, previous, params); err != nil {
+			return nil, err
+		}
+	}
+	if err := validatePlanSchema(planSchema, params); err != nil {
+		return nil, err
+	}
+	if o.parameterCacheDir != "" {
+		if err := cacheParameters(o.parameterCacheDir, targetSpec.FQName, plan, params); err != nil {
+			log.Warningf("Unable to cache parameters: %v", err)
+		}
+	}
+	return params, nil
+}
+
+// FindSpec returns the spec of the named bundle from the configured registries
+func FindSpec(bundleName string, bundleRegistry string) (*bundle.Spec, error) {
+	return NewRegistrySpecProvider(bundleRegistry).Get(bundleName)
+}
 
+// imagePullPolicy returns the pull policy of the bundle image. Unless one was
+// set, an image pinned by digest can't change and is only pulled when it is
+// not present, while a tag such as latest may have moved and is always
+// pulled. Local bundles are built on the node and never pulled.
+func imagePullPolicy(o *options, image string) v1.PullPolicy {
+	if o.localDir != "" {
+		return v1.PullIfNotPresent
+	}
+	if o.pullPolicy != "" {
+		return o.pullPolicy
+	}
+	if strings.Contains(image, "@") {
+		return v1.PullIfNotPresent
+	}
+	return v1.PullAlways
+}
+
+// BuildPod returns the pod which runs the execution context's action,
+// without creating it
+func BuildPod(ec runtime.ExecutionContext, opts ...Option) (*v1.Pod, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
 	pod := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   ec.BundleName,
-			Labels: ec.Metadata,
+			Name:      ec.BundleName,
+			Namespace: ec.Location,
+			Labels:    ec.Metadata,
 		},
 		Spec: v1.PodSpec{
 			Containers: []v1.Container{
 				{
-					Name:  podName,
+					Name:  ec.BundleName,
 					Image: ec.Image,
 					Args: []string{
 						ec.Action,
@@ -143,30 +656,21 @@ func RunBundle(action string, ns string, bundleName string, sandboxRole string,
 						ec.ExtraVars,
 					},
 					Env:             createPodEnv(ec),
-					ImagePullPolicy: "Always",
+					ImagePullPolicy: imagePullPolicy(o, ec.Image),
 				},
 			},
 			RestartPolicy:      v1.RestartPolicyNever,
 			ServiceAccountName: ec.Account,
 		},
 	}
-	_, err = k8scli.Client.CoreV1().Pods(ns).Create(pod)
-	if err != nil {
-		return "", err
-	}
-	fmt.Printf("Successfully created pod [%v] to %s [%v] in namespace [%v]\n", podName, ec.Action, bundleName, ns)
-
-	if printLogs {
-		printBundleLogs(podName, ns, action)
-	}
-
-	return
+	applyPodOptions(pod, o)
+	return pod, nil
 }
 
 func GetPodStatus(namespace string, podName string) (string, error) {
-	k8scli, err := clients.Kubernetes()
+	k8scli, err := util.KubernetesClient()
 	if err != nil {
-		panic(err.Error())
+		return "", markError(ErrClientInit, err)
 	}
 	pod, err := k8scli.Client.CoreV1().Pods(namespace).Get(podName, metav1.GetOptions{})
 	if err != nil {
@@ -176,13 +680,15 @@ func GetPodStatus(namespace string, podName string) (string, error) {
 	return string(status), nil
 }
 
-func printBundleLogs(podName string, namespace string, action string) {
-	k8scli, err := clients.Kubernetes()
+// printBundleLogs prints the pod's logs until it completes or stop is closed
+func printBundleLogs(podName string, namespace string, action string, logOpts *v1.PodLogOptions, stop <-chan struct{}) {
+	k8scli, err := util.KubernetesClient()
 	if err != nil {
-		panic(err.Error())
+		log.Errorf("Failed to follow the logs of pod [%v]: %v", podName, err)
+		return
 	}
 
-	logTailRequest := k8scli.Client.CoreV1().Pods(namespace).GetLogs(podName, &v1.PodLogOptions{Follow: true})
+	logTailRequest := k8scli.Client.CoreV1().Pods(namespace).GetLogs(podName, logOpts)
 
 	var requestStream io.ReadCloser
 	var podStarted bool
@@ -192,13 +698,27 @@ func printBundleLogs(podName string, namespace string, action string) {
 		if err != nil {
 			fmt.Printf("Waiting for APB %v pod [%v] to start...\n", action, podName)
 			log.Debugf("%v", err)
-			time.Sleep(3 * time.Second)
+			select {
+			case <-stop:
+				return
+			case <-time.After(3 * time.Second):
+			}
 		} else {
 			fmt.Printf("Pod started. Reading logs...\n")
 			podStarted = true
 		}
 	}
 	defer requestStream.Close()
+	// closing the stream ends the read below
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			requestStream.Close()
+		case <-done:
+		}
+	}()
 
 	fmt.Println("-+- ---------------------- -+-")
 	fmt.Println(" |         APB LOGS         | ")
@@ -208,111 +728,153 @@ func printBundleLogs(podName string, namespace string, action string) {
 	var doneReading bool
 	for doneReading == false {
 		n, err := requestStream.Read(buf)
-		if err == io.EOF {
+		if err != nil {
 			doneReading = true
 		}
 		fmt.Printf("%s", buf[:n])
 	}
 }
 
-func selectPlan(spec *bundle.Spec) bundle.Plan {
-	var planName string
-	var check = true
-	for check {
-		if len(spec.Plans) > 1 {
-			fmt.Printf("List of available plans:\n")
-			for _, plan := range spec.Plans {
-				fmt.Printf("name: %v\n", plan.Name)
-			}
-		} else {
-			return spec.Plans[0]
-		}
-		fmt.Printf("Enter name of plan to execute: ")
-		fmt.Scanln(&planName)
+// selectPlan returns the named plan, the bundle's only plan, the default
+// plan when the bundle has it, or else the plan the user chooses
+func selectPlan(spec *bundle.Spec, planName string, defaultPlan string, selector Selector) (bundle.Plan, error) {
+	if planName != "" {
+		var names []string
 		for _, plan := range spec.Plans {
 			if plan.Name == planName {
-				return plan
+				return plan, nil
 			}
+			names = append(names, plan.Name)
 		}
-		fmt.Printf("Did not find plan [%v], try again.\n\n", planName)
+		return bundle.Plan{}, fmt.Errorf("did not find plan [%v]. Available plans: %v", planName, names)
 	}
-	return bundle.Plan{}
+	if len(spec.Plans) == 1 {
+		return spec.Plans[0], nil
+	}
+	var names []string
+	for _, plan := range spec.Plans {
+		if defaultPlan != "" && plan.Name == defaultPlan {
+			return plan, nil
+		}
+		names = append(names, plan.Name)
+	}
+	planName, err := selector.Select("plan", names)
+	if err != nil {
+		return bundle.Plan{}, err
+	}
+	for _, plan := range spec.Plans {
+		if plan.Name == planName {
+			return plan, nil
+		}
+	}
+	return bundle.Plan{}, nil
+}
+
+// SelectBundle asks the user to choose one of the bundles in the configured
+// registries, or in bundleRegistry when it is given
+func SelectBundle(bundleRegistry string, selector Selector) (string, error) {
+	reg := []config.Registry{}
+	config.Registries.UnmarshalKey("Registries", &reg)
+	var names []string
+	for _, r := range reg {
+		if len(bundleRegistry) > 0 && r.Config.Name != bundleRegistry {
+			continue
+		}
+		for _, s := range r.Specs {
+			if !contains(names, s.FQName) {
+				names = append(names, s.FQName)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return "", errors.New("no APBs found in the configured registries. Run 'apb bundle list' to load them")
+	}
+	sort.Strings(names)
+	return selector.Select("APB", names)
 }
 
-func selectParameters(plan bundle.Plan) (bundle.Parameters, error) {
-	schemaPlan, err := bundle.ConvertPlansToSchema([]bundle.Plan{plan})
+// selectParameters prompts for a value for each of the plan's parameters.
+// Previous values, when given, are offered in place of the schema defaults.
+// Supplied values are used without prompting, unless they are invalid, and
+// those named in jsonKeys are decoded as JSON. Values are checked against
+// the config's rules after the bundle's own checks.
+func selectParameters(plan bundle.Plan, previous bundle.Parameters, cached bundle.Parameters, supplied map[string]string, jsonKeys map[string]bool, t *transformers, rules parameterRules, skipValidation bool) (bundle.Parameters, error) {
+	check := inputChecker(t, rules, skipValidation)
+	ordered, err := orderParameters(plan.Parameters)
 	if err != nil {
-		log.Errorf("Error converting APB plans to JSON Schema: %v", err)
 		return nil, err
 	}
-	planSchema := schemaPlan[0].Schemas
-	schemaParams := planSchema.ServiceInstance.Create["parameters"]
+	confirmed, err := confirmedParameters(plan)
+	if err != nil {
+		log.Warning(err)
+	}
 	params := bundle.Parameters{}
-	for _, param := range plan.Parameters {
+	for _, param := range ordered {
+		if dep, unmet := unmetDependency(param, params); unmet {
+			log.Debugf("Skipping parameter [%v] since it applies only when %v", param.Name, describeDependency(dep))
+			continue
+		}
+		if input, ok := supplied[param.Name]; ok {
+			var value interface{}
+			var verr *ValidationError
+			if jsonKeys[param.Name] {
+				value, verr = checkJSONInput(param, input, t, rules, skipValidation)
+			} else {
+				value, verr = check(param, input)
+			}
+			if verr == nil {
+				params.Add(param.Name, value)
+				continue
+			}
+			fmt.Printf("%v\n", verr.Message)
+		}
 		var inputValid = false
-		var paramDefault interface{}
-
-		if param.Default != nil {
-			paramDefault = param.Default
+		defaultValue, fromLastRun := lastRunDefault(param, previous, cached)
+		paramDefault, err := renderDefault(param, defaultValue, params)
+		if err != nil {
+			log.Warning(err)
+		}
+		var defaultSource string
+		if fromLastRun {
+			defaultSource = " (from last run)"
 		}
 
 		for !inputValid {
-			var paramInput string
-
 			if len(param.Description) > 0 {
-				fmt.Printf("Enter value for parameter [%v] (%v), default: [%v]: ", param.Name, param.Description, paramDefault)
+				fmt.Printf("Enter value for parameter [%v] (%v), default: [%v]%v: ", param.Name, param.Description, paramDefault, defaultSource)
 			} else {
-				fmt.Printf("Enter value for parameter [%v], default: [%v]: ", param.Name, paramDefault)
+				fmt.Printf("Enter value for parameter [%v], default: [%v]%v: ", param.Name, paramDefault, defaultSource)
 			}
 
-			if param.DisplayType == "password" {
-				passwordInputBytes, err := terminal.ReadPassword(int(syscall.Stdin))
-				fmt.Println()
-				if err != nil {
-					log.Errorf("Error while collecting password: %v", err)
-					continue
-				}
-				paramInput = string(passwordInputBytes)
-			} else {
-				fmt.Scanln(&paramInput)
-			}
-
-			if paramInput == "" {
-				switch paramDefault.(type) {
-				case int:
-					paramInput = strconv.Itoa(paramDefault.(int))
-				case string:
-					paramInput = paramDefault.(string)
-				case float64:
-					paramInput = strconv.FormatFloat(paramDefault.(float64), 'f', 0, 32)
-				case bool:
-					paramInput = strconv.FormatBool(paramDefault.(bool))
-				}
+			paramInput, matched, err := promptInput(param, confirmed[param.Name], promptReader)
+			if err != nil {
+				log.Errorf("Error while collecting input: %v", err)
+				continue
 			}
-			if param.Required == true && paramInput == "" {
-				fmt.Printf("Parameter [%v] is required. Please try again.\n", param.Name)
+			if !matched {
+				fmt.Printf("Values for parameter [%v] do not match. Please try again.\n", param.Name)
 				continue
 			}
 
-			if len(param.Enum) > 0 {
-				if !contains(param.Enum, paramInput) {
-					fmt.Printf("[%v] is not a valid option. Available options: %v\n", paramInput, param.Enum)
-					continue
-				}
+			if paramInput == "" {
+				paramInput = defaultInput(paramDefault)
 			}
-
-			input, err := pruneInput(paramInput, param)
-			if err != nil {
-				fmt.Printf("Error accepting input: %v\n", err)
-				fmt.Println("Please try again")
+			input, verr := check(param, paramInput)
+			if verr != nil {
+				fmt.Printf("%v. Please try again.\n", verr.Message)
 			} else {
 				inputValid = true
 				params.Add(param.Name, input)
 			}
 		}
 	}
-	v := validator.New(schemaParams)
-	if err := v.Validate(params); err != nil {
+	if skipValidation {
+		return params, nil
+	}
+	if err := validateDependencies(plan, params); err != nil {
+		return nil, err
+	}
+	if err := validateSchema(plan, params); err != nil {
 		log.Debugf("Error validating parameters: %v", err)
 		return nil, err
 	}
@@ -321,6 +883,26 @@ func selectParameters(plan bundle.Plan) (bundle.Parameters, error) {
 	return params, nil
 }
 
+// effectiveDefault returns the previous value of a parameter if there is
+// one, falling back to the default from the schema
+func effectiveDefault(param bundle.ParameterDescriptor, previous bundle.Parameters) interface{} {
+	if value, ok := previous[param.Name]; ok {
+		return value
+	}
+	return param.Default
+}
+
+// lastRunDefault returns the effective default of a parameter, preferring
+// the value cached by the last run of the bundle over the declared default
+func lastRunDefault(param bundle.ParameterDescriptor, previous bundle.Parameters, cached bundle.Parameters) (interface{}, bool) {
+	if _, ok := previous[param.Name]; !ok {
+		if value, ok := cached[param.Name]; ok {
+			return value, true
+		}
+	}
+	return effectiveDefault(param, previous), false
+}
+
 func createPodEnv(executionContext runtime.ExecutionContext) []v1.EnvVar {
 	podEnv := []v1.EnvVar{
 		v1.EnvVar{
@@ -343,7 +925,10 @@ func createPodEnv(executionContext runtime.ExecutionContext) []v1.EnvVar {
 	return podEnv
 }
 
-func createExtraVars(targetNamespace string, parameters *bundle.Parameters, plan bundle.Plan) (string, error) {
+// createExtraVars returns the parameters passed to the bundle as compact
+// JSON, with its keys sorted so the same parameters give the same JSON. The
+// reserved keys APBs use for bookkeeping are left out when minimal is set.
+func createExtraVars(targetNamespace string, parameters *bundle.Parameters, plan bundle.Plan, classID string, account string, minimal bool) (string, error) {
 	var paramsCopy bundle.Parameters
 	if parameters != nil && *parameters != nil {
 		paramsCopy = *parameters
@@ -354,15 +939,30 @@ func createExtraVars(targetNamespace string, parameters *bundle.Parameters, plan
 	if targetNamespace != "" {
 		paramsCopy["namespace"] = targetNamespace
 	}
+	if minimal {
+		extraVars, err := json.Marshal(paramsCopy)
+		return string(extraVars), err
+	}
 
 	paramsCopy["cluster"] = "openshift"
 	paramsCopy["_apb_plan_id"] = plan.Name
 	paramsCopy["_apb_service_instance_id"] = "1234"
-	paramsCopy["_apb_service_class_id"] = "1234"
+	paramsCopy["_apb_service_class_id"] = classID
+	paramsCopy["_apb_account"] = account
 	extraVars, err := json.Marshal(paramsCopy)
 	return string(extraVars), err
 }
 
+// serviceClassID returns the spec's ID when it carries one. Otherwise a
+// name-based UUID is derived from the spec's FQName, so every run of the
+// same bundle passes the bundle the same class id.
+func serviceClassID(spec *bundle.Spec) string {
+	if spec.ID != "" {
+		return spec.ID
+	}
+	return uuid.NewSHA1(uuid.NameSpace_URL, []byte(spec.FQName)).String()
+}
+
 func pruneInput(input string, param bundle.ParameterDescriptor) (interface{}, error) {
 	var output interface{}
 	var err error
@@ -386,6 +986,11 @@ func pruneInput(input string, param bundle.ParameterDescriptor) (interface{}, er
 		if err != nil {
 			return nil, errors.New("Input must be a float")
 		}
+	case "array":
+		output, err = parseItems(input)
+		if err != nil {
+			return nil, err
+		}
 	default:
 		output = input
 	}
@@ -1,8 +1,21 @@
 package runner
 
 import (
-	"github.com/automationbroker/bundle-lib/bundle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/automationbroker/bundle-lib/runtime"
+	"k8s.io/api/core/v1"
 )
 
 func TestContains(t *testing.T) {
@@ -133,6 +146,22 @@ func TestPruneInput(t *testing.T) {
 			input:     "22.4",
 			shouldErr: false,
 		},
+		{
+			name: "test valid comma separated array",
+			param: bundle.ParameterDescriptor{
+				Type: "array",
+			},
+			input:     "a, b,c",
+			shouldErr: false,
+		},
+		{
+			name: "test invalid JSON array",
+			param: bundle.ParameterDescriptor{
+				Type: "array",
+			},
+			input:     "[\"a\",",
+			shouldErr: true,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -167,7 +196,560 @@ func TestPruneInput(t *testing.T) {
 					t.Fatalf("got unexpected output type [%v]. expected [bool]", tc.param.Type)
 					return
 				}
+			case []interface{}:
+				if tc.param.Type != "array" {
+					t.Fatalf("got unexpected output type [%v]. expected [array]", tc.param.Type)
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestSupportedActions(t *testing.T) {
+	plan := bundle.Plan{
+		Name: "default",
+		Parameters: []bundle.ParameterDescriptor{
+			{Name: "db_name", Type: "string"},
+			{Name: "db_size", Type: "int", Updatable: true},
+		},
+		BindParameters: []bundle.ParameterDescriptor{
+			{Name: "bind_user", Type: "string"},
+		},
+	}
+	testCases := []struct {
+		name    string
+		spec    *bundle.Spec
+		actions []string
+		params  map[string][]string
+	}{
+		{
+			name: "test actions declared in metadata",
+			spec: &bundle.Spec{
+				Metadata: map[string]interface{}{
+					"actions": []interface{}{"provision", "deprovision", "bind", "update"},
+				},
+				Plans: []bundle.Plan{plan},
+			},
+			actions: []string{"provision", "deprovision", "bind", "update"},
+			params: map[string][]string{
+				"provision":   {"db_name", "db_size"},
+				"deprovision": nil,
+				"bind":        {"bind_user"},
+				"update":      {"db_size"},
+			},
+		},
+		{
+			name: "test actions inferred from spec",
+			spec: &bundle.Spec{
+				Bindable: true,
+				Plans:    []bundle.Plan{plan},
+			},
+			actions: []string{"provision", "deprovision", "bind", "unbind", "update"},
+		},
+		{
+			name: "test actions without metadata or updatable params",
+			spec: &bundle.Spec{
+				Plans: []bundle.Plan{{Name: "default"}},
+			},
+			actions: []string{"provision", "deprovision"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actions := SupportedActions(tc.spec)
+			if !reflect.DeepEqual(actions, tc.actions) {
+				t.Fatalf("expected actions %v, got %v", tc.actions, actions)
+				return
+			}
+			for action, expected := range tc.params {
+				var names []string
+				for _, param := range ActionParameters(tc.spec.Plans[0], action) {
+					names = append(names, param.Name)
+				}
+				if !reflect.DeepEqual(names, expected) {
+					t.Fatalf("expected parameters %v for action [%v], got %v", expected, action, names)
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestEffectiveDefault(t *testing.T) {
+	testCases := []struct {
+		name     string
+		param    bundle.ParameterDescriptor
+		previous bundle.Parameters
+		expected interface{}
+	}{
+		{
+			name:     "test previous value overrides schema default",
+			param:    bundle.ParameterDescriptor{Name: "size", Default: "small"},
+			previous: bundle.Parameters{"size": "large"},
+			expected: "large",
+		},
+		{
+			name:     "test schema default without previous value",
+			param:    bundle.ParameterDescriptor{Name: "size", Default: "small"},
+			previous: bundle.Parameters{"other": "large"},
+			expected: "small",
+		},
+		{
+			name:     "test schema default without previous parameters",
+			param:    bundle.ParameterDescriptor{Name: "size", Default: "small"},
+			previous: nil,
+			expected: "small",
+		},
+		{
+			name:     "test no default",
+			param:    bundle.ParameterDescriptor{Name: "size"},
+			previous: nil,
+			expected: nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			value := effectiveDefault(tc.param, tc.previous)
+			if value != tc.expected {
+				t.Fatalf("expected default [%v], got [%v]", tc.expected, value)
+			}
+		})
+	}
+}
+
+func TestRecordableParameters(t *testing.T) {
+	plan := bundle.Plan{
+		Parameters: []bundle.ParameterDescriptor{
+			{Name: "user", Type: "string"},
+			{Name: "password", Type: "string", DisplayType: "password"},
+		},
+	}
+	params := bundle.Parameters{"user": "admin", "password": "secret"}
+	recordable := recordableParameters(plan, params)
+	if _, ok := recordable["password"]; ok {
+		t.Fatalf("password parameter should not be recorded")
+	}
+	if recordable["user"] != "admin" {
+		t.Fatalf("expected user parameter to be recorded, got [%v]", recordable["user"])
+	}
+}
+
+func TestServiceClassID(t *testing.T) {
+	withID := &bundle.Spec{ID: "0e991006d21029e47abe71acc255e807", FQName: "dh-postgresql-apb"}
+	if id := serviceClassID(withID); id != withID.ID {
+		t.Fatalf("expected spec ID [%v], got [%v]", withID.ID, id)
+	}
+
+	first := serviceClassID(&bundle.Spec{FQName: "dh-postgresql-apb"})
+	second := serviceClassID(&bundle.Spec{FQName: "dh-postgresql-apb"})
+	if first != second {
+		t.Fatalf("expected a stable class id, got [%v] and [%v]", first, second)
+	}
+	if other := serviceClassID(&bundle.Spec{FQName: "dh-mysql-apb"}); other == first {
+		t.Fatalf("expected different bundles to get different class ids, both got [%v]", first)
+	}
+}
+
+func TestCreateExtraVars(t *testing.T) {
+	plan := bundle.Plan{Name: "dev"}
+	reserved := []string{"cluster", "_apb_plan_id", "_apb_service_instance_id", "_apb_service_class_id", "_apb_account"}
+	testCases := []struct {
+		name     string
+		minimal  bool
+		expected map[string]interface{}
+		absent   []string
+	}{
+		{
+			name: "test full extra vars",
+			expected: map[string]interface{}{
+				"db_name":               "wiki",
+				"namespace":             "foo-ns",
+				"cluster":               "openshift",
+				"_apb_plan_id":          "dev",
+				"_apb_service_class_id": "class-1",
+				"_apb_account":          "deployer",
+			},
+		},
+		{
+			name:    "test minimal extra vars",
+			minimal: true,
+			expected: map[string]interface{}{
+				"db_name":   "wiki",
+				"namespace": "foo-ns",
+			},
+			absent: reserved,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params := bundle.Parameters{"db_name": "wiki"}
+			extraVars, err := createExtraVars("foo-ns", &params, plan, "class-1", "deployer", tc.minimal)
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			vars := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(extraVars), &vars); err != nil {
+				t.Fatalf("failed to parse extra vars: %v", err)
+			}
+			for key, value := range tc.expected {
+				if vars[key] != value {
+					t.Fatalf("expected [%v] to be [%v], got extra vars [%v]", key, value, extraVars)
+				}
+			}
+			for _, key := range tc.absent {
+				if _, ok := vars[key]; ok {
+					t.Fatalf("expected no [%v], got extra vars [%v]", key, extraVars)
+				}
+			}
+		})
+	}
+	extraVars, err := createExtraVars("foo-ns", nil, plan, "class-1", "deployer", false)
+	if err != nil || !strings.Contains(extraVars, `"namespace":"foo-ns"`) {
+		t.Fatalf("expected extra vars without parameters, got [%v] [%v]", extraVars, err)
+	}
+}
+
+func TestImagePullPolicy(t *testing.T) {
+	testCases := []struct {
+		name     string
+		image    string
+		opts     []Option
+		expected v1.PullPolicy
+	}{
+		{
+			name:     "test latest tag",
+			image:    "docker.io/ansibleplaybookbundle/mediawiki-apb:latest",
+			expected: v1.PullAlways,
+		},
+		{
+			name:     "test untagged image",
+			image:    "docker.io/ansibleplaybookbundle/mediawiki-apb",
+			expected: v1.PullAlways,
+		},
+		{
+			name:     "test registry with port",
+			image:    "registry.local:5000/mediawiki-apb:v1.2",
+			expected: v1.PullAlways,
+		},
+		{
+			name:     "test digest",
+			image:    "docker.io/ansibleplaybookbundle/mediawiki-apb@sha256:0b4a8e8d8c7a6f0f1c4e4a2b9f3b8f0a3c3b0e1d2c3b4a5f6e7d8c9b0a1f2e3d",
+			expected: v1.PullIfNotPresent,
+		},
+		{
+			name:     "test tag and digest",
+			image:    "registry.local:5000/mediawiki-apb:v1.2@sha256:0b4a8e8d8c7a6f0f1c4e4a2b9f3b8f0a3c3b0e1d2c3b4a5f6e7d8c9b0a1f2e3d",
+			expected: v1.PullIfNotPresent,
+		},
+		{
+			name:     "test explicit policy overrides digest",
+			image:    "docker.io/ansibleplaybookbundle/mediawiki-apb@sha256:0b4a8e8d8c7a6f0f1c4e4a2b9f3b8f0a3c3b0e1d2c3b4a5f6e7d8c9b0a1f2e3d",
+			opts:     []Option{WithImagePullPolicy("Always")},
+			expected: v1.PullAlways,
+		},
+		{
+			name:     "test explicit policy overrides tag",
+			image:    "docker.io/ansibleplaybookbundle/mediawiki-apb:latest",
+			opts:     []Option{WithImagePullPolicy("Never")},
+			expected: v1.PullNever,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := newOptions(tc.opts)
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if policy := imagePullPolicy(o, tc.image); policy != tc.expected {
+				t.Fatalf("expected pull policy [%v], got [%v]", tc.expected, policy)
+			}
+		})
+	}
+}
+
+func TestBuildPod(t *testing.T) {
+	ec := runtime.ExecutionContext{
+		BundleName: "bundle-1234",
+		Metadata:   map[string]string{"bundle-action": "provision"},
+		Action:     "provision",
+		Image:      "docker.io/ansibleplaybookbundle/mediawiki-apb:latest",
+		Account:    "bundle-1234",
+		Location:   "foo-ns",
+		ExtraVars:  `{"namespace":"foo-ns"}`,
+	}
+	fsGroup := int64(2000)
+	testCases := []struct {
+		name       string
+		opts       []Option
+		pullPolicy v1.PullPolicy
+		dnsPolicy  v1.DNSPolicy
+		fsGroup    *int64
+		scheduler  string
+		runID      string
+		shouldErr  bool
+	}{
+		{
+			name:       "test default pod",
+			pullPolicy: v1.PullAlways,
+		},
+		{
+			name:       "test local bundle is not pulled",
+			opts:       []Option{WithLocalBundle("mediawiki-apb")},
+			pullPolicy: v1.PullIfNotPresent,
+		},
+		{
+			name:       "test explicit pull policy",
+			opts:       []Option{WithImagePullPolicy("IfNotPresent")},
+			pullPolicy: v1.PullIfNotPresent,
+		},
+		{
+			name:       "test auto pull policy",
+			opts:       []Option{WithImagePullPolicy("IfNotPresent"), WithImagePullPolicy(AutoPullPolicy)},
+			pullPolicy: v1.PullAlways,
+		},
+		{
+			name:       "test local bundle ignores pull policy",
+			opts:       []Option{WithLocalBundle("mediawiki-apb"), WithImagePullPolicy("Always")},
+			pullPolicy: v1.PullIfNotPresent,
+		},
+		{
+			name:      "test invalid pull policy",
+			opts:      []Option{WithImagePullPolicy("Sometimes")},
+			shouldErr: true,
+		},
+		{
+			name:       "test DNS policy",
+			opts:       []Option{WithDNSPolicy("Default")},
+			pullPolicy: v1.PullAlways,
+			dnsPolicy:  v1.DNSDefault,
+		},
+		{
+			name:       "test fsGroup",
+			opts:       []Option{WithFSGroup(fsGroup)},
+			pullPolicy: v1.PullAlways,
+			fsGroup:    &fsGroup,
+		},
+		{
+			name:       "test scheduler name",
+			opts:       []Option{WithSchedulerName("batch-scheduler")},
+			pullPolicy: v1.PullAlways,
+			scheduler:  "batch-scheduler",
+		},
+		{
+			name:      "test empty scheduler name",
+			opts:      []Option{WithSchedulerName("")},
+			shouldErr: true,
+		},
+		{
+			name:      "test invalid scheduler name",
+			opts:      []Option{WithSchedulerName("Batch Scheduler")},
+			shouldErr: true,
+		},
+		{
+			name:       "test run id",
+			opts:       []Option{WithRunID("JIRA-1234")},
+			pullPolicy: v1.PullAlways,
+			runID:      "JIRA-1234",
+		},
+		{
+			name:      "test invalid run id",
+			opts:      []Option{WithRunID("pipeline run/42")},
+			shouldErr: true,
+		},
+		{
+			name:      "test invalid option",
+			opts:      []Option{WithDNSPolicy("ClusterLast")},
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod, err := BuildPod(ec, tc.opts...)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("expected error but pod was built")
+			}
+			if pod.Name != ec.BundleName || pod.Namespace != ec.Location || pod.Spec.ServiceAccountName != ec.Account {
+				t.Fatalf("unexpected pod metadata [%v/%v] with account [%v]", pod.Namespace, pod.Name, pod.Spec.ServiceAccountName)
+			}
+			if pod.Spec.RestartPolicy != v1.RestartPolicyNever {
+				t.Fatalf("expected restart policy [%v], got [%v]", v1.RestartPolicyNever, pod.Spec.RestartPolicy)
+			}
+			container := pod.Spec.Containers[0]
+			expectedArgs := []string{"provision", "--extra-vars", ec.ExtraVars}
+			if container.Image != ec.Image || !reflect.DeepEqual(container.Args, expectedArgs) {
+				t.Fatalf("unexpected container image [%v] and args %v", container.Image, container.Args)
+			}
+			if container.ImagePullPolicy != tc.pullPolicy {
+				t.Fatalf("expected pull policy [%v], got [%v]", tc.pullPolicy, container.ImagePullPolicy)
+			}
+			if pod.Spec.DNSPolicy != tc.dnsPolicy {
+				t.Fatalf("expected DNS policy [%v], got [%v]", tc.dnsPolicy, pod.Spec.DNSPolicy)
+			}
+			if pod.Spec.SchedulerName != tc.scheduler {
+				t.Fatalf("expected scheduler [%v], got [%v]", tc.scheduler, pod.Spec.SchedulerName)
+			}
+			if pod.Labels[runIDLabel] != tc.runID || pod.Annotations[runIDLabel] != tc.runID {
+				t.Fatalf("expected run id [%v], got label [%v] and annotation [%v]", tc.runID, pod.Labels[runIDLabel], pod.Annotations[runIDLabel])
+			}
+			if pod.Labels["bundle-action"] != "provision" {
+				t.Fatalf("expected the execution context's labels, got [%v]", pod.Labels)
+			}
+			if _, ok := ec.Metadata[runIDLabel]; ok {
+				t.Fatalf("expected the execution context's metadata to be left unchanged, got [%v]", ec.Metadata)
+			}
+			if tc.fsGroup == nil && pod.Spec.SecurityContext != nil {
+				t.Fatalf("expected no security context, got [%v]", pod.Spec.SecurityContext)
+			}
+			if tc.fsGroup != nil && *pod.Spec.SecurityContext.FSGroup != *tc.fsGroup {
+				t.Fatalf("expected fsGroup [%v], got [%v]", *tc.fsGroup, *pod.Spec.SecurityContext.FSGroup)
+			}
+		})
+	}
+}
+
+// staticSpecs provides a fixed list of specs
+type staticSpecs []*bundle.Spec
+
+func (s staticSpecs) List() ([]*bundle.Spec, error) {
+	return s, nil
+}
+
+func (s staticSpecs) Get(fqname string) (*bundle.Spec, error) {
+	for _, spec := range s {
+		if spec.FQName == fqname {
+			return spec, nil
+		}
+	}
+	return nil, specNotFound(fqname, "")
+}
+
+func TestRunBundleValidateOnly(t *testing.T) {
+	specs := staticSpecs{{
+		FQName: "postgresql-apb",
+		Image:  "docker.io/ansibleplaybookbundle/postgresql-apb:latest",
+		Plans:  []bundle.Plan{databasePlan},
+	}}
+	testCases := []struct {
+		name        string
+		values      []string
+		constraints []string
+	}{
+		{
+			name:   "test valid parameters",
+			values: []string{"db_name=wiki", "db_version=9.5"},
+		},
+		{
+			name:        "test every invalid parameter is reported",
+			values:      []string{"db_size=large", "db_version=9.4"},
+			constraints: []string{"required", "type", "enum"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// there is no cluster, so the run fails if it reaches for one
+			podName, err := RunBundle("provision", "", "postgresql-apb", "", "", false, false, nil,
+				WithSpecProvider(specs), WithParameterValues(tc.values), WithValidateOnly())
+			if podName != "" {
+				t.Fatalf("expected no pod, got [%v]", podName)
+			}
+			if len(tc.constraints) == 0 {
+				if err != nil {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			verrs, ok := err.(ValidationErrors)
+			if !ok {
+				t.Fatalf("expected validation errors, got [%v]", err)
+			}
+			var constraints []string
+			for _, verr := range verrs {
+				constraints = append(constraints, verr.Constraint)
+			}
+			sort.Strings(constraints)
+			expected := append([]string{}, tc.constraints...)
+			sort.Strings(expected)
+			if !reflect.DeepEqual(constraints, expected) {
+				t.Fatalf("expected constraints %v, got %v", expected, constraints)
 			}
 		})
 	}
 }
+
+func TestRunBundleErrors(t *testing.T) {
+	specs := staticSpecs{{
+		FQName: "postgresql-apb",
+		Image:  "docker.io/ansibleplaybookbundle/postgresql-apb:latest",
+		Plans:  []bundle.Plan{databasePlan},
+	}}
+	testCases := []struct {
+		name       string
+		bundleName string
+		opts       []Option
+		expected   string
+	}{
+		{
+			name:       "test missing APB",
+			bundleName: "mysql-apb",
+			expected:   `could not find APB "mysql-apb"`,
+		},
+		{
+			name:       "test missing plan",
+			bundleName: "postgresql-apb",
+			opts:       []Option{WithPlan("prod")},
+			expected:   "did not find plan [prod]",
+		},
+		{
+			name:       "test invalid parameters",
+			bundleName: "postgresql-apb",
+			opts:       []Option{WithNonInteractive()},
+			expected:   "Parameter [db_name] is required",
+		},
+		{
+			name:       "test invalid option",
+			bundleName: "postgresql-apb",
+			opts:       []Option{WithDeadline(-time.Minute)},
+			expected:   "invalid deadline",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append([]Option{WithSpecProvider(specs)}, tc.opts...)
+			_, err := RunBundle("provision", "web", tc.bundleName, "edit", "", false, false, nil, opts...)
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Fatalf("expected error [%v], got [%v]", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestRunBundleMissingSpec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-specs")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "specs.yaml")
+	specs := "- name: postgresql-apb\n  image: docker.io/ansibleplaybookbundle/postgresql-apb:latest\n"
+	if err := ioutil.WriteFile(path, []byte(specs), 0600); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	podName, err := RunBundle("provision", "web", "mysql-apb", "edit", "", false, false, nil, WithSpecProvider(NewFileSpecProvider(path)))
+	if podName != "" {
+		t.Fatalf("expected no pod, got [%v]", podName)
+	}
+	expected := fmt.Sprintf("could not find APB %q in file [%v]", "mysql-apb", path)
+	if err == nil || err.Error() != expected {
+		t.Fatalf("expected error [%v], got [%v]", expected, err)
+	}
+	if !errors.Is(err, ErrSpecNotFound) {
+		t.Fatalf("expected [%v] to be an ErrSpecNotFound", err)
+	}
+}
@@ -0,0 +1,140 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/lestrrat/go-jsschema"
+	log "github.com/sirupsen/logrus"
+)
+
+// schemaActions are the actions whose parameters have a JSON schema
+var schemaActions = []string{"provision", "bind"}
+
+// actionSchema returns the JSON schema the parameters of the action are
+// validated against: the service instance schema generated from the plan's
+// parameters for provision, and the service binding one generated from its
+// bind parameters for bind
+func actionSchema(plan bundle.Plan, action string) (*schema.Schema, error) {
+	if !contains(schemaActions, action) {
+		return nil, fmt.Errorf("no schema for action [%v]. Actions with schemas: %v", action, schemaActions)
+	}
+	schemaPlan, err := bundle.ConvertPlansToSchema([]bundle.Plan{plan})
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert plan [%v] to JSON schema: %v", plan.Name, err)
+	}
+	s := schemaPlan[0].Schemas.ServiceInstance.Create["parameters"]
+	if action == "bind" {
+		s = schemaPlan[0].Schemas.ServiceBinding.Create["parameters"]
+	}
+	allowObjectProperties(s)
+	return s, nil
+}
+
+// PlanSchema returns the JSON schema the parameters of the plan are
+// validated against for the action, provision or bind, as written by
+// WriteSchema. A parameter with dependencies is never required by it, since
+// whether it applies depends on the other values.
+func PlanSchema(plan bundle.Plan, action string) (*schema.Schema, error) {
+	independent := plan
+	independent.Parameters = withoutConditionalRequired(plan.Parameters)
+	independent.BindParameters = withoutConditionalRequired(plan.BindParameters)
+	return actionSchema(independent, action)
+}
+
+func withoutConditionalRequired(params []bundle.ParameterDescriptor) []bundle.ParameterDescriptor {
+	var copied []bundle.ParameterDescriptor
+	for _, param := range params {
+		if len(param.Dependencies) > 0 {
+			param.Required = false
+		}
+		copied = append(copied, param)
+	}
+	return copied
+}
+
+// WriteSchema writes the JSON schema of the parameters of the bundle's plan
+// for the action, provision or bind, to w, for front-ends to render forms
+// which validate as the runner does. The bundle is found in the configured
+// registries and its shared parameter defaults are applied to the plan.
+func WriteSchema(bundleName string, planName string, action string, w io.Writer) error {
+	spec, err := FindSpec(bundleName, "")
+	if err != nil {
+		return err
+	}
+	if planName == "" && len(spec.Plans) != 1 {
+		return fmt.Errorf("APB [%v] has several plans. Name the one to write the schema of", bundleName)
+	}
+	plan, err := selectPlan(spec, planName, "", nil)
+	if err != nil {
+		return err
+	}
+	return writePlanSchema(spec, plan, action, w)
+}
+
+// WriteSchemas writes the JSON schema of each of the bundle's plans for the
+// action into dir, as <plan>.<action>.json. It returns the paths of the
+// files written.
+func WriteSchemas(bundleName string, action string, dir string) ([]string, error) {
+	spec, err := FindSpec(bundleName, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, plan := range spec.Plans {
+		path := filepath.Join(dir, fmt.Sprintf("%v.%v.json", plan.Name, action))
+		f, err := os.Create(path)
+		if err != nil {
+			return written, err
+		}
+		err = writePlanSchema(spec, plan, action, f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+func writePlanSchema(spec *bundle.Spec, plan bundle.Plan, action string, w io.Writer) error {
+	plan, err := applyBundleDefaults(spec, plan)
+	if err != nil {
+		log.Warningf("Ignoring parameter defaults of APB [%v]: %v", spec.FQName, err)
+	}
+	s, err := PlanSchema(plan, action)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the schema of plan [%v]: %v", plan.Name, err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", out)
+	return err
+}
@@ -0,0 +1,148 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/automationbroker/bundle-lib/registries"
+	"github.com/lestrrat/go-jsschema/validator"
+)
+
+var schemaPlan = bundle.Plan{
+	Name: "dev",
+	Parameters: []bundle.ParameterDescriptor{
+		{Name: "site_name", Type: "string", Required: true, Pattern: "^[a-z]+$"},
+		{Name: "replicas", Type: "int", Default: 1},
+		{Name: "settings", Type: "object"},
+		{Name: "client_id", Type: "string", Required: true, Dependencies: []bundle.Dependency{{Key: "auth_type", Value: "oauth"}}},
+		{Name: "auth_type", Type: "enum", Enum: []string{"basic", "oauth"}},
+	},
+	BindParameters: []bundle.ParameterDescriptor{
+		{Name: "bind_user", Type: "string", Required: true},
+	},
+}
+
+func TestPlanSchema(t *testing.T) {
+	testCases := []struct {
+		name       string
+		action     string
+		properties []string
+		required   []string
+		valid      map[string]interface{}
+		invalid    map[string]interface{}
+		shouldErr  bool
+	}{
+		{
+			name:       "test provision schema",
+			action:     "provision",
+			properties: []string{"auth_type", "client_id", "replicas", "settings", "site_name"},
+			required:   []string{"site_name"},
+			valid:      map[string]interface{}{"site_name": "wiki", "settings": map[string]interface{}{"debug": true}},
+			invalid:    map[string]interface{}{"site_name": "My Wiki"},
+		},
+		{
+			name:       "test bind schema",
+			action:     "bind",
+			properties: []string{"bind_user"},
+			required:   []string{"bind_user"},
+			valid:      map[string]interface{}{"bind_user": "admin"},
+			invalid:    map[string]interface{}{},
+		},
+		{
+			name:      "test action without schema",
+			action:    "deprovision",
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := PlanSchema(schemaPlan, tc.action)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("expected error but got a schema")
+			}
+			var properties []string
+			for name := range s.Properties {
+				properties = append(properties, name)
+			}
+			sort.Strings(properties)
+			if !reflect.DeepEqual(properties, tc.properties) {
+				t.Fatalf("expected properties %v, got %v", tc.properties, properties)
+			}
+			if !reflect.DeepEqual(s.Required, tc.required) {
+				t.Fatalf("expected required %v, got %v", tc.required, s.Required)
+			}
+			if err := validator.New(s).Validate(tc.valid); err != nil {
+				t.Fatalf("expected %v to be valid, got [%v]", tc.valid, err)
+			}
+			if err := validator.New(s).Validate(tc.invalid); err == nil {
+				t.Fatalf("expected %v to be invalid", tc.invalid)
+			}
+		})
+	}
+}
+
+func TestWriteSchema(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-schema")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	defer os.RemoveAll(dir)
+	saved := config.Registries
+	defer func() { config.Registries = saved }()
+	config.Registries, _ = config.InitJSONConfig(dir, "registries")
+	prod := schemaPlan
+	prod.Name = "prod"
+	spec := &bundle.Spec{FQName: "mediawiki-apb", Plans: []bundle.Plan{schemaPlan, prod}}
+	err = config.UpdateCachedRegistries(config.Registries, []config.Registry{
+		{Config: registries.Config{Name: "docker"}, Specs: []*bundle.Spec{spec}},
+	})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+
+	var out bytes.Buffer
+	if err := WriteSchema("mediawiki-apb", "dev", "bind", &out); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	written := map[string]interface{}{}
+	if err := json.Unmarshal(out.Bytes(), &written); err != nil {
+		t.Fatalf("expected a JSON schema, got [%v]:\n%v", err, out.String())
+	}
+	if _, ok := written["properties"].(map[string]interface{})["bind_user"]; !ok {
+		t.Fatalf("expected the bind schema, got:\n%v", out.String())
+	}
+	if err := WriteSchema("mediawiki-apb", "", "provision", &out); err == nil || !strings.Contains(err.Error(), "several plans") {
+		t.Fatalf("expected an error without a plan, got [%v]", err)
+	}
+	if err := WriteSchema("mediawiki-apb", "staging", "provision", &out); err == nil {
+		t.Fatalf("expected an error for a missing plan")
+	}
+
+	files, err := WriteSchemas("mediawiki-apb", "provision", filepath.Join(dir, "schemas"))
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	expected := []string{filepath.Join(dir, "schemas", "dev.provision.json"), filepath.Join(dir, "schemas", "prod.provision.json")}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("expected files %v, got %v", expected, files)
+	}
+	data, err := ioutil.ReadFile(files[1])
+	if err != nil || !strings.Contains(string(data), "site_name") {
+		t.Fatalf("expected the provision schema of prod, got [%v]:\n%s", err, data)
+	}
+}
@@ -0,0 +1,157 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+// runScript describes an apb invocation to be written out as a script
+type runScript struct {
+	Action    string
+	Bundle    string
+	Local     bool
+	Registry  string
+	Namespace string
+	Plan      bundle.Plan
+	Params    bundle.Parameters
+	// Flags are the other arguments the run was given, as --name=value
+	Flags []string
+}
+
+var invalidEnvChars = regexp.MustCompile("[^A-Z0-9_]+")
+
+var safeShellWord = regexp.MustCompile("^[A-Za-z0-9_./:=@,+-]+$")
+
+// writeRunScript writes a shell script which runs the same action of the
+// same bundle with the same plan and parameters, without prompting
+func writeRunScript(path string, run runScript) error {
+	if err := ioutil.WriteFile(path, []byte(run.render()), 0755); err != nil {
+		return fmt.Errorf("failed to write script [%v]: %v", path, err)
+	}
+	fmt.Printf("Wrote script to [%v]\n", path)
+	return nil
+}
+
+// render returns the script. Sensitive parameters are not written out, they
+// are read from environment variables which the script requires to be set.
+func (r runScript) render() string {
+	args, sensitive := r.command()
+
+	var script bytes.Buffer
+	script.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&script, "# Runs the %v action of APB [%v] with plan [%v]\n", r.Action, r.Bundle, r.Plan.Name)
+	script.WriteString("set -e\n\n")
+	for _, name := range sensitive {
+		env := parameterEnvVar(name)
+		fmt.Fprintf(&script, ": \"${%s:?set %s to the value of parameter %s}\"\n", env, env, name)
+	}
+	if len(sensitive) > 0 {
+		script.WriteString("\n")
+	}
+	script.WriteString(strings.Join(args, " \\\n    "))
+	script.WriteString("\n")
+	return script.String()
+}
+
+// commandLine returns the apb command repeating the run on one line
+func (r runScript) commandLine() string {
+	args, _ := r.command()
+	return strings.Join(args, " ")
+}
+
+// command returns the arguments of the apb command repeating the run, one
+// flag per argument, and the names of the sensitive parameters it reads
+// from the environment
+func (r runScript) command() ([]string, []string) {
+	var names []string
+	for name := range r.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := []string{fmt.Sprintf("apb bundle %s %s", r.Action, shellQuote(r.Bundle))}
+	if r.Local {
+		args = append(args, "--local")
+	}
+	if r.Registry != "" {
+		args = append(args, "--registry "+shellQuote(r.Registry))
+	}
+	args = append(args, "--namespace "+shellQuote(r.Namespace))
+	if r.Plan.Name != "" {
+		args = append(args, "--plan "+shellQuote(r.Plan.Name))
+	}
+	for _, flag := range r.Flags {
+		args = append(args, shellWord(flag))
+	}
+	args = append(args, "--non-interactive")
+	var sensitive []string
+	for _, name := range names {
+		if pd := r.Plan.GetParameter(name); pd != nil && isSensitive(*pd) {
+			sensitive = append(sensitive, name)
+			args = append(args, fmt.Sprintf("--set \"%s=${%s}\"", name, parameterEnvVar(name)))
+			continue
+		}
+		flag := "--set"
+		if pd := r.Plan.GetParameter(name); pd != nil && pd.Type == "object" {
+			// --set would keep the object as a string
+			if _, ok := r.Params[name].(string); !ok {
+				flag = "--set-json"
+			}
+		}
+		args = append(args, flag+" "+shellQuote(fmt.Sprintf("%s=%s", name, scriptValue(r.Params[name]))))
+	}
+	return args, sensitive
+}
+
+// parameterEnvVar returns the environment variable a sensitive parameter is
+// read from
+func parameterEnvVar(name string) string {
+	return "APB_PARAM_" + invalidEnvChars.ReplaceAllString(strings.ToUpper(name), "_")
+}
+
+// scriptValue returns the parameter value the way it would be typed
+func scriptValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// shellWord quotes s only if the shell would otherwise interpret it
+func shellWord(s string) string {
+	if safeShellWord.MatchString(s) {
+		return s
+	}
+	return shellQuote(s)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'"'"'`, -1) + "'"
+}
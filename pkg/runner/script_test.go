@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+func TestRunScript(t *testing.T) {
+	run := runScript{
+		Action:    "provision",
+		Bundle:    "mediawiki-apb",
+		Registry:  "dockerhub",
+		Namespace: "wiki",
+		Plan: bundle.Plan{
+			Name: "default",
+			Parameters: []bundle.ParameterDescriptor{
+				{Name: "admin_user", Type: "string"},
+				{Name: "admin_pass", Type: "string", DisplayType: "password"},
+				{Name: "replicas", Type: "int"},
+				{Name: "site_name", Type: "string"},
+			},
+		},
+		Params: bundle.Parameters{
+			"admin_user": "admin",
+			"admin_pass": "s3cret",
+			"replicas":   int64(2),
+			"site_name":  "Bob's wiki",
+		},
+		Flags: []string{"--sandbox-role=admin", "--dns-search=corp.example.com", "--namespace-label=owner=Bob Smith"},
+	}
+	script := run.render()
+
+	expected := []string{
+		"#!/bin/sh\n",
+		`: "${APB_PARAM_ADMIN_PASS:?set APB_PARAM_ADMIN_PASS to the value of parameter admin_pass}"`,
+		"apb bundle provision 'mediawiki-apb'",
+		"--registry 'dockerhub'",
+		"--namespace 'wiki'",
+		"--plan 'default'",
+		"--sandbox-role=admin",
+		"--dns-search=corp.example.com",
+		`'--namespace-label=owner=Bob Smith'`,
+		"--non-interactive",
+		`--set "admin_pass=${APB_PARAM_ADMIN_PASS}"`,
+		"--set 'admin_user=admin'",
+		"--set 'replicas=2'",
+		`--set 'site_name=Bob'"'"'s wiki'`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(script, e) {
+			t.Fatalf("expected script to contain [%v], got:\n%v", e, script)
+		}
+	}
+	if strings.Contains(script, "s3cret") {
+		t.Fatalf("expected sensitive value to be left out of the script, got:\n%v", script)
+	}
+	if strings.Index(script, "admin_pass=") > strings.Index(script, "admin_user=") {
+		t.Fatalf("expected parameters in name order, got:\n%v", script)
+	}
+}
+
+func TestRunCommandLine(t *testing.T) {
+	run := runScript{
+		Action:    "deprovision",
+		Bundle:    "mediawiki-apb",
+		Namespace: "wiki",
+		Plan: bundle.Plan{
+			Parameters: []bundle.ParameterDescriptor{
+				{Name: "admin_pass", Type: "string", DisplayType: "password"},
+			},
+		},
+		Params: bundle.Parameters{"admin_pass": "s3cret"},
+		Flags:  []string{"--follow"},
+	}
+	expected := `apb bundle deprovision 'mediawiki-apb' --namespace 'wiki' --follow --non-interactive --set "admin_pass=${APB_PARAM_ADMIN_PASS}"`
+	if line := run.commandLine(); line != expected {
+		t.Fatalf("expected command [%v], got [%v]", expected, line)
+	}
+}
+
+func TestParameterEnvVar(t *testing.T) {
+	if env := parameterEnvVar("db-password.v2"); env != "APB_PARAM_DB_PASSWORD_V2" {
+		t.Fatalf("expected [APB_PARAM_DB_PASSWORD_V2], got [%v]", env)
+	}
+}
+
+func TestRunCommandLineObject(t *testing.T) {
+	run := runScript{
+		Action:    "provision",
+		Bundle:    "mediawiki-apb",
+		Namespace: "wiki",
+		Plan: bundle.Plan{
+			Parameters: []bundle.ParameterDescriptor{
+				{Name: "app", Type: "string"},
+				{Name: "labels", Type: "object"},
+			},
+		},
+		Params: bundle.Parameters{"app": "wiki", "labels": map[string]interface{}{"tier": "web"}},
+	}
+	expected := `apb bundle provision 'mediawiki-apb' --namespace 'wiki' --non-interactive --set 'app=wiki' --set-json 'labels={"tier":"web"}'`
+	if line := run.commandLine(); line != expected {
+		t.Fatalf("expected command [%v], got [%v]", expected, line)
+	}
+
+	o, err := newOptions([]Option{WithJSONParameterValues([]string{`labels={"tier":"web"}`}), WithParameterValues([]string{"app=wiki"})})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	params, err := collectParameters(run.Plan, nil, o.parameterValues, o.jsonParameters, nil, nil, false)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if !reflect.DeepEqual(params, run.Params) {
+		t.Fatalf("expected the command to give parameters %v, got %v", run.Params, params)
+	}
+}
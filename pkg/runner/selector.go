@@ -0,0 +1,250 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/automationbroker/apb/pkg/util"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Selector asks the user to choose one of a list of choices. The kind names
+// what is being chosen, e.g. "plan".
+type Selector interface {
+	Select(kind string, choices []string) (string, error)
+}
+
+// errSelectionAborted is returned when the user interrupts a selection
+var errSelectionAborted = errors.New("selection aborted")
+
+// menuHeight is the most choices the menu shows at once
+const menuHeight = 10
+
+// NewSelector returns an arrow-key menu when both stdin and stdout are
+// terminals, and a plain prompt otherwise or when noTUI is set. The menu
+// highlights the current choice when util.Color allows it.
+func NewSelector(noTUI bool) Selector {
+	if !noTUI && terminal.IsTerminal(int(os.Stdin.Fd())) && terminal.IsTerminal(int(os.Stdout.Fd())) {
+		return &MenuSelector{fd: int(os.Stdin.Fd()), in: os.Stdin, out: os.Stdout, color: util.Color(os.Stdout)}
+	}
+	return &PromptSelector{in: os.Stdin, out: os.Stdout}
+}
+
+// PromptSelector lists the choices and reads the name of one
+type PromptSelector struct {
+	in  io.Reader
+	out io.Writer
+}
+
+// Select prompts until the name of one of the choices is entered
+func (p *PromptSelector) Select(kind string, choices []string) (string, error) {
+	for {
+		fmt.Fprintf(p.out, "List of available %vs:\n", kind)
+		for _, choice := range choices {
+			fmt.Fprintf(p.out, "name: %v\n", choice)
+		}
+		fmt.Fprintf(p.out, "Enter name of %v to execute: ", kind)
+		var name string
+		_, err := fmt.Fscanln(p.in, &name)
+		if err == io.EOF {
+			return "", errSelectionAborted
+		}
+		if contains(choices, name) {
+			return name, nil
+		}
+		fmt.Fprintf(p.out, "Did not find %v [%v], try again.\n\n", kind, name)
+	}
+}
+
+// nonInteractiveSelector refuses to choose, since there is no one to ask
+type nonInteractiveSelector struct{}
+
+func (nonInteractiveSelector) Select(kind string, choices []string) (string, error) {
+	return "", fmt.Errorf("a %v must be given when not running interactively. Available: %v", kind, choices)
+}
+
+// MenuSelector lets the user move through the choices with the arrow keys
+// and filter them by typing
+type MenuSelector struct {
+	fd    int
+	in    io.Reader
+	out   io.Writer
+	color bool
+}
+
+// Select shows the menu until a choice is made or it is interrupted
+func (s *MenuSelector) Select(kind string, choices []string) (string, error) {
+	oldState, err := terminal.MakeRaw(s.fd)
+	if err != nil {
+		return "", err
+	}
+	defer terminal.Restore(s.fd, oldState)
+
+	m := &menu{choices: choices, color: s.color}
+	lines := 0
+	for {
+		lines = m.render(s.out, kind, lines)
+		key, r, err := readKey(s.in)
+		if err != nil {
+			return "", err
+		}
+		choice, done := m.handle(key, r)
+		if !done {
+			continue
+		}
+		fmt.Fprintf(s.out, "\033[%dA\r\033[J", lines)
+		if choice == "" {
+			return "", errSelectionAborted
+		}
+		fmt.Fprintf(s.out, "Selected %v [%v]\r\n", kind, choice)
+		return choice, nil
+	}
+}
+
+type menuKey int
+
+const (
+	keyRune menuKey = iota
+	keyUp
+	keyDown
+	keyEnter
+	keyBackspace
+	keyInterrupt
+	keyIgnored
+)
+
+// readKey reads one key press from a terminal in raw mode
+func readKey(r io.Reader) (menuKey, rune, error) {
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return keyIgnored, 0, err
+	}
+	switch b[0] {
+	case '\r', '\n':
+		return keyEnter, 0, nil
+	case 127, '\b':
+		return keyBackspace, 0, nil
+	case 3, 4:
+		return keyInterrupt, 0, nil
+	case 27:
+		seq := make([]byte, 2)
+		if _, err := io.ReadFull(r, seq); err != nil {
+			return keyIgnored, 0, err
+		}
+		if seq[0] == '[' && seq[1] == 'A' {
+			return keyUp, 0, nil
+		}
+		if seq[0] == '[' && seq[1] == 'B' {
+			return keyDown, 0, nil
+		}
+		return keyIgnored, 0, nil
+	}
+	if b[0] >= ' ' && b[0] < 127 {
+		return keyRune, rune(b[0]), nil
+	}
+	return keyIgnored, 0, nil
+}
+
+// menu is the state of a MenuSelector: the filter typed so far and the
+// position of the cursor among the choices matching it
+type menu struct {
+	choices []string
+	filter  string
+	cursor  int
+	// color shows the current choice in reverse video
+	color bool
+}
+
+// matches returns the choices containing the filter, ignoring case
+func (m *menu) matches() []string {
+	var matches []string
+	for _, choice := range m.choices {
+		if strings.Contains(strings.ToLower(choice), strings.ToLower(m.filter)) {
+			matches = append(matches, choice)
+		}
+	}
+	return matches
+}
+
+// handle applies a key press. It reports whether the selection is done,
+// with the chosen value or an empty one when the menu was interrupted.
+func (m *menu) handle(key menuKey, r rune) (string, bool) {
+	matches := m.matches()
+	switch key {
+	case keyUp:
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case keyDown:
+		if m.cursor < len(matches)-1 {
+			m.cursor++
+		}
+	case keyEnter:
+		if len(matches) > 0 {
+			return matches[m.cursor], true
+		}
+	case keyBackspace:
+		if len(m.filter) > 0 {
+			m.filter = m.filter[:len(m.filter)-1]
+			m.cursor = 0
+		}
+	case keyRune:
+		m.filter += string(r)
+		m.cursor = 0
+	case keyInterrupt:
+		return "", true
+	}
+	return "", false
+}
+
+// render draws the menu over the previous lines drawn and returns the
+// number of lines it drew
+func (m *menu) render(w io.Writer, kind string, previous int) int {
+	if previous > 0 {
+		fmt.Fprintf(w, "\033[%dA", previous)
+	}
+	fmt.Fprintf(w, "\r\033[J")
+	fmt.Fprintf(w, "Select %v (arrow keys to move, type to filter): %v\r\n", kind, m.filter)
+	lines := 1
+	matches := m.matches()
+	start := 0
+	if m.cursor >= menuHeight {
+		start = m.cursor - menuHeight + 1
+	}
+	for i := start; i < len(matches) && i < start+menuHeight; i++ {
+		marker := " "
+		choice := matches[i]
+		if i == m.cursor {
+			marker = ">"
+			if m.color {
+				choice = "\033[7m" + choice + "\033[0m"
+			}
+		}
+		fmt.Fprintf(w, "%v %v\r\n", marker, choice)
+		lines++
+	}
+	if len(matches) == 0 {
+		fmt.Fprintf(w, "  no %vs match [%v]\r\n", kind, m.filter)
+		lines++
+	}
+	return lines
+}
@@ -0,0 +1,201 @@
+package runner
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+// fakeSelector returns a fixed choice and records what it was offered
+type fakeSelector struct {
+	choice  string
+	offered []string
+}
+
+func (f *fakeSelector) Select(kind string, choices []string) (string, error) {
+	f.offered = choices
+	return f.choice, nil
+}
+
+func TestSelectPlan(t *testing.T) {
+	spec := &bundle.Spec{
+		Plans: []bundle.Plan{{Name: "dev"}, {Name: "prod"}},
+	}
+	selector := &fakeSelector{choice: "prod"}
+	plan, err := selectPlan(spec, "", "", selector)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if plan.Name != "prod" || len(selector.offered) != 2 {
+		t.Fatalf("expected plan [prod] from 2 choices, got [%v] from %v", plan.Name, selector.offered)
+	}
+
+	single := &bundle.Spec{Plans: []bundle.Plan{{Name: "default"}}}
+	selector = &fakeSelector{}
+	plan, err = selectPlan(single, "", "", selector)
+	if err != nil || plan.Name != "default" || selector.offered != nil {
+		t.Fatalf("expected the only plan to be used without selection, got [%v]", plan.Name)
+	}
+
+	plan, err = selectPlan(spec, "dev", "", &fakeSelector{})
+	if err != nil || plan.Name != "dev" {
+		t.Fatalf("expected the named plan [dev], got [%v] [%v]", plan.Name, err)
+	}
+	if _, err := selectPlan(spec, "staging", "", &fakeSelector{}); err == nil {
+		t.Fatalf("expected error for a plan the spec does not have")
+	}
+}
+
+func TestSelectDefaultPlan(t *testing.T) {
+	spec := &bundle.Spec{
+		Plans: []bundle.Plan{{Name: "dev"}, {Name: "prod"}},
+	}
+	testCases := []struct {
+		name        string
+		planName    string
+		defaultPlan string
+		selector    Selector
+		expected    string
+		prompted    bool
+		shouldFail  bool
+	}{
+		{
+			name:        "test default plan in the spec",
+			defaultPlan: "prod",
+			selector:    &fakeSelector{choice: "dev"},
+			expected:    "prod",
+		},
+		{
+			name:        "test named plan over the default",
+			planName:    "dev",
+			defaultPlan: "prod",
+			selector:    &fakeSelector{},
+			expected:    "dev",
+		},
+		{
+			name:        "test default plan missing from the spec",
+			defaultPlan: "staging",
+			selector:    &fakeSelector{choice: "dev"},
+			expected:    "dev",
+			prompted:    true,
+		},
+		{
+			name:        "test default plan missing without prompting",
+			defaultPlan: "staging",
+			selector:    nonInteractiveSelector{},
+			shouldFail:  true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			plan, err := selectPlan(spec, tc.planName, tc.defaultPlan, tc.selector)
+			if tc.shouldFail {
+				if err == nil {
+					t.Fatalf("expected error, got plan [%v]", plan.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if plan.Name != tc.expected {
+				t.Fatalf("expected plan [%v], got [%v]", tc.expected, plan.Name)
+			}
+			if prompted := tc.selector.(*fakeSelector).offered != nil; prompted != tc.prompted {
+				t.Fatalf("expected prompted [%v], got [%v]", tc.prompted, prompted)
+			}
+		})
+	}
+}
+
+func TestPromptSelector(t *testing.T) {
+	p := &PromptSelector{in: strings.NewReader("staging\nprod\n"), out: ioutil.Discard}
+	choice, err := p.Select("plan", []string{"dev", "prod"})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if choice != "prod" {
+		t.Fatalf("expected [prod], got [%v]", choice)
+	}
+
+	p = &PromptSelector{in: strings.NewReader(""), out: ioutil.Discard}
+	if _, err := p.Select("plan", []string{"dev"}); err == nil {
+		t.Fatalf("expected error at end of input")
+	}
+}
+
+func TestMenu(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "test enter selects the first choice",
+			input:    "\r",
+			expected: "mediawiki-apb",
+		},
+		{
+			name:     "test arrow keys move the cursor",
+			input:    "\033[B\033[B\033[A\r",
+			expected: "mysql-apb",
+		},
+		{
+			name:     "test typing filters the choices",
+			input:    "POST\r",
+			expected: "postgresql-apb",
+		},
+		{
+			name:     "test backspace widens the filter",
+			input:    "postx\177\r",
+			expected: "postgresql-apb",
+		},
+		{
+			name:     "test enter without matches is ignored",
+			input:    "zzz\177\177\177\033[B\r",
+			expected: "mysql-apb",
+		},
+		{
+			name:     "test interrupt aborts",
+			input:    "\003",
+			expected: "",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &menu{choices: []string{"mediawiki-apb", "mysql-apb", "postgresql-apb"}}
+			in := strings.NewReader(tc.input)
+			for {
+				key, r, err := readKey(in)
+				if err != nil {
+					t.Fatalf("input ended before a selection was made")
+				}
+				m.render(ioutil.Discard, "APB", 0)
+				if choice, done := m.handle(key, r); done {
+					if choice != tc.expected {
+						t.Fatalf("expected [%v], got [%v]", tc.expected, choice)
+					}
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestMenuRenderColor(t *testing.T) {
+	for _, color := range []bool{false, true} {
+		var out bytes.Buffer
+		m := &menu{choices: []string{"mediawiki-apb", "mysql-apb"}, color: color}
+		m.render(&out, "APB", 0)
+		highlighted := strings.Contains(out.String(), "> \033[7mmediawiki-apb\033[0m")
+		if highlighted != color {
+			t.Fatalf("expected the current choice highlighted [%v], got:\n%q", color, out.String())
+		}
+		if strings.Contains(out.String(), "\033[7mmysql-apb") {
+			t.Fatalf("expected only the current choice highlighted, got:\n%q", out.String())
+		}
+	}
+}
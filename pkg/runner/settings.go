@@ -0,0 +1,199 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"k8s.io/api/core/v1"
+)
+
+// sandboxServiceAccount stands for the service account of the sandbox a
+// run creates, which is named after the bundle pod
+const sandboxServiceAccount = "<sandbox named after the pod>"
+
+// Settings are the effective settings of a run, resolved from its options
+// as RunBundle resolves them, for showing why a run does what it does.
+// Settings which depend on the bundle are left empty without one.
+type Settings struct {
+	Action    string `json:"action"`
+	Bundle    string `json:"bundle,omitempty"`
+	Image     string `json:"image,omitempty"`
+	Plan      string `json:"plan,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	// NamespaceSource is where the namespace came from, such as a flag or
+	// the current context
+	NamespaceSource      string                  `json:"namespaceSource,omitempty"`
+	NamespaceLabels      map[string]string       `json:"namespaceLabels,omitempty"`
+	NamespaceAnnotations map[string]string       `json:"namespaceAnnotations,omitempty"`
+	ServiceAccount       string                  `json:"serviceAccount"`
+	Account              string                  `json:"account"`
+	ImagePullPolicy      string                  `json:"imagePullPolicy"`
+	DNSPolicy            v1.DNSPolicy            `json:"dnsPolicy,omitempty"`
+	SchedulerName        string                  `json:"schedulerName,omitempty"`
+	Resources            v1.ResourceRequirements `json:"resources"`
+	// Parameters are the values given for the run, with those of password
+	// parameters redacted. All are redacted when the plan isn't known.
+	Parameters       map[string]string `json:"parameters,omitempty"`
+	NonInteractive   bool              `json:"nonInteractive"`
+	DryRun           bool              `json:"dryRun"`
+	OutputFormat     string            `json:"outputFormat,omitempty"`
+	Wait             bool              `json:"wait"`
+	Timeout          string            `json:"timeout,omitempty"`
+	Deadline         string            `json:"deadline,omitempty"`
+	OperationTimeout string            `json:"operationTimeout,omitempty"`
+	Cleanup          bool              `json:"cleanup"`
+	Retries          int               `json:"retries,omitempty"`
+}
+
+// EffectiveSettings resolves the settings a run of the bundle's action in
+// the namespace would use with the options, without running it. The bundle
+// may be empty to leave out the settings which depend on it. Its plan is
+// only resolved when it doesn't need choosing.
+func EffectiveSettings(action string, ns string, bundleName string, bundleRegistry string, opts ...Option) (*Settings, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	s := &Settings{
+		Action:               action,
+		Namespace:            ns,
+		NamespaceLabels:      o.namespaceLabels,
+		NamespaceAnnotations: o.namespaceAnnotations,
+		ServiceAccount:       sandboxServiceAccount,
+		ImagePullPolicy:      AutoPullPolicy,
+		DNSPolicy:            o.dnsPolicy,
+		SchedulerName:        o.schedulerName,
+		NonInteractive:       o.nonInteractive,
+		DryRun:               o.dryRun,
+		Wait:                 o.waiting(),
+		Timeout:              settingDuration(o.timeout(action)),
+		Deadline:             settingDuration(o.podDeadline),
+		OperationTimeout:     settingDuration(o.operationTimeout),
+		Cleanup:              o.cleanup,
+		Retries:              o.retry.retries,
+	}
+	if o.dryRun {
+		s.OutputFormat = o.outputFormat
+		if s.OutputFormat == "" {
+			s.OutputFormat = "yaml"
+		}
+	}
+	if o.generateNamespace {
+		s.Namespace = ""
+		s.NamespaceSource = "generated from the APB name"
+	}
+	if o.serviceAccount != "" {
+		s.ServiceAccount = o.serviceAccount
+	}
+	s.Account = s.ServiceAccount
+	if o.account != "" {
+		s.Account = o.account
+	}
+	if o.pullPolicy != "" {
+		s.ImagePullPolicy = string(o.pullPolicy)
+	}
+
+	var plan *bundle.Plan
+	if bundleName != "" {
+		spec, err := settingsSpec(bundleName, bundleRegistry, o)
+		if err != nil {
+			return nil, err
+		}
+		s.Bundle = spec.FQName
+		s.Image = spec.Image
+		if o.localDir != "" {
+			s.Image = localImage(spec)
+		}
+		s.ImagePullPolicy = string(imagePullPolicy(o, s.Image))
+		if err := WithRecommendedResources(spec)(o); err != nil {
+			return nil, err
+		}
+		if selected, ok := resolvedPlan(spec, o); ok {
+			plan = &selected
+			s.Plan = selected.Name
+		}
+	}
+	s.Resources = mergeResources(o.recommendedResources, o.resources)
+
+	if len(o.parameterValues) > 0 {
+		s.Parameters = map[string]string{}
+		var sensitive map[string]bool
+		if plan != nil {
+			sensitive = sensitiveParameters(*plan)
+		}
+		for name, value := range o.parameterValues {
+			if plan == nil || sensitive[name] {
+				value = redactedValue
+			}
+			s.Parameters[name] = value
+		}
+	}
+	return s, nil
+}
+
+// settingsSpec finds the bundle's spec as RunBundle does, without building
+// a local bundle's image. Specs are listed rather than got, so nothing is
+// printed along with the settings.
+func settingsSpec(bundleName string, bundleRegistry string, o *options) (*bundle.Spec, error) {
+	if o.localDir != "" {
+		return LoadLocalSpec(o.localDir)
+	}
+	provider := o.specProvider
+	if provider == nil {
+		provider = NewRegistrySpecProvider(bundleRegistry)
+	}
+	specs, err := provider.List()
+	if err != nil {
+		return nil, err
+	}
+	var found *bundle.Spec
+	for _, spec := range specs {
+		if spec.FQName != bundleName {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("found multiple APBs with matching name [%v]. Specify a registry with --registry", bundleName)
+		}
+		found = spec
+	}
+	if found == nil {
+		return nil, specNotFound(bundleName, "")
+	}
+	return found, nil
+}
+
+// resolvedPlan returns the plan a run would select without asking, and
+// whether there is one
+func resolvedPlan(spec *bundle.Spec, o *options) (bundle.Plan, bool) {
+	if _, ok := spec.GetPlan(o.defaultPlan); o.planName == "" && len(spec.Plans) != 1 && !ok {
+		return bundle.Plan{}, false
+	}
+	plan, err := selectPlan(spec, o.planName, o.defaultPlan, nil)
+	return plan, err == nil
+}
+
+// settingDuration shows a duration of zero, which doesn't bound anything,
+// as empty
+func settingDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
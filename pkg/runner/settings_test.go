@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/ghodss/yaml"
+)
+
+func TestEffectiveSettings(t *testing.T) {
+	specs := staticSpecs{{
+		FQName: "postgresql-apb",
+		Image:  "docker.io/ansibleplaybookbundle/postgresql-apb@sha256:0123",
+		Metadata: map[string]interface{}{
+			"resources": map[string]interface{}{
+				"requests": map[string]interface{}{"cpu": "100m", "memory": "256Mi"},
+			},
+		},
+		Plans: []bundle.Plan{
+			{Name: "dev", Parameters: []bundle.ParameterDescriptor{
+				{Name: "db_name", Type: "string"},
+				{Name: "db_password", Type: "string", DisplayType: "password"},
+			}},
+			{Name: "prod"},
+		},
+	}}
+	testCases := []struct {
+		name       string
+		bundleName string
+		opts       []Option
+		expected   string
+	}{
+		{
+			name:       "test settings merged with the APB's",
+			bundleName: "postgresql-apb",
+			opts: []Option{
+				WithSpecProvider(specs),
+				WithPlan("dev"),
+				WithParameterValues([]string{"db_name=wiki", "db_password=hunter2"}),
+				WithResources([]string{"memory=1Gi"}, nil),
+				WithNamespaceLabels([]string{"team=web"}),
+				WithWait(10 * time.Minute),
+				WithActionTimeouts([]string{"provision=20m"}),
+			},
+			expected: `account: <sandbox named after the pod>
+action: provision
+bundle: postgresql-apb
+cleanup: false
+dryRun: false
+image: docker.io/ansibleplaybookbundle/postgresql-apb@sha256:0123
+imagePullPolicy: IfNotPresent
+namespace: web
+namespaceLabels:
+  team: web
+nonInteractive: false
+parameters:
+  db_name: wiki
+  db_password: '***'
+plan: dev
+resources:
+  requests:
+    cpu: 100m
+    memory: 1Gi
+serviceAccount: <sandbox named after the pod>
+timeout: 20m0s
+wait: true
+`,
+		},
+		{
+			name: "test settings without an APB",
+			opts: []Option{
+				WithParameterValues([]string{"db_name=wiki"}),
+				WithServiceAccount("deployer"),
+				WithDryRun("json", ""),
+				WithDeadline(time.Hour),
+			},
+			expected: `account: deployer
+action: provision
+cleanup: false
+deadline: 1h0m0s
+dryRun: true
+imagePullPolicy: auto
+namespace: web
+nonInteractive: false
+outputFormat: json
+parameters:
+  db_name: '***'
+resources: {}
+serviceAccount: deployer
+timeout: 1h0m0s
+wait: true
+`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			settings, err := EffectiveSettings("provision", "web", tc.bundleName, "", tc.opts...)
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			out, err := yaml.Marshal(settings)
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if string(out) != tc.expected {
+				t.Fatalf("expected settings\n%v\ngot\n%v", tc.expected, string(out))
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/apb/pkg/util"
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/ghodss/yaml"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// configMapRef names the key of a config map holding specs
+type configMapRef struct {
+	namespace string
+	name      string
+	// key is empty to use the config map's only key
+	key string
+}
+
+func (r configMapRef) String() string {
+	return r.namespace + "/" + r.name
+}
+
+// parseConfigMapRef parses a reference given as namespace/name[:key]
+func parseConfigMapRef(ref string) (configMapRef, error) {
+	var r configMapRef
+	var err error
+	r.namespace, r.name, r.key, err = parseKeyRef("config map", ref)
+	return r, err
+}
+
+// parseKeyRef parses a reference to the key of an object of the kind, given
+// as namespace/name[:key]. The key is empty when it is left out.
+func parseKeyRef(kind string, ref string) (namespace string, name string, key string, err error) {
+	nsName := ref
+	if i := strings.Index(ref, ":"); i >= 0 {
+		nsName, key = ref[:i], ref[i+1:]
+		if key == "" {
+			return "", "", "", fmt.Errorf("invalid %v [%v]: empty key", kind, ref)
+		}
+	}
+	parts := strings.Split(nsName, "/")
+	if len(parts) != 2 {
+		return "", "", "", fmt.Errorf("invalid %v [%v]. Expected namespace/name[:key]", kind, ref)
+	}
+	namespace, name = parts[0], parts[1]
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return "", "", "", fmt.Errorf("invalid %v namespace [%v]: %v", kind, namespace, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return "", "", "", fmt.Errorf("invalid %v name [%v]: %v", kind, name, strings.Join(errs, "; "))
+	}
+	return namespace, name, key, nil
+}
+
+// loadConfigMapSpecs reads the specs, as a JSON or YAML list, from the key
+// of the config map
+func loadConfigMapSpecs(configMaps corev1.ConfigMapInterface, ref configMapRef) ([]*bundle.Spec, error) {
+	configMap, err := configMaps.Get(ref.name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, fmt.Errorf("config map [%v] not found", ref)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config map [%v]: %v", ref, err)
+	}
+	key := ref.key
+	if key == "" {
+		if len(configMap.Data) != 1 {
+			return nil, fmt.Errorf("config map [%v] has keys %v. Choose one with %v:<key>", ref, dataKeys(configMap.Data), ref)
+		}
+		for k := range configMap.Data {
+			key = k
+		}
+	}
+	data, ok := configMap.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("config map [%v] has no key [%v]. Found keys %v", ref, key, dataKeys(configMap.Data))
+	}
+	var specs []*bundle.Spec
+	if err := yaml.Unmarshal([]byte(data), &specs); err != nil {
+		return nil, fmt.Errorf("failed to parse specs in key [%v] of config map [%v]: %v", key, ref, err)
+	}
+	return specs, nil
+}
+
+func dataKeys(data map[string]string) []string {
+	keys := []string{}
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SpecProvider is where the runner finds the specs of the bundles it runs
+type SpecProvider interface {
+	// List returns all the specs of the provider
+	List() ([]*bundle.Spec, error)
+	// Get returns the spec with the FQName, failing when there is none
+	Get(fqname string) (*bundle.Spec, error)
+}
+
+// NewRegistrySpecProvider returns the provider of the specs cached for the
+// configured registries, or only for the named one when it isn't empty
+func NewRegistrySpecProvider(registry string) SpecProvider {
+	return &registrySpecs{registry: registry}
+}
+
+// NewFileSpecProvider returns the provider of the specs in a file, as a JSON
+// or YAML list like those cached for registries
+func NewFileSpecProvider(path string) SpecProvider {
+	return &fileSpecs{path: path}
+}
+
+// registrySpecs provides the specs cached for the configured registries
+type registrySpecs struct {
+	registry string
+}
+
+func (p *registrySpecs) registries() []config.Registry {
+	reg := []config.Registry{}
+	config.Registries.UnmarshalKey("Registries", &reg)
+	var selected []config.Registry
+	for _, r := range reg {
+		if len(p.registry) > 0 && r.Config.Name != p.registry {
+			continue
+		}
+		selected = append(selected, r)
+	}
+	return selected
+}
+
+func (p *registrySpecs) List() ([]*bundle.Spec, error) {
+	var specs []*bundle.Spec
+	for _, r := range p.registries() {
+		specs = append(specs, r.Specs...)
+	}
+	return specs, nil
+}
+
+func (p *registrySpecs) Get(fqname string) (*bundle.Spec, error) {
+	var candidateSpecs []*bundle.Spec
+	for _, r := range p.registries() {
+		for _, s := range r.Specs {
+			if s.FQName == fqname {
+				candidateSpecs = append(candidateSpecs, s)
+				fmt.Printf("Found APB [%v] in registry [%v]\n", fqname, r.Config.Name)
+			}
+		}
+	}
+	if len(candidateSpecs) == 0 {
+		if len(p.registry) > 0 {
+			return nil, specNotFound(fqname, fmt.Sprintf("registry [%v]", p.registry))
+		}
+		return nil, specNotFound(fqname, "configured registries")
+	}
+	if len(candidateSpecs) > 1 {
+		return nil, fmt.Errorf("found multiple APBs with matching name [%v]. Specify a registry with --registry", fqname)
+	}
+	return candidateSpecs[0], nil
+}
+
+// fileSpecs provides the specs listed in a file
+type fileSpecs struct {
+	path string
+}
+
+func (p *fileSpecs) List() ([]*bundle.Spec, error) {
+	data, err := ioutil.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read specs: %v", err)
+	}
+	var specs []*bundle.Spec
+	if err := yaml.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("failed to parse specs in file [%v]: %v", p.path, err)
+	}
+	return specs, nil
+}
+
+func (p *fileSpecs) Get(fqname string) (*bundle.Spec, error) {
+	return getSpec(p, fqname, fmt.Sprintf("file [%v]", p.path))
+}
+
+// configMapSpecs provides the specs in the key of a config map
+type configMapSpecs struct {
+	ref configMapRef
+}
+
+func (p *configMapSpecs) List() ([]*bundle.Spec, error) {
+	k8scli, err := util.KubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+	return loadConfigMapSpecs(k8scli.Client.CoreV1().ConfigMaps(p.ref.namespace), p.ref)
+}
+
+func (p *configMapSpecs) Get(fqname string) (*bundle.Spec, error) {
+	return getSpec(p, fqname, fmt.Sprintf("config map [%v]", p.ref))
+}
+
+// getSpec finds the bundle among the specs the provider lists, naming the
+// provider's source in what it prints
+func getSpec(p SpecProvider, fqname string, source string) (*bundle.Spec, error) {
+	specs, err := p.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range specs {
+		if s != nil && s.FQName == fqname {
+			fmt.Printf("Found APB [%v] in %v\n", fqname, source)
+			return s, nil
+		}
+	}
+	return nil, specNotFound(fqname, source)
+}
+
+// specNotFound is the error of a provider without the bundle, naming the
+// source it looked in when there is one. It is an ErrSpecNotFound.
+func specNotFound(fqname string, source string) error {
+	if source == "" {
+		return markError(ErrSpecNotFound, fmt.Errorf("could not find APB %q", fqname))
+	}
+	return markError(ErrSpecNotFound, fmt.Errorf("could not find APB %q in %v", fqname, source))
+}
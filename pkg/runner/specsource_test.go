@@ -0,0 +1,156 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/automationbroker/bundle-lib/registries"
+	"k8s.io/api/core/v1"
+)
+
+func TestParseConfigMapRef(t *testing.T) {
+	testCases := []struct {
+		ref       string
+		expected  configMapRef
+		shouldErr bool
+	}{
+		{ref: "apb/catalog", expected: configMapRef{namespace: "apb", name: "catalog"}},
+		{ref: "apb/catalog:specs.yaml", expected: configMapRef{namespace: "apb", name: "catalog", key: "specs.yaml"}},
+		{ref: "catalog", shouldErr: true},
+		{ref: "apb/catalog:", shouldErr: true},
+		{ref: "apb/team/catalog", shouldErr: true},
+		{ref: "APB/catalog", shouldErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.ref, func(t *testing.T) {
+			r, err := parseConfigMapRef(tc.ref)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("expected error for [%v]", tc.ref)
+			}
+			if r != tc.expected {
+				t.Fatalf("expected [%+v], got [%+v]", tc.expected, r)
+			}
+		})
+	}
+}
+
+func TestLoadConfigMapSpecs(t *testing.T) {
+	specsYAML := `- name: hello-apb
+  image: docker.io/example/hello-apb:latest
+  plans:
+  - name: default
+`
+	specsJSON := `[{"name": "mediawiki-apb", "image": "docker.io/example/mediawiki-apb:latest"}]`
+	configMaps := &fakeConfigMaps{configMaps: map[string]*v1.ConfigMap{
+		"single": {Data: map[string]string{"specs.yaml": specsYAML}},
+		"multi":  {Data: map[string]string{"specs.yaml": specsYAML, "specs.json": specsJSON}},
+		"broken": {Data: map[string]string{"specs": "name: [unclosed"}},
+	}}
+	testCases := []struct {
+		name     string
+		ref      configMapRef
+		expected string
+		errMsg   string
+	}{
+		{name: "only key", ref: configMapRef{name: "single"}, expected: "hello-apb"},
+		{name: "chosen key", ref: configMapRef{name: "multi", key: "specs.json"}, expected: "mediawiki-apb"},
+		{name: "ambiguous key", ref: configMapRef{name: "multi"}, errMsg: "has keys [specs.json specs.yaml]"},
+		{name: "missing key", ref: configMapRef{name: "single", key: "specs.json"}, errMsg: "has no key [specs.json]"},
+		{name: "missing config map", ref: configMapRef{name: "absent"}, errMsg: "not found"},
+		{name: "invalid specs", ref: configMapRef{name: "broken"}, errMsg: "failed to parse specs"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.ref.namespace = "apb"
+			specs, err := loadConfigMapSpecs(configMaps, tc.ref)
+			if tc.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+					t.Fatalf("expected error containing [%v], got [%v]", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if len(specs) != 1 || specs[0].FQName != tc.expected || specs[0].Image == "" {
+				t.Fatalf("expected spec [%v] with its image, got [%v]", tc.expected, specs)
+			}
+		})
+	}
+}
+
+func TestFileSpecProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-specs")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "specs.yaml")
+	specs := "- name: mediawiki-apb\n  image: docker.io/ansibleplaybookbundle/mediawiki-apb:latest\n- name: postgresql-apb\n  image: docker.io/ansibleplaybookbundle/postgresql-apb:latest\n"
+	if err := ioutil.WriteFile(path, []byte(specs), 0600); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+
+	var provider SpecProvider = NewFileSpecProvider(path)
+	listed, err := provider.List()
+	if err != nil || len(listed) != 2 {
+		t.Fatalf("expected two specs, got %v [%v]", listed, err)
+	}
+	spec, err := provider.Get("postgresql-apb")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if spec.Image != "docker.io/ansibleplaybookbundle/postgresql-apb:latest" {
+		t.Fatalf("got unexpected spec %+v", spec)
+	}
+	if _, err := provider.Get("mysql-apb"); err == nil || !strings.Contains(err.Error(), `could not find APB "mysql-apb" in file`) {
+		t.Fatalf("expected a missing APB to fail, got [%v]", err)
+	}
+	if _, err := NewFileSpecProvider(filepath.Join(dir, "missing.yaml")).List(); err == nil {
+		t.Fatalf("expected a missing file to fail")
+	}
+}
+
+func TestRegistrySpecProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-registries")
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	defer os.RemoveAll(dir)
+	saved := config.Registries
+	defer func() { config.Registries = saved }()
+	config.Registries, _ = config.InitJSONConfig(dir, "registries")
+	mediawiki := &bundle.Spec{FQName: "mediawiki-apb"}
+	err = config.UpdateCachedRegistries(config.Registries, []config.Registry{
+		{Config: registries.Config{Name: "docker"}, Specs: []*bundle.Spec{mediawiki}},
+		{Config: registries.Config{Name: "quay"}, Specs: []*bundle.Spec{mediawiki, {FQName: "postgresql-apb"}}},
+	})
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+
+	listed, err := NewRegistrySpecProvider("").List()
+	if err != nil || len(listed) != 3 {
+		t.Fatalf("expected the specs of every registry, got %v [%v]", listed, err)
+	}
+	if _, err := NewRegistrySpecProvider("").Get("mediawiki-apb"); err == nil || !strings.Contains(err.Error(), "multiple APBs") {
+		t.Fatalf("expected an APB in two registries to be ambiguous, got [%v]", err)
+	}
+	if spec, err := NewRegistrySpecProvider("quay").Get("mediawiki-apb"); err != nil || spec.FQName != "mediawiki-apb" {
+		t.Fatalf("expected the APB from the named registry, got %v [%v]", spec, err)
+	}
+	if _, err := NewRegistrySpecProvider("docker").Get("postgresql-apb"); err == nil {
+		t.Fatalf("expected an APB of another registry not to be found")
+	}
+}
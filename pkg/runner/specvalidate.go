@@ -0,0 +1,74 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+// ansibleVariableName matches the names Ansible accepts for variables, which
+// the parameters become in the bundle's extra vars
+var ansibleVariableName = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+
+// ValidateSpec checks the spec for authoring mistakes which would otherwise
+// only surface as confusing failures when the bundle is run
+func ValidateSpec(spec *bundle.Spec) ValidationErrors {
+	var verrs ValidationErrors
+	for _, plan := range spec.Plans {
+		verrs = append(verrs, validateParameterNames(plan)...)
+		verrs = append(verrs, validateEnumTypes(plan)...)
+	}
+	return verrs
+}
+
+// validateEnumTypes reports enum entries which can't be converted to the
+// declared type of their parameter
+func validateEnumTypes(plan bundle.Plan) ValidationErrors {
+	var verrs ValidationErrors
+	for _, param := range plan.Parameters {
+		for _, entry := range param.Enum {
+			if _, err := pruneInput(entry, param); err != nil {
+				verrs = append(verrs, ValidationError{
+					Parameter:  param.Name,
+					Constraint: "enum",
+					Message:    fmt.Sprintf("enum entry [%v] of parameter [%v] in plan [%v] is not of its type [%v]: %v", entry, param.Name, plan.Name, param.Type, err),
+				})
+			}
+		}
+	}
+	return verrs
+}
+
+// validateParameterNames reports parameters whose names are not valid
+// Ansible variable names, e.g. with dashes or spaces or a leading digit. The
+// bundle receives them in its extra vars but can't refer to them by name.
+func validateParameterNames(plan bundle.Plan) ValidationErrors {
+	var verrs ValidationErrors
+	for _, param := range plan.Parameters {
+		if !ansibleVariableName.MatchString(param.Name) {
+			verrs = append(verrs, ValidationError{
+				Parameter:  param.Name,
+				Constraint: "name",
+				Message:    fmt.Sprintf("parameter [%v] in plan [%v] is not a valid Ansible variable name: use letters, digits and underscores, not starting with a digit", param.Name, plan.Name),
+			})
+		}
+	}
+	return verrs
+}
@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+func TestValidateSpec(t *testing.T) {
+	testCases := []struct {
+		name     string
+		params   []bundle.ParameterDescriptor
+		expected []string
+		// constraint of the expected errors, enum unless given
+		constraint string
+	}{
+		{
+			name: "test matching enums",
+			params: []bundle.ParameterDescriptor{
+				{Name: "replicas", Type: "int", Enum: []string{"1", "3", "5"}},
+				{Name: "ratio", Type: "number", Enum: []string{"0.5", "1"}},
+				{Name: "debug", Type: "boolean", Enum: []string{"true", "false"}},
+				{Name: "size", Type: "enum", Enum: []string{"small", "large"}},
+				{Name: "name", Type: "string", Enum: []string{"wiki", "42"}},
+			},
+		},
+		{
+			name: "test mismatched enums",
+			params: []bundle.ParameterDescriptor{
+				{Name: "replicas", Type: "int", Enum: []string{"1", "three"}},
+				{Name: "ratio", Type: "number", Enum: []string{"half"}},
+				{Name: "debug", Type: "bool", Enum: []string{"yes"}},
+			},
+			expected: []string{"replicas", "ratio", "debug"},
+		},
+		{
+			name: "test valid names",
+			params: []bundle.ParameterDescriptor{
+				{Name: "mediawiki_db_schema", Type: "string"},
+				{Name: "_private", Type: "string"},
+				{Name: "Replicas2", Type: "int"},
+			},
+		},
+		{
+			name: "test invalid names",
+			params: []bundle.ParameterDescriptor{
+				{Name: "db-schema", Type: "string"},
+				{Name: "site name", Type: "string"},
+				{Name: "2fa_enabled", Type: "boolean"},
+				{Name: "admin.user", Type: "string"},
+			},
+			expected:   []string{"db-schema", "site name", "2fa_enabled", "admin.user"},
+			constraint: "name",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := &bundle.Spec{Plans: []bundle.Plan{{Name: "dev", Parameters: tc.params}}}
+			verrs := ValidateSpec(spec)
+			if len(verrs) != len(tc.expected) {
+				t.Fatalf("expected %d errors, got [%v]", len(tc.expected), verrs)
+			}
+			constraint := tc.constraint
+			if constraint == "" {
+				constraint = "enum"
+			}
+			for i, verr := range verrs {
+				if verr.Parameter != tc.expected[i] || verr.Constraint != constraint {
+					t.Fatalf("expected %v error for [%v], got %+v", constraint, tc.expected[i], verr)
+				}
+			}
+		})
+	}
+}
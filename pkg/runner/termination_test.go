@@ -0,0 +1,70 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/runtime"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTerminationMessage(t *testing.T) {
+	testCases := []struct {
+		name     string
+		state    v1.ContainerState
+		expected string
+	}{
+		{
+			name: "test terminated with a message",
+			state: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{
+				ExitCode: 0,
+				Message:  "{\"url\":\"http://wiki.example.com\"}\n",
+			}},
+			expected: `{"url":"http://wiki.example.com"}`,
+		},
+		{
+			name:  "test terminated without a message",
+			state: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}},
+		},
+		{
+			name:  "test still running",
+			state: v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pods := newFakePods(&v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "bundle-1234"},
+				Status: v1.PodStatus{
+					Phase:             v1.PodSucceeded,
+					ContainerStatuses: []v1.ContainerStatus{{Name: "bundle-1234", State: tc.state}},
+				},
+			})
+			pod, err := pods.Get("bundle-1234", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if message := terminationMessage(pod); message != tc.expected {
+				t.Fatalf("expected message [%v], got [%v]", tc.expected, message)
+			}
+		})
+	}
+}
+
+func TestTerminationMessageOption(t *testing.T) {
+	ec := runtime.ExecutionContext{BundleName: "bundle-1234", Action: "provision", Location: "foo-ns"}
+	pod, err := BuildPod(ec, WithTerminationMessage("/tmp/result", "FallbackToLogsOnError"))
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	container := pod.Spec.Containers[0]
+	if container.TerminationMessagePath != "/tmp/result" || container.TerminationMessagePolicy != v1.TerminationMessageFallbackToLogsOnError {
+		t.Fatalf("unexpected termination message path [%v] and policy [%v]", container.TerminationMessagePath, container.TerminationMessagePolicy)
+	}
+
+	for _, opt := range []Option{WithTerminationMessage("result", ""), WithTerminationMessage("", "Logs")} {
+		if _, err := BuildPod(ec, opt); err == nil {
+			t.Fatalf("expected an invalid termination message option to be refused")
+		}
+	}
+}
@@ -0,0 +1,106 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+// Transformer post-processes a parameter value once it has been converted to
+// the parameter's type, before it is validated
+type Transformer func(param bundle.ParameterDescriptor, value interface{}) (interface{}, error)
+
+// builtinTransformers are the transformers which can be named with
+// WithTransforms. They leave values other than strings unchanged.
+var builtinTransformers = map[string]Transformer{
+	"trim":  stringTransformer(strings.TrimSpace),
+	"lower": stringTransformer(strings.ToLower),
+	"upper": stringTransformer(strings.ToUpper),
+}
+
+func stringTransformer(f func(string) string) Transformer {
+	return func(param bundle.ParameterDescriptor, value interface{}) (interface{}, error) {
+		if s, ok := value.(string); ok {
+			return f(s), nil
+		}
+		return value, nil
+	}
+}
+
+// transformers are the transformers registered for parameters, by name and
+// by declared type. A nil *transformers passes every value through.
+type transformers struct {
+	byName map[string]Transformer
+	byType map[string]Transformer
+}
+
+// transform applies the transformer registered for the parameter's name, or
+// else the one for its type
+func (t *transformers) transform(param bundle.ParameterDescriptor, value interface{}) (interface{}, *ValidationError) {
+	if t == nil {
+		return value, nil
+	}
+	transformer, ok := t.byName[param.Name]
+	if !ok {
+		transformer, ok = t.byType[param.Type]
+	}
+	if !ok {
+		return value, nil
+	}
+	transformed, err := transformer(param, value)
+	if err != nil {
+		return nil, &ValidationError{
+			Parameter:  param.Name,
+			Constraint: "transform",
+			Message:    fmt.Sprintf("Failed to transform value [%v] of parameter [%v]: %v", value, param.Name, err),
+		}
+	}
+	return transformed, nil
+}
+
+// inputChecker returns the function which converts, transforms and, unless
+// skipValidation is set, validates input for a parameter. The config's
+// rules are checked last, even when skipping validation.
+func inputChecker(t *transformers, rules parameterRules, skipValidation bool) func(bundle.ParameterDescriptor, string) (interface{}, *ValidationError) {
+	return func(param bundle.ParameterDescriptor, input string) (interface{}, *ValidationError) {
+		if !skipValidation {
+			if verr := checkRequired(param, input); verr != nil {
+				return nil, verr
+			}
+		}
+		value, verr := coerceInput(param, input)
+		if verr != nil {
+			return nil, verr
+		}
+		value, verr = t.transform(param, value)
+		if verr != nil {
+			return nil, verr
+		}
+		if !skipValidation {
+			if verr := checkValue(param, value); verr != nil {
+				return nil, verr
+			}
+		}
+		if verr := rules.check(param, value); verr != nil {
+			return nil, verr
+		}
+		return value, nil
+	}
+}
@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+func TestTransformers(t *testing.T) {
+	plan := bundle.Plan{
+		Name: "dev",
+		Parameters: []bundle.ParameterDescriptor{
+			{Name: "region", Type: "enum", Enum: []string{"us-east", "eu-west"}},
+			{Name: "app_name", Type: "string"},
+			{Name: "size", Type: "string"},
+			{Name: "replicas", Type: "int"},
+		},
+	}
+	supplied := map[string]string{"region": "US-EAST", "app_name": " wiki ", "size": "m", "replicas": "2"}
+	expand := func(param bundle.ParameterDescriptor, value interface{}) (interface{}, error) {
+		sizes := map[string]string{"s": "small", "m": "medium"}
+		if size, ok := sizes[value.(string)]; ok {
+			return size, nil
+		}
+		return value, nil
+	}
+	double := func(param bundle.ParameterDescriptor, value interface{}) (interface{}, error) {
+		return value.(int64) * 2, nil
+	}
+	testCases := []struct {
+		name       string
+		opts       []Option
+		expected   bundle.Parameters
+		constraint string
+	}{
+		{
+			name:       "test no transformers",
+			constraint: "enum",
+		},
+		{
+			name: "test transformers by name and type",
+			opts: []Option{
+				WithTransforms([]string{"region=lower"}),
+				WithTypeTransformer("string", builtinTransformers["trim"]),
+				WithParameterTransformer("size", expand),
+				WithTypeTransformer("int", double),
+			},
+			expected: bundle.Parameters{"region": "us-east", "app_name": "wiki", "size": "medium", "replicas": int64(4)},
+		},
+		{
+			name: "test transformer error",
+			opts: []Option{
+				WithTransforms([]string{"region=lower"}),
+				WithParameterTransformer("replicas", func(param bundle.ParameterDescriptor, value interface{}) (interface{}, error) {
+					return nil, errors.New("too many")
+				}),
+			},
+			constraint: "transform",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := newOptions(tc.opts)
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			params, err := collectParameters(plan, nil, supplied, nil, o.transformers, nil, false)
+			if tc.constraint != "" {
+				verrs, ok := err.(ValidationErrors)
+				if !ok || verrs[0].Constraint != tc.constraint {
+					t.Fatalf("expected [%v] error, got [%v]", tc.constraint, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error [%v]", err)
+			}
+			if !reflect.DeepEqual(params, tc.expected) {
+				t.Fatalf("expected parameters %v, got %v", tc.expected, params)
+			}
+		})
+	}
+}
+
+func TestTransformsOption(t *testing.T) {
+	for _, transforms := range [][]string{{"region"}, {"=lower"}, {"region=reverse"}} {
+		if _, err := newOptions([]Option{WithTransforms(transforms)}); err == nil {
+			t.Fatalf("expected error for transforms %v", transforms)
+		}
+	}
+}
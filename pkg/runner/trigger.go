@@ -0,0 +1,234 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// Trigger is an event which should run a bundle with the given parameters
+type Trigger struct {
+	// ID identifies the trigger, so that it is only handled once
+	ID         string
+	Parameters map[string]string
+}
+
+// TriggerSource lists the triggers which are currently present
+type TriggerSource interface {
+	Triggers() ([]Trigger, error)
+}
+
+// DirectorySource finds triggers in the JSON files dropped into a
+// directory. Each file holds an object of parameter values. A file is
+// identified by its name and contents, so a changed file triggers again.
+type DirectorySource struct {
+	Dir string
+
+	invalid map[string]bool
+}
+
+// Triggers returns a trigger for each JSON file in the directory
+func (d *DirectorySource) Triggers() ([]Trigger, error) {
+	files, err := filepath.Glob(filepath.Join(d.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var triggers []Trigger
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		id := triggerID(filepath.Base(file), data)
+		params, err := parameterStrings(data)
+		if err != nil {
+			if d.invalid == nil {
+				d.invalid = map[string]bool{}
+			}
+			if !d.invalid[id] {
+				log.Warningf("Ignoring trigger file [%v]: %v", file, err)
+				d.invalid[id] = true
+			}
+			continue
+		}
+		triggers = append(triggers, Trigger{ID: id, Parameters: params})
+	}
+	return triggers, nil
+}
+
+// triggerID identifies a trigger by its name and contents, so that an edit
+// triggers again
+func triggerID(name string, data []byte) string {
+	return fmt.Sprintf("%s@%x", name, sha256.Sum256(data))
+}
+
+// parameterStrings reads a JSON object of parameter values, keeping strings
+// as they are and writing other values as JSON, the way they would be typed
+func parameterStrings(data []byte) (map[string]string, error) {
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("expected a JSON object of parameter values: %v", err)
+	}
+	params := map[string]string{}
+	for name, value := range values {
+		if s, ok := value.(string); ok {
+			params[name] = s
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		params[name] = string(encoded)
+	}
+	return params, nil
+}
+
+// ConfigMapSource finds triggers in the config maps matching a label
+// selector. The config map's data are the parameter values. Like a file, a
+// config map is identified by its name and data, so an edited one triggers
+// again.
+type ConfigMapSource struct {
+	ConfigMaps corev1.ConfigMapInterface
+	Selector   string
+}
+
+// Triggers returns a trigger for each matching config map
+func (c *ConfigMapSource) Triggers() ([]Trigger, error) {
+	list, err := c.ConfigMaps.List(metav1.ListOptions{LabelSelector: c.Selector})
+	if err != nil {
+		return nil, err
+	}
+	var triggers []Trigger
+	for _, cm := range list.Items {
+		// the keys are written in order, so the same data gives the same ID
+		data, err := json.Marshal(cm.Data)
+		if err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, Trigger{ID: triggerID(cm.Name, data), Parameters: cm.Data})
+	}
+	return triggers, nil
+}
+
+// HandledTriggers records the IDs of the triggers already handled in a
+// file, so that a restarted watch doesn't handle them again. Without a path
+// they are only kept in memory.
+type HandledTriggers struct {
+	Path string
+
+	ids map[string]bool
+}
+
+// LoadHandledTriggers reads the triggers recorded in the file, if there is
+// one yet
+func LoadHandledTriggers(path string) (*HandledTriggers, error) {
+	h := &HandledTriggers{Path: path, ids: map[string]bool{}}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, fmt.Errorf("invalid handled triggers file [%v]: %v", path, err)
+	}
+	for _, id := range ids {
+		h.ids[id] = true
+	}
+	return h, nil
+}
+
+// handled is whether the trigger was handled before
+func (h *HandledTriggers) handled(id string) bool {
+	return h.ids[id]
+}
+
+// record adds the trigger to those handled, and saves them when there is a
+// path
+func (h *HandledTriggers) record(id string) error {
+	if h.ids == nil {
+		h.ids = map[string]bool{}
+	}
+	h.ids[id] = true
+	if h.Path == "" {
+		return nil
+	}
+	var ids []string
+	for id := range h.ids {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	data, err := json.MarshalIndent(ids, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.Path), 0755); err != nil {
+		return err
+	}
+	// a watch stopped while saving leaves the previous file in place
+	tmp := h.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, h.Path)
+}
+
+// WatchTriggers polls the source and handles each trigger the first time it
+// is seen, until stop is closed. Triggers are recorded as handled before
+// handling them, so a failed one isn't retried. A nil handled keeps them in
+// memory. Failures are logged and do not stop the watch.
+func WatchTriggers(source TriggerSource, handled *HandledTriggers, interval time.Duration, stop <-chan struct{}, handle func(Trigger) error) {
+	if handled == nil {
+		handled = &HandledTriggers{}
+	}
+	for {
+		triggers, err := source.Triggers()
+		if err != nil {
+			log.Warningf("Failed to list triggers: %v", err)
+		}
+		for _, t := range triggers {
+			if handled.handled(t.ID) {
+				continue
+			}
+			if err := handled.record(t.ID); err != nil {
+				log.Warningf("Failed to record trigger [%v] as handled: %v", t.ID, err)
+			}
+			if err := handle(t); err != nil {
+				log.Errorf("Failed to handle trigger [%v]: %v", t.ID, err)
+			}
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDirectorySource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-triggers")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name string, content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write trigger file: %v", err)
+		}
+	}
+	write("wiki.json", `{"app_name": "wiki", "replicas": 2, "debug": true}`)
+	write("broken.json", `{"app_name": `)
+	write("notes.txt", `not a trigger`)
+
+	source := &DirectorySource{Dir: dir}
+	triggers, err := source.Triggers()
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if len(triggers) != 1 {
+		t.Fatalf("expected 1 trigger, got %v", triggers)
+	}
+	expected := map[string]string{"app_name": "wiki", "replicas": "2", "debug": "true"}
+	for k, v := range expected {
+		if triggers[0].Parameters[k] != v {
+			t.Fatalf("expected parameter [%v=%v], got [%v]", k, v, triggers[0].Parameters[k])
+		}
+	}
+
+	write("wiki.json", `{"app_name": "blog"}`)
+	changed, err := source.Triggers()
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if changed[0].ID == triggers[0].ID {
+		t.Fatalf("expected a changed file to be a new trigger")
+	}
+}
+
+type fakeTriggerSource struct {
+	triggers [][]Trigger
+	polls    int
+}
+
+func (f *fakeTriggerSource) Triggers() ([]Trigger, error) {
+	if f.polls >= len(f.triggers) {
+		return f.triggers[len(f.triggers)-1], nil
+	}
+	f.polls++
+	return f.triggers[f.polls-1], nil
+}
+
+func TestWatchTriggers(t *testing.T) {
+	source := &fakeTriggerSource{
+		triggers: [][]Trigger{
+			{{ID: "a"}},
+			{{ID: "a"}, {ID: "b"}},
+			{{ID: "b"}, {ID: "c"}},
+		},
+	}
+	stop := make(chan struct{})
+	var handled []string
+	WatchTriggers(source, nil, time.Millisecond, stop, func(trigger Trigger) error {
+		handled = append(handled, trigger.ID)
+		if trigger.ID == "c" {
+			close(stop)
+		}
+		return nil
+	})
+	if len(handled) != 3 || handled[0] != "a" || handled[1] != "b" || handled[2] != "c" {
+		t.Fatalf("expected each trigger to be handled once, got %v", handled)
+	}
+}
+
+func TestConfigMapSource(t *testing.T) {
+	configMaps := &fakeConfigMaps{configMaps: map[string]*v1.ConfigMap{
+		"wiki": {
+			ObjectMeta: metav1.ObjectMeta{Name: "wiki", UID: "1234"},
+			Data:       map[string]string{"app_name": "wiki", "replicas": "2"},
+		},
+	}}
+	source := &ConfigMapSource{ConfigMaps: configMaps, Selector: "apb-trigger=mediawiki"}
+	triggers, err := source.Triggers()
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if len(triggers) != 1 || triggers[0].Parameters["app_name"] != "wiki" {
+		t.Fatalf("expected 1 trigger, got %v", triggers)
+	}
+	again, err := source.Triggers()
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if again[0].ID != triggers[0].ID {
+		t.Fatalf("expected an unchanged config map to be the same trigger")
+	}
+
+	configMaps.configMaps["wiki"].Data = map[string]string{"app_name": "blog", "replicas": "2"}
+	changed, err := source.Triggers()
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if changed[0].ID == triggers[0].ID {
+		t.Fatalf("expected an edited config map to be a new trigger")
+	}
+}
+
+// stoppingTriggerSource returns its triggers, then stops the watch on the
+// next poll
+type stoppingTriggerSource struct {
+	triggers []Trigger
+	stop     chan struct{}
+	polled   bool
+}
+
+func (s *stoppingTriggerSource) Triggers() ([]Trigger, error) {
+	if s.polled {
+		close(s.stop)
+		return nil, nil
+	}
+	s.polled = true
+	return s.triggers, nil
+}
+
+func TestWatchTriggersRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-triggers")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "triggers", "watch.json")
+
+	watch := func(triggers ...Trigger) []string {
+		handled, err := LoadHandledTriggers(path)
+		if err != nil {
+			t.Fatalf("got unexpected error [%v]", err)
+		}
+		source := &stoppingTriggerSource{triggers: triggers, stop: make(chan struct{})}
+		var ids []string
+		WatchTriggers(source, handled, time.Millisecond, source.stop, func(trigger Trigger) error {
+			ids = append(ids, trigger.ID)
+			return errors.New("failed to run")
+		})
+		return ids
+	}
+	if ids := watch(Trigger{ID: "a"}, Trigger{ID: "b"}); len(ids) != 2 {
+		t.Fatalf("expected both triggers to be handled, got %v", ids)
+	}
+	if ids := watch(Trigger{ID: "a"}, Trigger{ID: "b"}, Trigger{ID: "c"}); len(ids) != 1 || ids[0] != "c" {
+		t.Fatalf("expected only the new trigger to be handled after a restart, got %v", ids)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatalf("failed to write handled triggers: %v", err)
+	}
+	if _, err := LoadHandledTriggers(path); err == nil {
+		t.Fatalf("expected an invalid handled triggers file to fail")
+	}
+}
@@ -0,0 +1,95 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// DefaultUnschedulableTimeout is how long a bundle pod may be pending with
+// FailedScheduling events before waiting for it fails
+const DefaultUnschedulableTimeout = 2 * time.Minute
+
+// failedSchedulingReason is the reason of the scheduler's events for a pod
+// it could not place
+const failedSchedulingReason = "FailedScheduling"
+
+// podCheck is called with the pod on every poll while waiting for it, and
+// ends the wait with the error it returns
+type podCheck func(pod *v1.Pod) error
+
+// allChecks runs each of the checks in turn, ending the wait with the first
+// error
+func allChecks(checks ...podCheck) podCheck {
+	return func(pod *v1.Pod) error {
+		for _, check := range checks {
+			if check == nil {
+				continue
+			}
+			if err := check(pod); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// unschedulableCheck fails a pod which has been pending for longer than
+// the timeout and which the scheduler failed to place, with the scheduler's
+// reason. A pod which is only slow to start, e.g. pulling its image, is left
+// to the wait's timeout, as is one scheduled after earlier failures. A
+// timeout of zero never fails the pod.
+func unschedulableCheck(events corev1.EventInterface, timeout time.Duration) podCheck {
+	return func(pod *v1.Pod) error {
+		if timeout <= 0 || pod.Status.Phase != v1.PodPending || !unschedulable(pod) {
+			return nil
+		}
+		pending := time.Since(pod.CreationTimestamp.Time)
+		if pending < timeout {
+			return nil
+		}
+		recent, err := podEvents(events, pod.Name)
+		if err != nil {
+			log.Warningf("Failed to get events of pod [%v]: %v", pod.Name, err)
+			return nil
+		}
+		// events of an earlier pod of the same name are left out
+		for i := len(recent) - 1; i >= 0; i-- {
+			event := recent[i]
+			if event.Reason != failedSchedulingReason || event.LastTimestamp.Before(&pod.CreationTimestamp) {
+				continue
+			}
+			return fmt.Errorf("pod [%v] could not be scheduled for %v: %v", pod.Name, pending.Round(time.Second), event.Message)
+		}
+		return nil
+	}
+}
+
+// unschedulable is whether the scheduler currently can't place the pod
+func unschedulable(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled {
+			return condition.Status == v1.ConditionFalse && condition.Reason == v1.PodReasonUnschedulable
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func schedulingEvent(podName string, reason string, message string, at time.Time) v1.Event {
+	return v1.Event{
+		InvolvedObject: v1.ObjectReference{Kind: "Pod", Name: podName},
+		Type:           v1.EventTypeWarning,
+		Reason:         reason,
+		Message:        message,
+		LastTimestamp:  metav1.NewTime(at),
+	}
+}
+
+func TestUnschedulableCheck(t *testing.T) {
+	now := time.Now()
+	insufficientCPU := "0/3 nodes are available: 3 Insufficient cpu."
+	testCases := []struct {
+		name  string
+		phase v1.PodPhase
+		// scheduled is the status of the pod's PodScheduled condition
+		scheduled v1.ConditionStatus
+		created   time.Time
+		events    []v1.Event
+		timeout   time.Duration
+		err       string
+	}{
+		{
+			name:      "unschedulable past the timeout",
+			phase:     v1.PodPending,
+			scheduled: v1.ConditionFalse,
+			created:   now.Add(-3 * time.Minute),
+			events: []v1.Event{
+				schedulingEvent("bundle-1234", failedSchedulingReason, "0/3 nodes are available: 3 node(s) didn't match node selector.", now.Add(-2*time.Minute)),
+				schedulingEvent("bundle-1234", failedSchedulingReason, insufficientCPU, now.Add(-time.Minute)),
+			},
+			timeout: 2 * time.Minute,
+			err:     insufficientCPU,
+		},
+		{
+			name:      "unschedulable within the timeout",
+			phase:     v1.PodPending,
+			scheduled: v1.ConditionFalse,
+			created:   now.Add(-time.Minute),
+			events:    []v1.Event{schedulingEvent("bundle-1234", failedSchedulingReason, insufficientCPU, now)},
+			timeout:   2 * time.Minute,
+		},
+		{
+			name:      "pending without scheduling failures",
+			phase:     v1.PodPending,
+			scheduled: v1.ConditionFalse,
+			created:   now.Add(-3 * time.Minute),
+			events:    []v1.Event{schedulingEvent("bundle-1234", "Pulling", "pulling image", now)},
+			timeout:   2 * time.Minute,
+		},
+		{
+			name:      "scheduling failure of an earlier pod",
+			phase:     v1.PodPending,
+			scheduled: v1.ConditionFalse,
+			created:   now.Add(-3 * time.Minute),
+			events:    []v1.Event{schedulingEvent("bundle-1234", failedSchedulingReason, insufficientCPU, now.Add(-time.Hour))},
+			timeout:   2 * time.Minute,
+		},
+		{
+			name:      "scheduling failure of another pod",
+			phase:     v1.PodPending,
+			scheduled: v1.ConditionFalse,
+			created:   now.Add(-3 * time.Minute),
+			events:    []v1.Event{schedulingEvent("bundle-5678", failedSchedulingReason, insufficientCPU, now)},
+			timeout:   2 * time.Minute,
+		},
+		{
+			name:      "scheduled after scheduling failures",
+			phase:     v1.PodPending,
+			scheduled: v1.ConditionTrue,
+			created:   now.Add(-3 * time.Minute),
+			events:    []v1.Event{schedulingEvent("bundle-1234", failedSchedulingReason, insufficientCPU, now.Add(-time.Minute))},
+			timeout:   2 * time.Minute,
+		},
+		{
+			name:    "pending without a scheduled condition",
+			phase:   v1.PodPending,
+			created: now.Add(-3 * time.Minute),
+			events:  []v1.Event{schedulingEvent("bundle-1234", failedSchedulingReason, insufficientCPU, now.Add(-time.Minute))},
+			timeout: 2 * time.Minute,
+		},
+		{
+			name:    "running",
+			phase:   v1.PodRunning,
+			created: now.Add(-3 * time.Minute),
+			events:  []v1.Event{schedulingEvent("bundle-1234", failedSchedulingReason, insufficientCPU, now.Add(-2*time.Minute))},
+			timeout: 2 * time.Minute,
+		},
+		{
+			name:      "disabled",
+			phase:     v1.PodPending,
+			scheduled: v1.ConditionFalse,
+			created:   now.Add(-3 * time.Minute),
+			events:    []v1.Event{schedulingEvent("bundle-1234", failedSchedulingReason, insufficientCPU, now)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "bundle-1234", CreationTimestamp: metav1.NewTime(tc.created)},
+				Status:     v1.PodStatus{Phase: tc.phase},
+			}
+			if tc.scheduled != "" {
+				condition := v1.PodCondition{Type: v1.PodScheduled, Status: tc.scheduled}
+				if tc.scheduled == v1.ConditionFalse {
+					condition.Reason = v1.PodReasonUnschedulable
+				}
+				pod.Status.Conditions = []v1.PodCondition{condition}
+			}
+			err := unschedulableCheck(&fakeEvents{events: tc.events}, tc.timeout)(pod)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing [%v], got [%v]", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestWaitForUnschedulablePod(t *testing.T) {
+	savedPollInterval := pollInterval
+	defer func() { pollInterval = savedPollInterval }()
+	pollInterval = time.Millisecond
+	pods := newFakePods(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "bundle-1234", CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour))},
+		Status: v1.PodStatus{
+			Phase:      v1.PodPending,
+			Conditions: []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionFalse, Reason: v1.PodReasonUnschedulable}},
+		},
+	})
+	events := &fakeEvents{events: []v1.Event{
+		schedulingEvent("bundle-1234", failedSchedulingReason, "0/1 nodes are available: 1 node(s) had taints that the pod didn't tolerate.", time.Now()),
+	}}
+	phase, err := waitForPodCompletion(pods, "bundle-1234", 0, unschedulableCheck(events, time.Minute), nil)
+	if err == nil || !strings.Contains(err.Error(), "could not be scheduled") {
+		t.Fatalf("expected the wait to fail fast, got [%v]", err)
+	}
+	if phase != v1.PodPending {
+		t.Fatalf("expected phase [%v], got [%v]", v1.PodPending, phase)
+	}
+}
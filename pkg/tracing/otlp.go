@@ -0,0 +1,247 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package tracing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultEndpoint    = "http://localhost:4318"
+	defaultServiceName = "apb"
+	defaultTimeout     = 10 * time.Second
+	scopeName          = "github.com/automationbroker/apb"
+
+	spanKindInternal = 1
+	statusCodeError  = 2
+)
+
+// OTLPTracer exports the spans of each trace to an OTLP/HTTP collector,
+// as JSON, once the trace's root span ends
+type OTLPTracer struct {
+	endpoint    string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+
+	mu       sync.Mutex
+	finished map[string][]otlpSpan
+}
+
+// NewOTLPTracer returns a tracer configured by the standard OTLP exporter
+// environment variables: OTEL_EXPORTER_OTLP_TRACES_ENDPOINT (or
+// OTEL_EXPORTER_OTLP_ENDPOINT, with /v1/traces appended), the _HEADERS and
+// _TIMEOUT variants of either, and OTEL_SERVICE_NAME.
+func NewOTLPTracer() (*OTLPTracer, error) {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if base == "" {
+			base = defaultEndpoint
+		}
+		endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+	headers, err := parseHeaders(otlpEnv("HEADERS"))
+	if err != nil {
+		return nil, err
+	}
+	timeout := defaultTimeout
+	if ms := otlpEnv("TIMEOUT"); ms != "" {
+		n, err := strconv.Atoi(ms)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid OTLP timeout [%v], expected milliseconds", ms)
+		}
+		timeout = time.Duration(n) * time.Millisecond
+	}
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	return &OTLPTracer{
+		endpoint:    endpoint,
+		headers:     headers,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: timeout},
+		finished:    map[string][]otlpSpan{},
+	}, nil
+}
+
+// otlpEnv returns the traces specific variable with the suffix, falling
+// back to the one shared by all signals
+func otlpEnv(suffix string) string {
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_" + suffix); v != "" {
+		return v
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_" + suffix)
+}
+
+// parseHeaders parses a comma separated list of key=value pairs
+func parseHeaders(s string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid OTLP header [%v], expected key=value", pair)
+		}
+		headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return headers, nil
+}
+
+// Start starts a span, in a new trace when parent is nil or not a span of
+// this tracer
+func (t *OTLPTracer) Start(name string, parent Span) Span {
+	s := &span{
+		tracer: t,
+		data: otlpSpan{
+			SpanID:            randomID(8),
+			Name:              name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		},
+	}
+	if p, ok := parent.(*span); ok {
+		s.data.TraceID = p.data.TraceID
+		s.data.ParentSpanID = p.data.SpanID
+	} else {
+		s.data.TraceID = randomID(16)
+		s.root = true
+	}
+	return s
+}
+
+func (t *OTLPTracer) finish(s *span) {
+	t.mu.Lock()
+	spans := append(t.finished[s.data.TraceID], s.data)
+	if !s.root {
+		t.finished[s.data.TraceID] = spans
+		t.mu.Unlock()
+		return
+	}
+	delete(t.finished, s.data.TraceID)
+	t.mu.Unlock()
+	if err := t.export(spans); err != nil {
+		log.Warningf("Failed to export trace [%v]: %v", s.data.TraceID, err)
+	}
+}
+
+func (t *OTLPTracer) export(spans []otlpSpan) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": []otlpAttribute{stringAttribute("service.name", t.serviceName)},
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]string{"name": scopeName},
+						"spans": spans,
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector at [%v] returned [%v]", t.endpoint, resp.Status)
+	}
+	return nil
+}
+
+type span struct {
+	tracer *OTLPTracer
+	root   bool
+	once   sync.Once
+	data   otlpSpan
+}
+
+func (s *span) SetAttribute(key string, value string) {
+	s.data.Attributes = append(s.data.Attributes, stringAttribute(key, value))
+}
+
+func (s *span) End(err error) {
+	s.once.Do(func() {
+		s.data.EndTimeUnixNano = strconv.FormatInt(time.Now().UnixNano(), 10)
+		if err != nil {
+			s.data.Status = &otlpStatus{Code: statusCodeError, Message: err.Error()}
+		}
+		s.tracer.finish(s)
+	})
+}
+
+// otlpSpan is a span in the JSON encoding of the OTLP protocol
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func stringAttribute(key string, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: map[string]string{"stringValue": value}}
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
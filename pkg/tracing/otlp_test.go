@@ -0,0 +1,161 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+type exportRequest struct {
+	ResourceSpans []struct {
+		Resource struct {
+			Attributes []otlpAttribute `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []struct {
+			Spans []otlpSpan `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+func TestOTLPTracer(t *testing.T) {
+	var exports []exportRequest
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got unexpected request [%v] [%v]", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		authorization = r.Header.Get("Authorization")
+		var req exportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("got unexpected error [%v]", err)
+		}
+		exports = append(exports, req)
+	}))
+	defer server.Close()
+
+	setEnv(t, "OTEL_EXPORTER_OTLP_ENDPOINT", server.URL+"/")
+	setEnv(t, "OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer abc")
+	setEnv(t, "OTEL_SERVICE_NAME", "apb-test")
+	tracer, err := NewOTLPTracer()
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+
+	run := tracer.Start("apb.run", nil)
+	run.SetAttribute("apb.bundle", "hello-apb")
+	stage := tracer.Start("apb.wait", run)
+	stage.End(errors.New("pod [bundle-1234] failed"))
+	if len(exports) != 0 {
+		t.Fatalf("expected nothing to be exported before the run ends")
+	}
+	run.End(nil)
+	run.End(nil)
+
+	if len(exports) != 1 {
+		t.Fatalf("expected one export, got %v", len(exports))
+	}
+	if authorization != "Bearer abc" {
+		t.Fatalf("expected headers from the environment, got [%v]", authorization)
+	}
+	rs := exports[0].ResourceSpans[0]
+	if rs.Resource.Attributes[0].Value["stringValue"] != "apb-test" {
+		t.Fatalf("got unexpected resource %v", rs.Resource.Attributes)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %v", spans)
+	}
+	wait, root := spans[0], spans[1]
+	if root.Name != "apb.run" || root.ParentSpanID != "" || len(root.TraceID) != 32 || len(root.SpanID) != 16 {
+		t.Fatalf("got unexpected root span %+v", root)
+	}
+	if root.Attributes[0].Key != "apb.bundle" || root.Status != nil {
+		t.Fatalf("got unexpected root span %+v", root)
+	}
+	if wait.TraceID != root.TraceID || wait.ParentSpanID != root.SpanID {
+		t.Fatalf("expected [%v] to be a child of the run, got %+v", wait.Name, wait)
+	}
+	if wait.Status == nil || wait.Status.Code != statusCodeError || wait.Status.Message != "pod [bundle-1234] failed" {
+		t.Fatalf("expected failed status, got %+v", wait.Status)
+	}
+}
+
+func TestOTLPTracerConfig(t *testing.T) {
+	testCases := []struct {
+		name       string
+		env        map[string]string
+		endpoint   string
+		shouldFail bool
+	}{
+		{
+			name:     "test default endpoint",
+			endpoint: "http://localhost:4318/v1/traces",
+		},
+		{
+			name: "test traces endpoint",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT":        "http://collector:4318",
+				"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://traces:4318/custom",
+			},
+			endpoint: "http://traces:4318/custom",
+		},
+		{
+			name:       "test invalid headers",
+			env:        map[string]string{"OTEL_EXPORTER_OTLP_HEADERS": "Authorization"},
+			shouldFail: true,
+		},
+		{
+			name:       "test invalid timeout",
+			env:        map[string]string{"OTEL_EXPORTER_OTLP_TRACES_TIMEOUT": "5s"},
+			shouldFail: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, key := range []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TRACES_TIMEOUT"} {
+				setEnv(t, key, tc.env[key])
+			}
+			tracer, err := NewOTLPTracer()
+			if err != nil {
+				if !tc.shouldFail {
+					t.Fatalf("got unexpected error [%v]", err)
+				}
+				return
+			}
+			if tc.shouldFail {
+				t.Fatalf("expected configuration to fail")
+			}
+			if tracer.endpoint != tc.endpoint {
+				t.Fatalf("expected endpoint [%v], got [%v]", tc.endpoint, tracer.endpoint)
+			}
+		})
+	}
+}
+
+func TestNoop(t *testing.T) {
+	span := Noop.Start("apb.run", nil)
+	span.SetAttribute("apb.bundle", "hello-apb")
+	span.End(nil)
+	if _, ok := Noop.Start("apb.wait", span).(noopSpan); !ok {
+		t.Fatalf("expected a noop span")
+	}
+}
+
+// setEnv sets the environment variable for the rest of the test
+func setEnv(t *testing.T, key string, value string) {
+	old, existed := os.LookupEnv(key)
+	os.Setenv(key, value)
+	if value == "" {
+		os.Unsetenv(key)
+	}
+	t.Cleanup(func() {
+		if existed {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package tracing records the stages of a bundle run as spans
+package tracing
+
+// Tracer starts spans. A span without a parent starts a new trace.
+type Tracer interface {
+	Start(name string, parent Span) Span
+}
+
+// Span is a timed stage of a run
+type Span interface {
+	SetAttribute(key string, value string)
+	// End finishes the span, marking it failed when err is not nil
+	End(err error)
+}
+
+// Noop is a Tracer which records nothing
+var Noop Tracer = noopTracer{}
+
+type noopTracer struct{}
+
+type noopSpan struct{}
+
+func (noopTracer) Start(name string, parent Span) Span {
+	return noopSpan{}
+}
+
+func (noopSpan) SetAttribute(key string, value string) {}
+
+func (noopSpan) End(err error) {}
@@ -0,0 +1,66 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package util
+
+import (
+	"sync"
+
+	"github.com/automationbroker/apb/pkg/version"
+	"github.com/automationbroker/bundle-lib/clients"
+	"k8s.io/client-go/kubernetes"
+)
+
+// UserAgent is sent with the requests of the Kubernetes client, so they can
+// be told apart in the API server's audit logs
+var UserAgent = DefaultUserAgent()
+
+var userAgentOnce sync.Once
+
+// DefaultUserAgent returns the user agent naming the apb version
+func DefaultUserAgent() string {
+	return "apb/" + version.Version
+}
+
+// KubernetesClient returns the shared bundle-lib Kubernetes client. The
+// first call sets UserAgent on it, which every later caller, including the
+// bundle-lib runtime, then sends.
+func KubernetesClient() (*clients.KubernetesClient, error) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
+	}
+	userAgentOnce.Do(func() {
+		err = setUserAgent(k8scli, UserAgent)
+	})
+	return k8scli, err
+}
+
+// setUserAgent sets the user agent on the client's config and recreates its
+// clientset from it. bundle-lib builds the config itself, so this is the
+// only place to set it.
+func setUserAgent(k8scli *clients.KubernetesClient, userAgent string) error {
+	if userAgent == "" || k8scli.ClientConfig == nil {
+		return nil
+	}
+	k8scli.ClientConfig.UserAgent = userAgent
+	client, err := kubernetes.NewForConfig(k8scli.ClientConfig)
+	if err != nil {
+		return err
+	}
+	k8scli.Client = client
+	return nil
+}
@@ -0,0 +1,48 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestSetUserAgent(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.UserAgent()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+	}))
+	defer server.Close()
+
+	config := &rest.Config{Host: server.URL}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	k8scli := &clients.KubernetesClient{Client: client, ClientConfig: config}
+	if err := setUserAgent(k8scli, DefaultUserAgent()); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if k8scli.ClientConfig.UserAgent != DefaultUserAgent() {
+		t.Fatalf("expected user agent [%v] on the config, got [%v]", DefaultUserAgent(), k8scli.ClientConfig.UserAgent)
+	}
+	k8scli.Client.CoreV1().Namespaces().Get("apb", metav1.GetOptions{})
+	if received != DefaultUserAgent() {
+		t.Fatalf("expected requests with user agent [%v], got [%v]", DefaultUserAgent(), received)
+	}
+
+	if err := setUserAgent(k8scli, "pipeline/42"); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	k8scli.Client.CoreV1().Namespaces().Get("apb", metav1.GetOptions{})
+	if received != "pipeline/42" {
+		t.Fatalf("expected requests with user agent [pipeline/42], got [%v]", received)
+	}
+}
@@ -0,0 +1,40 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package util
+
+import (
+	"os"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// NoColor turns off colored output, whatever the terminal supports
+var NoColor bool
+
+// Color reports whether output written to f is colored. It is only for a
+// terminal, and never with NoColor or a NO_COLOR environment variable set.
+func Color(f *os.File) bool {
+	return colorEnabled(NoColor, os.Getenv("NO_COLOR"), terminal.IsTerminal(int(f.Fd())))
+}
+
+// colorEnabled decides whether to color output, given the --no-color flag,
+// the value of NO_COLOR and whether the output is a terminal. NO_COLOR turns
+// color off when set to anything but an empty string, see
+// https://no-color.org.
+func colorEnabled(noColor bool, noColorEnv string, isTerminal bool) bool {
+	return !noColor && noColorEnv == "" && isTerminal
+}
@@ -0,0 +1,27 @@
+package util
+
+import "testing"
+
+func TestColorEnabled(t *testing.T) {
+	testCases := []struct {
+		name       string
+		noColor    bool
+		noColorEnv string
+		isTerminal bool
+		expected   bool
+	}{
+		{name: "test terminal", isTerminal: true, expected: true},
+		{name: "test pipe", isTerminal: false, expected: false},
+		{name: "test no-color flag", noColor: true, isTerminal: true, expected: false},
+		{name: "test NO_COLOR set", noColorEnv: "1", isTerminal: true, expected: false},
+		{name: "test NO_COLOR empty", noColorEnv: "", isTerminal: true, expected: true},
+		{name: "test NO_COLOR on a pipe", noColorEnv: "true", isTerminal: false, expected: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if enabled := colorEnabled(tc.noColor, tc.noColorEnv, tc.isTerminal); enabled != tc.expected {
+				t.Fatalf("expected color [%v], got [%v]", tc.expected, enabled)
+			}
+		})
+	}
+}
@@ -17,12 +17,31 @@
 package util
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/automationbroker/bundle-lib/clients"
+	"github.com/ghodss/yaml"
 	log "github.com/sirupsen/logrus"
+	authv1 "k8s.io/api/authentication/v1"
+	authclient "k8s.io/client-go/kubernetes/typed/authentication/v1"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/tools/clientcmd/api/latest"
 )
 
+// ClusterInfo describes the cluster and user a kubeconfig points at
+type ClusterInfo struct {
+	Context   string
+	Server    string
+	User      string
+	Namespace string
+}
+
 // GetCurrentNamespace returns the current OpenShift namespace or an empty string
 func GetCurrentNamespace(configPath string) string {
 	if configPath == "" {
@@ -39,3 +58,138 @@ func GetCurrentNamespace(configPath string) string {
 	}
 	return strings.Split(config.CurrentContext, "/")[0]
 }
+
+// GetContextNamespace returns the namespace of a kubeconfig context, or an
+// empty string
+func GetContextNamespace(configPath string, context string) string {
+	config, err := ContextConfig(configPath, context)
+	if err != nil {
+		log.Errorf("Error loading context [%v]: %v", context, err)
+		return ""
+	}
+	if ns := config.Contexts[context].Namespace; ns != "" {
+		return ns
+	}
+	if len(strings.Split(context, "/")) < 3 {
+		return ""
+	}
+	return strings.Split(context, "/")[0]
+}
+
+// ContextConfig returns a self-contained kubeconfig holding only the
+// context, which is made current
+func ContextConfig(configPath string, context string) (*clientcmdapi.Config, error) {
+	if configPath == "" {
+		configPath = clientcmd.RecommendedHomeFile
+	}
+	config, err := clientcmd.LoadFromFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if context == "" {
+		context = config.CurrentContext
+	}
+	if _, ok := config.Contexts[context]; !ok {
+		return nil, fmt.Errorf("context [%v] not found in kubeconfig [%v]", context, configPath)
+	}
+	config.CurrentContext = context
+	if err := clientcmdapi.MinifyConfig(config); err != nil {
+		return nil, err
+	}
+	if err := clientcmdapi.FlattenConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// connectClients creates the bundle-lib clients, which are shared by every
+// later caller
+var connectClients = func() error {
+	if _, err := KubernetesClient(); err != nil {
+		return err
+	}
+	_, err := clients.Openshift()
+	return err
+}
+
+// UseCluster connects the clients to a kubeconfig context. The bundle-lib
+// clients only read ~/.kube/config, so they are created while HOME points
+// at a directory holding the context's kubeconfig, and HOME is restored
+// once they are.
+func UseCluster(configPath string, context string) error {
+	config, err := ContextConfig(configPath, context)
+	if err != nil {
+		return err
+	}
+	home, err := ioutil.TempDir("", "apb-cluster")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(home)
+	if err := writeKubeconfig(config, filepath.Join(home, ".kube", "config")); err != nil {
+		return err
+	}
+
+	origHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", origHome)
+	if err := os.Setenv("HOME", home); err != nil {
+		return err
+	}
+	return connectClients()
+}
+
+// writeKubeconfig writes the kubeconfig as versioned YAML
+func writeKubeconfig(config *clientcmdapi.Config, path string) error {
+	versioned, err := latest.Scheme.ConvertToVersion(config, latest.ExternalVersion)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(versioned)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// GetClusterInfo returns the current context, its server and user, and the
+// namespace resolved from a kubeconfig
+func GetClusterInfo(configPath string) (*ClusterInfo, error) {
+	if configPath == "" {
+		configPath = clientcmd.RecommendedHomeFile
+	}
+	config, err := clientcmd.LoadFromFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	info := &ClusterInfo{
+		Context:   config.CurrentContext,
+		Namespace: GetCurrentNamespace(configPath),
+	}
+	if context, ok := config.Contexts[config.CurrentContext]; ok {
+		info.User = context.AuthInfo
+		if cluster, ok := config.Clusters[context.Cluster]; ok {
+			info.Server = cluster.Server
+		}
+	}
+	return info, nil
+}
+
+// GetAuthenticatedUser asks the API server which user a bearer token belongs to
+func GetAuthenticatedUser(reviews authclient.TokenReviewInterface, token string) (string, error) {
+	review, err := reviews.Create(&authv1.TokenReview{
+		Spec: authv1.TokenReviewSpec{Token: token},
+	})
+	if err != nil {
+		return "", err
+	}
+	if !review.Status.Authenticated {
+		if review.Status.Error != "" {
+			return "", errors.New(review.Status.Error)
+		}
+		return "", errors.New("token is not authenticated")
+	}
+	return review.Status.User.Username, nil
+}
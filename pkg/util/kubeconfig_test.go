@@ -1,7 +1,12 @@
 package util
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+
+	authv1 "k8s.io/api/authentication/v1"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 func TestGetCurrentNamespace(t *testing.T) {
@@ -38,3 +43,142 @@ func TestGetCurrentNamespace(t *testing.T) {
 		})
 	}
 }
+
+func TestGetClusterInfo(t *testing.T) {
+	info, err := GetClusterInfo("testdata/config")
+	if err != nil {
+		t.Fatalf("unexpected error loading cluster info: %v", err)
+	}
+	expected := ClusterInfo{
+		Context:   "foo-ns/foo.example.com:443/leto",
+		Server:    "https://172.17.0.1:8443",
+		User:      "admin/172-17-0-1:8443",
+		Namespace: "foo-ns",
+	}
+	if *info != expected {
+		t.Fatalf("expected cluster info %+v, got %+v", expected, *info)
+	}
+	if _, err := GetClusterInfo("testdata/path/that/doesnt/exist"); err == nil {
+		t.Fatalf("expected error loading missing kubeconfig")
+	}
+}
+
+type fakeTokenReviews struct {
+	users map[string]string
+}
+
+func (f *fakeTokenReviews) Create(review *authv1.TokenReview) (*authv1.TokenReview, error) {
+	user, ok := f.users[review.Spec.Token]
+	review.Status.Authenticated = ok
+	review.Status.User.Username = user
+	return review, nil
+}
+
+func TestGetAuthenticatedUser(t *testing.T) {
+	reviews := &fakeTokenReviews{users: map[string]string{"good-token": "developer"}}
+	testCases := []struct {
+		name      string
+		token     string
+		user      string
+		shouldErr bool
+	}{
+		{
+			name:  "authenticated token",
+			token: "good-token",
+			user:  "developer",
+		},
+		{
+			name:      "unauthenticated token",
+			token:     "bad-token",
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			user, err := GetAuthenticatedUser(reviews, tc.token)
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("got unexpected error [%v]", err)
+				return
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("expected error but got user [%v]", user)
+				return
+			}
+			if user != tc.user {
+				t.Fatalf("expected user [%v], got [%v]", tc.user, user)
+			}
+		})
+	}
+}
+
+func TestContextConfig(t *testing.T) {
+	testCases := []struct {
+		name      string
+		context   string
+		expected  string
+		shouldErr bool
+	}{
+		{
+			name:     "current context",
+			expected: "foo-ns/foo.example.com:443/leto",
+		},
+		{
+			name:     "named context",
+			context:  "foo-ns/foo.example.com:443/leto",
+			expected: "foo-ns/foo.example.com:443/leto",
+		},
+		{
+			name:      "undefined context",
+			context:   "prod",
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := ContextConfig("testdata/config", tc.context)
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("got unexpected error [%v]", err)
+				return
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("expected error but got context [%v]", config.CurrentContext)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if config.CurrentContext != tc.expected || len(config.Contexts) != 1 {
+				t.Fatalf("expected only context [%v], got %v", tc.expected, config.Contexts)
+			}
+		})
+	}
+	if ns := GetContextNamespace("testdata/config", "foo-ns/foo.example.com:443/leto"); ns != "foo-ns" {
+		t.Fatalf("expected namespace [foo-ns], got [%v]", ns)
+	}
+}
+
+func TestUseCluster(t *testing.T) {
+	defer func(orig func() error) { connectClients = orig }(connectClients)
+	home := os.Getenv("HOME")
+	var context string
+	connectClients = func() error {
+		config, err := clientcmd.LoadFromFile(filepath.Join(os.Getenv("HOME"), ".kube", "config"))
+		if err != nil {
+			return err
+		}
+		context = config.CurrentContext
+		return nil
+	}
+	if err := UseCluster("testdata/config", "foo-ns/foo.example.com:443/leto"); err != nil {
+		t.Fatalf("got unexpected error [%v]", err)
+	}
+	if context != "foo-ns/foo.example.com:443/leto" {
+		t.Fatalf("expected clients to connect to context [foo-ns/foo.example.com:443/leto], got [%v]", context)
+	}
+	if os.Getenv("HOME") != home {
+		t.Fatalf("expected HOME to be restored to [%v], got [%v]", home, os.Getenv("HOME"))
+	}
+	if err := UseCluster("testdata/config", "prod"); err == nil {
+		t.Fatalf("expected error for an undefined context")
+	}
+}